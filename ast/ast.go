@@ -0,0 +1,216 @@
+// Package ast declares the types used to represent the syntax tree of expressions.
+//
+// Trees are created by goval.Parse, but can also be constructed (or modified) manually,
+// for example by rule editors. They can be converted to JSON and back, and into source text.
+package ast
+
+// Node is an element of the abstract syntax tree that is created by the parser.
+//
+// Positions are 1-based byte offsets within the expression string,
+// the same way they are reported within error messages.
+// They are 0 within trees that were constructed manually.
+type Node interface {
+	Pos() int // position of the first character belonging to the node
+	End() int // position of the first character immediately after the node
+}
+
+// Program is a parsed expression.
+type Program struct {
+	Root     Node
+	Comments []Comment
+}
+
+// Comment is a `// line` or `/* general */` comment found within an expression.
+// Comments do not influence the result, but are kept so that tooling (formatters, ...) can restore them.
+type Comment struct {
+	Pos  int    `json:"pos"`  // position of the leading '/', same counting as within error messages
+	Text string `json:"text"` // comment text, including the comment markers
+}
+
+// Literal is a nil, bool, number or string literal.
+type Literal struct {
+	ValuePos int
+	Raw      string      // literal as written within the source, for example `0xFF` or `"te\"xt"`; can be empty
+	Value    interface{} // nil, bool, int, float64 or string
+}
+
+// InterpolatedString is a string literal with embedded expressions, like f"Hello {name}".
+type InterpolatedString struct {
+	ValuePos int
+	Raw      string // literal as written within the source, including the leading 'f'; can be empty
+	Parts    []Node // *Literal nodes for the text in between embedded expressions
+}
+
+// ArrayLit is an array literal `[a, b, ...c]`.
+type ArrayLit struct {
+	Lbrack int
+	Elems  []Node // can contain *Spread nodes
+	Rbrack int
+}
+
+// ObjectLit is an object literal `{"a": b, ...c}`.
+type ObjectLit struct {
+	Lbrace  int
+	Members []Node // *KeyValue or *Spread nodes
+	Rbrace  int
+}
+
+// KeyValue is a single member of an object literal.
+type KeyValue struct {
+	Key   Node
+	Colon int
+	Value Node
+}
+
+// Spread is the spread operator `...x` within array literals, object literals or function arguments.
+type Spread struct {
+	Ellipsis int
+	X        Node
+}
+
+// Ident is a variable access.
+type Ident struct {
+	NamePos int
+	Name    string
+}
+
+// UnaryExpr is a unary operation `-x`, `!x` or `~x`.
+type UnaryExpr struct {
+	OpPos int
+	Op    string
+	X     Node
+}
+
+// BinaryExpr is a binary operation like `x + y`, `x && y` or `x in y`.
+type BinaryExpr struct {
+	X     Node
+	OpPos int
+	Op    string
+	Y     Node
+}
+
+// TernaryExpr is the conditional operator `cond ? then : else`.
+type TernaryExpr struct {
+	Cond     Node
+	Question int
+	Then     Node
+	Colon    int
+	Else     Node
+}
+
+// ParenExpr is a parenthesized expression.
+type ParenExpr struct {
+	Lparen int
+	X      Node
+	Rparen int
+}
+
+// CallExpr is a function call.
+type CallExpr struct {
+	Func   *Ident
+	Lparen int
+	Args   []Node // can contain *Spread nodes
+	Rparen int
+}
+
+// PipeExpr is the pipe operator `x |> call(args)`, which calls the function with x as its first argument.
+type PipeExpr struct {
+	X     Node
+	OpPos int
+	Call  *CallExpr
+}
+
+// SelectorExpr is a field access `x.sel`.
+type SelectorExpr struct {
+	X   Node
+	Sel *Ident
+}
+
+// IndexExpr is an index or field access `x[index]`.
+type IndexExpr struct {
+	X      Node
+	Lbrack int
+	Index  Node
+	Rbrack int
+}
+
+// SliceExpr is a slice operation `x[low:high]`. Low and High are optional.
+type SliceExpr struct {
+	X      Node
+	Lbrack int
+	Low    Node
+	High   Node
+	Rbrack int
+}
+
+// ForClause is the `for a, b in x if cond` part of comprehensions.
+type ForClause struct {
+	For  int
+	Vars []*Ident // one or two loop variables
+	In   int
+	X    Node
+	If   int  // 0 if there is no condition
+	Cond Node // optional
+}
+
+// ArrayComp is an array comprehension `[elem for x in arr if cond]`.
+type ArrayComp struct {
+	Lbrack int
+	Elem   Node
+	Clause *ForClause
+	Rbrack int
+}
+
+// ObjectComp is an object comprehension `{key: value for k, v in obj if cond}`.
+type ObjectComp struct {
+	Lbrace int
+	Key    Node
+	Value  Node
+	Clause *ForClause
+	Rbrace int
+}
+
+func (n *Literal) Pos() int            { return n.ValuePos }
+func (n *InterpolatedString) Pos() int { return n.ValuePos }
+func (n *ArrayLit) Pos() int           { return n.Lbrack }
+func (n *ObjectLit) Pos() int          { return n.Lbrace }
+func (n *KeyValue) Pos() int           { return n.Key.Pos() }
+func (n *Spread) Pos() int             { return n.Ellipsis }
+func (n *Ident) Pos() int              { return n.NamePos }
+func (n *UnaryExpr) Pos() int          { return n.OpPos }
+func (n *BinaryExpr) Pos() int         { return n.X.Pos() }
+func (n *TernaryExpr) Pos() int        { return n.Cond.Pos() }
+func (n *ParenExpr) Pos() int          { return n.Lparen }
+func (n *CallExpr) Pos() int           { return n.Func.Pos() }
+func (n *PipeExpr) Pos() int           { return n.X.Pos() }
+func (n *SelectorExpr) Pos() int       { return n.X.Pos() }
+func (n *IndexExpr) Pos() int          { return n.X.Pos() }
+func (n *SliceExpr) Pos() int          { return n.X.Pos() }
+func (n *ForClause) Pos() int          { return n.For }
+func (n *ArrayComp) Pos() int          { return n.Lbrack }
+func (n *ObjectComp) Pos() int         { return n.Lbrace }
+
+func (n *Literal) End() int            { return n.ValuePos + len(n.Raw) }
+func (n *InterpolatedString) End() int { return n.ValuePos + len(n.Raw) }
+func (n *ArrayLit) End() int           { return n.Rbrack + 1 }
+func (n *ObjectLit) End() int          { return n.Rbrace + 1 }
+func (n *KeyValue) End() int           { return n.Value.End() }
+func (n *Spread) End() int             { return n.X.End() }
+func (n *Ident) End() int              { return n.NamePos + len(n.Name) }
+func (n *UnaryExpr) End() int          { return n.X.End() }
+func (n *BinaryExpr) End() int         { return n.Y.End() }
+func (n *TernaryExpr) End() int        { return n.Else.End() }
+func (n *ParenExpr) End() int          { return n.Rparen + 1 }
+func (n *CallExpr) End() int           { return n.Rparen + 1 }
+func (n *PipeExpr) End() int           { return n.Call.End() }
+func (n *SelectorExpr) End() int       { return n.Sel.End() }
+func (n *IndexExpr) End() int          { return n.Rbrack + 1 }
+func (n *SliceExpr) End() int          { return n.Rbrack + 1 }
+func (n *ArrayComp) End() int          { return n.Rbrack + 1 }
+func (n *ObjectComp) End() int         { return n.Rbrace + 1 }
+func (n *ForClause) End() int {
+	if n.Cond != nil {
+		return n.Cond.End()
+	}
+	return n.X.End()
+}
@@ -0,0 +1,626 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// JSON encoding
+//
+// Each node is encoded as JSON object with a "type" member that contains the name of the node type, like "BinaryExpr".
+// All other members are named after the struct fields in lowerCamelCase.
+// Positions that are 0, nil nodes and empty raw literals are omitted.
+// Literal values keep their type: floats always contain a decimal point or exponent, integers never do.
+//
+// Nodes are encoded with json.Marshal, and decoded with UnmarshalNode.
+// Decoding validates the tree structure, operators and identifiers. Unknown members are ignored.
+// Values of literals with a raw text are derived from it, since JSON cannot represent all strings, like invalid UTF-8.
+
+// position is a position within the source. It is omitted if it is 0.
+type position int
+
+// field is a single member of a JSON-encoded node.
+type field struct {
+	name  string
+	value interface{}
+}
+
+// marshalNode encodes a node with the given type and fields, in the given order.
+func marshalNode(typ string, fields ...field) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":`)
+	buf.WriteString(strconv.Quote(typ))
+	for _, f := range fields {
+		if f.value == nil || f.value == position(0) || f.value == "" {
+			continue
+		}
+		data, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`,"` + f.name + `":`)
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalJSON encodes the literal. Floats are encoded with a decimal point or exponent, so that they are not decoded as integers.
+func (n *Literal) MarshalJSON() ([]byte, error) {
+	var value interface{}
+	switch v := n.Value.(type) {
+	case nil:
+	case bool:
+		value = json.RawMessage(strconv.FormatBool(v))
+	case string:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		value = json.RawMessage(data)
+	case int:
+		value = json.RawMessage(strconv.Itoa(v))
+	case float64:
+		str, err := formatFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		value = json.RawMessage(str)
+	default:
+		return nil, fmt.Errorf("ast error: invalid literal value of type %T", n.Value)
+	}
+	return marshalNode("Literal",
+		field{"valuePos", position(n.ValuePos)},
+		field{"raw", n.Raw},
+		field{"value", value},
+	)
+}
+
+func (n *InterpolatedString) MarshalJSON() ([]byte, error) {
+	return marshalNode("InterpolatedString",
+		field{"valuePos", position(n.ValuePos)},
+		field{"raw", n.Raw},
+		field{"parts", nodeList(n.Parts)},
+	)
+}
+
+func (n *ArrayLit) MarshalJSON() ([]byte, error) {
+	return marshalNode("ArrayLit",
+		field{"lbrack", position(n.Lbrack)},
+		field{"elems", nodeList(n.Elems)},
+		field{"rbrack", position(n.Rbrack)},
+	)
+}
+
+func (n *ObjectLit) MarshalJSON() ([]byte, error) {
+	return marshalNode("ObjectLit",
+		field{"lbrace", position(n.Lbrace)},
+		field{"members", nodeList(n.Members)},
+		field{"rbrace", position(n.Rbrace)},
+	)
+}
+
+func (n *KeyValue) MarshalJSON() ([]byte, error) {
+	return marshalNode("KeyValue",
+		field{"key", n.Key},
+		field{"colon", position(n.Colon)},
+		field{"value", n.Value},
+	)
+}
+
+func (n *Spread) MarshalJSON() ([]byte, error) {
+	return marshalNode("Spread",
+		field{"ellipsis", position(n.Ellipsis)},
+		field{"x", n.X},
+	)
+}
+
+func (n *Ident) MarshalJSON() ([]byte, error) {
+	return marshalNode("Ident",
+		field{"namePos", position(n.NamePos)},
+		field{"name", n.Name},
+	)
+}
+
+func (n *UnaryExpr) MarshalJSON() ([]byte, error) {
+	return marshalNode("UnaryExpr",
+		field{"opPos", position(n.OpPos)},
+		field{"op", n.Op},
+		field{"x", n.X},
+	)
+}
+
+func (n *BinaryExpr) MarshalJSON() ([]byte, error) {
+	return marshalNode("BinaryExpr",
+		field{"x", n.X},
+		field{"opPos", position(n.OpPos)},
+		field{"op", n.Op},
+		field{"y", n.Y},
+	)
+}
+
+func (n *TernaryExpr) MarshalJSON() ([]byte, error) {
+	return marshalNode("TernaryExpr",
+		field{"cond", n.Cond},
+		field{"question", position(n.Question)},
+		field{"then", n.Then},
+		field{"colon", position(n.Colon)},
+		field{"else", n.Else},
+	)
+}
+
+func (n *ParenExpr) MarshalJSON() ([]byte, error) {
+	return marshalNode("ParenExpr",
+		field{"lparen", position(n.Lparen)},
+		field{"x", n.X},
+		field{"rparen", position(n.Rparen)},
+	)
+}
+
+func (n *CallExpr) MarshalJSON() ([]byte, error) {
+	return marshalNode("CallExpr",
+		field{"func", n.Func},
+		field{"lparen", position(n.Lparen)},
+		field{"args", nodeList(n.Args)},
+		field{"rparen", position(n.Rparen)},
+	)
+}
+
+func (n *PipeExpr) MarshalJSON() ([]byte, error) {
+	return marshalNode("PipeExpr",
+		field{"x", n.X},
+		field{"opPos", position(n.OpPos)},
+		field{"call", n.Call},
+	)
+}
+
+func (n *SelectorExpr) MarshalJSON() ([]byte, error) {
+	return marshalNode("SelectorExpr",
+		field{"x", n.X},
+		field{"sel", n.Sel},
+	)
+}
+
+func (n *IndexExpr) MarshalJSON() ([]byte, error) {
+	return marshalNode("IndexExpr",
+		field{"x", n.X},
+		field{"lbrack", position(n.Lbrack)},
+		field{"index", n.Index},
+		field{"rbrack", position(n.Rbrack)},
+	)
+}
+
+func (n *SliceExpr) MarshalJSON() ([]byte, error) {
+	return marshalNode("SliceExpr",
+		field{"x", n.X},
+		field{"lbrack", position(n.Lbrack)},
+		field{"low", n.Low},
+		field{"high", n.High},
+		field{"rbrack", position(n.Rbrack)},
+	)
+}
+
+func (n *ForClause) MarshalJSON() ([]byte, error) {
+	return marshalNode("ForClause",
+		field{"for", position(n.For)},
+		field{"vars", n.Vars},
+		field{"in", position(n.In)},
+		field{"x", n.X},
+		field{"if", position(n.If)},
+		field{"cond", n.Cond},
+	)
+}
+
+func (n *ArrayComp) MarshalJSON() ([]byte, error) {
+	return marshalNode("ArrayComp",
+		field{"lbrack", position(n.Lbrack)},
+		field{"elem", n.Elem},
+		field{"clause", n.Clause},
+		field{"rbrack", position(n.Rbrack)},
+	)
+}
+
+func (n *ObjectComp) MarshalJSON() ([]byte, error) {
+	return marshalNode("ObjectComp",
+		field{"lbrace", position(n.Lbrace)},
+		field{"key", n.Key},
+		field{"value", n.Value},
+		field{"clause", n.Clause},
+		field{"rbrace", position(n.Rbrace)},
+	)
+}
+
+// nodeList makes sure that lists are always encoded as arrays, even if they are nil.
+func nodeList(nodes []Node) []Node {
+	if nodes == nil {
+		return []Node{}
+	}
+	return nodes
+}
+
+type jsonProgram struct {
+	Root     json.RawMessage `json:"root"`
+	Comments []Comment       `json:"comments,omitempty"`
+}
+
+// MarshalJSON encodes the program as JSON object with the members "root" and "comments".
+func (p *Program) MarshalJSON() ([]byte, error) {
+	root, err := json.Marshal(p.Root)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonProgram{
+		Root:     root,
+		Comments: p.Comments,
+	})
+}
+
+// UnmarshalJSON decodes a program that was encoded with MarshalJSON.
+func (p *Program) UnmarshalJSON(data []byte) error {
+	var prog jsonProgram
+	if err := json.Unmarshal(data, &prog); err != nil {
+		return fmt.Errorf("ast error: %w", err)
+	}
+	root, err := UnmarshalNode(prog.Root)
+	if err != nil {
+		return err
+	}
+	p.Root = root
+	p.Comments = prog.Comments
+	return nil
+}
+
+// UnmarshalNode decodes a JSON-encoded node, including all of its children.
+// Returns an error if the tree is incomplete or invalid.
+func UnmarshalNode(data []byte) (node Node, err error) {
+	defer recoverError(&err)
+	return decodeNode(data, "root"), nil
+}
+
+// recoverError converts panics caused by invalid trees into errors.
+// Runtime errors are bugs, and therefore not recovered.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		if _, ok := r.(runtime.Error); ok {
+			panic(r)
+		}
+		*err = r.(error)
+	}
+}
+
+// object is a JSON-encoded node that is being decoded.
+type object struct {
+	typ     string
+	members map[string]json.RawMessage
+}
+
+// isNull reports whether the JSON value is missing or null.
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
+
+// decodeNode decodes a node. name describes where the node is located within its parent.
+func decodeNode(data json.RawMessage, name string) Node {
+	return decodeObject(data, name).node()
+}
+
+// decodeObject decodes the members and type of a node.
+func decodeObject(data json.RawMessage, name string) object {
+	if isNull(data) {
+		panic(fmt.Errorf("ast error: %s is missing", name))
+	}
+	o := object{}
+	if err := json.Unmarshal(data, &o.members); err != nil {
+		panic(fmt.Errorf("ast error: %s needs to be a JSON object", name))
+	}
+	if err := json.Unmarshal(o.members["type"], &o.typ); err != nil || o.typ == "" {
+		panic(fmt.Errorf("ast error: %s has no node type", name))
+	}
+	return o
+}
+
+// node decodes the node, including all of its children.
+func (o object) node() Node {
+	switch o.typ {
+	case "Literal":
+		return o.literal(rawValue)
+	case "InterpolatedString":
+		raw := o.str("raw")
+		if len(raw) < 2 {
+			return &InterpolatedString{ValuePos: o.pos("valuePos"), Raw: raw, Parts: o.nodes("parts")}
+		}
+		return &InterpolatedString{ValuePos: o.pos("valuePos"), Raw: raw, Parts: o.parts(raw[1])}
+	case "ArrayLit":
+		return &ArrayLit{Lbrack: o.pos("lbrack"), Elems: o.nodes("elems"), Rbrack: o.pos("rbrack")}
+	case "ObjectLit":
+		members := o.nodes("members")
+		for _, m := range members {
+			switch m.(type) {
+			case *KeyValue, *Spread:
+			default:
+				panic(fmt.Errorf("ast error: ObjectLit members need to be KeyValue or Spread nodes"))
+			}
+		}
+		return &ObjectLit{Lbrace: o.pos("lbrace"), Members: members, Rbrace: o.pos("rbrace")}
+	case "KeyValue":
+		return &KeyValue{Key: o.child("key"), Colon: o.pos("colon"), Value: o.child("value")}
+	case "Spread":
+		return &Spread{Ellipsis: o.pos("ellipsis"), X: o.child("x")}
+	case "Ident":
+		name := o.name("name")
+		if !isIdentifier(name) {
+			panic(fmt.Errorf("ast error: invalid identifier %q", name))
+		}
+		return &Ident{NamePos: o.pos("namePos"), Name: name}
+	case "UnaryExpr":
+		return &UnaryExpr{OpPos: o.pos("opPos"), Op: o.op(unaryOperators), X: o.child("x")}
+	case "BinaryExpr":
+		return &BinaryExpr{X: o.child("x"), OpPos: o.pos("opPos"), Op: o.op(binaryOperators), Y: o.child("y")}
+	case "TernaryExpr":
+		return &TernaryExpr{Cond: o.child("cond"), Question: o.pos("question"), Then: o.child("then"), Colon: o.pos("colon"), Else: o.child("else")}
+	case "ParenExpr":
+		return &ParenExpr{Lparen: o.pos("lparen"), X: o.child("x"), Rparen: o.pos("rparen")}
+	case "CallExpr":
+		return &CallExpr{Func: o.ident("func"), Lparen: o.pos("lparen"), Args: o.nodes("args"), Rparen: o.pos("rparen")}
+	case "PipeExpr":
+		call, ok := o.child("call").(*CallExpr)
+		if !ok {
+			panic(fmt.Errorf("ast error: PipeExpr.call needs to be a CallExpr"))
+		}
+		return &PipeExpr{X: o.child("x"), OpPos: o.pos("opPos"), Call: call}
+	case "SelectorExpr":
+		return &SelectorExpr{X: o.child("x"), Sel: o.ident("sel")}
+	case "IndexExpr":
+		return &IndexExpr{X: o.child("x"), Lbrack: o.pos("lbrack"), Index: o.child("index"), Rbrack: o.pos("rbrack")}
+	case "SliceExpr":
+		return &SliceExpr{X: o.child("x"), Lbrack: o.pos("lbrack"), Low: o.optNode("low"), High: o.optNode("high"), Rbrack: o.pos("rbrack")}
+	case "ForClause":
+		var vars []*Ident
+		for _, v := range o.nodes("vars") {
+			ident, ok := v.(*Ident)
+			if !ok {
+				panic(fmt.Errorf("ast error: ForClause.vars need to be Ident nodes"))
+			}
+			vars = append(vars, ident)
+		}
+		if len(vars) != 1 && len(vars) != 2 {
+			panic(fmt.Errorf("ast error: ForClause needs one or two variables"))
+		}
+		return &ForClause{For: o.pos("for"), Vars: vars, In: o.pos("in"), X: o.child("x"), If: o.pos("if"), Cond: o.optNode("cond")}
+	case "ArrayComp":
+		return &ArrayComp{Lbrack: o.pos("lbrack"), Elem: o.child("elem"), Clause: o.clause(), Rbrack: o.pos("rbrack")}
+	case "ObjectComp":
+		return &ObjectComp{Lbrace: o.pos("lbrace"), Key: o.child("key"), Value: o.child("value"), Clause: o.clause(), Rbrace: o.pos("rbrace")}
+	}
+	panic(fmt.Errorf("ast error: unknown node type %q", o.typ))
+}
+
+func (o object) member(name string, v interface{}) {
+	if data := o.members[name]; !isNull(data) {
+		if err := json.Unmarshal(data, v); err != nil {
+			panic(fmt.Errorf("ast error: invalid %s.%s", o.typ, name))
+		}
+	}
+}
+
+func (o object) pos(name string) int {
+	var pos int
+	o.member(name, &pos)
+	return pos
+}
+
+func (o object) str(name string) string {
+	var str string
+	o.member(name, &str)
+	return str
+}
+
+// name returns a string that must not be empty.
+func (o object) name(name string) string {
+	str := o.str(name)
+	if str == "" {
+		panic(fmt.Errorf("ast error: %s.%s is missing", o.typ, name))
+	}
+	return str
+}
+
+func (o object) op(valid map[string]int) string {
+	op := o.name("op")
+	if _, ok := valid[op]; !ok {
+		panic(fmt.Errorf("ast error: invalid %s operator %q", o.typ, op))
+	}
+	return op
+}
+
+func (o object) child(name string) Node {
+	return decodeNode(o.members[name], o.typ+"."+name)
+}
+
+func (o object) optNode(name string) Node {
+	if isNull(o.members[name]) {
+		return nil
+	}
+	return o.child(name)
+}
+
+func (o object) nodes(name string) []Node {
+	var list []json.RawMessage
+	o.member(name, &list)
+	nodes := make([]Node, len(list))
+	for i, data := range list {
+		nodes[i] = decodeNode(data, fmt.Sprintf("%s.%s[%d]", o.typ, name, i))
+	}
+	return nodes
+}
+
+func (o object) ident(name string) *Ident {
+	ident, ok := o.child(name).(*Ident)
+	if !ok {
+		panic(fmt.Errorf("ast error: %s.%s needs to be an Ident", o.typ, name))
+	}
+	return ident
+}
+
+func (o object) clause() *ForClause {
+	clause, ok := o.child("clause").(*ForClause)
+	if !ok {
+		panic(fmt.Errorf("ast error: %s.clause needs to be a ForClause", o.typ))
+	}
+	return clause
+}
+
+// parts decodes the parts of an interpolated string.
+// The raw text of text parts is written between the given quotes.
+func (o object) parts(quote byte) []Node {
+	var list []json.RawMessage
+	o.member("parts", &list)
+	nodes := make([]Node, len(list))
+	for i, data := range list {
+		part := decodeObject(data, fmt.Sprintf("%s.parts[%d]", o.typ, i))
+		if part.typ == "Literal" {
+			nodes[i] = part.literal(func(raw string) (interface{}, bool) {
+				return textValue(raw, quote)
+			})
+		} else {
+			nodes[i] = part.node()
+		}
+	}
+	return nodes
+}
+
+// literal decodes a literal. If the raw text is given, the value is derived from it using rawValue.
+// A value that is given as well needs to match.
+func (o object) literal(rawValue func(raw string) (interface{}, bool)) *Literal {
+	lit := &Literal{ValuePos: o.pos("valuePos"), Raw: o.str("raw"), Value: o.value("value")}
+	if lit.Raw == "" {
+		return lit
+	}
+	val, ok := rawValue(lit.Raw)
+	if !ok {
+		panic(fmt.Errorf("ast error: invalid Literal.raw %q", lit.Raw))
+	}
+	if _, ok := o.members["value"]; ok && lit.Value != jsonValue(val) {
+		panic(fmt.Errorf("ast error: Literal.value does not match Literal.raw %q", lit.Raw))
+	}
+	lit.Value = val
+	return lit
+}
+
+// rawValue returns the value of a literal as written within the source, the same way the parser does.
+func rawValue(raw string) (interface{}, bool) {
+	switch raw {
+	case "nil":
+		return nil, true
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	if raw[0] == '"' || raw[0] == '`' {
+		str, err := strconv.Unquote(raw)
+		return str, err == nil
+	}
+
+	hex := strings.HasPrefix(raw, "0x") || strings.HasPrefix(raw, "0X")
+	if hex && !strings.ContainsAny(raw, "pP") {
+		i, err := strconv.ParseUint(raw, 0, strconv.IntSize)
+		return int(i), err == nil
+	}
+	if !hex && !strings.ContainsAny(raw, ".eE") {
+		i, err := strconv.ParseInt(raw, 0, strconv.IntSize)
+		return int(i), err == nil
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	return f, err == nil
+}
+
+// textValue returns the value of a text part within an interpolated string, the same way the parser does.
+// Braces are escaped by doubling them.
+func textValue(raw string, quote byte) (interface{}, bool) {
+	var sb strings.Builder
+	for {
+		end := strings.IndexAny(raw, "{}")
+		seg := raw
+		if end >= 0 {
+			seg = raw[:end]
+		}
+		if quote == '"' {
+			str, err := strconv.Unquote(`"` + seg + `"`)
+			if err != nil {
+				return nil, false
+			}
+			seg = str
+		}
+		sb.WriteString(seg)
+		if end < 0 {
+			return sb.String(), true
+		}
+		if end+1 >= len(raw) || raw[end+1] != raw[end] {
+			return nil, false
+		}
+		sb.WriteByte(raw[end])
+		raw = raw[end+2:]
+	}
+}
+
+// jsonValue returns the literal value as it is decoded from JSON.
+// Invalid UTF-8 within strings is replaced.
+func jsonValue(val interface{}) interface{} {
+	str, ok := val.(string)
+	if !ok {
+		return val
+	}
+	data, _ := json.Marshal(str)
+	_ = json.Unmarshal(data, &str)
+	return str
+}
+
+// isIdentifier reports whether the name can be parsed as identifier, like `name` or `$1`.
+func isIdentifier(name string) bool {
+	switch name {
+	case "nil", "true", "false", "in", "IN", "for", "if":
+		return false
+	}
+	if digits := strings.TrimPrefix(name, "$"); digits != name {
+		return digits != "" && strings.Trim(digits, "0123456789") == ""
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// value decodes a literal value. Numbers without decimal point or exponent are integers.
+func (o object) value(name string) interface{} {
+	data := o.members[name]
+	if isNull(data) {
+		return nil
+	}
+	var val interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&val); err != nil {
+		panic(fmt.Errorf("ast error: invalid %s.%s", o.typ, name))
+	}
+	switch v := val.(type) {
+	case bool, string:
+		return v
+	case json.Number:
+		if !strings.ContainsAny(string(v), ".eE") {
+			if i, err := strconv.ParseInt(string(v), 10, strconv.IntSize); err == nil {
+				return int(i)
+			}
+		} else if f, err := v.Float64(); err == nil {
+			return f
+		}
+		panic(fmt.Errorf("ast error: number %s is out of range", v))
+	}
+	panic(fmt.Errorf("ast error: %s.%s needs to be nil, bool, number or string", o.typ, name))
+}
@@ -0,0 +1,158 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+)
+
+// roundTrip encodes and decodes the program.
+func roundTrip(t *testing.T, program *ast.Program) *ast.Program {
+	t.Helper()
+	data, err := json.Marshal(program)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	var decoded ast.Program
+	assert.NoError(t, json.Unmarshal(data, &decoded), "json: %s", data)
+	return &decoded
+}
+
+var allNodes = []string{
+	`nil`,
+	`true || false`,
+	`0xFF + 1.50 + 1e3 + 1.0 + 42`,
+	`"te\"xt" + ` + "`raw` + ``",
+	`f"Hello {name}, {{escaped}} {1 + f` + "`{x}`" + `}!"`,
+	`[1, ...arr, [], [[]]]`,
+	`{"a": 1, b: 2, ...obj, {}: {}}`,
+	`-a + !b + ~c`,
+	`a ? b : c`,
+	`(1 + 2) * ((3))`,
+	`f() + g(1, ...args)`,
+	`x |> f() |> g(1)`,
+	`a.b[c][1:2][:3][4:][:]`,
+	`[x * 2 for x in arr if x > 1]`,
+	`{k: v for k, v in obj}`,
+	`a in [1, 2] && $1 ** 2 >= 1`,
+	`"\xFF" + "\u00e4" + 0xFFFFFFFFFFFFFFFF + 1_000 + 0x1p-2`,
+	`f"\xFF{{\n}}{x}" + f` + "`\\{{}}`",
+	`/* a */ 1 + // b
+	2`,
+}
+
+func Test_RoundTrip(t *testing.T) {
+	for _, src := range allNodes {
+		program, err := goval.Parse(src)
+		if !assert.NoError(t, err, "src: %s", src) {
+			continue
+		}
+		assert.Equal(t, program, roundTrip(t, program), "src: %s", src)
+	}
+}
+
+func Test_RoundTrip_LiteralTypes(t *testing.T) {
+	for _, val := range []interface{}{nil, false, true, 0, -5, 1.0, 0.0, 1e300, 1e-7, "", "text", "\x00\n"} {
+		program := &ast.Program{Root: &ast.Literal{Value: val}}
+		assert.Equal(t, program, roundTrip(t, program), "value: %#v", val)
+	}
+}
+
+func Test_MarshalJSON(t *testing.T) {
+	program, err := goval.Parse(`f(a) + 1.0 // one`)
+	assert.NoError(t, err)
+	data, err := json.Marshal(program)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"root": {
+			"type": "BinaryExpr",
+			"x": {
+				"type": "CallExpr",
+				"func": {"type": "Ident", "namePos": 1, "name": "f"},
+				"lparen": 2,
+				"args": [{"type": "Ident", "namePos": 3, "name": "a"}],
+				"rparen": 4
+			},
+			"opPos": 6,
+			"op": "+",
+			"y": {"type": "Literal", "valuePos": 8, "raw": "1.0", "value": 1.0}
+		},
+		"comments": [{"pos": 12, "text": "// one"}]
+	}`, string(data))
+
+	// positions and raw literals are optional:
+	data, err = json.Marshal(&ast.UnaryExpr{Op: "!", X: &ast.Literal{Value: 1.0}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"UnaryExpr","op":"!","x":{"type":"Literal","value":1.0}}`, string(data))
+
+	_, err = json.Marshal(&ast.Literal{Value: []int{}})
+	assert.Error(t, err)
+}
+
+func Test_UnmarshalNode(t *testing.T) {
+	node, err := ast.UnmarshalNode([]byte(`{
+		"type": "BinaryExpr",
+		"op": "+",
+		"x": {"type": "Ident", "name": "a", "editorId": 17},
+		"y": {"type": "Literal", "value": 2}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &ast.BinaryExpr{
+		X:  &ast.Ident{Name: "a"},
+		Op: "+",
+		Y:  &ast.Literal{Value: 2},
+	}, node)
+
+	errors := map[string]string{
+		`null`:                              "ast error: root is missing",
+		`[]`:                                "ast error: root needs to be a JSON object",
+		`{}`:                                "ast error: root has no node type",
+		`{"type": "Foo"}`:                   "ast error: unknown node type \"Foo\"",
+		`{"type": "Ident"}`:                 "ast error: Ident.name is missing",
+		`{"type": "Ident", "name": "a b"}`:  "ast error: invalid identifier \"a b\"",
+		`{"type": "Ident", "name": "true"}`: "ast error: invalid identifier \"true\"",
+		`{"type": "Ident", "name": "1a"}`:   "ast error: invalid identifier \"1a\"",
+		`{"type": "Ident", "name": "$"}`:    "ast error: invalid identifier \"$\"",
+		`{"type": "InterpolatedString", "raw": "f\"}\"", "parts": [{"type": "Literal", "raw": "}"}]}`: "ast error: invalid Literal.raw \"}\"",
+		`{"type": "Literal", "raw": "1 + 1"}`:                                               "ast error: invalid Literal.raw \"1 + 1\"",
+		`{"type": "Literal", "raw": "1", "value": 2}`:                                       "ast error: Literal.value does not match Literal.raw \"1\"",
+		`{"type": "Literal", "raw": "\"a\"", "value": 1}`:                                   "ast error: Literal.value does not match Literal.raw \"\\\"a\\\"\"",
+		`{"type": "Literal", "value": [1]}`:                                                 "ast error: Literal.value needs to be nil, bool, number or string",
+		`{"type": "Literal", "value": 1e999}`:                                               "ast error: number 1e999 is out of range",
+		`{"type": "Literal", "value": 99999999999999999999}`:                                "ast error: number 99999999999999999999 is out of range",
+		`{"type": "Literal", "valuePos": "1"}`:                                              "ast error: invalid Literal.valuePos",
+		`{"type": "UnaryExpr", "op": "+", "x": {"type": "Ident", "name": "a"}}`:             "ast error: invalid UnaryExpr operator \"+\"",
+		`{"type": "BinaryExpr", "op": "+", "x": {"type": "Ident", "name": "a"}}`:            "ast error: BinaryExpr.y is missing",
+		`{"type": "ArrayLit", "elems": [{"type": "Literal"}, null]}`:                        "ast error: ArrayLit.elems[1] is missing",
+		`{"type": "ObjectLit", "members": [{"type": "Literal"}]}`:                           "ast error: ObjectLit members need to be KeyValue or Spread nodes",
+		`{"type": "CallExpr", "func": {"type": "Literal"}}`:                                 "ast error: CallExpr.func needs to be an Ident",
+		`{"type": "PipeExpr", "x": {"type": "Literal"}, "call": {"type": "Literal"}}`:       "ast error: PipeExpr.call needs to be a CallExpr",
+		`{"type": "ArrayComp", "elem": {"type": "Literal"}, "clause": {"type": "Literal"}}`: "ast error: ArrayComp.clause needs to be a ForClause",
+		`{"type": "ForClause", "vars": [], "x": {"type": "Literal"}}`:                       "ast error: ForClause needs one or two variables",
+	}
+	for data, expected := range errors {
+		_, err := ast.UnmarshalNode([]byte(data))
+		assert.EqualError(t, err, expected, "json: %s", data)
+	}
+
+	var program ast.Program
+	assert.EqualError(t, json.Unmarshal([]byte(`{"comments": []}`), &program), "ast error: root is missing")
+}
+
+func Test_EvaluateDecoded(t *testing.T) {
+	program, err := goval.Parse(`[x * factor for x in values if x > 1]`)
+	assert.NoError(t, err)
+
+	decoded := roundTrip(t, program)
+	vars := map[string]interface{}{
+		"factor": 2,
+		"values": []interface{}{1, 2, 3},
+	}
+	result, err := goval.NewEvaluator().EvaluateAST(decoded, vars, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{4, 6}, result)
+}
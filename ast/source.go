@@ -0,0 +1,331 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Precedence levels of expressions, from lowest to highest.
+// Mirrors the operator precedence declared within the parser.
+const (
+	PrecPipe = iota + 1
+	PrecTernary
+	PrecOr
+	PrecAnd
+	PrecBitOr
+	PrecBitXor
+	PrecBitAnd
+	PrecEquality
+	PrecComparison
+	PrecShift
+	PrecAdditive
+	PrecMultiplicative
+	PrecUnary
+	PrecIn
+	PrecPostfix
+	PrecPrimary
+)
+
+var unaryOperators = map[string]int{
+	"-": PrecUnary,
+	"!": PrecUnary,
+	"~": PrecUnary,
+}
+
+var binaryOperators = map[string]int{
+	"||": PrecOr,
+	"&&": PrecAnd,
+	"|":  PrecBitOr,
+	"^":  PrecBitXor,
+	"&":  PrecBitAnd,
+	"==": PrecEquality,
+	"!=": PrecEquality,
+	"<":  PrecComparison,
+	"<=": PrecComparison,
+	">":  PrecComparison,
+	">=": PrecComparison,
+	"<<": PrecShift,
+	">>": PrecShift,
+	"+":  PrecAdditive,
+	"-":  PrecAdditive,
+	"*":  PrecMultiplicative,
+	"/":  PrecMultiplicative,
+	"%":  PrecMultiplicative,
+	"**": PrecMultiplicative,
+	"in": PrecIn,
+}
+
+// Source converts the node back into an expression string.
+//
+// Parentheses within the tree are kept, missing ones are added where the operator precedence requires them.
+// Literals are printed the way they were written, unless their raw text is empty.
+// The result is written on a single line, without comments. Use the format package for pretty-printing.
+//
+// Returns an error if the tree is incomplete or invalid.
+func Source(n Node) (src string, err error) {
+	defer recoverError(&err)
+	var sb strings.Builder
+	printNode(&sb, n)
+	return sb.String(), nil
+}
+
+// Precedence returns the precedence level of the node.
+// Operands with a lower precedence than their operator need to be wrapped in parentheses.
+// Parenthesized expressions, literals and identifiers have the highest precedence.
+func Precedence(n Node) int {
+	switch n := n.(type) {
+	case *Literal:
+		if n.Raw == "" && isNegative(n.Value) {
+			return PrecUnary // printed with a leading '-'
+		}
+	case *PipeExpr:
+		return PrecPipe
+	case *TernaryExpr:
+		return PrecTernary
+	case *BinaryExpr:
+		return binaryOperators[n.Op]
+	case *UnaryExpr:
+		return PrecUnary
+	case *SelectorExpr, *IndexExpr, *SliceExpr:
+		return PrecPostfix
+	}
+	return PrecPrimary
+}
+
+func isNegative(val interface{}) bool {
+	switch v := val.(type) {
+	case int:
+		return v < 0
+	case float64:
+		return v < 0 || (v == 0 && 1/v < 0)
+	}
+	return false
+}
+
+// printOperand prints an operand, which is wrapped in parentheses if its precedence is below minPrec.
+func printOperand(sb *strings.Builder, n Node, minPrec int) {
+	if Precedence(n) >= minPrec {
+		printNode(sb, n)
+		return
+	}
+	sb.WriteByte('(')
+	printNode(sb, n)
+	sb.WriteByte(')')
+}
+
+func printNode(sb *strings.Builder, node Node) {
+	switch n := node.(type) {
+	case nil:
+		panic(fmt.Errorf("ast error: missing node"))
+	case *Literal:
+		if n.Raw != "" {
+			sb.WriteString(n.Raw)
+		} else {
+			sb.WriteString(literalSource(n.Value))
+		}
+	case *InterpolatedString:
+		if n.Raw != "" {
+			sb.WriteString(n.Raw)
+		} else {
+			printInterpolation(sb, n.Parts)
+		}
+	case *ArrayLit:
+		printList(sb, "[", n.Elems, "]")
+	case *ObjectLit:
+		printList(sb, "{", n.Members, "}")
+	case *KeyValue:
+		printNode(sb, n.Key)
+		sb.WriteString(": ")
+		printNode(sb, n.Value)
+	case *Spread:
+		sb.WriteString("...")
+		printNode(sb, n.X)
+	case *Ident:
+		if n == nil {
+			panic(fmt.Errorf("ast error: missing identifier"))
+		}
+		sb.WriteString(n.Name)
+	case *UnaryExpr:
+		if _, ok := unaryOperators[n.Op]; !ok {
+			panic(fmt.Errorf("ast error: invalid unary operator %q", n.Op))
+		}
+		var x strings.Builder
+		printOperand(&x, n.X, PrecUnary)
+		sb.WriteString(n.Op)
+		if n.Op == "-" && strings.HasPrefix(x.String(), "-") {
+			sb.WriteByte(' ') // "--" would be a different token
+		}
+		sb.WriteString(x.String())
+	case *BinaryExpr:
+		prec, ok := binaryOperators[n.Op]
+		if !ok {
+			panic(fmt.Errorf("ast error: invalid binary operator %q", n.Op))
+		}
+		printOperand(sb, n.X, prec)
+		sb.WriteString(" " + n.Op + " ")
+		printOperand(sb, n.Y, prec+1)
+	case *TernaryExpr:
+		printOperand(sb, n.Cond, PrecTernary+1)
+		sb.WriteString(" ? ")
+		printNode(sb, n.Then)
+		sb.WriteString(" : ")
+		printOperand(sb, n.Else, PrecTernary)
+	case *ParenExpr:
+		sb.WriteByte('(')
+		printNode(sb, n.X)
+		sb.WriteByte(')')
+	case *CallExpr:
+		if n == nil {
+			panic(fmt.Errorf("ast error: missing function call"))
+		}
+		printNode(sb, n.Func)
+		printList(sb, "(", n.Args, ")")
+	case *PipeExpr:
+		printOperand(sb, n.X, PrecPipe)
+		sb.WriteString(" |> ")
+		printNode(sb, n.Call)
+	case *SelectorExpr:
+		if lit, ok := n.X.(*Literal); ok && isNumber(lit.Value) {
+			printOperand(sb, n.X, PrecPrimary+1) // "1.a" would be parsed as "1." followed by "a"
+		} else {
+			printOperand(sb, n.X, PrecPostfix)
+		}
+		sb.WriteByte('.')
+		printNode(sb, n.Sel)
+	case *IndexExpr:
+		printOperand(sb, n.X, PrecPostfix)
+		sb.WriteByte('[')
+		printNode(sb, n.Index)
+		sb.WriteByte(']')
+	case *SliceExpr:
+		printOperand(sb, n.X, PrecPostfix)
+		sb.WriteByte('[')
+		if n.Low != nil {
+			printNode(sb, n.Low)
+		}
+		sb.WriteByte(':')
+		if n.High != nil {
+			printNode(sb, n.High)
+		}
+		sb.WriteByte(']')
+	case *ForClause:
+		if n == nil {
+			panic(fmt.Errorf("ast error: missing for clause"))
+		}
+		if len(n.Vars) != 1 && len(n.Vars) != 2 {
+			panic(fmt.Errorf("ast error: for clause needs one or two variables"))
+		}
+		sb.WriteString("for ")
+		for i, v := range n.Vars {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			printNode(sb, v)
+		}
+		sb.WriteString(" in ")
+		printNode(sb, n.X)
+		if n.Cond != nil {
+			sb.WriteString(" if ")
+			printNode(sb, n.Cond)
+		}
+	case *ArrayComp:
+		sb.WriteByte('[')
+		printNode(sb, n.Elem)
+		sb.WriteByte(' ')
+		printNode(sb, n.Clause)
+		sb.WriteByte(']')
+	case *ObjectComp:
+		sb.WriteByte('{')
+		printNode(sb, n.Key)
+		sb.WriteString(": ")
+		printNode(sb, n.Value)
+		sb.WriteByte(' ')
+		printNode(sb, n.Clause)
+		sb.WriteByte('}')
+	default:
+		panic(fmt.Errorf("ast error: unsupported node %T", node))
+	}
+}
+
+func printList(sb *strings.Builder, open string, elems []Node, close string) {
+	sb.WriteString(open)
+	for i, elem := range elems {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		printNode(sb, elem)
+	}
+	sb.WriteString(close)
+}
+
+// printInterpolation creates an interpolated string from its parts.
+func printInterpolation(sb *strings.Builder, parts []Node) {
+	sb.WriteString(`f"`)
+	for _, part := range parts {
+		if lit, ok := part.(*Literal); ok {
+			if str, ok := lit.Value.(string); ok {
+				quoted := literalSource(str)
+				quoted = quoted[1 : len(quoted)-1]
+				quoted = strings.ReplaceAll(quoted, "{", "{{")
+				quoted = strings.ReplaceAll(quoted, "}", "}}")
+				sb.WriteString(quoted)
+				continue
+			}
+		}
+		var expr strings.Builder
+		printNode(&expr, part)
+		if strings.ContainsAny(expr.String(), "\"\\") {
+			panic(fmt.Errorf("ast error: embedded expressions cannot contain double-quotes or backslashes"))
+		}
+		sb.WriteByte('{')
+		if strings.HasPrefix(expr.String(), "{") {
+			sb.WriteByte(' ') // "{{" would be an escaped brace
+		}
+		sb.WriteString(expr.String())
+		sb.WriteByte('}')
+	}
+	sb.WriteByte('"')
+}
+
+func isNumber(val interface{}) bool {
+	switch val.(type) {
+	case int, float64:
+		return true
+	}
+	return false
+}
+
+// literalSource converts a literal value into source text.
+func literalSource(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		str, err := formatFloat(v)
+		if err != nil {
+			panic(err)
+		}
+		return str
+	case string:
+		return strconv.Quote(v)
+	}
+	panic(fmt.Errorf("ast error: invalid literal value of type %T", val))
+}
+
+// formatFloat converts a float into text that always contains a decimal point or exponent,
+// so that it is not mistaken for an integer.
+func formatFloat(f float64) (string, error) {
+	str := strconv.FormatFloat(f, 'g', -1, 64)
+	if strings.ContainsAny(str, "IN") { // Inf, NaN
+		return "", fmt.Errorf("ast error: non-finite number %s", str)
+	}
+	if !strings.ContainsAny(str, ".e") {
+		str += ".0"
+	}
+	return str, nil
+}
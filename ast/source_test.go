@@ -0,0 +1,106 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+)
+
+func Test_Source_Parsed(t *testing.T) {
+	for _, src := range allNodes[:len(allNodes)-1] { // all but the one containing comments
+		program, err := goval.Parse(src)
+		if !assert.NoError(t, err, "src: %s", src) {
+			continue
+		}
+		res, err := ast.Source(program.Root)
+		assert.NoError(t, err)
+		assert.Equal(t, src, res)
+	}
+
+	program, err := goval.Parse("a+( b )  // comment")
+	assert.NoError(t, err)
+	res, err := ast.Source(program.Root)
+	assert.NoError(t, err)
+	assert.Equal(t, "a + (b)", res)
+}
+
+func assertSource(t *testing.T, expected string, node ast.Node) {
+	t.Helper()
+	res, err := ast.Source(node)
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, res)
+	}
+}
+
+func ident(name string) *ast.Ident {
+	return &ast.Ident{Name: name}
+}
+
+func lit(val interface{}) *ast.Literal {
+	return &ast.Literal{Value: val}
+}
+
+func binary(x ast.Node, op string, y ast.Node) *ast.BinaryExpr {
+	return &ast.BinaryExpr{X: x, Op: op, Y: y}
+}
+
+func Test_Source_Constructed(t *testing.T) {
+	// literals without raw text:
+	assertSource(t, `[nil, true, 1, 1.0, 1.5, 1e+100, "a\"b\n"]`, &ast.ArrayLit{Elems: []ast.Node{
+		lit(nil), lit(true), lit(1), lit(1.0), lit(1.5), lit(1e100), lit("a\"b\n"),
+	}})
+
+	// missing parentheses are added:
+	assertSource(t, "(a + b) * c", binary(binary(ident("a"), "+", ident("b")), "*", ident("c")))
+	assertSource(t, "a + b * c", binary(ident("a"), "+", binary(ident("b"), "*", ident("c"))))
+	assertSource(t, "a - (b - c)", binary(ident("a"), "-", binary(ident("b"), "-", ident("c"))))
+	assertSource(t, "a - b - c", binary(binary(ident("a"), "-", ident("b")), "-", ident("c")))
+	assertSource(t, "-(a + b)", &ast.UnaryExpr{Op: "-", X: binary(ident("a"), "+", ident("b"))})
+	assertSource(t, "- -a", &ast.UnaryExpr{Op: "-", X: &ast.UnaryExpr{Op: "-", X: ident("a")}})
+	assertSource(t, "- -1", &ast.UnaryExpr{Op: "-", X: lit(-1)})
+	assertSource(t, "(-1).a", &ast.SelectorExpr{X: lit(-1), Sel: ident("a")})
+	assertSource(t, "(1).a", &ast.SelectorExpr{X: lit(1), Sel: ident("a")})
+	assertSource(t, "(a ? b : c)[0]", &ast.IndexExpr{
+		X:     &ast.TernaryExpr{Cond: ident("a"), Then: ident("b"), Else: ident("c")},
+		Index: lit(0),
+	})
+	assertSource(t, "(a ? b : c) ? d : e ? f : g", &ast.TernaryExpr{
+		Cond: &ast.TernaryExpr{Cond: ident("a"), Then: ident("b"), Else: ident("c")},
+		Then: ident("d"),
+		Else: &ast.TernaryExpr{Cond: ident("e"), Then: ident("f"), Else: ident("g")},
+	})
+	assertSource(t, "(x |> f()) + 1", binary(&ast.PipeExpr{X: ident("x"), Call: &ast.CallExpr{Func: ident("f")}}, "+", lit(1)))
+
+	// interpolated strings without raw text:
+	assertSource(t, `f"a{{\"{x + 1}"`, &ast.InterpolatedString{Parts: []ast.Node{
+		lit(`a{"`), binary(ident("x"), "+", lit(1)),
+	}})
+	assertSource(t, `f"{ {}}"`, &ast.InterpolatedString{Parts: []ast.Node{&ast.ObjectLit{}}})
+
+	// the result can be evaluated:
+	src, err := ast.Source(binary(binary(lit(1), "+", lit(2)), "*", lit(3)))
+	assert.NoError(t, err)
+	result, err := goval.NewEvaluator().Evaluate(src, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, result)
+}
+
+func Test_Source_Errors(t *testing.T) {
+	_, err := ast.Source(binary(ident("a"), "+", nil))
+	assert.EqualError(t, err, "ast error: missing node")
+	_, err = ast.Source(binary(ident("a"), "=", ident("b")))
+	assert.EqualError(t, err, "ast error: invalid binary operator \"=\"")
+	_, err = ast.Source(&ast.CallExpr{Args: []ast.Node{}})
+	assert.EqualError(t, err, "ast error: missing identifier")
+	_, err = ast.Source(lit([]int{}))
+	assert.EqualError(t, err, "ast error: invalid literal value of type []int")
+	_, err = ast.Source(&ast.InterpolatedString{Parts: []ast.Node{lit("a"), lit("b")}})
+	assert.NoError(t, err)
+	_, err = ast.Source(&ast.InterpolatedString{Parts: []ast.Node{lit(1)}})
+	assert.NoError(t, err)
+	_, err = ast.Source(&ast.InterpolatedString{Parts: []ast.Node{binary(lit("a"), "+", ident("b"))}})
+	assert.EqualError(t, err, "ast error: embedded expressions cannot contain double-quotes or backslashes")
+}
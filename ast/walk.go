@@ -0,0 +1,73 @@
+package ast
+
+// Inspect traverses the tree in depth-first order.
+// It starts by calling f(node), and continues with the children of node if f returns true.
+// Nil nodes (like missing slice bounds) are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+	for _, child := range children(node) {
+		Inspect(child, f)
+	}
+}
+
+// isNil reports whether the node is nil, including typed nil pointers like optional conditions of for clauses.
+func isNil(node Node) bool {
+	switch n := node.(type) {
+	case nil:
+		return true
+	case *Ident:
+		return n == nil
+	case *CallExpr:
+		return n == nil
+	case *ForClause:
+		return n == nil
+	}
+	return false
+}
+
+// children returns the direct children of the node, in source order.
+func children(node Node) []Node {
+	switch n := node.(type) {
+	case *InterpolatedString:
+		return n.Parts
+	case *ArrayLit:
+		return n.Elems
+	case *ObjectLit:
+		return n.Members
+	case *KeyValue:
+		return []Node{n.Key, n.Value}
+	case *Spread:
+		return []Node{n.X}
+	case *UnaryExpr:
+		return []Node{n.X}
+	case *BinaryExpr:
+		return []Node{n.X, n.Y}
+	case *TernaryExpr:
+		return []Node{n.Cond, n.Then, n.Else}
+	case *ParenExpr:
+		return []Node{n.X}
+	case *CallExpr:
+		return append([]Node{n.Func}, n.Args...)
+	case *PipeExpr:
+		return []Node{n.X, n.Call}
+	case *SelectorExpr:
+		return []Node{n.X, n.Sel}
+	case *IndexExpr:
+		return []Node{n.X, n.Index}
+	case *SliceExpr:
+		return []Node{n.X, n.Low, n.High}
+	case *ForClause:
+		nodes := make([]Node, 0, len(n.Vars)+2)
+		for _, v := range n.Vars {
+			nodes = append(nodes, v)
+		}
+		return append(nodes, n.X, n.Cond)
+	case *ArrayComp:
+		return []Node{n.Elem, n.Clause}
+	case *ObjectComp:
+		return []Node{n.Key, n.Value, n.Clause}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+)
+
+func Test_Inspect(t *testing.T) {
+	program, err := goval.Parse(`a in [b, -c] && f(d, ...e) ? g.h[i][j:] : {k: v for k, v in l if m} + f"{n}" + (x |> y(z))`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var idents []string
+	ast.Inspect(program.Root, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			idents = append(idents, id.Name)
+		}
+		return true
+	})
+	assert.Equal(t, []string{"a", "b", "c", "f", "d", "e", "g", "h", "i", "j", "k", "v", "k", "v", "l", "m", "n", "x", "y", "z"}, idents)
+
+	// skip children:
+	idents = nil
+	ast.Inspect(program.Root, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			idents = append(idents, id.Name)
+		}
+		_, call := n.(*ast.CallExpr)
+		_, comp := n.(*ast.ObjectComp)
+		return !call && !comp
+	})
+	assert.Equal(t, []string{"a", "b", "c", "g", "h", "i", "j", "n", "x"}, idents)
+
+	for _, src := range allNodes {
+		program, err := goval.Parse(src)
+		if !assert.NoError(t, err, "src: %s", src) {
+			continue
+		}
+		ast.Inspect(program.Root, func(n ast.Node) bool {
+			assert.NotNil(t, n, "src: %s", src)
+			return true
+		})
+	}
+}
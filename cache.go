@@ -0,0 +1,85 @@
+package goval
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/maja42/goval/internal"
+)
+
+// CacheStats contains statistics about the expression cache of an Evaluator.
+type CacheStats struct {
+	Hits     uint64 // Number of evaluations that used a cached expression
+	Misses   uint64 // Number of evaluations that needed to parse the expression
+	Entries  int    // Number of cached expressions
+	Capacity int    // Maximum number of cached expressions
+}
+
+// expressionCache is a least-recently-used cache of compiled expressions, keyed by expression text.
+// Safe for concurrent use.
+type expressionCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // of *cacheEntry, most recently used first
+	hits     uint64
+	misses   uint64
+}
+
+type cacheEntry struct {
+	str      string
+	compiled *internal.Compiled
+}
+
+func newExpressionCache(capacity int) *expressionCache {
+	return &expressionCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// compile returns the compiled expression, either from the cache or by compiling it.
+// Expressions that cannot be compiled are not cached.
+func (c *expressionCache) compile(str string) (*internal.Compiled, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[str]; ok {
+		c.hits++
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*cacheEntry).compiled, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// Compile without holding the lock, so that other expressions can be evaluated in the meantime.
+	compiled, err := internal.Compile(str)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[str]; ok { // compiled concurrently
+		c.order.MoveToFront(elem)
+		return compiled, nil
+	}
+	c.entries[str] = c.order.PushFront(&cacheEntry{str: str, compiled: compiled})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).str)
+	}
+	return compiled, nil
+}
+
+func (c *expressionCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Hits:     c.hits,
+		Misses:   c.misses,
+		Entries:  c.order.Len(),
+		Capacity: c.capacity,
+	}
+}
@@ -0,0 +1,99 @@
+package goval
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Cache(t *testing.T) {
+	eval := NewEvaluator(WithCache(2))
+	assert.Equal(t, CacheStats{Capacity: 2}, eval.CacheStats())
+
+	assertResult := func(expected interface{}, str string) {
+		t.Helper()
+		result, err := eval.Evaluate(str, map[string]interface{}{"x": 2}, nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, result)
+		}
+	}
+
+	assertResult(3, `x + 1`)
+	assertResult(3, `x + 1`)
+	assertResult(4, `x * 2`)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2, Entries: 2, Capacity: 2}, eval.CacheStats())
+
+	assertResult(3, `x + 1`)  // most recently used
+	assertResult(8, `x ** 3`) // replaces "x * 2"
+	assertResult(3, `x + 1`)
+	assertResult(4, `x * 2`)
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 4, Entries: 2, Capacity: 2}, eval.CacheStats())
+}
+
+func Test_Cache_Errors(t *testing.T) {
+	eval := NewEvaluator(WithCache(10))
+
+	for i := 0; i < 2; i++ {
+		_, err := eval.Evaluate(`1 +`, nil, nil)
+		assert.EqualError(t, err, "syntax error: unexpected $end")
+
+		_, err = eval.Evaluate(`x`, nil, nil) // evaluation errors don't affect the cache
+		assert.EqualError(t, err, "var error: variable \"x\" does not exist")
+	}
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 3, Entries: 1, Capacity: 10}, eval.CacheStats())
+}
+
+func Test_Cache_Disabled(t *testing.T) {
+	eval := NewEvaluator(WithCache(0))
+	result, err := eval.Evaluate(`1 + 2`, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result)
+	assert.Equal(t, CacheStats{}, eval.CacheStats())
+}
+
+func Test_Cache_Concurrency(t *testing.T) {
+	eval := NewEvaluator(WithCache(5))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				n := (i + j) % 10
+				result, err := eval.Evaluate(fmt.Sprintf("x + %d", n), map[string]interface{}{"x": i}, nil)
+				if assert.NoError(t, err) {
+					assert.Equal(t, i+n, result)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := eval.CacheStats()
+	assert.Equal(t, uint64(800), stats.Hits+stats.Misses)
+	assert.Equal(t, 5, stats.Entries)
+}
+
+func Benchmark_Evaluator(b *testing.B) {
+	variables := map[string]interface{}{"a": 21, "b": 42.5}
+	benchmarks := []struct {
+		name string
+		eval *Evaluator
+	}{
+		{"uncached", NewEvaluator()},
+		{"cached", NewEvaluator(WithCache(16))},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bm.eval.Evaluate(`(a * 1.8) + 32 - b / 4`, variables, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
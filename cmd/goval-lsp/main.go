@@ -0,0 +1,71 @@
+// Command goval-lsp is a language server for goval expressions.
+//
+// Usage:
+//
+//	goval-lsp [-schema file]
+//
+// The server speaks the Language Server Protocol over stdin and stdout, and supports
+//   - diagnostics for syntax errors, schema errors and lint warnings,
+//   - completion of variable, field and function names,
+//   - hover with types and function documentation,
+//   - formatting of whole documents.
+//
+// The variables and functions that are available to expressions are loaded from a JSON schema file (see package schema).
+// Clients can also pass the file as initialization option: {"schema": "path/to/schema.json"}.
+// Without schema, only syntax errors and lint warnings are reported.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/maja42/goval/schema"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1 // also returned if the client exits without shutdown request
+	exitUsage   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run starts the server with the given arguments and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("goval-lsp", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, "Usage: goval-lsp [flags]\n\n"+
+			"Language server for goval expressions, speaking LSP over stdin and stdout.\n\n"+
+			"Flags:\n")
+		flags.PrintDefaults()
+	}
+	schemaFile := flags.String("schema", "", "JSON `file` describing the available variables and functions")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "goval-lsp: unexpected argument %q\n", flags.Arg(0))
+		return exitUsage
+	}
+
+	var sch *schema.Schema
+	if *schemaFile != "" {
+		var err error
+		if sch, err = loadSchema(*schemaFile); err != nil {
+			fmt.Fprintf(stderr, "goval-lsp: %s\n", err)
+			return exitFailure
+		}
+	}
+	return newServer(sch, stdout).serve(stdin, stderr)
+}
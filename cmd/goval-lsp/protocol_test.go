@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Framing(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeMessage(&buf, &message{Method: "exit"}))
+	assert.Equal(t, "Content-Length: 33\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"exit\"}", buf.String())
+
+	r := bufio.NewReader(strings.NewReader(buf.String() +
+		"Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: 36\r\n\r\n{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":[]}" +
+		"Content-Length: x\r\n\r\n"))
+	msg, err := readMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, "exit", msg.Method)
+	msg, err = readMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(msg.ID))
+	assert.Equal(t, "[]", string(msg.Result))
+	_, err = readMessage(r)
+	assert.EqualError(t, err, `invalid Content-Length "x"`)
+}
+
+func Test_Positions(t *testing.T) {
+	text := "a +\n\"ä😀\" + b\n"
+	assertConversion := func(pos int, p position) {
+		t.Helper()
+		assert.Equal(t, pos, offset(text, p), "offset of %v", p)
+		assert.Equal(t, p, toPosition(text, pos), "position of %d", pos)
+	}
+	assertConversion(1, position{0, 0})
+	assertConversion(4, position{0, 3})
+	assertConversion(5, position{1, 0})
+	assertConversion(6, position{1, 1})  // ä
+	assertConversion(8, position{1, 2})  // 😀 (two bytes for ä, two UTF-16 units for 😀)
+	assertConversion(12, position{1, 4}) // closing quote
+	assertConversion(17, position{1, 9})
+	assertConversion(18, position{2, 0})
+
+	assert.Equal(t, 4, offset(text, position{0, 10})) // clamped to the end of the line
+	assert.Equal(t, 18, offset(text, position{5, 0})) // clamped to the end of the document
+	assert.Equal(t, 8, offset(text, position{1, 3}))  // within a surrogate pair
+	assert.Equal(t, position{2, 0}, toPosition(text, 100))
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+	"github.com/maja42/goval/format"
+	"github.com/maja42/goval/lint"
+	"github.com/maja42/goval/schema"
+)
+
+// codeRequestFailed is an LSP error code for requests that were valid, but could not be performed.
+const codeRequestFailed = -32803
+
+// server is a language server for a single client.
+// Documents are synchronized in full; each change publishes new diagnostics.
+type server struct {
+	schema *schema.Schema // nil if no schema is configured
+	docs   map[string]string
+	out    io.Writer
+
+	shutdown bool // a shutdown request was received
+}
+
+func newServer(s *schema.Schema, out io.Writer) *server {
+	return &server{
+		schema: s,
+		docs:   make(map[string]string),
+		out:    out,
+	}
+}
+
+// serve handles messages until the exit notification is received, and returns the exit code.
+func (s *server) serve(in io.Reader, stderr io.Writer) int {
+	r := bufio.NewReader(in)
+	for {
+		msg, err := readMessage(r)
+		var rpcErr *responseError
+		if errors.As(err, &rpcErr) {
+			err = s.send(&message{ID: json.RawMessage("null"), Error: rpcErr})
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				fmt.Fprintf(stderr, "goval-lsp: %s\n", err)
+			}
+			return exitFailure
+		}
+		if msg == nil {
+			continue
+		}
+
+		if msg.ID == nil { // notification
+			if msg.Method == "exit" {
+				if s.shutdown {
+					return exitOK
+				}
+				return exitFailure
+			}
+			err = s.safeNotify(msg.Method, msg.Params)
+		} else {
+			err = s.respond(msg.ID, msg.Method, msg.Params)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "goval-lsp: %s\n", err)
+			return exitFailure
+		}
+	}
+}
+
+func (s *server) send(msg *message) error {
+	return writeMessage(s.out, msg)
+}
+
+// respond handles a request and sends the response.
+func (s *server) respond(id json.RawMessage, method string, params json.RawMessage) error {
+	result, err := s.safeHandle(method, params)
+	resp := &message{ID: id}
+	if err != nil {
+		var rpcErr *responseError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &responseError{Code: codeRequestFailed, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return s.send(resp)
+	}
+	if resp.Result, err = json.Marshal(result); err != nil {
+		return err
+	}
+	return s.send(resp)
+}
+
+// safeHandle handles a request, and turns panics into errors so that a single request cannot bring down the server.
+func (s *server) safeHandle(method string, params json.RawMessage) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error: %v", r)
+		}
+	}()
+	return s.handle(method, params)
+}
+
+func (s *server) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		var p initializeParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		if file := p.InitializationOptions.Schema; file != "" {
+			sch, err := loadSchema(file)
+			if err != nil {
+				return nil, err
+			}
+			s.schema = sch
+		}
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":           1, // full
+				"completionProvider":         map[string]interface{}{"triggerCharacters": []string{"."}},
+				"hoverProvider":              true,
+				"documentFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "goval-lsp"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/completion":
+		var p textDocumentPositionParams
+		text, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return s.completion(text, offset(text, p.Position)), nil
+	case "textDocument/hover":
+		var p textDocumentPositionParams
+		text, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return s.hover(text, offset(text, p.Position)), nil
+	case "textDocument/formatting":
+		var p formattingParams
+		text, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return formatting(text), nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q is not supported", method)}
+}
+
+// safeNotify handles a notification, and reports panics to the client so that a single notification cannot bring down the server.
+func (s *server) safeNotify(method string, params json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.logMessage(fmt.Sprintf("internal error: %v", r))
+		}
+	}()
+	return s.notify(method, params)
+}
+
+// notify handles a notification. Unknown notifications are ignored.
+func (s *server) notify(method string, params json.RawMessage) error {
+	var uri string
+	switch method {
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := decode(params, &p); err != nil {
+			return nil
+		}
+		uri = p.TextDocument.URI
+		s.docs[uri] = p.TextDocument.Text
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := decode(params, &p); err != nil || len(p.ContentChanges) == 0 {
+			return nil
+		}
+		uri = p.TextDocument.URI
+		s.docs[uri] = p.ContentChanges[len(p.ContentChanges)-1].Text
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := decode(params, &p); err != nil {
+			return nil
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return s.publish(p.TextDocument.URI, []diagnostic{}) // clear
+	default:
+		return nil
+	}
+	return s.publish(uri, s.diagnostics(s.docs[uri]))
+}
+
+func (s *server) publish(uri string, diags []diagnostic) error {
+	params, err := json.Marshal(publishDiagnosticsParams{URI: uri, Diagnostics: diags})
+	if err != nil {
+		return err
+	}
+	return s.send(&message{Method: "textDocument/publishDiagnostics", Params: params})
+}
+
+func (s *server) logMessage(msg string) error {
+	params, err := json.Marshal(logMessageParams{Type: messageTypeError, Message: msg})
+	if err != nil {
+		return err
+	}
+	return s.send(&message{Method: "window/logMessage", Params: params})
+}
+
+// document decodes the params and returns the text of the referenced document.
+func (s *server) document(params json.RawMessage, p interface{}, doc *textDocumentIdentifier) (string, error) {
+	if err := decode(params, p); err != nil {
+		return "", err
+	}
+	text, ok := s.docs[doc.URI]
+	if !ok {
+		return "", &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %q is not open", doc.URI)}
+	}
+	return text, nil
+}
+
+func decode(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func loadSchema(file string) (*schema.Schema, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	sch, err := schema.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return sch, nil
+}
+
+// diagnostics returns syntax errors, schema errors and lint warnings.
+// Empty documents are not reported.
+func (s *server) diagnostics(text string) []diagnostic {
+	diags := []diagnostic{}
+	if strings.TrimSpace(text) == "" {
+		return diags
+	}
+	program, err := goval.Parse(text)
+	if err != nil {
+		pos := 1
+		var syntaxErr *goval.SyntaxError
+		if errors.As(err, &syntaxErr) && syntaxErr.Pos > 0 {
+			pos = syntaxErr.Pos
+		}
+		return append(diags, diagnostic{
+			Range:    toRange(text, pos, pos+1),
+			Severity: severityError,
+			Source:   "goval",
+			Message:  err.Error(),
+		})
+	}
+
+	var linter lint.Linter
+	if s.schema != nil {
+		for _, e := range s.schema.Check(program.Root).Errors {
+			diags = append(diags, diagnostic{
+				Range:    toRange(text, e.Pos, e.End),
+				Severity: severityError,
+				Source:   "goval",
+				Message:  e.Msg,
+			})
+		}
+		linter.Required = s.schema.RequiredPaths()
+	}
+	for _, w := range linter.LintAST(program) {
+		diags = append(diags, diagnostic{
+			Range:    toRange(text, w.Pos, w.End),
+			Severity: severityWarning,
+			Code:     w.Rule,
+			Source:   "goval",
+			Message:  w.Message,
+		})
+	}
+	return diags
+}
+
+// completion returns the fields of the object before a '.', or all variables and functions.
+func (s *server) completion(text string, pos int) *completionList {
+	list := &completionList{Items: []completionItem{}}
+	if s.schema == nil {
+		return list
+	}
+	start := pos - 1 // byte index of the cursor
+	for start > 0 && isIdentByte(text[start-1]) {
+		start--
+	}
+
+	if start > 0 && text[start-1] == '.' {
+		end := start - 1
+		begin := end
+		for begin > 0 && (isIdentByte(text[begin-1]) || text[begin-1] == '.') {
+			begin--
+		}
+		t := s.schema.Lookup(text[begin:end])
+		if t == nil {
+			return list
+		}
+		for _, name := range t.FieldNames() {
+			field := t.Fields[name]
+			list.Items = append(list.Items, completionItem{
+				Label:         name,
+				Kind:          kindField,
+				Detail:        field.String(),
+				Documentation: field.Doc,
+			})
+		}
+		return list
+	}
+
+	for _, name := range s.schema.VariableNames() {
+		v := s.schema.Variables[name]
+		list.Items = append(list.Items, completionItem{
+			Label:         name,
+			Kind:          kindVariable,
+			Detail:        v.String(),
+			Documentation: v.Doc,
+		})
+	}
+	for _, name := range s.schema.FunctionNames() {
+		f := s.schema.Functions[name]
+		list.Items = append(list.Items, completionItem{
+			Label:         name,
+			Kind:          kindFunction,
+			Detail:        f.Signature(name),
+			Documentation: f.Doc,
+		})
+	}
+	return list
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// hover describes the identifier at the given position, or the type of the innermost expression.
+// Returns nil if there is nothing to describe.
+func (s *server) hover(text string, pos int) *hover {
+	program, err := goval.Parse(text)
+	if err != nil {
+		return nil
+	}
+	sch := s.schema
+	if sch == nil {
+		sch = &schema.Schema{}
+	}
+	info := sch.Check(program.Root)
+
+	var node ast.Node
+	funcs := make(map[ast.Node]bool)
+	selectors := make(map[ast.Node]*ast.SelectorExpr)
+	ast.Inspect(program.Root, func(n ast.Node) bool {
+		if pos < n.Pos() || pos >= n.End() {
+			return false
+		}
+		node = n
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			funcs[n.Func] = true
+		case *ast.SelectorExpr:
+			selectors[n.Sel] = n
+		}
+		return true
+	})
+	if node == nil {
+		return nil
+	}
+
+	t := info.Types[node]
+	var value string
+	switch n := node.(type) {
+	case *ast.Ident:
+		if funcs[n] {
+			f, ok := sch.Functions[n.Name]
+			if !ok {
+				return nil
+			}
+			value = codeBlock(f.Signature(n.Name)) + docs(f.Doc)
+			break
+		}
+		name := n.Name
+		if sel := selectors[n]; sel != nil {
+			if path := exprPath(sel); path != "" {
+				name = path
+			}
+		}
+		value = codeBlock(name+": "+t.String()) + docs(t.Doc)
+	default:
+		if t == nil || t.Kind == "" || t.Kind == schema.Any {
+			return nil
+		}
+		value = codeBlock(t.String())
+	}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: value},
+		Range:    toRange(text, node.Pos(), node.End()),
+	}
+}
+
+// exprPath returns the variable or field path of an expression, like "user.address.city".
+// Returns an empty string if the expression is no path.
+func exprPath(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.Ident:
+		return n.Name
+	case *ast.SelectorExpr:
+		if x := exprPath(n.X); x != "" {
+			return x + "." + n.Sel.Name
+		}
+	}
+	return ""
+}
+
+func codeBlock(code string) string {
+	return "```goval\n" + code + "\n```"
+}
+
+func docs(doc string) string {
+	if doc == "" {
+		return ""
+	}
+	return "\n\n" + doc
+}
+
+// formatting returns the edits for formatting the whole document.
+// Returns nil if the document cannot be parsed.
+func formatting(text string) []textEdit {
+	res, err := format.Source(text)
+	if err != nil {
+		return nil
+	}
+	if strings.HasSuffix(text, "\n") {
+		res += "\n"
+	}
+	if res == text {
+		return []textEdit{}
+	}
+	return []textEdit{{
+		Range:   toRange(text, 1, len(text)+1),
+		NewText: res,
+	}}
+}
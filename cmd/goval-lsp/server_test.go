@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maja42/goval/schema"
+)
+
+const testSchema = `{
+	"variables": {
+		"user": {"type": "object", "doc": "The current user.", "fields": {
+			"id":   {"type": "string", "required": true, "doc": "Unique ID."},
+			"age":  {"type": "number"},
+			"tags": {"type": "array", "elem": {"type": "string"}},
+			"meta": null
+		}},
+		"limit": {"type": "number"}
+	},
+	"functions": {
+		"len": {"params": [{"name": "value"}], "result": {"type": "number"}, "doc": "Returns the length."}
+	}
+}`
+
+// client is an LSP client talking to an in-process server.
+type client struct {
+	t      *testing.T
+	in     io.WriteCloser
+	out    chan *message // messages sent by the server; read concurrently, since writes to pipes block
+	nextID int
+	exit   chan int // receives the exit code of the server
+
+	notifications []*message // received, but not yet consumed
+}
+
+func startServer(t *testing.T, args ...string) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{
+		t:    t,
+		in:   inW,
+		out:  make(chan *message, 100),
+		exit: make(chan int, 1),
+	}
+	go func() {
+		r := bufio.NewReader(outR)
+		for {
+			msg, err := readMessage(r)
+			if err != nil {
+				close(c.out)
+				return
+			}
+			c.out <- msg
+		}
+	}()
+	go func() {
+		var stderr bytes.Buffer
+		code := run(args, inR, outW, &stderr)
+		outW.Close()
+		c.exit <- code
+	}()
+	t.Cleanup(func() { inW.Close() })
+	return c
+}
+
+func writeSchema(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(path, []byte(testSchema), 0600))
+	return path
+}
+
+func (c *client) send(msg *message) {
+	c.t.Helper()
+	require.NoError(c.t, writeMessage(c.in, msg))
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	data, err := json.Marshal(params)
+	require.NoError(c.t, err)
+	c.send(&message{Method: method, Params: data})
+}
+
+// request sends a request, and decodes the result of the response into result.
+// Returns the error of the response.
+func (c *client) request(method string, params interface{}, result interface{}) *responseError {
+	c.t.Helper()
+	c.nextID++
+	id, _ := json.Marshal(c.nextID)
+	data, err := json.Marshal(params)
+	require.NoError(c.t, err)
+	c.send(&message{ID: id, Method: method, Params: data})
+
+	for msg := range c.out {
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		require.Equal(c.t, string(id), string(msg.ID))
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			require.NoError(c.t, json.Unmarshal(msg.Result, result))
+		}
+		return nil
+	}
+	c.t.Fatal("server stopped")
+	return nil
+}
+
+// diagnostics returns the next published diagnostics.
+func (c *client) diagnostics() publishDiagnosticsParams {
+	c.t.Helper()
+	msg := c.notifications[0] // notifications are sent before the response of the following request
+	c.notifications = c.notifications[1:]
+	require.Equal(c.t, "textDocument/publishDiagnostics", msg.Method)
+	var params publishDiagnosticsParams
+	require.NoError(c.t, json.Unmarshal(msg.Params, &params))
+	return params
+}
+
+func (c *client) initialize(options interface{}) {
+	c.t.Helper()
+	var result struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	err := c.request("initialize", map[string]interface{}{"initializationOptions": options}, &result)
+	require.Nil(c.t, err)
+	assert.Equal(c.t, true, result.Capabilities["hoverProvider"])
+	c.notify("initialized", struct{}{})
+}
+
+// open opens a document and returns its diagnostics.
+func (c *client) open(uri, text string) []diagnostic {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "goval", "version": 1, "text": text},
+	})
+	c.ping()
+	diags := c.diagnostics()
+	assert.Equal(c.t, uri, diags.URI)
+	return diags.Diagnostics
+}
+
+// ping sends a request that is not supported, to wait until all previous notifications were handled.
+func (c *client) ping() {
+	c.t.Helper()
+	err := c.request("$/ping", nil, nil)
+	require.NotNil(c.t, err)
+	assert.Equal(c.t, codeMethodNotFound, err.Code)
+}
+
+func (c *client) stop() int {
+	c.t.Helper()
+	require.Nil(c.t, c.request("shutdown", nil, nil))
+	c.notify("exit", nil)
+	return <-c.exit
+}
+
+func docPosition(uri string, line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     position{Line: line, Character: character},
+	}
+}
+
+func Test_Diagnostics(t *testing.T) {
+	c := startServer(t, "-schema", writeSchema(t))
+	c.initialize(nil)
+
+	assert.Empty(t, c.open("file:///empty.expr", "\n"))
+	assert.Empty(t, c.open("file:///valid.expr", "user.age > 18 && len(user.tags) > 0"))
+
+	diags := c.open("file:///syntax.expr", "user.age >\n  && true")
+	assert.Equal(t, []diagnostic{{
+		Range:    rangeT{Start: position{1, 2}, End: position{1, 3}},
+		Severity: severityError,
+		Source:   "goval",
+		Message:  "syntax error: unexpected AND",
+	}}, diags)
+
+	diags = c.open("file:///schema.expr", "user.name == nil || user.id == nil")
+	assert.Equal(t, []diagnostic{{
+		Range:    rangeT{Start: position{0, 0}, End: position{0, 9}},
+		Severity: severityError,
+		Source:   "goval",
+		Message:  `var error: object has no member "name"`,
+	}, {
+		Range:    rangeT{Start: position{0, 20}, End: position{0, 34}},
+		Severity: severityWarning,
+		Code:     "nil-required",
+		Source:   "goval",
+		Message:  "user.id is required and never nil, the comparison is always false",
+	}}, diags)
+
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": "file:///schema.expr", "version": 2},
+		"contentChanges": []map[string]string{{"text": `"ä" + limit - user.id`}},
+	})
+	c.ping()
+	params := c.diagnostics()
+	assert.Equal(t, "file:///schema.expr", params.URI)
+	assert.Equal(t, []diagnostic{{
+		Range:    rangeT{Start: position{0, 0}, End: position{0, 21}},
+		Severity: severityError,
+		Source:   "goval",
+		Message:  "type error: cannot subtract type string and string",
+	}}, params.Diagnostics)
+
+	c.notify("textDocument/didClose", map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file:///schema.expr"},
+	})
+	c.ping()
+	params = c.diagnostics()
+	assert.Empty(t, params.Diagnostics)
+
+	assert.Equal(t, exitOK, c.stop())
+}
+
+func Test_Diagnostics_WithoutSchema(t *testing.T) {
+	c := startServer(t)
+	c.initialize(nil)
+
+	assert.Empty(t, c.open("file:///a.expr", "unknown.field > 1"))
+	diags := c.open("file:///b.expr", "a ? 1 : 1")
+	if assert.Len(t, diags, 1) {
+		assert.Equal(t, "identical-branches", diags[0].Code)
+	}
+	assert.Equal(t, exitOK, c.stop())
+}
+
+func Test_Completion(t *testing.T) {
+	c := startServer(t)
+	c.initialize(map[string]string{"schema": writeSchema(t)})
+	c.open("file:///a.expr", "user.ag > 1 && l")
+
+	labels := func(list completionList) []string {
+		var labels []string
+		for _, item := range list.Items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+
+	var list completionList
+	require.Nil(t, c.request("textDocument/completion", docPosition("file:///a.expr", 0, 7), &list))
+	assert.Equal(t, []string{"age", "id", "meta", "tags"}, labels(list))
+	assert.Equal(t, completionItem{Label: "id", Kind: kindField, Detail: "string", Documentation: "Unique ID."}, list.Items[1])
+	assert.Equal(t, completionItem{Label: "meta", Kind: kindField, Detail: "any"}, list.Items[2])
+
+	require.Nil(t, c.request("textDocument/completion", docPosition("file:///a.expr", 0, 16), &list))
+	assert.Equal(t, []string{"limit", "user", "len"}, labels(list))
+	assert.Equal(t, completionItem{Label: "len", Kind: kindFunction, Detail: "len(value: any) number", Documentation: "Returns the length."}, list.Items[2])
+
+	require.Nil(t, c.request("textDocument/completion", docPosition("file:///a.expr", 0, 0), &list))
+	assert.Equal(t, []string{"limit", "user", "len"}, labels(list))
+
+	c.open("file:///b.expr", "user.tags.")
+	require.Nil(t, c.request("textDocument/completion", docPosition("file:///b.expr", 0, 10), &list))
+	assert.Empty(t, list.Items)
+
+	err := c.request("textDocument/completion", docPosition("file:///unknown.expr", 0, 0), &list)
+	require.NotNil(t, err)
+	assert.Equal(t, codeInvalidParams, err.Code)
+
+	assert.Equal(t, exitOK, c.stop())
+}
+
+func Test_Hover(t *testing.T) {
+	c := startServer(t, "-schema", writeSchema(t))
+	c.initialize(nil)
+	c.open("file:///a.expr", "len(user.tags) > limit + 1")
+
+	assertHover := func(expected string, character int) {
+		t.Helper()
+		var h *hover
+		require.Nil(t, c.request("textDocument/hover", docPosition("file:///a.expr", 0, character), &h))
+		if expected == "" {
+			assert.Nil(t, h)
+			return
+		}
+		if assert.NotNil(t, h) {
+			assert.Equal(t, "markdown", h.Contents.Kind)
+			assert.Equal(t, expected, h.Contents.Value)
+		}
+	}
+	assertHover("```goval\nlen(value: any) number\n```\n\nReturns the length.", 1)
+	assertHover("```goval\nuser: object\n```\n\nThe current user.", 5)
+	assertHover("```goval\nuser.tags: array<string>\n```", 10)
+	assertHover("```goval\nnumber\n```", 3) // parentheses of the call
+	assertHover("```goval\nlimit: number\n```", 17)
+	assertHover("```goval\nnumber\n```", 23)
+	assertHover("", 30)
+
+	c.open("file:///a.expr", "user +") // invalid
+	assertHover("", 1)
+
+	assert.Equal(t, exitOK, c.stop())
+}
+
+func Test_Formatting(t *testing.T) {
+	c := startServer(t)
+	c.initialize(nil)
+
+	var edits []textEdit
+	c.open("file:///a.expr", "a+( b )\n\n")
+	require.Nil(t, c.request("textDocument/formatting", map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file:///a.expr"},
+	}, &edits))
+	assert.Equal(t, []textEdit{{
+		Range:   rangeT{Start: position{0, 0}, End: position{2, 0}},
+		NewText: "a + b\n",
+	}}, edits)
+
+	c.open("file:///b.expr", "a + b")
+	require.Nil(t, c.request("textDocument/formatting", map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file:///b.expr"},
+	}, &edits))
+	assert.Empty(t, edits)
+
+	edits = nil
+	c.open("file:///c.expr", "a +")
+	require.Nil(t, c.request("textDocument/formatting", map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file:///c.expr"},
+	}, &edits))
+	assert.Nil(t, edits)
+
+	c.open("file:///d.expr", "a /* x */ .c")
+	require.Nil(t, c.request("textDocument/formatting", map[string]interface{}{
+		"textDocument": map[string]string{"uri": "file:///d.expr"},
+	}, &edits))
+	assert.Equal(t, []textEdit{{
+		Range:   rangeT{Start: position{0, 0}, End: position{0, 12}},
+		NewText: "a /* x */.c",
+	}}, edits)
+
+	assert.Equal(t, exitOK, c.stop())
+}
+
+func Test_Lifecycle(t *testing.T) {
+	c := startServer(t)
+	c.initialize(nil)
+	c.notify("exit", nil)
+	assert.Equal(t, exitFailure, <-c.exit) // exit without shutdown
+
+	c = startServer(t)
+	err := c.request("initialize", map[string]interface{}{
+		"initializationOptions": map[string]string{"schema": "missing.json"},
+	}, nil)
+	require.NotNil(t, err)
+	assert.Equal(t, codeRequestFailed, err.Code)
+	assert.Equal(t, exitOK, c.stop())
+
+	c = startServer(t)
+	_, werr := io.WriteString(c.in, "Content-Length: 5\r\n\r\n{...}")
+	require.NoError(t, werr)
+	msg := <-c.out
+	assert.Equal(t, "null", string(msg.ID))
+	assert.Equal(t, codeParseError, msg.Error.Code)
+	c.in.Close()
+	assert.Equal(t, exitFailure, <-c.exit)
+}
+
+func Test_RecoveredPanics(t *testing.T) {
+	// schemas that are not loaded from JSON are not validated, and can crash the checker
+	sch := &schema.Schema{Functions: map[string]*schema.Function{"f": nil}}
+	var in, out, stderr bytes.Buffer
+	open, _ := json.Marshal(didOpenParams{TextDocument: textDocumentItem{URI: "file:///a.expr", Text: "f()"}})
+	require.NoError(t, writeMessage(&in, &message{Method: "textDocument/didOpen", Params: open}))
+	hover, _ := json.Marshal(docPosition("file:///a.expr", 0, 0))
+	require.NoError(t, writeMessage(&in, &message{ID: json.RawMessage("1"), Method: "textDocument/hover", Params: hover}))
+
+	s := newServer(sch, &out)
+	assert.Equal(t, exitFailure, s.serve(&in, &stderr)) // the server keeps running until the input ends
+	assert.Empty(t, stderr.String())
+
+	r := bufio.NewReader(&out)
+	msg, err := readMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, "window/logMessage", msg.Method)
+	var params logMessageParams
+	require.NoError(t, json.Unmarshal(msg.Params, &params))
+	assert.Equal(t, messageTypeError, params.Type)
+	assert.Contains(t, params.Message, "internal error: runtime error: invalid memory address or nil pointer dereference")
+
+	msg, err = readMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(msg.ID))
+	if assert.NotNil(t, msg.Error) {
+		assert.Equal(t, codeRequestFailed, msg.Error.Code)
+		assert.Contains(t, msg.Error.Message, "internal error: ")
+	}
+}
+
+func Test_Run_Errors(t *testing.T) {
+	var stderr bytes.Buffer
+	assert.Equal(t, exitUsage, run([]string{"x"}, nil, io.Discard, &stderr))
+	assert.Equal(t, "goval-lsp: unexpected argument \"x\"\n", stderr.String())
+
+	stderr.Reset()
+	path := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"variables": {"a": {"type": "int"}}}`), 0600))
+	assert.Equal(t, exitFailure, run([]string{"-schema", path}, nil, io.Discard, &stderr))
+	assert.Equal(t, "goval-lsp: "+path+": schema error: a: unknown type \"int\"\n", stderr.String())
+}
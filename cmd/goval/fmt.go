@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/maja42/goval/format"
+)
+
+// runFmt formats expression files and returns the exit code.
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("goval fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, "Usage: goval fmt [flags] [file ...]\n\n"+
+			"Formats expressions in their canonical form.\n"+
+			"Without files, the expression is read from stdin and written to stdout.\n\n"+
+			"Flags:\n")
+		flags.PrintDefaults()
+	}
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	list := flags.Bool("l", false, "list files whose formatting differs")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprint(stderr, "goval: cannot use -w with stdin\n")
+			return exitUsage
+		}
+		if stdin == nil {
+			fmt.Fprint(stderr, "goval: no files given\n")
+			flags.Usage()
+			return exitUsage
+		}
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "goval: %s\n", err)
+			return exitFailure
+		}
+		res, err := formatSource(string(data))
+		if err != nil {
+			fmt.Fprintf(stderr, "goval: <stdin>: %s\n", err)
+			return exitFailure
+		}
+		if *list {
+			if res != string(data) {
+				fmt.Fprintln(stdout, "<stdin>")
+			}
+			return exitOK
+		}
+		fmt.Fprint(stdout, res)
+		return exitOK
+	}
+
+	code := exitOK
+	for _, file := range flags.Args() {
+		if err := formatFile(file, *write, *list, stdout); err != nil {
+			fmt.Fprintf(stderr, "goval: %s\n", err)
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// formatFile formats a single file.
+// The result is written back into the file, or to stdout.
+func formatFile(file string, write, list bool, stdout io.Writer) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	res, err := formatSource(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	changed := res != string(data)
+	if list && changed {
+		fmt.Fprintln(stdout, file)
+	}
+	if write {
+		if !changed {
+			return nil
+		}
+		stat, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(file, []byte(res), stat.Mode().Perm())
+	}
+	if !list {
+		fmt.Fprint(stdout, res)
+	}
+	return nil
+}
+
+// formatSource formats an expression, terminated by a line break.
+func formatSource(src string) (string, error) {
+	res, err := format.Source(src)
+	if err != nil {
+		return "", err
+	}
+	return res + "\n", nil
+}
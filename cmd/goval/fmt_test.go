@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Fmt_Stdin(t *testing.T) {
+	code, out, errOut := runCmd("1+2*(3)", "fmt")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "1 + 2 * 3\n", out)
+	assert.Empty(t, errOut)
+
+	code, _, errOut = runCmd("1+", "fmt")
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, "goval: <stdin>: syntax error: unexpected $end\n", errOut)
+
+	code, _, _ = runCmd("", "fmt")
+	assert.Equal(t, exitUsage, code)
+	code, _, _ = runCmd("1", "fmt", "-w")
+	assert.Equal(t, exitUsage, code)
+}
+
+func Test_Fmt_Files(t *testing.T) {
+	unformatted := writeFile(t, "a.expr", "a&&(b)")
+	formatted := writeFile(t, "b.expr", "a && b\n")
+
+	code, out, _ := runCmd("", "fmt", unformatted, formatted)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "a && b\na && b\n", out)
+
+	code, out, _ = runCmd("", "fmt", "-l", unformatted, formatted)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, unformatted+"\n", out)
+
+	code, out, _ = runCmd("", "fmt", "-w", unformatted, formatted)
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, out)
+	data, err := os.ReadFile(unformatted)
+	assert.NoError(t, err)
+	assert.Equal(t, "a && b\n", string(data))
+
+	invalid := writeFile(t, "c.expr", "a &&")
+	code, out, errOut := runCmd("", "fmt", invalid, formatted)
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, "a && b\n", out)
+	assert.Equal(t, "goval: "+invalid+": syntax error: unexpected $end\n", errOut)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/internal"
+)
+
+// builtin is a function that is available within all expressions evaluated by the command.
+type builtin struct {
+	usage string
+	fn    goval.ExpressionFunction
+}
+
+var builtins = map[string]builtin{
+	"len": {
+		usage: "len(x) returns the length of a string, array or object",
+		fn: func(args ...interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, errors.New("expected exactly 1 argument")
+			}
+			switch v := args[0].(type) {
+			case string:
+				return len(v), nil
+			case []interface{}:
+				return len(v), nil
+			case map[string]interface{}:
+				return len(v), nil
+			}
+			return nil, fmt.Errorf("expected string, array or object, but was %s", internal.TypeOf(args[0]))
+		},
+	},
+	"keys": {
+		usage: "keys(obj) returns the sorted keys of an object",
+		fn: func(args ...interface{}) (interface{}, error) {
+			obj, err := objectArg(args)
+			if err != nil {
+				return nil, err
+			}
+			keys := sortedKeys(obj)
+			res := make([]interface{}, len(keys))
+			for i, k := range keys {
+				res[i] = k
+			}
+			return res, nil
+		},
+	},
+	"values": {
+		usage: "values(obj) returns the values of an object, sorted by their keys",
+		fn: func(args ...interface{}) (interface{}, error) {
+			obj, err := objectArg(args)
+			if err != nil {
+				return nil, err
+			}
+			keys := sortedKeys(obj)
+			res := make([]interface{}, len(keys))
+			for i, k := range keys {
+				res[i] = obj[k]
+			}
+			return res, nil
+		},
+	},
+	"lower": {
+		usage: "lower(str) converts a string to lower case",
+		fn: func(args ...interface{}) (interface{}, error) {
+			str, err := stringArg(args)
+			return strings.ToLower(str), err
+		},
+	},
+	"upper": {
+		usage: "upper(str) converts a string to upper case",
+		fn: func(args ...interface{}) (interface{}, error) {
+			str, err := stringArg(args)
+			return strings.ToUpper(str), err
+		},
+	},
+}
+
+// functions returns all builtin functions.
+func functions() map[string]goval.ExpressionFunction {
+	funcs := make(map[string]goval.ExpressionFunction, len(builtins))
+	for name, b := range builtins {
+		funcs[name] = b.fn
+	}
+	return funcs
+}
+
+func objectArg(args []interface{}) (map[string]interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected exactly 1 argument")
+	}
+	obj, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected object, but was %s", internal.TypeOf(args[0]))
+	}
+	return obj, nil
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringArg(args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("expected exactly 1 argument")
+	}
+	str, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("expected string, but was %s", internal.TypeOf(args[0]))
+	}
+	return str, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/maja42/goval/lint"
+)
+
+// runLint reports likely mistakes within expression files and returns the exit code.
+// The exit code is exitFalsy if there are warnings.
+func runLint(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("goval lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, "Usage: goval lint [flags] [file ...]\n\n"+
+			"Reports likely mistakes within expressions.\n"+
+			"Without files, the expression is read from stdin.\n"+
+			"Rules: "+strings.Join(lint.Rules, ", ")+"\n\n"+
+			"Flags:\n")
+		flags.PrintDefaults()
+	}
+	var linter lint.Linter
+	flags.Var((*stringList)(&linter.Required), "required", "variable or field path that is never nil, like user.id (can be repeated)")
+	flags.Var((*stringList)(&linter.Disable), "disable", "rule ID that is not reported (can be repeated)")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	for _, rule := range linter.Disable {
+		if !knownRule(rule) {
+			fmt.Fprintf(stderr, "goval: unknown rule %q\n", rule)
+			return exitUsage
+		}
+	}
+
+	if flags.NArg() == 0 {
+		if stdin == nil {
+			fmt.Fprint(stderr, "goval: no files given\n")
+			flags.Usage()
+			return exitUsage
+		}
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "goval: %s\n", err)
+			return exitFailure
+		}
+		return lintSource(&linter, "<stdin>", string(data), stdout, stderr)
+	}
+
+	code := exitOK
+	for _, file := range flags.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "goval: %s\n", err)
+			code = exitFailure
+			continue
+		}
+		if res := lintSource(&linter, file, string(data), stdout, stderr); res > code {
+			code = res
+		}
+	}
+	return code
+}
+
+// lintSource writes the warnings of a single expression to stdout.
+func lintSource(linter *lint.Linter, name, src string, stdout, stderr io.Writer) int {
+	warnings, err := linter.Lint(src)
+	if err != nil {
+		fmt.Fprintf(stderr, "goval: %s: %s\n", name, err)
+		return exitFailure
+	}
+	for _, w := range warnings {
+		line, col := position(src, w.Pos)
+		fmt.Fprintf(stdout, "%s:%d:%d: %s [%s]\n", name, line, col, w.Message, w.Rule)
+	}
+	if len(warnings) > 0 {
+		return exitFalsy
+	}
+	return exitOK
+}
+
+// position converts a 1-based byte position within the source into a line and column.
+func position(src string, pos int) (line, col int) {
+	before := src[:pos-1]
+	line = strings.Count(before, "\n") + 1
+	col = pos - 1 - strings.LastIndexByte(before, '\n')
+	return line, col
+}
+
+func knownRule(rule string) bool {
+	for _, r := range lint.Rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Lint_Stdin(t *testing.T) {
+	code, out, errOut := runCmd("a && b", "lint")
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, out)
+	assert.Empty(t, errOut)
+
+	code, out, _ = runCmd("id == nil || x == 0.5", "lint", "-required", "id")
+	assert.Equal(t, exitFalsy, code)
+	assert.Equal(t, "<stdin>:1:1: id is required and never nil, the comparison is always false [nil-required]\n"+
+		"<stdin>:1:14: floating point numbers are compared for equality [float-equality]\n", out)
+
+	code, out, _ = runCmd("x == 0.5", "lint", "-disable", "float-equality")
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, out)
+
+	code, _, errOut = runCmd("x ==", "lint")
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, "goval: <stdin>: syntax error: unexpected $end\n", errOut)
+
+	code, _, errOut = runCmd("x", "lint", "-disable", "unknown")
+	assert.Equal(t, exitUsage, code)
+	assert.Equal(t, "goval: unknown rule \"unknown\"\n", errOut)
+	code, _, _ = runCmd("", "lint")
+	assert.Equal(t, exitUsage, code)
+}
+
+func Test_Lint_Files(t *testing.T) {
+	clean := writeFile(t, "a.expr", "a && b\n")
+	warning := writeFile(t, "b.expr", "a ? 1 : 1")
+	invalid := writeFile(t, "c.expr", "a &&")
+
+	code, out, _ := runCmd("", "lint", clean, warning)
+	assert.Equal(t, exitFalsy, code)
+	assert.Equal(t, warning+":1:1: both branches of the ternary operator are identical [identical-branches]\n", out)
+
+	code, out, errOut := runCmd("", "lint", invalid, warning)
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, warning+":1:1: both branches of the ternary operator are identical [identical-branches]\n", out)
+	assert.Equal(t, "goval: "+invalid+": syntax error: unexpected $end\n", errOut)
+}
+
+func Test_Lint_MultiLine(t *testing.T) {
+	file := writeFile(t, "multi.expr", "a &&\n  // comment\n  x == 0.5 ||\n    (b ? 1 : 1)\n")
+
+	code, out, _ := runCmd("", "lint", file)
+	assert.Equal(t, exitFalsy, code)
+	assert.Equal(t, file+":3:3: floating point numbers are compared for equality [float-equality]\n"+
+		file+":4:6: both branches of the ternary operator are identical [identical-branches]\n", out)
+}
@@ -0,0 +1,189 @@
+// Command goval evaluates expressions from the command line.
+//
+// Usage:
+//
+//	goval [flags] [file ...]
+//	goval repl [flags]
+//	goval fmt [flags] [file ...]
+//	goval lint [flags] [file ...]
+//
+// Expressions are passed via -e or loaded from files. Variables are read as JSON object from the file given by --vars,
+// or from stdin if it is not a terminal.
+// Each result is written to stdout on its own line.
+//
+// The repl command starts an interactive session.
+// The fmt command formats expression files in their canonical form.
+// The lint command reports likely mistakes within expression files, and exits with status 1 if there are any.
+//
+// Similar to `jq -e`, the exit status reflects the last result:
+//
+//	0  the last result was neither false nor nil
+//	1  the last result was false or nil
+//	2  invalid usage
+//	3  an expression or the variables could not be evaluated
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/maja42/goval"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFalsy   = 1
+	exitUsage   = 2
+	exitFailure = 3
+)
+
+func main() {
+	var stdin io.Reader
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice == 0 {
+		stdin = os.Stdin
+	}
+	os.Exit(run(os.Args[1:], stdin, os.Stdout, os.Stderr))
+}
+
+// stringList is a flag that can be specified multiple times.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringList) Set(val string) error {
+	*s = append(*s, val)
+	return nil
+}
+
+// run executes the command with the given arguments and returns the exit code.
+// stdin is nil if it should not be used for reading variables.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "repl" {
+		return runREPL(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "fmt" {
+		return runFmt(args[1:], stdin, stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "lint" {
+		return runLint(args[1:], stdin, stdout, stderr)
+	}
+
+	flags := flag.NewFlagSet("goval", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, "Usage: goval [flags] [file ...]\n"+
+			"       goval repl [flags]\n"+
+			"       goval fmt [flags] [file ...]\n"+
+			"       goval lint [flags] [file ...]\n\n"+
+			"Evaluates expressions passed via -e or loaded from files.\n"+
+			"Variables are read from --vars, or from stdin if it is not a terminal.\n\n"+
+			"Flags:\n")
+		flags.PrintDefaults()
+	}
+
+	var exprs stringList
+	flags.Var(&exprs, "e", "expression to evaluate (can be repeated)")
+	varsFile := flags.String("vars", "", "JSON file containing an object with variables (\"-\" for stdin)")
+	nullInput := flags.Bool("n", false, "don't read variables from stdin")
+	output := flags.String("o", "json", "output format: \"json\" or \"go\" (Go-literal)")
+	raw := flags.Bool("r", false, "write string results without quotes")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *output != "json" && *output != "go" {
+		fmt.Fprintf(stderr, "goval: invalid output format %q\n", *output)
+		return exitUsage
+	}
+
+	for _, file := range flags.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(stderr, "goval: %s\n", err)
+			return exitFailure
+		}
+		exprs = append(exprs, string(data))
+	}
+	if len(exprs) == 0 {
+		fmt.Fprint(stderr, "goval: no expression given\n")
+		flags.Usage()
+		return exitUsage
+	}
+
+	variables, err := readVariables(*varsFile, *nullInput, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "goval: %s\n", err)
+		return exitFailure
+	}
+
+	eval := goval.NewEvaluator()
+	code := exitOK
+	for _, expr := range exprs {
+		result, err := eval.Evaluate(expr, variables, functions())
+		if err != nil {
+			fmt.Fprintf(stderr, "goval: %s\n", err)
+			return exitFailure
+		}
+		str, err := formatResult(result, *output, *raw)
+		if err != nil {
+			fmt.Fprintf(stderr, "goval: %s\n", err)
+			return exitFailure
+		}
+		fmt.Fprintln(stdout, str)
+
+		code = exitOK
+		if result == nil || result == false {
+			code = exitFalsy
+		}
+	}
+	return code
+}
+
+// readVariables reads the variables from the given file, or from stdin.
+func readVariables(file string, nullInput bool, stdin io.Reader) (map[string]interface{}, error) {
+	var data []byte
+	var err error
+	switch {
+	case file == "-":
+		if stdin == nil {
+			return nil, errors.New("cannot read variables from stdin")
+		}
+		data, err = io.ReadAll(stdin)
+	case file != "":
+		data, err = os.ReadFile(file)
+	case !nullInput && stdin != nil:
+		data, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return goval.DecodeJSONVariables(data)
+}
+
+// formatResult converts an evaluation result into the given output format.
+func formatResult(result interface{}, output string, raw bool) (string, error) {
+	if str, ok := result.(string); ok && raw {
+		return str, nil
+	}
+	if output == "go" {
+		if result == nil {
+			return "nil", nil
+		}
+		return fmt.Sprintf("%#v", result), nil
+	}
+	data, err := goval.EncodeJSON(result)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runCmd(stdin string, args ...string) (int, string, string) {
+	var in io.Reader // nil if stdin is a terminal
+	if stdin != "" {
+		in = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	code := run(args, in, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func Test_Eval(t *testing.T) {
+	code, out, errOut := runCmd("", "-e", "1 + 2")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "3\n", out)
+	assert.Empty(t, errOut)
+
+	code, out, _ = runCmd("", "-e", `"a" + "b"`, "-e", `[1, {"x": nil}]`)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "\"ab\"\n[1,{\"x\":null}]\n", out)
+
+	code, out, _ = runCmd("", "-r", "-e", `"a" + "b"`, "-e", `[1]`)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "ab\n[1]\n", out)
+}
+
+func Test_GoOutput(t *testing.T) {
+	code, out, _ := runCmd("", "-o", "go", "-e", `[1, 2.5, "x", nil]`, "-e", "nil", "-e", `{"b": 1, "a": true}`)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "[]interface {}{1, 2.5, \"x\", interface {}(nil)}\n"+
+		"nil\n"+
+		"map[string]interface {}{\"a\":true, \"b\":1}\n", out)
+}
+
+func Test_Variables(t *testing.T) {
+	vars := `{"user": {"age": 42, "name": "Ann"}}`
+
+	code, out, _ := runCmd(vars, "-e", "user.age >= 18")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "true\n", out)
+
+	code, out, _ = runCmd(vars, "--vars", "-", "-e", "user.name")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "\"Ann\"\n", out)
+
+	file := writeFile(t, "vars.json", vars)
+	code, out, _ = runCmd("", "--vars", file, "-e", "user.age")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "42\n", out)
+
+	// stdin is ignored if -n or --vars is given:
+	code, _, errOut := runCmd(vars, "-n", "-e", "user")
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, "goval: var error: variable \"user\" does not exist\n", errOut)
+
+	code, out, _ = runCmd(`{"user": 1}`, "--vars", file, "-e", "user.age")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "42\n", out)
+}
+
+func Test_ExpressionFiles(t *testing.T) {
+	rule := writeFile(t, "rule.goval", "// adults only\nage >= 18\n")
+	name := writeFile(t, "name.goval", "name")
+
+	code, out, _ := runCmd(`{"age": 12, "name": "Bob"}`, "-e", "age", rule, name)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "12\nfalse\n\"Bob\"\n", out)
+
+	code, _, errOut := runCmd("", filepath.Join(t.TempDir(), "missing.goval"))
+	assert.Equal(t, exitFailure, code)
+	assert.Contains(t, errOut, "no such file or directory")
+}
+
+func Test_ExitStatus(t *testing.T) {
+	code, out, _ := runCmd("", "-e", "false")
+	assert.Equal(t, exitFalsy, code)
+	assert.Equal(t, "false\n", out)
+
+	code, out, _ = runCmd("", "-e", "nil")
+	assert.Equal(t, exitFalsy, code)
+	assert.Equal(t, "null\n", out)
+
+	code, _, _ = runCmd("", "-e", "false", "-e", "0")
+	assert.Equal(t, exitOK, code) // only the last result counts
+
+	code, _, _ = runCmd("", "-e", "true", "-e", `""`)
+	assert.Equal(t, exitOK, code)
+}
+
+func Test_Errors(t *testing.T) {
+	code, out, errOut := runCmd("", "-e", "1 +")
+	assert.Equal(t, exitFailure, code)
+	assert.Empty(t, out)
+	assert.Equal(t, "goval: syntax error: unexpected $end\n", errOut)
+
+	code, _, errOut = runCmd("[1]", "-e", "1")
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, "goval: json error: variables need to be a JSON object\n", errOut)
+
+	code, _, errOut = runCmd("", "-e", "1e308 * 10")
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, "goval: json error: result contains a non-finite number\n", errOut)
+
+	code, _, errOut = runCmd("", "--vars", "-", "-e", "1")
+	assert.Equal(t, exitFailure, code)
+	assert.Equal(t, "goval: cannot read variables from stdin\n", errOut)
+}
+
+func Test_Usage(t *testing.T) {
+	code, _, errOut := runCmd("")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, errOut, "goval: no expression given\nUsage: goval")
+
+	code, _, errOut = runCmd("", "-o", "xml", "-e", "1")
+	assert.Equal(t, exitUsage, code)
+	assert.Equal(t, "goval: invalid output format \"xml\"\n", errOut)
+
+	code, _, _ = runCmd("", "--unknown")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, errOut = runCmd("", "-h")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, errOut, "Usage: goval")
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/peterh/liner"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/internal"
+)
+
+const replHelp = `Enter expressions to evaluate them. Results are stored in the variables $1, $2, ...
+Input continues on the next line as long as brackets are unbalanced.
+
+Commands:
+  :vars              list all variables
+  :funcs             list all functions
+  :load file.json    load variables from a JSON file
+  :type expr         show the type of the result
+  :ast expr          show the syntax tree
+  :help              show this help
+  :quit              exit (or press Ctrl+D)
+`
+
+// prompter reads lines of user input.
+// Implemented by liner.State.
+type prompter interface {
+	Prompt(prompt string) (string, error)
+	AppendHistory(item string)
+}
+
+type repl struct {
+	out       io.Writer
+	output    string // output format
+	eval      *goval.Evaluator
+	variables map[string]interface{}
+	functions map[string]goval.ExpressionFunction
+	results   int // number of results that were stored in numbered variables
+}
+
+func newREPL(out io.Writer, output string) *repl {
+	return &repl{
+		out:       out,
+		output:    output,
+		eval:      goval.NewEvaluator(),
+		variables: make(map[string]interface{}),
+		functions: functions(),
+	}
+}
+
+// runREPL starts an interactive session and returns the exit code.
+func runREPL(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("goval repl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	varsFile := flags.String("vars", "", "JSON file containing an object with variables")
+	output := flags.String("o", "json", "output format: \"json\" or \"go\" (Go-literal)")
+	history := flags.String("history", defaultHistoryFile(), "file for storing the input history (empty to disable)")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *output != "json" && *output != "go" {
+		fmt.Fprintf(stderr, "goval: invalid output format %q\n", *output)
+		return exitUsage
+	}
+
+	r := newREPL(stdout, *output)
+	if *varsFile != "" {
+		if err := r.load(*varsFile); err != nil {
+			fmt.Fprintf(stderr, "goval: %s\n", err)
+			return exitFailure
+		}
+	}
+
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetWordCompleter(r.complete)
+
+	if *history != "" {
+		if f, err := os.Open(*history); err == nil {
+			_, _ = line.ReadHistory(f)
+			f.Close()
+		}
+		defer func() {
+			if f, err := os.Create(*history); err == nil {
+				_, _ = line.WriteHistory(f)
+				f.Close()
+			}
+		}()
+	}
+
+	fmt.Fprint(stdout, replHelp+"\n")
+	r.loop(line)
+	return exitOK
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".goval_history")
+}
+
+// loop reads and executes input until the user exits.
+func (r *repl) loop(p prompter) {
+	for {
+		input, err := r.read(p)
+		if errors.Is(err, liner.ErrPromptAborted) {
+			continue // Ctrl+C discards the current input
+		}
+		if err != nil {
+			return
+		}
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+		p.AppendHistory(input)
+		if !r.execute(input) {
+			return
+		}
+	}
+}
+
+// read reads a single input, which continues on the next line as long as brackets are unbalanced.
+func (r *repl) read(p prompter) (string, error) {
+	prompt := "> "
+	var lines []string
+	for {
+		line, err := p.Prompt(prompt)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+		input := strings.Join(lines, "\n")
+		if !isIncomplete(input) {
+			return input, nil
+		}
+		prompt = "... "
+	}
+}
+
+// execute executes a single input.
+// Returns false if the session should end.
+func (r *repl) execute(input string) bool {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, ":") {
+		result, err := r.eval.Evaluate(input, r.variables, r.functions)
+		if err != nil {
+			r.printf("error: %s\n", err)
+			return true
+		}
+		r.results++
+		name := "$" + strconv.Itoa(r.results)
+		r.variables[name] = result
+		r.printf("%s = %s\n", name, r.format(result))
+		return true
+	}
+
+	cmd, arg := input, ""
+	if idx := strings.IndexFunc(input, unicode.IsSpace); idx >= 0 {
+		cmd, arg = input[:idx], strings.TrimSpace(input[idx:])
+	}
+
+	switch cmd {
+	case ":quit", ":q", ":exit":
+		return false
+	case ":help":
+		r.printf("%s", replHelp)
+	case ":vars":
+		for _, name := range sortedVariables(r.variables) {
+			r.printf("%s = %s\n", name, r.format(r.variables[name]))
+		}
+	case ":funcs":
+		names := make([]string, 0, len(builtins))
+		for name := range builtins {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			r.printf("%s\n", builtins[name].usage)
+		}
+	case ":load":
+		if arg == "" {
+			r.printf("error: missing file name\n")
+			break
+		}
+		if err := r.load(arg); err != nil {
+			r.printf("error: %s\n", err)
+		}
+	case ":type":
+		result, err := r.eval.Evaluate(arg, r.variables, r.functions)
+		if err != nil {
+			r.printf("error: %s\n", err)
+			break
+		}
+		r.printf("%s (%T)\n", internal.TypeOf(result), result)
+	case ":ast":
+		program, err := internal.Parse(arg)
+		if err != nil {
+			r.printf("error: %s\n", err)
+			break
+		}
+		r.printf("%s", internal.Dump(program.Root))
+	default:
+		r.printf("error: unknown command %q, see :help\n", cmd)
+	}
+	return true
+}
+
+// load adds all variables from the given JSON file.
+func (r *repl) load(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	vars, err := goval.DecodeJSONVariables(data)
+	if err != nil {
+		return err
+	}
+	for name, val := range vars {
+		r.variables[name] = val
+	}
+	r.printf("loaded %d variables\n", len(vars))
+	return nil
+}
+
+func (r *repl) format(result interface{}) string {
+	str, err := formatResult(result, r.output, false)
+	if err != nil {
+		return fmt.Sprintf("%v (%s)", result, err)
+	}
+	return str
+}
+
+func (r *repl) printf(format string, args ...interface{}) {
+	fmt.Fprintf(r.out, format, args...)
+}
+
+// complete returns completions for the word in front of the cursor.
+// Completes commands, variable and function names.
+func (r *repl) complete(line string, pos int) (head string, completions []string, tail string) {
+	head, tail = line[:pos], line[pos:]
+	start := strings.LastIndexFunc(head, func(c rune) bool {
+		return !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '$' || c == ':')
+	}) + 1
+	head, word := head[:start], head[start:]
+
+	var candidates []string
+	if strings.HasPrefix(word, ":") {
+		candidates = []string{":vars", ":funcs", ":load ", ":type ", ":ast ", ":help", ":quit"}
+	} else {
+		candidates = sortedVariables(r.variables)
+		for name := range r.functions {
+			candidates = append(candidates, name+"(")
+		}
+	}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			completions = append(completions, c)
+		}
+	}
+	sort.Strings(completions)
+	return head, completions, tail
+}
+
+// sortedVariables returns all variable names.
+// Numbered variables come last, in ascending order.
+func sortedVariables(vars map[string]interface{}) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ni, iNumbered := resultNumber(names[i])
+		nj, jNumbered := resultNumber(names[j])
+		if iNumbered != jNumbered {
+			return jNumbered
+		}
+		if iNumbered {
+			return ni < nj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func resultNumber(name string) (int, bool) {
+	if !strings.HasPrefix(name, "$") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(name[1:])
+	return n, err == nil
+}
+
+// isIncomplete reports whether the input contains unclosed brackets, strings or comments.
+func isIncomplete(input string) bool {
+	depth := 0
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; c {
+		case '"', '`':
+			for i++; i < len(input) && input[i] != c; i++ {
+				if c == '"' && input[i] == '\\' {
+					i++
+				}
+				if c == '"' && i < len(input) && input[i] == '\n' {
+					return false // double-quoted strings cannot span multiple lines
+				}
+			}
+			if i >= len(input) {
+				return c == '`'
+			}
+		case '/':
+			if strings.HasPrefix(input[i:], "//") {
+				end := strings.IndexByte(input[i:], '\n')
+				if end < 0 {
+					return depth > 0
+				}
+				i += end
+			} else if strings.HasPrefix(input[i:], "/*") {
+				end := strings.Index(input[i+2:], "*/")
+				if end < 0 {
+					return true
+				}
+				i += end + 3
+			}
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		}
+	}
+	return depth > 0
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/peterh/liner"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePrompter returns the given lines as user input.
+type fakePrompter struct {
+	lines   []string
+	prompts []string
+	history []string
+}
+
+func (p *fakePrompter) Prompt(prompt string) (string, error) {
+	p.prompts = append(p.prompts, prompt)
+	if len(p.lines) == 0 {
+		return "", io.EOF
+	}
+	line := p.lines[0]
+	p.lines = p.lines[1:]
+	if line == "^C" {
+		return "", liner.ErrPromptAborted
+	}
+	return line, nil
+}
+
+func (p *fakePrompter) AppendHistory(item string) {
+	p.history = append(p.history, item)
+}
+
+func runSession(lines ...string) (string, *fakePrompter, *repl) {
+	var out bytes.Buffer
+	r := newREPL(&out, "json")
+	p := &fakePrompter{lines: lines}
+	r.loop(p)
+	return out.String(), p, r
+}
+
+func Test_REPL_ResultVariables(t *testing.T) {
+	out, p, _ := runSession("1 + 2", "", "$1 * 2", "[$1, $2]", "$4")
+	assert.Equal(t, "$1 = 3\n"+
+		"$2 = 6\n"+
+		"$3 = [3,6]\n"+
+		"error: var error: variable \"$4\" does not exist\n", out)
+	assert.Equal(t, []string{"1 + 2", "$1 * 2", "[$1, $2]", "$4"}, p.history)
+
+	out, _, _ = runSession(`{"name": "ann", "a": {"b": [1, 2]}}`, "$1.name", "$1.a.b[1]")
+	assert.Equal(t, "$1 = {\"a\":{\"b\":[1,2]},\"name\":\"ann\"}\n"+
+		"$2 = \"ann\"\n"+
+		"$3 = 2\n", out)
+}
+
+func Test_REPL_MultiLine(t *testing.T) {
+	out, p, _ := runSession("[1,", "2,", "3] + [", "4]", `"a" + "b"`)
+	assert.Equal(t, "$1 = [1,2,3,4]\n$2 = \"ab\"\n", out)
+	assert.Equal(t, []string{"> ", "... ", "... ", "... ", "> ", "> "}, p.prompts)
+	assert.Equal(t, []string{"[1,\n2,\n3] + [\n4]", `"a" + "b"`}, p.history)
+
+	// Ctrl+C discards incomplete input:
+	out, _, _ = runSession("[1,", "^C", "2")
+	assert.Equal(t, "$1 = 2\n", out)
+}
+
+func Test_REPL_IsIncomplete(t *testing.T) {
+	assert.False(t, isIncomplete(``))
+	assert.False(t, isIncomplete(`1 + 2`))
+	assert.False(t, isIncomplete(`f(1, [2], {"a": 3})`))
+	assert.False(t, isIncomplete(`1)`)) // reported by the parser
+	assert.False(t, isIncomplete(`"(" + "\"["`))
+	assert.False(t, isIncomplete("1 // (\n"))
+	assert.False(t, isIncomplete(`"unterminated`))
+
+	assert.True(t, isIncomplete(`f(`))
+	assert.True(t, isIncomplete(`[1, {"a": [`))
+	assert.True(t, isIncomplete("`multi\nline"))
+	assert.True(t, isIncomplete(`1 /* comment`))
+	assert.True(t, isIncomplete("[ // )\n"))
+}
+
+func Test_REPL_Commands(t *testing.T) {
+	vars := writeFile(t, "vars.json", `{"user": {"name": "Ann"}, "age": 42}`)
+
+	out, _, r := runSession(":load "+vars, "user.name", ":vars", ":type age", ":type 1.5", ":type $1", ":unknown", ":load")
+	assert.Equal(t, "loaded 2 variables\n"+
+		"$1 = \"Ann\"\n"+
+		"age = 42\n"+
+		"user = {\"name\":\"Ann\"}\n"+
+		"$1 = \"Ann\"\n"+
+		"number (int)\n"+
+		"number (float64)\n"+
+		"string (string)\n"+
+		"error: unknown command \":unknown\", see :help\n"+
+		"error: missing file name\n", out)
+	assert.Equal(t, 42, r.variables["age"])
+
+	out, _, _ = runSession(":funcs")
+	assert.Equal(t, "keys(obj) returns the sorted keys of an object\n"+
+		"len(x) returns the length of a string, array or object\n"+
+		"lower(str) converts a string to lower case\n"+
+		"upper(str) converts a string to upper case\n"+
+		"values(obj) returns the values of an object, sorted by their keys\n", out)
+
+	out, _, _ = runSession(":ast 1 + a", ":ast 1 +", ":type x")
+	assert.Equal(t, "BinaryExpr +\n"+
+		"  Literal 1\n"+
+		"  Ident a\n"+
+		"error: syntax error: unexpected $end\n"+
+		"error: var error: variable \"x\" does not exist\n", out)
+
+	out, _, _ = runSession(":load missing.json")
+	assert.Contains(t, out, "error: open missing.json")
+
+	out, _, _ = runSession(":help")
+	assert.Equal(t, replHelp, out)
+
+	out, p, _ := runSession("1", ":quit", "2")
+	assert.Equal(t, "$1 = 1\n", out)
+	assert.Equal(t, []string{"2"}, p.lines)
+}
+
+func Test_REPL_Complete(t *testing.T) {
+	r := newREPL(io.Discard, "json")
+	r.variables["user"] = 1
+	r.variables["upper_limit"] = 2
+	r.variables["$1"] = 3
+	r.variables["$10"] = 4
+	r.variables["$2"] = 5
+
+	head, completions, tail := r.complete("1 + u", 5)
+	assert.Equal(t, "1 + ", head)
+	assert.Equal(t, []string{"upper(", "upper_limit", "user"}, completions)
+	assert.Equal(t, "", tail)
+
+	head, completions, tail = r.complete("[le] + x", 3)
+	assert.Equal(t, "[", head)
+	assert.Equal(t, []string{"len("}, completions)
+	assert.Equal(t, "] + x", tail)
+
+	_, completions, _ = r.complete("$1", 2)
+	assert.Equal(t, []string{"$1", "$10"}, completions)
+
+	_, completions, _ = r.complete(":t", 2)
+	assert.Equal(t, []string{":type "}, completions)
+
+	_, completions, _ = r.complete("x", 1)
+	assert.Empty(t, completions)
+}
+
+func Test_REPL_SortedVariables(t *testing.T) {
+	vars := map[string]interface{}{"b": 1, "$10": 1, "a": 1, "$2": 1, "$1": 1}
+	assert.Equal(t, []string{"a", "b", "$1", "$2", "$10"}, sortedVariables(vars))
+}
+
+func Test_REPL_GoOutput(t *testing.T) {
+	var out bytes.Buffer
+	r := newREPL(&out, "go")
+	r.loop(&fakePrompter{lines: []string{`[1, "a"]`, "nil"}})
+	assert.Equal(t, "$1 = []interface {}{1, \"a\"}\n$2 = nil\n", out.String())
+}
+
+func Test_REPL_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitUsage, run([]string{"repl", "-o", "xml"}, nil, &stdout, &stderr))
+	assert.Equal(t, "goval: invalid output format \"xml\"\n", stderr.String())
+
+	stderr.Reset()
+	assert.Equal(t, exitOK, run([]string{"repl", "-h"}, nil, &stdout, &stderr))
+	assert.True(t, strings.Contains(stderr.String(), "-history"))
+}
@@ -1,32 +1,196 @@
 package goval
 
 import (
+	"fmt"
+
+	"github.com/maja42/goval/ast"
 	"github.com/maja42/goval/internal"
 )
 
 // NewEvaluator creates a new evaluator.
-func NewEvaluator() *Evaluator {
-	return &Evaluator{}
+//
+// Accepts options, like WithCache, WithVariables, WithFunctions, WithStrictMode and WithLimits.
+func NewEvaluator(options ...Option) *Evaluator {
+	e := &Evaluator{}
+	for _, option := range options {
+		option(e)
+	}
+	return e
 }
 
 // Evaluator is used to evaluate expression strings.
+//
+// Can be used concurrently. Options can only be set during construction.
 type Evaluator struct {
+	cache     *expressionCache // nil if disabled
+	variables map[string]interface{}
+	functions map[string]ExpressionFunction
+	strict    bool
+	limits    Limits
 }
 
 // ExpressionFunction can be called from within expressions.
 //
-// The returned object needs to have one of the following types: `nil`, `bool`, `int`, `float64`, `string`, `[]interface{}` or `map[string]interface{}`.
+// The returned object needs to have one of the following types: `nil`, `bool`, `int`, `float64`, `string`, `[]interface{}`, `map[string]interface{}` or Value.
 type ExpressionFunction = func(args ...interface{}) (interface{}, error)
 
 // Evaluate the given expression string.
 //
 // Optionally accepts a list of variables (accessible but not modifiable from within expressions).
+// They shadow default variables with the same name (see WithVariables).
 //
 // Optionally accepts a list of expression functions (can be called from within expressions).
+// They shadow default functions with the same name (see WithFunctions).
 //
 // Returns the resulting object or an error.
 //
-// Stateless. Can be called concurrently. If expression functions modify variables, concurrent execution requires additional synchronization.
+// If the evaluator has a cache (see WithCache), the parsed expression is cached and reused by subsequent calls.
+//
+// Can be called concurrently. If expression functions modify variables, concurrent execution requires additional synchronization.
 func (e *Evaluator) Evaluate(str string, variables map[string]interface{}, functions map[string]ExpressionFunction) (result interface{}, err error) {
-	return internal.Evaluate(str, variables, functions)
+	if e.limits.MaxLength > 0 && len(str) > e.limits.MaxLength {
+		return nil, fmt.Errorf("limit error: expression is longer than %d bytes", e.limits.MaxLength)
+	}
+	var compiled *internal.Compiled
+	if e.cache != nil {
+		compiled, err = e.cache.compile(str)
+	} else {
+		compiled, err = internal.Compile(str)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e.evaluate(compiled, variables, functions)
+}
+
+// evaluate applies the evaluator's options to the evaluation of a compiled expression.
+func (e *Evaluator) evaluate(compiled *internal.Compiled, variables map[string]interface{}, functions map[string]ExpressionFunction) (interface{}, error) {
+	scope, functions, err := e.prepare(compiled, variables, functions)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.EvaluateLimited(scope, functions, e.limits.MaxSteps)
+}
+
+// parse parses the expression string for tracing and partial evaluation, and verifies it against the evaluator's options.
+func (e *Evaluator) parse(str string, variables map[string]interface{}, functions map[string]ExpressionFunction) (*internal.Program, *internal.Scope, map[string]ExpressionFunction, error) {
+	if e.limits.MaxLength > 0 && len(str) > e.limits.MaxLength {
+		return nil, nil, nil, fmt.Errorf("limit error: expression is longer than %d bytes", e.limits.MaxLength)
+	}
+	program, err := internal.Parse(str)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	compiled, err := program.Compile() // the compiled expression knows its depth
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	scope, functions, err := e.prepare(compiled, variables, functions)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return program, scope, functions, nil
+}
+
+// prepare verifies the expression and arguments against the evaluator's options.
+// Returns the scope and functions that are accessible from within the expression.
+func (e *Evaluator) prepare(compiled *internal.Compiled, variables map[string]interface{}, functions map[string]ExpressionFunction) (*internal.Scope, map[string]ExpressionFunction, error) {
+	if e.limits.MaxDepth > 0 && compiled.Depth() > e.limits.MaxDepth {
+		return nil, nil, fmt.Errorf("limit error: expression is nested deeper than %d levels", e.limits.MaxDepth)
+	}
+	if e.strict {
+		for name := range variables {
+			if _, ok := e.variables[name]; ok {
+				return nil, nil, fmt.Errorf("var error: variable %q shadows a default variable", name)
+			}
+		}
+		for name := range functions {
+			if _, ok := e.functions[name]; ok {
+				return nil, nil, fmt.Errorf("syntax error: function %q shadows a default function", name)
+			}
+		}
+	}
+
+	// Per-call functions are merged (there are usually few), variables are layered.
+	switch {
+	case len(e.functions) == 0:
+	case len(functions) == 0:
+		functions = e.functions
+	default:
+		merged := make(map[string]ExpressionFunction, len(e.functions)+len(functions))
+		for name, f := range e.functions {
+			merged[name] = f
+		}
+		for name, f := range functions {
+			merged[name] = f
+		}
+		functions = merged
+	}
+	return internal.NewScopeWithDefaults(variables, e.variables), functions, nil
+}
+
+// CacheStats returns statistics about the evaluator's expression cache (see WithCache).
+// Returns zero values if the cache is disabled.
+func (e *Evaluator) CacheStats() CacheStats {
+	if e.cache == nil {
+		return CacheStats{}
+	}
+	return e.cache.stats()
+}
+
+// SyntaxError is returned if an expression cannot be parsed.
+// Pos contains the position at which parsing failed, using the same counting as error messages. It is 0 if unknown.
+type SyntaxError = internal.SyntaxError
+
+// Parse the given expression string into an abstract syntax tree.
+//
+// The tree can be inspected, modified, converted to JSON (see package ast) and evaluated with EvaluateAST.
+func Parse(str string) (*ast.Program, error) {
+	program, err := internal.Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Program{
+		Root:     program.Root,
+		Comments: program.Comments,
+	}, nil
+}
+
+// EvaluateAST evaluates a parsed expression.
+//
+// Accepts the same variables and functions as Evaluate, and applies the same options (except for the cache).
+// Manually constructed trees need to be valid. Trees created by Parse or ast.UnmarshalNode always are.
+func (e *Evaluator) EvaluateAST(program *ast.Program, variables map[string]interface{}, functions map[string]ExpressionFunction) (result interface{}, err error) {
+	p := internal.Program{Root: program.Root}
+	compiled, err := p.Compile()
+	if err != nil {
+		return nil, err
+	}
+	return e.evaluate(compiled, variables, functions)
+}
+
+// Compile parses the given expression string and compiles it.
+//
+// Compiled expressions can be evaluated repeatedly without being parsed again,
+// which is considerably faster than calling Evaluate with the same expression string.
+func Compile(str string) (*Expression, error) {
+	compiled, err := internal.Compile(str)
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{compiled: compiled}, nil
+}
+
+// Expression is a compiled expression.
+//
+// Immutable. Can be evaluated concurrently.
+type Expression struct {
+	compiled *internal.Compiled
+}
+
+// Evaluate the compiled expression.
+//
+// Accepts the same variables and functions as Evaluator.Evaluate, and returns identical results.
+func (x *Expression) Evaluate(variables map[string]interface{}, functions map[string]ExpressionFunction) (result interface{}, err error) {
+	return x.compiled.Evaluate(variables, functions)
 }
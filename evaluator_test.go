@@ -1,8 +1,10 @@
 package goval
 
 import (
-	"github.com/stretchr/testify/assert"
+	"errors"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_Evaluator(t *testing.T) {
@@ -20,3 +22,64 @@ func Test_Evaluator(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 42, result)
 }
+
+func Test_EvaluateAST(t *testing.T) {
+	program, err := Parse("func(var) + 21 // comment")
+	assert.NoError(t, err)
+	assert.Len(t, program.Comments, 1)
+
+	functions := map[string]ExpressionFunction{
+		"func": func(args ...interface{}) (interface{}, error) {
+			return args[0], nil
+		},
+	}
+	result, err := NewEvaluator().EvaluateAST(program, map[string]interface{}{"var": 21}, functions)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, result)
+
+	_, err = NewEvaluator().EvaluateAST(program, nil, functions)
+	assert.EqualError(t, err, "var error: variable \"var\" does not exist")
+
+	_, err = Parse("1 +")
+	assert.EqualError(t, err, "syntax error: unexpected $end")
+}
+
+func Test_SyntaxError(t *testing.T) {
+	assertPos := func(expected int, str string) {
+		t.Helper()
+		_, err := Parse(str)
+		var syntaxErr *SyntaxError
+		if assert.True(t, errors.As(err, &syntaxErr), "expression: %s", str) {
+			assert.Equal(t, expected, syntaxErr.Pos, "expression: %s", str)
+		}
+	}
+	assertPos(4, "1 +")
+	assertPos(5, "1 + ) + 2")
+	assertPos(3, "a #")
+	assertPos(10, `"a" + f"{)}"`)
+	assertPos(1, `/* comment`)
+}
+
+func Test_Compile(t *testing.T) {
+	expression, err := Compile("[x * factor for x in values if x > 1]")
+	assert.NoError(t, err)
+
+	variables := map[string]interface{}{
+		"values": []interface{}{1, 2, 3},
+		"factor": 2,
+	}
+	result, err := expression.Evaluate(variables, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{4, 6}, result)
+
+	variables["factor"] = 1.5
+	result, err = expression.Evaluate(variables, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{3.0, 4.5}, result)
+
+	_, err = expression.Evaluate(nil, nil)
+	assert.EqualError(t, err, "var error: variable \"values\" does not exist")
+
+	_, err = Compile("1 +")
+	assert.EqualError(t, err, "syntax error: unexpected $end")
+}
@@ -65,8 +65,8 @@ func main() {
 
 	eval := goval.NewEvaluator()
 
+	reader := bufio.NewReader(os.Stdin)
 	for {
-		reader := bufio.NewReader(os.Stdin)
 		fmt.Print("> ")
 		input, err := reader.ReadString('\n')
 		if err != nil {
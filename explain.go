@@ -0,0 +1,146 @@
+package goval
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/maja42/goval/ast"
+)
+
+// Explain returns the conditions that decided the boolean result of the traced expression.
+//
+// Logical operators are followed into their operands: A false conjunction (&&) is explained by one of its false operands,
+// a true conjunction by all of them. Disjunctions (||) behave the other way round.
+// If multiple operands decide the result on their own, the one with the fewest conditions is chosen.
+// Negations (!) and ternary operators are explained by their operands.
+// Everything else, like comparisons, variables and function calls, is a condition. Literals are not.
+//
+// Returns nil if the result is not a bool, or if the evaluation failed.
+// Use Condition to describe the returned conditions.
+func (t *Trace) Explain() []*Trace {
+	if _, ok := truthy(t); !ok || t.Err != nil {
+		return nil
+	}
+	return t.explain()
+}
+
+func (t *Trace) explain() []*Trace {
+	value, _ := truthy(t)
+
+	switch n := t.Node.(type) {
+	case *ast.Literal:
+		return nil
+	case *ast.UnaryExpr:
+		if n.Op == "!" && len(t.Children) == 1 {
+			return t.Children[0].explain()
+		}
+	case *ast.BinaryExpr:
+		if (n.Op == "&&" || n.Op == "||") && len(t.Children) == 2 {
+			// An operand decides the result on its own if it has the same value as the whole operation, and:
+			//   && is false (false && x == false)
+			//   || is true  (true || x == true)
+			decisive := value == (n.Op == "||")
+			if decisive {
+				return minimal(t.Children, value)
+			}
+			return append(t.Children[0].explain(), t.Children[1].explain()...)
+		}
+	case *ast.TernaryExpr:
+		if len(t.Children) == 3 {
+			cond, _ := truthy(t.Children[0])
+			chosen := t.Children[2]
+			if cond {
+				chosen = t.Children[1]
+			}
+			return append(t.Children[0].explain(), chosen.explain()...)
+		}
+	}
+	return []*Trace{t}
+}
+
+// minimal returns the shortest explanation of all operands with the given truth value.
+func minimal(operands []*Trace, value bool) (conditions []*Trace) {
+	found := false
+	for _, op := range operands {
+		if v, ok := truthy(op); !ok || v != value {
+			continue
+		}
+		if explanation := op.explain(); !found || len(explanation) < len(conditions) {
+			conditions, found = explanation, true
+		}
+	}
+	return conditions
+}
+
+// truthy returns the truth value of the traced result.
+// Returns false if the result is neither a bool nor a Truther.
+func truthy(t *Trace) (value bool, ok bool) {
+	switch v := t.Value.(type) {
+	case bool:
+		return v, true
+	case Truther:
+		return v.Truthy(), true
+	}
+	return false, false
+}
+
+// negatedOperators contains the opposite of all comparison operators.
+var negatedOperators = map[string]string{
+	"==": "!=",
+	"!=": "==",
+	"<":  ">=",
+	"<=": ">",
+	">":  "<=",
+	">=": "<",
+}
+
+// Condition describes the traced sub-expression as a condition that is fulfilled by its result,
+// annotated with the values of its operands. Examples:
+//
+//	account.verified == false
+//	user.age (17) < 18
+//	isAdmin(user) == true
+//
+// Comparisons that are false are negated if possible.
+func (t *Trace) Condition() string {
+	n, ok := t.Node.(*ast.BinaryExpr)
+	if !ok || len(t.Children) != 2 || t.Err != nil {
+		return t.Source + " == " + t.result(true)
+	}
+	_, comparison := negatedOperators[n.Op]
+	x, y := t.Children[0], t.Children[1]
+
+	switch value, _ := t.Value.(bool); {
+	case value && (comparison || n.Op == "in"):
+		return fmt.Sprintf("%s %s %s", operand(x), n.Op, operand(y))
+	case !value && comparison && negatable(n.Op, x.Value, y.Value):
+		return fmt.Sprintf("%s %s %s", operand(x), negatedOperators[n.Op], operand(y))
+	}
+	return t.Source + " == " + t.result(true)
+}
+
+// negatable reports whether the opposite of a comparison is fulfilled if the comparison isn't.
+// This is not the case for NaN and custom values, which might not be totally ordered.
+func negatable(op string, x, y interface{}) bool {
+	if op == "==" || op == "!=" {
+		return true
+	}
+	ordered := func(val interface{}) bool {
+		switch v := val.(type) {
+		case int, string:
+			return true
+		case float64:
+			return !math.IsNaN(v)
+		}
+		return false
+	}
+	return ordered(x) && ordered(y)
+}
+
+// operand returns the source of the operand, annotated with its value unless it is a literal.
+func operand(t *Trace) string {
+	if _, ok := t.Node.(*ast.Literal); ok {
+		return t.Source
+	}
+	return t.Source + " (" + t.result(false) + ")"
+}
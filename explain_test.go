@@ -0,0 +1,62 @@
+package goval
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Explain(t *testing.T) {
+	vars := map[string]interface{}{
+		"account": map[string]interface{}{"verified": false, "balance": 120.5},
+		"user":    map[string]interface{}{"age": 17, "roles": []interface{}{"guest"}},
+		"a":       true,
+		"b":       false,
+		"c":       false,
+		"nan":     math.NaN(),
+	}
+	functions := map[string]ExpressionFunction{
+		"isAdmin": func(args ...interface{}) (interface{}, error) {
+			return false, nil
+		},
+	}
+	assertExplanation := func(str string, expected ...string) {
+		t.Helper()
+		_, trace, err := NewEvaluator().Trace(str, vars, functions)
+		if !assert.NoError(t, err, str) {
+			return
+		}
+		var conditions []string
+		for _, c := range trace.Explain() {
+			conditions = append(conditions, c.Condition())
+		}
+		assert.Equal(t, expected, conditions, str)
+	}
+
+	assertExplanation(`account.verified`, `account.verified == false`)
+	assertExplanation(`a && (b || c)`, `b == false`, `c == false`)
+	assertExplanation(`a && (b || c) && true`, `b == false`, `c == false`)
+	assertExplanation(`a || b`, `a == true`)
+	assertExplanation(`(b || c) && !a`, `a == true`) // fewer conditions
+	assertExplanation(`a && !b`, `a == true`, `b == false`)
+	assertExplanation(`account.balance > 100 && account.verified`, `account.verified == false`)
+	assertExplanation(`user.age >= 18 || isAdmin(user)`, `user.age (17) < 18`, `isAdmin(user) == false`)
+	assertExplanation(`"admin" in user.roles`, `"admin" in user.roles == false`)
+	assertExplanation(`"guest" in user.roles`, `"guest" in user.roles (["guest"])`)
+	assertExplanation(`user.age == 17`, `user.age (17) == 17`)
+	assertExplanation(`user.age + 1 != 18`, `user.age + 1 (18) == 18`)
+	assertExplanation(`nan > 0`, `nan > 0 == false`) // not negatable
+	assertExplanation(`a ? user.age < 18 : b`, `a == true`, `user.age (17) < 18`)
+	assertExplanation(`true`)
+}
+
+func Test_Explain_NoBool(t *testing.T) {
+	_, trace, err := NewEvaluator().Trace(`1 + 2`, nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, trace.Explain())
+
+	_, trace, err = NewEvaluator().Trace(`true && missing`, nil, nil)
+	assert.Error(t, err)
+	assert.Nil(t, trace.Explain())
+}
@@ -0,0 +1,170 @@
+package format
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// The formatter first converts the syntax tree into a document, which is then rendered with a maximum line width.
+// Groups are rendered on a single line if they fit, otherwise all their line breaks are used.
+
+type doc interface{}
+
+type textDoc string
+
+type lineDoc int
+
+const (
+	line         lineDoc = iota // space, or line break
+	softline                    // nothing, or line break
+	hardline                    // always a line break
+	breakParent                 // nothing, but forces the enclosing group to break
+	commentBreak                // line break after line comments, merged with an immediately following line break
+)
+
+// nestDoc indents all line breaks within its content by one level.
+type nestDoc struct {
+	content doc
+}
+
+type groupDoc struct {
+	content doc
+	broken  bool // contains a hardline or breakParent
+}
+
+type concatDoc []doc
+
+func concat(docs ...doc) doc {
+	return concatDoc(docs)
+}
+
+func nest(docs ...doc) doc {
+	return nestDoc{content: concatDoc(docs)}
+}
+
+func group(docs ...doc) doc {
+	content := concatDoc(docs)
+	return groupDoc{content: content, broken: forcesBreak(content)}
+}
+
+// forcesBreak reports whether the document contains a hardline or breakParent that is not within a nested group.
+// Nested groups that are broken propagate this to their parent.
+func forcesBreak(d doc) bool {
+	switch d := d.(type) {
+	case lineDoc:
+		return d == hardline || d == breakParent || d == commentBreak
+	case nestDoc:
+		return forcesBreak(d.content)
+	case groupDoc:
+		return d.broken
+	case concatDoc:
+		for _, c := range d {
+			if forcesBreak(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type renderCmd struct {
+	indent int
+	flat   bool
+	doc    doc
+}
+
+// render converts the document into text with the given maximum line width.
+func render(d doc, width int, indent string) string {
+	var sb strings.Builder
+	col := 0
+	stack := []renderCmd{{doc: d}}
+	pending := -1 // indentation of a pending commentBreak, or -1
+
+	newline := func(level int) {
+		sb.WriteByte('\n')
+		sb.WriteString(strings.Repeat(indent, level))
+		col = level * len(indent)
+		pending = -1
+	}
+
+	for len(stack) > 0 {
+		cmd := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		switch d := cmd.doc.(type) {
+		case textDoc:
+			if pending >= 0 && d != "" {
+				newline(pending)
+			}
+			sb.WriteString(string(d))
+			col += utf8.RuneCountInString(string(d))
+		case concatDoc:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, renderCmd{cmd.indent, cmd.flat, d[i]})
+			}
+		case nestDoc:
+			stack = append(stack, renderCmd{cmd.indent + 1, cmd.flat, d.content})
+		case groupDoc:
+			flat := cmd.flat || (!d.broken && fits(width-col, renderCmd{cmd.indent, true, d.content}, stack))
+			stack = append(stack, renderCmd{cmd.indent, flat, d.content})
+		case lineDoc:
+			if d == breakParent {
+				break
+			}
+			if d == commentBreak {
+				pending = cmd.indent // the line break is written in front of the following text
+				break
+			}
+			if cmd.flat && d != hardline {
+				if d == line && pending < 0 {
+					sb.WriteByte(' ')
+					col++
+				}
+				break
+			}
+			newline(cmd.indent)
+		}
+	}
+	return sb.String()
+}
+
+// fits reports whether cmd, followed by the remaining commands up to the next line break, fits into the given width.
+func fits(width int, cmd renderCmd, rest []renderCmd) bool {
+	cmds := []renderCmd{cmd}
+	restIdx := len(rest)
+	for width >= 0 {
+		if len(cmds) == 0 {
+			if restIdx == 0 {
+				return true
+			}
+			restIdx--
+			cmds = append(cmds, rest[restIdx])
+		}
+		c := cmds[len(cmds)-1]
+		cmds = cmds[:len(cmds)-1]
+
+		switch d := c.doc.(type) {
+		case textDoc:
+			width -= utf8.RuneCountInString(string(d))
+		case concatDoc:
+			for i := len(d) - 1; i >= 0; i-- {
+				cmds = append(cmds, renderCmd{c.indent, c.flat, d[i]})
+			}
+		case nestDoc:
+			cmds = append(cmds, renderCmd{c.indent + 1, c.flat, d.content})
+		case groupDoc:
+			cmds = append(cmds, renderCmd{c.indent, c.flat && !d.broken, d.content})
+		case lineDoc:
+			if d == breakParent {
+				break
+			}
+			if !c.flat || d == hardline || d == commentBreak {
+				return true
+			}
+			if d == line {
+				width--
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,350 @@
+// Package format converts expressions into their canonical form.
+//
+// Formatting normalizes spacing, removes redundant parentheses and wraps expressions that exceed the line width.
+// Literals are printed the way they were written, comments are kept.
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maja42/goval/ast"
+	"github.com/maja42/goval/internal"
+)
+
+const (
+	// Width is the maximum line width. Longer expressions are wrapped where possible.
+	Width = 80
+	// Indent is used for indenting wrapped lines.
+	Indent = "    "
+)
+
+// Source formats the given expression.
+// Returns an error if the expression cannot be parsed.
+func Source(src string) (string, error) {
+	program, err := internal.Parse(src)
+	if err != nil {
+		return "", err
+	}
+	f := &formatter{
+		src:      src,
+		comments: program.Comments,
+	}
+	d := concat(
+		f.node(program.Root),
+		f.trailingComments(program.Root.End(), len(src)+1),
+	)
+	return render(d, Width, Indent), nil
+}
+
+// unparen removes all parentheses around the node.
+func unparen(n internal.Node) internal.Node {
+	for {
+		p, ok := n.(*internal.ParenExpr)
+		if !ok {
+			return n
+		}
+		n = p.X
+	}
+}
+
+type formatter struct {
+	src      string
+	comments []internal.Comment
+	next     int // index of the next comment that was not printed yet
+}
+
+// node returns the document for the given node, preceded by all comments in front of it.
+// Parentheses are removed. They are added by the caller where necessary.
+// Comments between the node (including its parentheses) and a following operator are kept behind the node.
+// Comments in front of commas, closing brackets and the end of the source are left to the caller.
+func (f *formatter) node(n internal.Node) doc {
+	next := f.tokenAfter(n.End())
+	n = unparen(n)
+	d := concat(f.leadingComments(n.Pos()), f.expr(n))
+	if next <= len(f.src) && !strings.ContainsRune(",)]}", rune(f.src[next-1])) {
+		// code after a line comment is indented
+		d = concat(d, nest(f.sameLineComments(n.End(), next)), f.ownLineComments(next))
+	}
+	return d
+}
+
+// operand returns the document for an operand, which is wrapped in parentheses if its precedence is below minPrec.
+func (f *formatter) operand(n internal.Node, minPrec int) doc {
+	if ast.Precedence(unparen(n)) >= minPrec {
+		return f.node(n)
+	}
+	return concat(textDoc("("), f.node(n), textDoc(")"))
+}
+
+func (f *formatter) expr(n internal.Node) doc {
+	switch n := n.(type) {
+	case *internal.Literal:
+		return textDoc(n.Raw)
+	case *internal.InterpolatedString:
+		f.skipComments(n.End()) // comments within embedded expressions are part of the raw literal
+		return textDoc(n.Raw)
+	case *internal.Ident:
+		return textDoc(n.Name)
+	case *internal.ArrayLit:
+		return f.list("[", n.Elems, n.Rbrack, "]")
+	case *internal.ObjectLit:
+		return f.list("{", n.Members, n.Rbrace, "}")
+	case *internal.KeyValue:
+		return concat(f.node(n.Key), textDoc(": "), f.node(n.Value))
+	case *internal.Spread:
+		return concat(textDoc("..."), f.node(n.X))
+	case *internal.UnaryExpr:
+		x := f.operand(n.X, ast.PrecUnary)
+		if u, ok := unparen(n.X).(*internal.UnaryExpr); ok && n.Op == "-" && u.Op == "-" {
+			x = concat(textDoc("("), f.node(n.X), textDoc(")")) // "--" would be a different token
+		}
+		return concat(textDoc(n.Op), x)
+	case *internal.BinaryExpr:
+		return f.binary(n)
+	case *internal.TernaryExpr:
+		return group(
+			f.operand(n.Cond, ast.PrecTernary+1),
+			nest(
+				line, textDoc("? "), f.node(n.Then),
+				line, textDoc(": "), f.operand(n.Else, ast.PrecTernary),
+			),
+		)
+	case *internal.CallExpr:
+		name := concat(textDoc(n.Func.Name), f.trailingComments(n.Func.End(), n.Lparen))
+		return concat(name, f.list("(", n.Args, n.Rparen, ")"))
+	case *internal.PipeExpr:
+		return f.pipe(n)
+	case *internal.SelectorExpr:
+		x := f.operand(n.X, ast.PrecPostfix)
+		if lit, ok := unparen(n.X).(*internal.Literal); ok && isNumber(lit.Value) {
+			x = concat(textDoc("("), f.node(n.X), textDoc(")")) // "1.a" would be parsed as "1." followed by "a"
+		}
+		return concat(x, textDoc("."), f.leadingComments(n.Sel.Pos()), textDoc(n.Sel.Name))
+	case *internal.IndexExpr:
+		x := f.operand(n.X, ast.PrecPostfix)
+		index := concat(f.node(n.Index), f.trailingComments(n.Index.End(), n.Rbrack))
+		return concat(x, textDoc("["), index, textDoc("]"))
+	case *internal.SliceExpr:
+		d := concatDoc{f.operand(n.X, ast.PrecPostfix), textDoc("[")}
+		colon := f.tokenAfter(n.Lbrack + 1)
+		if n.Low != nil {
+			d = append(d, f.node(n.Low))
+			colon = f.tokenAfter(n.Low.End())
+		}
+		d = append(d, textDoc(":"))
+		if n.High != nil {
+			first := f.next
+			comments := f.trailingComments(colon+1, n.High.Pos())
+			if f.next > first && strings.HasPrefix(f.comments[f.next-1].Text, "/*") {
+				comments = concat(comments, textDoc(" ")) // separates block comments from the bound
+			}
+			d = append(d, nest(comments, f.node(n.High)))
+		}
+		return append(d, f.trailingComments(colon+1, n.Rbrack), textDoc("]"))
+	case *internal.ArrayComp:
+		return f.comprehension("[", []doc{f.node(n.Elem)}, n.Clause, n.Rbrack, "]")
+	case *internal.ObjectComp:
+		elem := concat(f.node(n.Key), textDoc(": "), f.node(n.Value))
+		return f.comprehension("{", []doc{elem}, n.Clause, n.Rbrace, "}")
+	}
+	panic(fmt.Sprintf("format: unknown node %T", n))
+}
+
+func isNumber(val interface{}) bool {
+	switch val.(type) {
+	case int, float64:
+		return true
+	}
+	return false
+}
+
+// binary formats a chain of binary operations with the same precedence, like `a && b && c`.
+// If the chain does not fit into a single line, each operation starts a new line.
+func (f *formatter) binary(n *internal.BinaryExpr) doc {
+	prec := ast.Precedence(n)
+
+	// operations are left-associative, so the chain is nested within the left operand
+	chain := []*internal.BinaryExpr{n}
+	for {
+		x, ok := unparen(chain[0].X).(*internal.BinaryExpr)
+		if !ok || ast.Precedence(x) != prec {
+			break
+		}
+		chain = append([]*internal.BinaryExpr{x}, chain...)
+	}
+
+	first := f.operand(chain[0].X, prec)
+	rest := make(concatDoc, 0, 3*len(chain))
+	for _, b := range chain {
+		rest = append(rest, textDoc(" "+b.Op), line, f.operand(b.Y, prec+1))
+	}
+	return group(first, nest(rest))
+}
+
+// pipe formats a chain of pipe operations like `x |> f() |> g()`.
+func (f *formatter) pipe(n *internal.PipeExpr) doc {
+	chain := []*internal.PipeExpr{n}
+	for {
+		x, ok := unparen(chain[0].X).(*internal.PipeExpr)
+		if !ok {
+			break
+		}
+		chain = append([]*internal.PipeExpr{x}, chain...)
+	}
+
+	first := f.operand(chain[0].X, ast.PrecPipe)
+	rest := make(concatDoc, 0, 2*len(chain))
+	for _, p := range chain {
+		rest = append(rest, line, concat(textDoc("|> "), f.node(p.Call)))
+	}
+	return group(first, nest(rest))
+}
+
+// list formats comma-separated elements within brackets.
+// If they do not fit into a single line, each element is placed on its own line.
+func (f *formatter) list(open string, elems []internal.Node, closePos int, close string) doc {
+	if len(elems) == 0 {
+		return group(textDoc(open), nest(f.innerComments(closePos)), softline, textDoc(close))
+	}
+
+	content := concatDoc{softline}
+	for i, elem := range elems {
+		content = append(content, f.node(elem))
+		if i+1 < len(elems) {
+			content = append(content, textDoc(","), f.trailingComments(elem.End(), elems[i+1].Pos()), line)
+		}
+	}
+	content = append(content, f.trailingComments(elems[len(elems)-1].End(), closePos))
+	return group(textDoc(open), nest(content), softline, textDoc(close))
+}
+
+// comprehension formats array and object comprehensions.
+func (f *formatter) comprehension(open string, elem []doc, clause *internal.ForClause, closePos int, close string) doc {
+	vars := make([]string, len(clause.Vars))
+	for i, v := range clause.Vars {
+		vars[i] = v.Name
+	}
+
+	content := concatDoc{softline}
+	content = append(content, elem...)
+	content = append(content,
+		line, f.leadingComments(clause.Pos()),
+		textDoc("for "+strings.Join(vars, ", ")+" in "), f.node(clause.X),
+	)
+	if clause.Cond != nil {
+		content = append(content, line, f.leadingComments(clause.If), textDoc("if "), f.node(clause.Cond))
+	}
+	content = append(content, f.trailingComments(clause.End(), closePos))
+	return group(textDoc(open), nest(content), softline, textDoc(close))
+}
+
+// leadingComments returns all comments in front of the given position.
+func (f *formatter) leadingComments(pos int) doc {
+	var d concatDoc
+	for f.next < len(f.comments) && f.comments[f.next].Pos < pos {
+		c := f.comments[f.next]
+		f.next++
+		d = append(d, textDoc(c.Text))
+		if f.followedByNewline(c) {
+			d = append(d, hardline)
+		} else {
+			d = append(d, textDoc(" "))
+		}
+	}
+	return d
+}
+
+// trailingComments returns all comments in front of the given position.
+// Comments on the same line as the preceding code (which ends at prevEnd) stay on that line,
+// all others are placed on their own lines. Line comments must be followed by a line break.
+func (f *formatter) trailingComments(prevEnd, pos int) doc {
+	return concat(f.sameLineComments(prevEnd, pos), f.ownLineComments(pos))
+}
+
+// sameLineComments returns all comments in front of the given position
+// that are on the same line as the preceding code, which ends at prevEnd.
+func (f *formatter) sameLineComments(prevEnd, pos int) doc {
+	var d concatDoc
+	for f.next < len(f.comments) && f.comments[f.next].Pos < pos {
+		c := f.comments[f.next]
+		if c.Pos > prevEnd && strings.Contains(f.src[prevEnd-1:c.Pos-1], "\n") {
+			break
+		}
+		f.next++
+		d = append(d, textDoc(" "+c.Text))
+		if strings.HasPrefix(c.Text, "//") {
+			d = append(d, commentBreak)
+		}
+		prevEnd = c.Pos + len(c.Text)
+	}
+	return d
+}
+
+// innerComments returns all comments in front of the given position, separated by spaces.
+// Used within empty brackets.
+func (f *formatter) innerComments(pos int) doc {
+	var d concatDoc
+	for f.next < len(f.comments) && f.comments[f.next].Pos < pos {
+		c := f.comments[f.next]
+		if len(d) > 0 {
+			d = append(d, textDoc(" "))
+		}
+		f.next++
+		d = append(d, textDoc(c.Text))
+		if strings.HasPrefix(c.Text, "//") {
+			d = append(d, breakParent)
+		}
+	}
+	return d
+}
+
+// ownLineComments returns all remaining comments in front of the given position, each one on its own line.
+func (f *formatter) ownLineComments(pos int) doc {
+	var d concatDoc
+	for f.next < len(f.comments) && f.comments[f.next].Pos < pos {
+		c := f.comments[f.next]
+		f.next++
+		d = append(d, hardline, textDoc(c.Text))
+		if strings.HasPrefix(c.Text, "//") {
+			d = append(d, commentBreak)
+		}
+	}
+	return d
+}
+
+// skipComments drops all comments in front of the given position.
+func (f *formatter) skipComments(pos int) {
+	for f.next < len(f.comments) && f.comments[f.next].Pos < pos {
+		f.next++
+	}
+}
+
+// tokenAfter returns the position of the first token at or behind the given position, skipping whitespace and comments.
+// Returns the position behind the source if there are no more tokens.
+func (f *formatter) tokenAfter(pos int) int {
+	idx := f.next
+	for pos <= len(f.src) {
+		for idx < len(f.comments) && f.comments[idx].Pos < pos {
+			idx++
+		}
+		if idx < len(f.comments) && f.comments[idx].Pos == pos {
+			pos += len(f.comments[idx].Text)
+			continue
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(f.src[pos-1])) {
+			return pos
+		}
+		pos++
+	}
+	return pos
+}
+
+// followedByNewline reports whether there is a line break between the comment and the following code.
+func (f *formatter) followedByNewline(c internal.Comment) bool {
+	if strings.HasPrefix(c.Text, "//") {
+		return true
+	}
+	rest := f.src[c.Pos-1+len(c.Text):]
+	return strings.HasPrefix(strings.TrimLeft(rest, " \t\r"), "\n")
+}
@@ -0,0 +1,285 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/maja42/goval/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertFormat(t *testing.T, expected string, src string) {
+	t.Helper()
+	res, err := Source(src)
+	if !assert.NoError(t, err, "src: %s", src) {
+		return
+	}
+	assert.Equal(t, expected, res, "src: %s", src)
+
+	// formatting must be idempotent:
+	again, err := Source(res)
+	if assert.NoError(t, err, "formatted: %s", res) {
+		assert.Equal(t, res, again, "formatted: %s", res)
+	}
+}
+
+func Test_Spacing(t *testing.T) {
+	assertFormat(t, "1 + 2", "1+2")
+	assertFormat(t, "1 + 2 * 3", "  1 +   2*3  ")
+	assertFormat(t, "a && !b || c", "a&&!b||c")
+	assertFormat(t, "-a + ~b", "- a+~ b")
+	assertFormat(t, "a ? b : c", "a?b:c")
+	assertFormat(t, "f(1, 2, ...x)", "f( 1,2 , ... x )")
+	assertFormat(t, "f()", "f( )")
+	assertFormat(t, "x |> f(1) |> g()", "x|>f(1)|>g()")
+	assertFormat(t, "a.b[c][1:2][:3][4:][:]", "a . b [ c ] [1 : 2][ :3][4: ][ : ]")
+	assertFormat(t, "[1, 2, 3]", "[ 1,2,3 ]")
+	assertFormat(t, "[]", "[ ]")
+	assertFormat(t, `{"a": 1, ...b}`, `{ "a" :1,...b }`)
+	assertFormat(t, "{}", "{ }")
+	assertFormat(t, "[x * 2 for x in arr if x > 1]", "[x*2 for x in arr if x>1]")
+	assertFormat(t, "{k: v for k, v in obj}", "{k:v for k,v in obj}")
+	assertFormat(t, "a in [1, 2]", "a IN [1,2]")
+	assertFormat(t, "2 ** 3", "2 * * 3")
+	assertFormat(t, "a < -b", "a<-b")
+}
+
+func Test_LiteralsAreKept(t *testing.T) {
+	assertFormat(t, "0xFF + 1.50 + 1e3", "0xFF+1.50+1e3")
+	assertFormat(t, "`raw` + \"te\\\"xt\"", "`raw`+\"te\\\"xt\"")
+	assertFormat(t, `f"{ a+b }" + "x"`, `f"{ a+b }"+"x"`)
+	assertFormat(t, "nil == false", "nil==false")
+}
+
+func Test_RedundantParentheses(t *testing.T) {
+	assertFormat(t, "1 + 2 * 3", "(1 + (2 * 3))")
+	assertFormat(t, "(1 + 2) * 3", "((1 + 2)) * 3")
+	assertFormat(t, "1 - 2 - 3", "(1 - 2) - 3")
+	assertFormat(t, "1 - (2 - 3)", "1 - (2 - 3)")
+	assertFormat(t, "1 + (2 + 3)", "1 + (2 + 3)") // right-associative grouping is kept
+	assertFormat(t, "a || b && c", "a || (b && c)")
+	assertFormat(t, "(a || b) && c", "(a || b) && c")
+	assertFormat(t, "a | b ^ c & d", "a | (b ^ (c & d))")
+	assertFormat(t, "a == b < c", "a == (b < c)")
+	assertFormat(t, "(a == b) < c", "(a == b) < c")
+	assertFormat(t, "1 << 2 + 3", "1 << (2 + 3)")
+	assertFormat(t, "-a.b", "-(a.b)")
+	assertFormat(t, "(-a).b", "(-a).b")
+	assertFormat(t, "-a ** 2", "(-a) ** 2")
+	assertFormat(t, "-(a ** 2)", "-(a ** 2)")
+	assertFormat(t, "!a in b", "!(a in b)")
+	assertFormat(t, "(!a) in b", "(!a) in b")
+	assertFormat(t, "1 + 2 in arr", "1 + (2 in arr)")
+	assertFormat(t, "(1 + 2) in arr", "(1 + 2) in arr")
+	assertFormat(t, "(a + b).c[0]", "(a + b).c[0]")
+	assertFormat(t, "(a ? b : c)[0]", "(a ? b : c)[0]")
+	assertFormat(t, "-(-a)", "-(-a)")
+	assertFormat(t, "!!a", "!(!a)")
+	assertFormat(t, "(1).a", "(1).a")
+	assertFormat(t, `"a".b`, `("a").b`)
+	assertFormat(t, "[...a + b]", "[...(a + b)]")
+	assertFormat(t, "f(a + b, c ? d : e)", "f((a + b), (c ? d : e))")
+
+	// ternary operator:
+	assertFormat(t, "a || b ? c : d", "(a || b) ? c : d")
+	assertFormat(t, "(a ? b : c) ? d : e", "(a ? b : c) ? d : e")
+	assertFormat(t, "a ? b ? c : d : e", "a ? (b ? c : d) : e")
+	assertFormat(t, "a ? b : c ? d : e", "a ? b : (c ? d : e)")
+	assertFormat(t, "(a ? b : c) + 1", "(a ? b : c) + 1")
+	assertFormat(t, "a ? b : c + 1", "a ? b : (c + 1)")
+
+	// pipes:
+	assertFormat(t, "a + b |> f()", "(a + b) |> f()")
+	assertFormat(t, "(x |> f()) + 1", "(x |> f()) + 1")
+	assertFormat(t, "a ? b : c |> f()", "(a ? b : c) |> f()")
+	assertFormat(t, "a ? b : (c |> f())", "a ? b : (c |> f())")
+	assertFormat(t, "x |> f(y |> g())", "x |> f((y |> g()))")
+}
+
+func Test_Wrapping(t *testing.T) {
+	assertFormat(t, "user.age >= 18 &&\n"+
+		"    user.country in allowedCountries &&\n"+
+		"    !user.banned &&\n"+
+		"    user.email != \"\"",
+		`user.age >= 18 && user.country in allowedCountries && !user.banned && user.email != ""`)
+
+	assertFormat(t, "[\n"+
+		"    \"first element\",\n"+
+		"    \"second element\",\n"+
+		"    \"third element\",\n"+
+		"    \"fourth element\",\n"+
+		"    \"fifth element\"\n"+
+		"]",
+		`["first element", "second element", "third element", "fourth element", "fifth element"]`)
+
+	assertFormat(t, "{\n"+
+		"    \"name\": user.firstName + \" \" + user.lastName,\n"+
+		"    \"tags\": [\"customer\", \"premium\"],\n"+
+		"    ...defaults\n"+
+		"}",
+		`{"name": user.firstName + " " + user.lastName, "tags": ["customer", "premium"], ...defaults}`)
+
+	assertFormat(t, "user.subscription.active\n"+
+		"    ? \"subscribed since \" + user.subscription.since\n"+
+		"    : \"not subscribed, please visit our website\"",
+		`user.subscription.active ? "subscribed since " + user.subscription.since : "not subscribed, please visit our website"`)
+
+	assertFormat(t, "orders\n"+
+		"    |> filterByStatus(\"open\")\n"+
+		"    |> sortBy(\"createdAt\")\n"+
+		"    |> limit(maximumNumberOfResults)",
+		`orders |> filterByStatus("open") |> sortBy("createdAt") |> limit(maximumNumberOfResults)`)
+
+	assertFormat(t, "[\n"+
+		"    item.price * item.quantity\n"+
+		"    for item in order.items\n"+
+		"    if item.category in discountedCategories\n"+
+		"]",
+		`[item.price * item.quantity for item in order.items if item.category in discountedCategories]`)
+
+	// inner groups are only wrapped if necessary:
+	assertFormat(t, "calculateShippingCost(\n"+
+		"    order.destination.country,\n"+
+		"    [item.weight for item in order.items],\n"+
+		"    express ? \"express\" : \"standard\"\n"+
+		")",
+		`calculateShippingCost(order.destination.country, [item.weight for item in order.items], express ? "express" : "standard")`)
+
+	assertFormat(t, "isEligible(\n"+
+		"    user,\n"+
+		"    user.registeredSince >= minimumMembershipDuration &&\n"+
+		"        user.totalPurchases > minimumPurchases\n"+
+		")",
+		`isEligible(user, user.registeredSince >= minimumMembershipDuration && user.totalPurchases > minimumPurchases)`)
+}
+
+func Test_Comments(t *testing.T) {
+	assertFormat(t, "// leading\na + b", "// leading\na+b")
+	assertFormat(t, "/* inline */ a + b", "/* inline */ a+b")
+	assertFormat(t, "a + b // trailing", "a+b   // trailing")
+	assertFormat(t, "a + b /* trailing */", "a+b/* trailing */")
+	assertFormat(t, "a + b\n// last line", "a+b\n  // last line")
+	assertFormat(t, "a /* b */ + b", "a /* b */ + b")
+	assertFormat(t, "f(1 /* one */)", "f(1 /* one */)")
+	assertFormat(t, "f(/* none */)", "f( /* none */ )")
+	assertFormat(t, `f"{1 /* b */}" + 1 // c`, `f"{1 /* b */}"+1 // c`)
+
+	assertFormat(t, "[\n"+
+		"    1, // one\n"+
+		"    2,\n"+
+		"    // three\n"+
+		"    3 /* three */\n"+
+		"]",
+		"[1, // one\n2,\n// three\n3 /* three */]")
+
+	assertFormat(t, "[\n"+
+		"    1,\n"+
+		"    2 // two\n"+
+		"]",
+		"[1, 2 // two\n]")
+
+	assertFormat(t, "{\n"+
+		"    // defaults\n"+
+		"    ...defaults,\n"+
+		"    \"a\": 1 // override\n"+
+		"}",
+		"{\n// defaults\n...defaults, \"a\": 1 // override\n}")
+
+	assertFormat(t, "[\n"+
+		"    x\n"+
+		"    // all elements\n"+
+		"    for x in arr\n"+
+		"    if x // not empty\n"+
+		"]",
+		"[x\n// all elements\nfor x in arr if x // not empty\n]")
+
+	assertFormat(t, "a &&\n"+
+		"    // comment\n"+
+		"    b",
+		"a && // comment\nb")
+}
+
+// Test_Comments_StayBehindToken verifies that comments are kept behind the token in front of them.
+func Test_Comments_StayBehindToken(t *testing.T) {
+	assertFormat(t, "a /* x */.c", "a /* x */ .c")
+	assertFormat(t, "a // x\n    .c", "a // x\n.c")
+	assertFormat(t, "a.b // x\n    .c", "a.b // x\n.c")
+	assertFormat(t, "a./* x */ c", "a. /* x */ c")
+	assertFormat(t, "a\n// x\n.c", "a\n// x\n.c")
+	assertFormat(t, "a /* x */ ? b : c", "a /* x */ ? b : c")
+	assertFormat(t, "a\n    ? b // x\n    : c", "a ? b // x\n: c")
+	assertFormat(t, "a // x\n    [0]", "a // x\n[0]")
+	assertFormat(t, "a[0 /* x */] + b", "a[0 /* x */] + b")
+	assertFormat(t, "f(a) // c\n    |> g()", "f(a) // c\n|> g()")
+	assertFormat(t, "a[1: // c\n    2]", "a[1: // c\n 2]")
+	assertFormat(t, "a[1 /* x */: /* y */]", "a[1 /* x */ : /* y */]")
+	assertFormat(t, "x[1: /* h */ 2]", "x[1: /* h */ 2]")
+	assertFormat(t, "x[: /* h */ /* i */ 2]", "x[:/* h */ /* i */ 2]")
+	assertFormat(t, "x[1:\n    // h\n    2]", "x[1:\n// h\n2]")
+	assertFormat(t, "x[1:\n    /* h */ 2]", "x[1:\n/* h */ 2]")
+	assertFormat(t, "f /* x */(a)", "f /* x */ (a)")
+	assertFormat(t, "a /* x */ * b", "(a /* x */) * b")
+	assertFormat(t, "{a /* x */: 1}", "{a /* x */: 1}")
+}
+
+func Test_Errors(t *testing.T) {
+	_, err := Source("1 +")
+	assert.EqualError(t, err, "syntax error: unexpected $end")
+	_, err = Source("")
+	assert.Error(t, err)
+	_, err = Source("// only a comment")
+	assert.Error(t, err)
+}
+
+// Test_SameResult verifies that formatted expressions evaluate to the same result.
+func Test_SameResult(t *testing.T) {
+	variables := map[string]interface{}{
+		"a":   3,
+		"b":   4.5,
+		"t":   true,
+		"f":   false,
+		"s":   "text",
+		"arr": []interface{}{1, 2, 3},
+		"obj": map[string]interface{}{"a": 1, "b": []interface{}{2}},
+	}
+	functions := map[string]internal.ExpressionFunction{
+		"id": func(args ...interface{}) (interface{}, error) {
+			return args[0], nil
+		},
+	}
+
+	exprs := []string{
+		"1 + 2 * 3 - 4 / 2 % 3",
+		"(1 + 2) * (3 - 4)",
+		"2 ** 3 ** 2", "2 * 3 ** 2", "(-2) ** 2", "-(2 ** 2)", "2 ** (3 ** 2)",
+		"10 - (4 - 3) - 2",
+		"t && (f || t)", "!(t && f)", "!t || f",
+		"a | 4 ^ 2 & 7", "(a | 4) ^ (2 & 7)", "1 << a + 1", "(1 << a) + 1", "~a & 0xF",
+		"a == 3 != (b < 5)", "(a < b) == (b > a)",
+		"t ? (f ? 1 : 2) : 3", "(t ? f : t) ? 1 : 2", "f ? 1 : t ? 2 : 3", "(t ? a : b) + 1",
+		"2 in arr", "(1 + 1) in arr", "!(2 in arr)", "!t in [false]",
+		"arr[1:][0]", "(arr + [4])[3]", "obj.b[0] + obj[\"a\"]", "s[1:a]",
+		"(arr |> id())[0]", "a + (1 |> id())", "(a + 1) |> id()", "t ? 1 : (2 |> id())", "(t ? 1 : 2) |> id()",
+		"[x * 2 for x in arr if x > (1 + 0)]", "{\"k\" + s: v for k, v in obj if k == \"a\"}",
+		"[...arr + [4], ...(arr)]", "{...obj, \"c\": (1 + 2)}",
+		"id(...(arr), (a + 1))",
+		"f\"{a + 1}\" + (s)",
+		"-(-a)", "- -a", "(-a) - -a",
+		"obj /* x */ .a", "obj // x\n.b[0]", "arr // x\n[1]", "s[1: // x\n2]", "t /* x */ ? 1 : 2",
+		"arr // x\n|> id()", "id /* x */ (a)",
+	}
+	for _, expr := range exprs {
+		formatted, err := Source(expr)
+		if !assert.NoError(t, err, "expr: %s", expr) {
+			continue
+		}
+		expected, expectedErr := internal.Evaluate(expr, variables, functions)
+		actual, actualErr := internal.Evaluate(formatted, variables, functions)
+		assert.Equal(t, expectedErr, actualErr, "expr: %s, formatted: %s", expr, formatted)
+		assert.Equal(t, expected, actual, "expr: %s, formatted: %s", expr, formatted)
+
+		again, err := Source(formatted)
+		if assert.NoError(t, err) {
+			assert.Equal(t, formatted, again)
+		}
+	}
+}
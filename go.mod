@@ -2,10 +2,16 @@ module github.com/maja42/goval
 
 go 1.18
 
-require github.com/stretchr/testify v1.8.1
+require (
+	github.com/mattn/go-sqlite3 v1.14.16
+	github.com/peterh/liner v1.2.2
+	github.com/stretchr/testify v1.8.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mattn/go-runewidth v0.0.3 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.0.0-20211117180635-dee7805ff2e1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,34 @@
+package internal
+
+import "github.com/maja42/goval/ast"
+
+// Program is a parsed expression.
+type Program struct {
+	Root     Node
+	Comments []Comment
+}
+
+// The syntax tree is declared within the public ast package.
+type (
+	Node               = ast.Node
+	Comment            = ast.Comment
+	Literal            = ast.Literal
+	InterpolatedString = ast.InterpolatedString
+	ArrayLit           = ast.ArrayLit
+	ObjectLit          = ast.ObjectLit
+	KeyValue           = ast.KeyValue
+	Spread             = ast.Spread
+	Ident              = ast.Ident
+	UnaryExpr          = ast.UnaryExpr
+	BinaryExpr         = ast.BinaryExpr
+	TernaryExpr        = ast.TernaryExpr
+	ParenExpr          = ast.ParenExpr
+	CallExpr           = ast.CallExpr
+	PipeExpr           = ast.PipeExpr
+	SelectorExpr       = ast.SelectorExpr
+	IndexExpr          = ast.IndexExpr
+	SliceExpr          = ast.SliceExpr
+	ForClause          = ast.ForClause
+	ArrayComp          = ast.ArrayComp
+	ObjectComp         = ast.ObjectComp
+)
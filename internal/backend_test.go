@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// backends are the different ways of evaluating expressions. They need to behave identically.
+var backends = []struct {
+	name     string
+	evaluate func(str string, variables map[string]interface{}, functions map[string]ExpressionFunction) (interface{}, error)
+}{
+	{"tree", Evaluate},
+	{"compiled", evaluateCompiled},
+	{"trace", evaluateTraced},
+}
+
+func evaluateCompiled(str string, variables map[string]interface{}, functions map[string]ExpressionFunction) (interface{}, error) {
+	compiled, err := Compile(str)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Evaluate(variables, functions)
+}
+
+func evaluateTraced(str string, variables map[string]interface{}, functions map[string]ExpressionFunction) (interface{}, error) {
+	program, err := Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	result, _, err := program.Trace(NewScope(variables), functions, 0)
+	return result, err
+}
+
+// evaluate evaluates the expression with every backend and verifies that all of them behave identically.
+// Returns the result of the first backend.
+func evaluate(t *testing.T, str string, variables map[string]interface{}, functions map[string]ExpressionFunction) (result interface{}, err error) {
+	t.Helper()
+	for idx, backend := range backends {
+		res, e := backend.evaluate(str, variables, functions)
+		if idx == 0 {
+			result, err = res, e
+			continue
+		}
+		assert.Equal(t, result, res, "backend %q returned a different result for %s", backend.name, str)
+		assert.Equal(t, errorString(err), errorString(e), "backend %q returned a different error for %s", backend.name, str)
+	}
+	return result, err
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func Test_Compiled_Reuse(t *testing.T) {
+	compiled, err := Compile(`[x * i for i in [1, 2, 3] if i != skip]`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(x int) {
+			defer wg.Done()
+			result, err := compiled.Evaluate(map[string]interface{}{"x": x, "skip": 2}, nil)
+			if assert.NoError(t, err) {
+				assert.Equal(t, []interface{}{x, 3 * x}, result)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_, err = compiled.Evaluate(map[string]interface{}{"x": 1}, nil)
+	assert.EqualError(t, err, `var error: variable "skip" does not exist`)
+}
+
+func Test_Compiled_NestedComprehensions(t *testing.T) {
+	vars := map[string]interface{}{
+		"matrix": []interface{}{
+			[]interface{}{1, 2},
+			[]interface{}{3, 4},
+		},
+	}
+	assertEvaluation(t, vars, []interface{}{
+		[]interface{}{2, 4},
+		[]interface{}{6, 8},
+	}, `[[v * 2 for v in row] for row in matrix]`)
+	assertEvaluation(t, vars, map[string]interface{}{
+		"0": map[string]interface{}{"0": 1, "1": 2},
+		"1": map[string]interface{}{"0": 3, "1": 4},
+	}, `{"" + i: {"" + j: v for j, v in row} for i, row in matrix}`)
+	assertEvaluation(t, vars, []interface{}{1, 2, 3}, `[v for v in [...matrix[0], ...matrix[1]] if v < 4]`)
+}
+
+func Test_Compiled_Shadowing(t *testing.T) {
+	vars := map[string]interface{}{"x": 10}
+	assertEvaluation(t, vars, []interface{}{11, 3}, `[x + 1 for x in [x, 2]]`)
+	assertEvaluation(t, vars, []interface{}{[]interface{}{2, 3}}, `[[x + 1 for x in [x, 2]] for x in [1]]`)
+	assertEvaluation(t, vars, []interface{}{"y", "x"}, `[x for x, x in {"b": "x", "a": "y"}]`)
+	assertEvaluation(t, vars, 10, `[x for x in [1]][0] + x - 1`)
+}
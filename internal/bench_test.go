@@ -0,0 +1,87 @@
+package internal
+
+import "testing"
+
+var benchmarkExpressions = []struct {
+	name string
+	expr string
+}{
+	{"arithmetic", `(a * 1.8) + 32 - b / 4`},
+	{"logic", `a > 10 && b < 100 || !flag`},
+	{"strings", `"Hello " + user.name + ", you have " + len(items) + " items"`},
+	{"collections", `[...items, a, b][1:3]`},
+	{"comprehension", `[x * 2 for x in items if x % 2 == 0]`},
+}
+
+func benchmarkVariables() (map[string]interface{}, map[string]ExpressionFunction) {
+	variables := map[string]interface{}{
+		"a":     21,
+		"b":     42.5,
+		"flag":  true,
+		"user":  map[string]interface{}{"name": "Ann"},
+		"items": []interface{}{1, 2, 3, 4, 5, 6, 7, 8},
+	}
+	functions := map[string]ExpressionFunction{
+		"len": func(args ...interface{}) (interface{}, error) {
+			return len(args[0].([]interface{})), nil
+		},
+	}
+	return variables, functions
+}
+
+// Benchmark_Evaluate parses and evaluates the expression on every iteration.
+func Benchmark_Evaluate(b *testing.B) {
+	variables, functions := benchmarkVariables()
+	for _, bm := range benchmarkExpressions {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := Evaluate(bm.expr, variables, functions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Benchmark_Compiled evaluates an expression that was compiled once.
+func Benchmark_Compiled(b *testing.B) {
+	variables, functions := benchmarkVariables()
+	for _, bm := range benchmarkExpressions {
+		b.Run(bm.name, func(b *testing.B) {
+			compiled, err := Compile(bm.expr)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := compiled.Evaluate(variables, functions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Benchmark_Numeric evaluates numeric expressions on unboxed values.
+func Benchmark_Numeric(b *testing.B) {
+	names := []string{"a", "b", "flag"}
+	values := []Number{Int(21), Float(42.5), Bool(true)}
+
+	for _, bm := range benchmarkExpressions[:2] { // arithmetic and logic
+		b.Run(bm.name, func(b *testing.B) {
+			expr, err := CompileNumeric(bm.expr, names)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := expr.Evaluate(values); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
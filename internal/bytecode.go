@@ -0,0 +1,150 @@
+package internal
+
+import "fmt"
+
+// bytecode is an expression that was compiled into instructions for a stack machine.
+// It is used by numeric expressions and supports literals, variables, parentheses, the ternary operator
+// and all unary and binary operators except 'in'.
+type bytecode struct {
+	code     []instruction
+	consts   []interface{}
+	names    []string // variable names
+	maxStack int      // maximum stack depth
+}
+
+type instruction struct {
+	op  opcode
+	arg int
+}
+
+type opcode uint8
+
+const (
+	opConst  opcode = iota // push consts[arg]
+	opVar                  // push variable names[arg]
+	opSelect               // pop condition, then- and else-value; push one of the values
+
+	// unary operators: pop operand, push result
+	opNeg
+	opNot
+	opBitNot
+
+	// binary operators: pop both operands, push result
+	opAdd
+	opSub
+	opMul
+	opDiv
+	opPow
+	opMod
+	opEqual
+	opNotEqual
+	opLess
+	opLessEqual
+	opGreater
+	opGreaterEqual
+	opAnd
+	opOr
+	opBitOr
+	opBitAnd
+	opBitXor
+	opShiftLeft
+	opShiftRight
+)
+
+var unaryOpcodes = map[string]opcode{
+	"-": opNeg,
+	"!": opNot,
+	"~": opBitNot,
+}
+
+var binaryOpcodes = map[string]opcode{
+	"+":  opAdd,
+	"-":  opSub,
+	"*":  opMul,
+	"/":  opDiv,
+	"**": opPow,
+	"%":  opMod,
+	"==": opEqual,
+	"!=": opNotEqual,
+	"<":  opLess,
+	"<=": opLessEqual,
+	">":  opGreater,
+	">=": opGreaterEqual,
+	"&&": opAnd,
+	"||": opOr,
+	"|":  opBitOr,
+	"&":  opBitAnd,
+	"^":  opBitXor,
+	"<<": opShiftLeft,
+	">>": opShiftRight,
+}
+
+type compiler struct {
+	*bytecode
+	nameIdx map[string]int // deduplicates names
+	depth   int            // current stack depth
+}
+
+func newCompiler() *compiler {
+	return &compiler{
+		bytecode: &bytecode{},
+		nameIdx:  make(map[string]int),
+	}
+}
+
+// emit appends an instruction which changes the stack depth by delta.
+func (c *compiler) emit(op opcode, arg int, delta int) {
+	c.code = append(c.code, instruction{op, arg})
+	c.depth += delta
+	if c.depth > c.maxStack {
+		c.maxStack = c.depth
+	}
+}
+
+func (c *compiler) constant(val interface{}) int {
+	c.consts = append(c.consts, val)
+	return len(c.consts) - 1
+}
+
+func (c *compiler) name(name string) int {
+	if idx, ok := c.nameIdx[name]; ok {
+		return idx
+	}
+	c.names = append(c.names, name)
+	c.nameIdx[name] = len(c.names) - 1
+	return len(c.names) - 1
+}
+
+func (c *compiler) compile(node Node) {
+	switch n := node.(type) {
+	case *Literal:
+		c.emit(opConst, c.constant(n.Value), 1)
+	case *Ident:
+		c.emit(opVar, c.name(n.Name), 1)
+	case *UnaryExpr:
+		op, ok := unaryOpcodes[n.Op]
+		if !ok {
+			panic(fmt.Errorf("syntax error: unsupported operation %q", n.Op))
+		}
+		c.compile(n.X)
+		c.emit(op, 0, 0)
+	case *BinaryExpr:
+		op, ok := binaryOpcodes[n.Op]
+		if !ok {
+			panic(fmt.Errorf("syntax error: unsupported operation %q", n.Op))
+		}
+		c.compile(n.X)
+		c.compile(n.Y)
+		c.emit(op, 0, -1)
+	case *TernaryExpr:
+		// all operands are evaluated (no short-circuiting)
+		c.compile(n.Cond)
+		c.compile(n.Then)
+		c.compile(n.Else)
+		c.emit(opSelect, 0, -2)
+	case *ParenExpr:
+		c.compile(n.X)
+	default:
+		panic(fmt.Errorf("syntax error: unsupported node %T", node))
+	}
+}
@@ -0,0 +1,127 @@
+package internal
+
+import "fmt"
+
+// Compiled is a parsed expression that was verified once, so that it can be evaluated repeatedly without being parsed again.
+// Parsing dominates the time of a single evaluation. Compiled expressions skip it, and are evaluated by walking their syntax tree.
+//
+// Results and errors are identical to Program.Evaluate.
+// Compiled expressions are immutable and can be evaluated concurrently.
+type Compiled struct {
+	program *Program
+	depth   int // nesting depth of the syntax tree
+}
+
+// Compile parses the given expression string and compiles it.
+func Compile(str string) (*Compiled, error) {
+	program, err := Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	return program.Compile()
+}
+
+// Compile verifies the parsed expression, which might have been constructed manually.
+func (p *Program) Compile() (compiled *Compiled, err error) {
+	defer recoverError(&err)
+	return &Compiled{program: p, depth: verify(p.Root)}, nil
+}
+
+// Depth returns the nesting depth of the expression's syntax tree.
+func (c *Compiled) Depth() int {
+	return c.depth
+}
+
+// Evaluate the compiled expression.
+func (c *Compiled) Evaluate(variables map[string]interface{}, functions map[string]ExpressionFunction) (result interface{}, err error) {
+	return c.EvaluateIn(NewScope(variables), functions)
+}
+
+// EvaluateIn evaluates the compiled expression within the given scope.
+func (c *Compiled) EvaluateIn(s *Scope, functions map[string]ExpressionFunction) (result interface{}, err error) {
+	return c.program.EvaluateLimited(s, functions, 0)
+}
+
+// EvaluateLimited evaluates the compiled expression within the given scope.
+// Fails if more than maxSteps nodes are evaluated. Zero means unlimited.
+// Time spent within expression functions is not limited.
+func (c *Compiled) EvaluateLimited(s *Scope, functions map[string]ExpressionFunction, maxSteps int) (result interface{}, err error) {
+	return c.program.EvaluateLimited(s, functions, maxSteps)
+}
+
+// verify panics if the node contains operators or nodes that cannot be evaluated.
+// Returns the nesting depth of the node. Each operator, literal and variable is one level.
+func verify(node Node) int {
+	depth := 0
+	nested := func(nodes ...Node) {
+		for _, n := range nodes {
+			if n == nil {
+				continue // optional slice bounds and conditions
+			}
+			if spread, ok := n.(*Spread); ok {
+				n = spread.X
+			}
+			if d := verify(n); d > depth {
+				depth = d
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *Literal, *Ident:
+	case *InterpolatedString:
+		nested(n.Parts...)
+	case *ArrayLit:
+		nested(n.Elems...)
+	case *ObjectLit:
+		for _, member := range n.Members {
+			if kv, ok := member.(*KeyValue); ok {
+				nested(kv.Key, kv.Value)
+			} else {
+				nested(member)
+			}
+		}
+	case *UnaryExpr:
+		switch n.Op {
+		case "-", "!", "~":
+		default:
+			panic(fmt.Errorf("syntax error: unsupported operation %q", n.Op))
+		}
+		nested(n.X)
+	case *BinaryExpr:
+		if _, ok := binaryOpcodes[n.Op]; !ok && n.Op != "in" {
+			panic(fmt.Errorf("syntax error: unsupported operation %q", n.Op))
+		}
+		nested(n.X, n.Y)
+	case *TernaryExpr:
+		nested(n.Cond, n.Then, n.Else)
+	case *ParenExpr:
+		nested(n.X)
+	case *CallExpr:
+		nested(n.Args...)
+	case *PipeExpr:
+		nested(n.X)
+		nested(n.Call.Args...)
+	case *SelectorExpr:
+		nested(n.X)
+	case *IndexExpr:
+		nested(n.X, n.Index)
+	case *SliceExpr:
+		nested(n.X, n.Low, n.High)
+	case *ArrayComp:
+		verifyClause(n.Clause)
+		nested(n.Clause.X, n.Clause.Cond, n.Elem)
+	case *ObjectComp:
+		verifyClause(n.Clause)
+		nested(n.Clause.X, n.Clause.Cond, n.Key, n.Value)
+	default:
+		panic(fmt.Errorf("syntax error: unsupported node %T", node))
+	}
+	return depth + 1
+}
+
+func verifyClause(clause *ForClause) {
+	if len(clause.Vars) != 1 && len(clause.Vars) != 2 {
+		panic(fmt.Errorf("syntax error: comprehensions require one or two loop variables"))
+	}
+}
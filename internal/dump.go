@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dump returns a human-readable representation of the syntax tree, with one node per line.
+// Child nodes are indented below their parents.
+func Dump(node Node) string {
+	var sb strings.Builder
+	dump(&sb, node, 0)
+	return sb.String()
+}
+
+func dump(sb *strings.Builder, node Node, depth int) {
+	line := func(format string, args ...interface{}) {
+		sb.WriteString(strings.Repeat("  ", depth))
+		fmt.Fprintf(sb, format, args...)
+		sb.WriteByte('\n')
+	}
+	children := func(nodes ...Node) {
+		for _, n := range nodes {
+			dump(sb, n, depth+1)
+		}
+	}
+
+	switch n := node.(type) {
+	case nil: // optional node that is not set
+		line("-")
+	case *Literal:
+		line("Literal %s", n.Raw)
+	case *InterpolatedString:
+		line("InterpolatedString")
+		children(n.Parts...)
+	case *ArrayLit:
+		line("ArrayLit")
+		children(n.Elems...)
+	case *ObjectLit:
+		line("ObjectLit")
+		children(n.Members...)
+	case *KeyValue:
+		line("KeyValue")
+		children(n.Key, n.Value)
+	case *Spread:
+		line("Spread")
+		children(n.X)
+	case *Ident:
+		line("Ident %s", n.Name)
+	case *UnaryExpr:
+		line("UnaryExpr %s", n.Op)
+		children(n.X)
+	case *BinaryExpr:
+		line("BinaryExpr %s", n.Op)
+		children(n.X, n.Y)
+	case *TernaryExpr:
+		line("TernaryExpr")
+		children(n.Cond, n.Then, n.Else)
+	case *ParenExpr:
+		line("ParenExpr")
+		children(n.X)
+	case *CallExpr:
+		line("CallExpr %s", n.Func.Name)
+		children(n.Args...)
+	case *PipeExpr:
+		line("PipeExpr")
+		children(n.X, n.Call)
+	case *SelectorExpr:
+		line("SelectorExpr %s", n.Sel.Name)
+		children(n.X)
+	case *IndexExpr:
+		line("IndexExpr")
+		children(n.X, n.Index)
+	case *SliceExpr:
+		line("SliceExpr")
+		children(n.X, n.Low, n.High)
+	case *ForClause:
+		names := make([]string, len(n.Vars))
+		for i, v := range n.Vars {
+			names[i] = v.Name
+		}
+		line("ForClause %s", strings.Join(names, ", "))
+		children(n.X)
+		if n.Cond != nil {
+			children(n.Cond)
+		}
+	case *ArrayComp:
+		line("ArrayComp")
+		children(n.Elem, n.Clause)
+	case *ObjectComp:
+		line("ObjectComp")
+		children(n.Key, n.Value, n.Clause)
+	default:
+		line("%T", n)
+	}
+}
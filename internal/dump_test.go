@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Dump(t *testing.T) {
+	program, err := Parse(`a.b[1:] + -f(1, ...c) |> g() ? [x for x, y in z if y] : {"k": (2)}`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	expected := `TernaryExpr
+  PipeExpr
+    BinaryExpr +
+      SliceExpr
+        SelectorExpr b
+          Ident a
+        Literal 1
+        -
+      UnaryExpr -
+        CallExpr f
+          Literal 1
+          Spread
+            Ident c
+    CallExpr g
+  ArrayComp
+    Ident x
+    ForClause x, y
+      Ident z
+      Ident y
+  ObjectLit
+    KeyValue
+      Literal "k"
+      ParenExpr
+        Literal 2
+`
+	assert.Equal(t, expected, Dump(program.Root))
+
+	program, err = Parse(`f"a{b}" `)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "InterpolatedString\n  Literal a\n  Ident b\n", Dump(program.Root))
+	}
+}
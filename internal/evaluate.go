@@ -1,20 +1,403 @@
 package internal
 
 import (
+	"fmt"
 	"runtime"
+	"sort"
 )
 
 func Evaluate(str string, variables map[string]interface{}, functions map[string]ExpressionFunction) (result interface{}, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if _, ok := r.(runtime.Error); ok {
-				panic(r)
+	program, err := Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	return program.Evaluate(variables, functions)
+}
+
+// Parse the given expression string into an abstract syntax tree.
+func Parse(str string) (program *Program, err error) {
+	return ParseAt(str, 1)
+}
+
+// ParseAt parses an expression that is embedded within a larger source, starting at the given 1-based position.
+// All positions within the syntax tree and errors are relative to the larger source.
+func ParseAt(str string, pos int) (program *Program, err error) {
+	defer recoverError(&err)
+
+	lexer := newLexerAt(str, pos)
+	yyNewParser().Parse(lexer)
+	return &Program{
+		Root:     lexer.Result(),
+		Comments: lexer.Comments(),
+	}, nil
+}
+
+// Evaluate the parsed expression.
+func (p *Program) Evaluate(variables map[string]interface{}, functions map[string]ExpressionFunction) (result interface{}, err error) {
+	return p.EvaluateIn(NewScope(variables), functions)
+}
+
+// EvaluateIn evaluates the parsed expression within the given scope.
+func (p *Program) EvaluateIn(s *Scope, functions map[string]ExpressionFunction) (result interface{}, err error) {
+	return p.EvaluateLimited(s, functions, 0)
+}
+
+// EvaluateLimited evaluates the parsed expression within the given scope.
+// Fails if more than maxSteps nodes are evaluated. Zero means unlimited.
+// Time spent within expression functions is not limited.
+func (p *Program) EvaluateLimited(s *Scope, functions map[string]ExpressionFunction, maxSteps int) (result interface{}, err error) {
+	defer recoverError(&err)
+
+	e := evaluator{
+		functions: functions,
+		maxSteps:  maxSteps,
+	}
+	return e.eval(p.Root, s), nil
+}
+
+// recoverError converts panics caused by invalid expressions into errors.
+// Runtime errors are bugs, and therefore not recovered.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		if _, ok := r.(runtime.Error); ok {
+			panic(r)
+		}
+		*err = r.(error)
+	}
+}
+
+// Scope contains all variables that are accessible from within an expression.
+// Comprehensions introduce nested scopes with a single variable each.
+type Scope struct {
+	parent    *Scope
+	name      string
+	value     interface{}
+	variables map[string]interface{} // only set on the outermost scope
+	defaults  map[string]interface{} // only set on the outermost scope
+}
+
+// NewScope creates the outermost scope, containing the given variables.
+func NewScope(variables map[string]interface{}) *Scope {
+	return &Scope{variables: variables}
+}
+
+// NewScopeWithDefaults creates the outermost scope, containing the given variables and defaults.
+// Variables shadow defaults with the same name.
+func NewScopeWithDefaults(variables, defaults map[string]interface{}) *Scope {
+	return &Scope{variables: variables, defaults: defaults}
+}
+
+// With returns a nested scope that additionally contains the given variable.
+// Shadows variables with the same name.
+func (s *Scope) With(name string, value interface{}) *Scope {
+	return &Scope{parent: s, name: name, value: value}
+}
+
+func (s *Scope) lookup(name string) interface{} {
+	for ; s.parent != nil; s = s.parent {
+		if s.name == name {
+			return s.value
+		}
+	}
+	if val, ok := s.variables[name]; ok {
+		return val
+	}
+	return accessVar(s.defaults, name)
+}
+
+// has reports whether the scope contains the given variable.
+func (s *Scope) has(name string) bool {
+	for ; s.parent != nil; s = s.parent {
+		if s.name == name {
+			return true
+		}
+	}
+	if _, ok := s.variables[name]; ok {
+		return true
+	}
+	_, ok := s.defaults[name]
+	return ok
+}
+
+type evaluator struct {
+	functions map[string]ExpressionFunction
+	trace     *TraceNode // currently evaluated node; nil if tracing is disabled
+	maxSteps  int        // maximum number of evaluated nodes; zero means unlimited
+	steps     int        // number of evaluated nodes
+}
+
+func (e *evaluator) eval(node Node, s *Scope) interface{} {
+	if e.maxSteps > 0 {
+		if e.steps++; e.steps > e.maxSteps {
+			panic(fmt.Errorf("limit error: evaluation exceeds %d steps", e.maxSteps))
+		}
+	}
+	if e.trace != nil {
+		return e.evalTraced(node, s)
+	}
+	return e.evalNode(node, s)
+}
+
+func (e *evaluator) evalNode(node Node, s *Scope) interface{} {
+	switch n := node.(type) {
+	case *Literal:
+		return n.Value
+	case *InterpolatedString:
+		var str interface{} = ""
+		for _, part := range n.Parts {
+			str = add(str, e.eval(part, s))
+		}
+		return str
+	case *ArrayLit:
+		return e.evalList(n.Elems, s)
+	case *ObjectLit:
+		obj := newObjectLiteral()
+		for _, member := range n.Members {
+			if spread, ok := member.(*Spread); ok {
+				spreadObject(obj, e.eval(spread.X, s))
+				continue
 			}
-			err = r.(error)
+			kv := member.(*KeyValue)
+			addObjectMember(obj, e.eval(kv.Key, s), e.eval(kv.Value, s))
+		}
+		return obj.values
+	case *Ident:
+		return s.lookup(n.Name)
+	case *UnaryExpr:
+		return evalUnary(n.Op, e.eval(n.X, s))
+	case *BinaryExpr:
+		return evalBinary(n.Op, e.eval(n.X, s), e.eval(n.Y, s))
+	case *TernaryExpr:
+		// all operands are evaluated (no short-circuiting)
+		cond, then, els := e.eval(n.Cond, s), e.eval(n.Then, s), e.eval(n.Else, s)
+		if asBool(cond) {
+			return then
+		}
+		return els
+	case *ParenExpr:
+		return e.eval(n.X, s)
+	case *CallExpr:
+		return callFunction(e.functions, n.Func.Name, e.evalList(n.Args, s))
+	case *PipeExpr:
+		args := append([]interface{}{e.eval(n.X, s)}, e.evalList(n.Call.Args, s)...)
+		return callFunction(e.functions, n.Call.Func.Name, args)
+	case *SelectorExpr:
+		return accessMember(e.eval(n.X, s), n.Sel.Name)
+	case *IndexExpr:
+		return accessField(e.eval(n.X, s), e.eval(n.Index, s))
+	case *SliceExpr:
+		val := e.eval(n.X, s)
+		var from, to interface{}
+		if n.Low != nil {
+			from = e.eval(n.Low, s)
+		}
+		if n.High != nil {
+			to = e.eval(n.High, s)
 		}
-	}()
+		return slice(val, from, to)
+	case *ArrayComp:
+		arr := make([]interface{}, 0)
+		e.iterate(n.Clause, s, func(inner *Scope) {
+			arr = append(arr, e.eval(n.Elem, inner))
+		})
+		return arr
+	case *ObjectComp:
+		obj := make(map[string]interface{})
+		e.iterate(n.Clause, s, func(inner *Scope) {
+			key := asObjectKey(e.eval(n.Key, inner))
+			obj[key] = e.eval(n.Value, inner)
+		})
+		return obj
+	}
+	panic(fmt.Errorf("syntax error: unsupported node %T", node))
+}
 
-	lexer := NewLexer(str, variables, functions)
-	yyNewParser().Parse(lexer)
-	return lexer.Result(), nil
+// evalList evaluates array elements or function arguments, expanding spread operators.
+func (e *evaluator) evalList(nodes []Node, s *Scope) []interface{} {
+	list := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		if spread, ok := node.(*Spread); ok {
+			list = spreadArray(list, e.eval(spread.X, s))
+			continue
+		}
+		list = append(list, e.eval(node, s))
+	}
+	return list
+}
+
+// iterate calls fn with a new scope for every element of the comprehension's source that fulfills the condition.
+func (e *evaluator) iterate(clause *ForClause, s *Scope, fn func(inner *Scope)) {
+	src := e.eval(clause.X, s)
+	keys, values := iterationElements(src)
+
+	for idx := range values {
+		inner := loopScope(s, clause.Vars, isObject(src), keys[idx], values[idx])
+		if clause.Cond != nil && !asBool(e.eval(clause.Cond, inner)) {
+			continue
+		}
+		fn(inner)
+	}
+}
+
+// loopScope returns the scope of a single loop iteration.
+func loopScope(s *Scope, vars []*Ident, object bool, key, value interface{}) *Scope {
+	switch {
+	case len(vars) == 2:
+		return s.With(vars[0].Name, key).With(vars[1].Name, value)
+	case object: // a single loop variable iterates over object keys...
+		return s.With(vars[0].Name, key)
+	default: // ... or array elements
+		return s.With(vars[0].Name, value)
+	}
+}
+
+// Iterate calls fn with a new scope for every element of val, the same way comprehensions iterate over their source.
+// Accepts one or two loop variables. Stops on the first error returned by fn.
+func Iterate(s *Scope, val interface{}, vars []string, fn func(inner *Scope) error) (err error) {
+	defer recoverError(&err)
+	keys, values := iterationElements(val)
+
+	idents := make([]*Ident, len(vars))
+	for i, name := range vars {
+		idents[i] = &Ident{Name: name}
+	}
+	for idx := range values {
+		if err := fn(loopScope(s, idents, isObject(val), keys[idx], values[idx])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterationElements returns the keys (indices for arrays) and values that comprehensions iterate over.
+// Object members are iterated in the order of their keys.
+func iterationElements(val interface{}) (keys []interface{}, values []interface{}) {
+	switch v := val.(type) {
+	case []interface{}:
+		keys = make([]interface{}, len(v))
+		for idx := range v {
+			keys[idx] = idx
+		}
+		return keys, v
+	case map[string]interface{}:
+		sorted := make([]string, 0, len(v))
+		for k := range v {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		keys = make([]interface{}, len(v))
+		values = make([]interface{}, len(v))
+		for idx, k := range sorted {
+			keys[idx] = k
+			values[idx] = v[k]
+		}
+		return keys, values
+	}
+	panic(fmt.Errorf("type error: cannot iterate over %s", TypeOf(val)))
+}
+
+// ToString converts a value into a string, the same way as during string concatenation.
+func ToString(val interface{}) (str string, err error) {
+	defer recoverError(&err)
+	if s, ok := val.(string); ok {
+		return s, nil
+	}
+	return add("", val).(string), nil
+}
+
+// ToBool converts a value into a bool, the same way as for conditions of the ternary operator.
+func ToBool(val interface{}) (b bool, err error) {
+	defer recoverError(&err)
+	return asBool(val), nil
+}
+
+func isObject(val interface{}) bool {
+	_, ok := val.(map[string]interface{})
+	return ok
+}
+
+func evalUnary(op string, val interface{}) interface{} {
+	switch op {
+	case "-":
+		return unaryMinus(val)
+	case "!":
+		return !asBool(val)
+	case "~":
+		return ^asInteger(val)
+	}
+	panic(fmt.Errorf("syntax error: unsupported operation %q", op))
+}
+
+func evalBinary(op string, val1, val2 interface{}) interface{} {
+	switch op {
+	case "+":
+		return add(val1, val2)
+	case "-":
+		return sub(val1, val2)
+	case "*":
+		return mul(val1, val2)
+	case "/":
+		return div(val1, val2)
+	case "**":
+		return pow(val1, val2)
+	case "%":
+		return mod(val1, val2)
+
+	case "==":
+		return deepEqual(val1, val2)
+	case "!=":
+		return !deepEqual(val1, val2)
+	case "<", ">", "<=", ">=":
+		return compare(val1, val2, op)
+	case "&&":
+		return logicalAnd(val1, val2)
+	case "||":
+		return logicalOr(val1, val2)
+
+	case "|":
+		return asInteger(val1) | asInteger(val2)
+	case "&":
+		return asInteger(val1) & asInteger(val2)
+	case "^":
+		return asInteger(val1) ^ asInteger(val2)
+	case "<<":
+		return shiftLeft(asInteger(val1), asInteger(val2))
+	case ">>":
+		return shiftRight(asInteger(val1), asInteger(val2))
+
+	case "in":
+		return arrayContains(val2, val1)
+	}
+	panic(fmt.Errorf("syntax error: unsupported operation %q", op))
+}
+
+// logicalAnd requires both operands to be bool. Both operands are always evaluated (no short-circuiting).
+func logicalAnd(val1, val2 interface{}) bool {
+	left := asBool(val1)
+	right := asBool(val2)
+	return left && right
+}
+
+// logicalOr requires both operands to be bool. Both operands are always evaluated (no short-circuiting).
+func logicalOr(val1, val2 interface{}) bool {
+	left := asBool(val1)
+	right := asBool(val2)
+	return left || right
+}
+
+// shiftLeft shifts l to the left. Negative shift counts shift to the right.
+func shiftLeft(l, r int) int {
+	if r >= 0 {
+		return l << uint(r)
+	}
+	return l >> uint(-r)
+}
+
+// shiftRight shifts l to the right. Negative shift counts shift to the left.
+func shiftRight(l, r int) int {
+	if r >= 0 {
+		return l >> uint(r)
+	}
+	return l << uint(-r)
 }
@@ -3,5 +3,5 @@
 
 package internal
 
-//go:generate echo "Generating parser 'using golang.org/x/tools/cmd/goyacc...'"
-//go:generate goyacc -o parser.go parser.go.y
+//go:generate echo "Generating parser 'using golang.org/x/tools/cmd/goyacc v0.45.0...'"
+//go:generate go run golang.org/x/tools/cmd/goyacc@v0.45.0 -o parser.go parser.go.y
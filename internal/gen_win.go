@@ -2,5 +2,5 @@
 
 package internal
 
-//go:generate cmd /C echo "Generating parser 'using golang.org/x/tools/cmd/goyacc...'"
-//go:generate goyacc.exe -o parser.go parser.go.y
+//go:generate cmd /C echo "Generating parser 'using golang.org/x/tools/cmd/goyacc v0.45.0...'"
+//go:generate go run golang.org/x/tools/cmd/goyacc@v0.45.0 -o parser.go parser.go.y
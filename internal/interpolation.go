@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"fmt"
+	"go/token"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// parseInterpolation parses the string literal of an interpolated string like f"Hello {name}".
+// pos is the position of the leading 'f', lit the string literal (including quotes) that follows it.
+//
+// Braces can be escaped by doubling them: f"{{literal}}".
+// Within double-quoted strings, embedded expressions cannot contain double-quotes. Back-ticks can be used instead.
+func (l *Lexer) parseInterpolation(pos int, lit string) *InterpolatedString {
+	litPos := pos + 1
+	if len(lit) < 2 || lit[0] != lit[len(lit)-1] {
+		l.Perrorf(token.Pos(litPos), "parse error: cannot unquote string literal")
+	}
+	quote := lit[0]
+	body := lit[1 : len(lit)-1]
+	bodyPos := litPos + 1
+
+	node := &InterpolatedString{
+		ValuePos: pos,
+		Raw:      "f" + lit,
+		Parts:    make([]Node, 0),
+	}
+
+	var text strings.Builder // unquoted text of the current text part
+	textStart := 0           // index of the current raw text segment within body
+	segStart := 0            // index of the current text part within body
+
+	flushSegment := func(end int) {
+		raw := body[textStart:end]
+		if quote == '"' {
+			unquoted, err := strconv.Unquote(`"` + raw + `"`)
+			if err != nil {
+				l.Perrorf(token.Pos(litPos), "parse error: cannot unquote string literal")
+			}
+			raw = unquoted
+		}
+		text.WriteString(raw)
+	}
+	flushPart := func(end int) {
+		flushSegment(end)
+		if end > segStart {
+			node.Parts = append(node.Parts, &Literal{
+				ValuePos: bodyPos + segStart,
+				Raw:      body[segStart:end],
+				Value:    text.String(),
+			})
+		}
+		text.Reset()
+	}
+
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			if quote == '"' {
+				i++ // skip escaped character
+			}
+		case '}':
+			if i+1 >= len(body) || body[i+1] != '}' {
+				l.Perrorf(token.Pos(bodyPos+i), "parse error: single '}' in interpolated string")
+			}
+			flushSegment(i)
+			text.WriteByte('}')
+			i++
+			textStart = i + 1
+		case '{':
+			if i+1 < len(body) && body[i+1] == '{' {
+				flushSegment(i)
+				text.WriteByte('{')
+				i++
+				textStart = i + 1
+				continue
+			}
+			flushPart(i)
+
+			end := embeddedExpressionEnd(body, i+1)
+			if end < 0 {
+				l.Perrorf(token.Pos(bodyPos+i), "parse error: interpolation is not terminated")
+			}
+			node.Parts = append(node.Parts, l.parseEmbedded(body[i+1:end], bodyPos+i+1, bodyPos+i))
+
+			i = end
+			textStart = i + 1
+			segStart = i + 1
+		}
+	}
+	flushPart(len(body))
+	return node
+}
+
+// embeddedExpressionEnd returns the index of the closing brace that terminates the expression starting at 'start'.
+// Returns -1 if there is none.
+func embeddedExpressionEnd(body string, start int) int {
+	depth := 0
+	for i := start; i < len(body); i++ {
+		switch c := body[i]; c {
+		case '"', '`':
+			// skip string literals, which may contain braces
+			for i++; i < len(body) && body[i] != c; i++ {
+				if c == '"' && body[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// parseEmbedded parses an expression that is embedded within an interpolated string.
+// Comments are added to the outer lexer.
+func (l *Lexer) parseEmbedded(src string, pos int, bracePos int) Node {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			panic(fmt.Errorf("%w, in interpolation at position %d", r.(error), bracePos))
+		}
+	}()
+
+	lexer := newLexerAt(src, pos)
+	yyNewParser().Parse(lexer)
+	l.comments = append(l.comments, lexer.Comments()...)
+	return lexer.Result()
+}
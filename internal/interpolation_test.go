@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Interpolation(t *testing.T) {
+	vars := getTestVars()
+	vars["user"] = map[string]interface{}{"name": "Ann"}
+	vars["count"] = 3
+
+	assertEvaluation(t, vars, "Hello Ann, you have 3 items", `f"Hello {user.name}, you have {count} items"`)
+	assertEvaluation(t, vars, "Hello Ann, you have 3 items", "f`Hello {user.name}, you have {count} items`")
+	assertEvaluation(t, vars, "", `f""`)
+	assertEvaluation(t, vars, "", "f``")
+	assertEvaluation(t, vars, "text", `f"text"`)
+	assertEvaluation(t, vars, "42", `f"{int}"`)
+	assertEvaluation(t, vars, "4.2 text nil true false", `f"{float} {str} {nl} {tr} {fl}"`)
+	assertEvaluation(t, vars, "42text", `f"{int}{str}"`)
+	assertEvaluation(t, vars, "<44>", `f"<{int + 2}>"`)
+	assertEvaluation(t, vars, "Hello Ann!", "f\"Hello {user[`name`]}!\"")
+	assertEvaluation(t, vars, "Hello Ann!", "f`Hello {user[\"name\"]}!`")
+	assertEvaluation(t, vars, "1", "f`{ {\"a\": 1}.a }`")
+	assertEvaluation(t, vars, "a} b", "f`{\"a}\"} b`")
+	assertEvaluation(t, vars, "x=5", "f`x={f\"{2 + 3}\"}`")
+	assertEvaluation(t, vars, "Ann has 3", `f"{user.name} has {count /* items */}"`)
+
+	// escaping:
+	assertEvaluation(t, vars, "{int} = 42", `f"{{int}} = {int}"`)
+	assertEvaluation(t, vars, "{}", `f"{{}}"`)
+	assertEvaluation(t, vars, "\t42\n", `f"\t{int}\n"`)
+	assertEvaluation(t, vars, `\t42\n`, "f`\\t{int}\\n`")
+	assertEvaluation(t, vars, `"42"`, `f"\"{int}\""`)
+
+	// within other expressions:
+	assertEvaluation(t, vars, "Ann!", `f"{user.name}" + "!"`)
+	assertEvaluation(t, vars, []interface{}{"#1", "#2"}, `[f"#{x}" for x in [1, 2]]`)
+	assertEvaluation(t, vars, true, `f"{int}" == "42"`)
+
+	// 'f' is still a normal identifier otherwise:
+	vars["f"] = "var"
+	assertEvaluation(t, vars, "var", `f`)
+	assertEvaluation(t, vars, "vartext", `f + "text"`)
+}
+
+func Test_Interpolation_InvalidTypes(t *testing.T) {
+	vars := getTestVars()
+	assertEvalError(t, vars, "type error: cannot add or concatenate type string and array", `f"arr: {arr}"`)
+	assertEvalError(t, vars, "type error: cannot add or concatenate type string and object", `f"{obj}"`)
+	assertEvalError(t, vars, "var error: variable \"unknown\" does not exist", `f"{unknown}"`)
+}
+
+func Test_Interpolation_InvalidSyntax(t *testing.T) {
+	vars := getTestVars()
+	assertEvalError(t, vars, "syntax error: unexpected $end, in interpolation at position 6", `f"abc{}"`)
+	assertEvalError(t, vars, "syntax error: unexpected $end, in interpolation at position 3", `f"{1 +}"`)
+	assertEvalError(t, vars, "syntax error: unexpected LITERAL_NUMBER, in interpolation at position 3", `f"{1 2}"`)
+	assertEvalError(t, vars, "parse error: cannot parse integer at position 8, in interpolation at position 3", `f"{1 + 99999999999999999999999}"`)
+	assertEvalError(t, vars, "unknown token \"ILLEGAL\" (\"§\") at position 11, in interpolation at position 6", `f"abc{1 + § }"`)
+	assertEvalError(t, vars, "parse error: interpolation is not terminated at position 6", `f"abc{1 + 2"`)
+	assertEvalError(t, vars, "parse error: single '}' in interpolated string at position 6", `f"abc}"`)
+	assertEvalError(t, vars, "parse error: cannot unquote string literal at position 2", `f"abc`)
+	assertEvalError(t, vars, "parse error: cannot unquote string literal at position 2", `f"\q{1}"`)
+	assertEvalError(t, vars, "syntax error: unexpected LITERAL_STRING", `f "abc"`)
+}
+
+func Test_Interpolation_Comments(t *testing.T) {
+	program, err := Parse(`/* a */ f"{1 /* b */}" // c`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []Comment{
+			{Pos: 1, Text: "/* a */"},
+			{Pos: 14, Text: "/* b */"},
+			{Pos: 24, Text: "// c"},
+		}, program.Comments)
+	}
+}
@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"go/scanner"
 	"go/token"
@@ -13,38 +12,40 @@ import (
 const BitSizeOfInt = int(unsafe.Sizeof(0)) * 8
 
 type Token struct {
+	pos     int
 	literal string
 	value   interface{}
 }
 
 type Lexer struct {
+	src     string
+	base    int // position of the first character
 	scanner scanner.Scanner
-	result  interface{}
+	result  Node
 
 	nextTokenType int
 	nextTokenInfo Token
+	pos           int // position of the last scanned token, for error messages
 
-	variables map[string]interface{}
-	functions map[string]ExpressionFunction
+	comments []Comment
 }
 
-func NewLexer(src string, variables map[string]interface{}, functions map[string]ExpressionFunction) *Lexer {
-	if variables == nil {
-		variables = map[string]interface{}{}
-	}
-	if functions == nil {
-		functions = map[string]ExpressionFunction{}
-	}
+func NewLexer(src string) *Lexer {
+	return newLexerAt(src, 1)
+}
 
+// newLexerAt creates a lexer for an expression that is embedded within a larger source,
+// starting at the given position. All reported positions are relative to the larger source.
+func newLexerAt(src string, base int) *Lexer {
 	lexer := &Lexer{
-		variables: variables,
-		functions: functions,
+		src:  src,
+		base: base,
 	}
 
 	fset := token.NewFileSet()
-	file := fset.AddFile("", fset.Base(), len(src))
+	file := fset.AddFile("", base, len(src))
 
-	lexer.scanner.Init(file, []byte(src), nil, 0)
+	lexer.scanner.Init(file, []byte(src), nil, scanner.ScanComments)
 	return lexer
 }
 
@@ -55,6 +56,13 @@ func (l *Lexer) scan() (token.Pos, token.Token, string) {
 			// go/scanner automatically inserted this token --> ignore it
 			continue
 		}
+		if tok == token.COMMENT {
+			if strings.HasPrefix(lit, "/*") && (len(lit) < 4 || !strings.HasSuffix(lit, "*/")) {
+				l.Perrorf(pos, "parse error: comment not terminated")
+			}
+			l.comments = append(l.comments, Comment{Pos: int(pos), Text: lit})
+			continue
+		}
 		if tok.IsKeyword() {
 			// go knows about keywords, we don't. So we treat them as simple identifiers
 			tok = token.IDENT
@@ -75,11 +83,16 @@ func (l *Lexer) Lex(lval *yySymType) int {
 	}
 
 	pos, tok, lit := l.scan()
+	l.pos = int(pos)
 
 	tokenInfo := Token{
+		pos:     int(pos),
 		value:   nil,
 		literal: lit,
 	}
+	if lit == "" && tok.IsOperator() {
+		tokenInfo.literal = tok.String()
+	}
 
 	switch tok {
 
@@ -153,13 +166,25 @@ func (l *Lexer) Lex(lval *yySymType) int {
 		// Remember the minus-operator and omit it the next time:
 		l.nextTokenType = int('-')
 		l.nextTokenInfo = Token{
+			pos:     int(pos) + 1,
 			value:   nil,
 			literal: "-",
 		}
 
 		// Bit manipulations
 
-	case token.AND, token.OR, token.XOR:
+	case token.OR:
+		if l.charAt(int(pos)+1) == '>' && l.charAt(int(pos)+2) != '=' && l.charAt(int(pos)+2) != '>' {
+			// This token is not known by go, so we combine '|' and '>' into the pipe-operator.
+			// Only a '>' token is combined; go scans '>=' and '>>' as different tokens.
+			l.scan()
+			tokenType = PIPE
+			tokenInfo.literal = "|>"
+			break
+		}
+		tokenType = int('|')
+
+	case token.AND, token.XOR:
 		tokenType = int(tok.String()[0])
 
 	case token.SHL:
@@ -168,7 +193,12 @@ func (l *Lexer) Lex(lval *yySymType) int {
 		tokenType = SHR
 
 	case token.IDENT:
-		if lit == "nil" {
+		if next := l.charAt(int(pos) + 1); lit == "f" && (next == '"' || next == '`') {
+			tokenType = LITERAL_INTERPOLATION
+			_, _, strLit := l.scan()
+			tokenInfo.literal = lit + strLit
+			tokenInfo.value = l.parseInterpolation(int(pos), strLit)
+		} else if lit == "nil" {
 			tokenType = LITERAL_NIL
 		} else if lit == "true" {
 			tokenType = LITERAL_BOOL
@@ -178,6 +208,11 @@ func (l *Lexer) Lex(lval *yySymType) int {
 			tokenInfo.value = false
 		} else if lit == "in" || lit == "IN" {
 			tokenType = IN
+			tokenInfo.literal = "in"
+		} else if lit == "for" {
+			tokenType = FOR
+		} else if lit == "if" {
+			tokenType = IF
 		} else {
 			tokenType = IDENT
 		}
@@ -191,6 +226,9 @@ func (l *Lexer) Lex(lval *yySymType) int {
 	case token.COLON:
 		tokenType = int(':')
 
+	case token.ELLIPSIS:
+		tokenType = ELLIPSIS
+
 	case token.LBRACK, token.RBRACK,
 		token.LBRACE, token.RBRACE,
 		token.LPAREN, token.RPAREN:
@@ -212,6 +250,18 @@ func (l *Lexer) Lex(lval *yySymType) int {
 			tokenType = BIT_NOT
 			break
 		}
+		if lit == "$" && isDigit(l.charAt(int(pos)+1)) {
+			// Numbered variables like `$1` are not known by go, so we combine '$' and the digits into an identifier.
+			// The digits are read directly, since go would scan `$1.name` as the float `1.` followed by an identifier.
+			end := int(pos) + 1
+			for isDigit(l.charAt(end)) {
+				end++
+			}
+			tokenType = IDENT
+			tokenInfo.literal = l.src[int(pos)-l.base : end-l.base]
+			l.skipTo(end)
+			break
+		}
 		fallthrough
 
 	default:
@@ -222,17 +272,54 @@ func (l *Lexer) Lex(lval *yySymType) int {
 	return tokenType
 }
 
+// skipTo continues scanning at the given position.
+func (l *Lexer) skipTo(pos int) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", pos, len(l.src)-(pos-l.base))
+	l.scanner.Init(file, []byte(l.src[pos-l.base:]), nil, scanner.ScanComments)
+}
+
+// charAt returns the source character at the given position, or 0 if the position is out of range.
+func (l *Lexer) charAt(pos int) byte {
+	idx := pos - l.base
+	if idx < 0 || idx >= len(l.src) {
+		return 0
+	}
+	return l.src[idx]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// SyntaxError is raised if an expression cannot be parsed.
+type SyntaxError struct {
+	Msg string
+	Pos int // position at which parsing failed; 0 if unknown
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Msg
+}
+
 func (l *Lexer) Error(e string) {
-	panic(errors.New(e))
+	// the keywords of comprehensions are identifiers outside of comprehensions
+	e = strings.Replace(e, "IDENT or FOR or IF", "IDENT", 1)
+	panic(&SyntaxError{Msg: e, Pos: l.pos})
 }
 
 func (l *Lexer) Perrorf(pos token.Pos, format string, a ...interface{}) {
 	if pos.IsValid() {
 		format = format + " at position " + strconv.Itoa(int(pos))
 	}
-	panic(fmt.Errorf(format, a...))
+	panic(&SyntaxError{Msg: fmt.Sprintf(format, a...), Pos: int(pos)})
 }
 
-func (l *Lexer) Result() interface{} {
+func (l *Lexer) Result() Node {
 	return l.result
 }
+
+// Comments returns all comments that were encountered so far, in source order.
+func (l *Lexer) Comments() []Comment {
+	return l.comments
+}
@@ -0,0 +1,427 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Number is an int, float64 or bool.
+// It is used by numeric expressions, which don't box values into interfaces and therefore don't allocate.
+// The zero value is the int 0.
+type Number struct {
+	kind numberKind
+	b    bool
+	i    int
+	f    float64
+}
+
+type numberKind uint8
+
+const (
+	kindInt numberKind = iota
+	kindFloat
+	kindBool
+)
+
+// Int returns an int Number.
+func Int(i int) Number {
+	return Number{kind: kindInt, i: i}
+}
+
+// Float returns a float64 Number.
+func Float(f float64) Number {
+	return Number{kind: kindFloat, f: f}
+}
+
+// Bool returns a bool Number.
+func Bool(b bool) Number {
+	return Number{kind: kindBool, b: b}
+}
+
+// NumberOf converts an int, float64 or bool into a Number.
+func NumberOf(val interface{}) (Number, error) {
+	switch v := val.(type) {
+	case int:
+		return Int(v), nil
+	case float64:
+		return Float(v), nil
+	case bool:
+		return Bool(v), nil
+	}
+	return Number{}, fmt.Errorf("type error: required number or bool, but was %s", TypeOf(val))
+}
+
+// IsInt returns true if the number is an int.
+func (n Number) IsInt() bool {
+	return n.kind == kindInt
+}
+
+// IsFloat returns true if the number is a float64.
+func (n Number) IsFloat() bool {
+	return n.kind == kindFloat
+}
+
+// IsBool returns true if the number is a bool.
+func (n Number) IsBool() bool {
+	return n.kind == kindBool
+}
+
+// Int returns the number as int. Floats are truncated, bools are 0.
+func (n Number) Int() int {
+	if n.kind == kindFloat {
+		return int(n.f)
+	}
+	return n.i
+}
+
+// Float returns the number as float64. Bools are 0.
+func (n Number) Float() float64 {
+	if n.kind == kindInt {
+		return float64(n.i)
+	}
+	return n.f
+}
+
+// Bool returns the bool. Numbers are false.
+func (n Number) Bool() bool {
+	return n.b
+}
+
+// Interface returns the number as int, float64 or bool.
+func (n Number) Interface() interface{} {
+	switch n.kind {
+	case kindFloat:
+		return n.f
+	case kindBool:
+		return n.b
+	}
+	return n.i
+}
+
+// String formats the number the same way as during string concatenation.
+func (n Number) String() string {
+	return add("", n.Interface()).(string)
+}
+
+// NumericExpression is an expression that only operates on numbers and bools.
+//
+// It is compiled into bytecode, which is executed by a stack machine that works on unboxed values.
+// Evaluations don't allocate, unless an error occurs.
+// Results and errors are identical to Program.Evaluate.
+//
+// Supports literals, variables, parentheses, the ternary operator and all unary and binary operators except 'in'.
+// Numeric expressions are immutable and can be evaluated concurrently.
+type NumericExpression struct {
+	compiled  *bytecode
+	consts    []Number
+	variables []string
+}
+
+// numericStackSize is the maximum stack depth of numeric expressions that can be evaluated without allocating.
+const numericStackSize = 32
+
+// CompileNumeric parses the given expression string and compiles it into a numeric expression.
+// The expression can only access the given variables, which are passed to Evaluate in the same order.
+func CompileNumeric(str string, variables []string) (*NumericExpression, error) {
+	program, err := Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	return program.CompileNumeric(variables)
+}
+
+// CompileNumeric compiles the parsed expression into a numeric expression.
+// The expression can only access the given variables, which are passed to Evaluate in the same order.
+func (p *Program) CompileNumeric(variables []string) (expr *NumericExpression, err error) {
+	defer recoverError(&err)
+
+	declared := make(map[string]struct{}, len(variables))
+	for _, name := range variables {
+		if _, ok := declared[name]; ok {
+			return nil, fmt.Errorf("var error: variable %q is declared twice", name)
+		}
+		declared[name] = struct{}{}
+	}
+	checkNumeric(p.Root, declared)
+
+	// Variable names are the first names of the compiled expression, so that their index can be used as slot.
+	c := newCompiler()
+	for _, name := range variables {
+		c.name(name)
+	}
+	c.compile(p.Root)
+
+	expr = &NumericExpression{
+		compiled:  c.bytecode,
+		consts:    make([]Number, len(c.consts)),
+		variables: append([]string(nil), variables...),
+	}
+	for idx, val := range c.consts {
+		if expr.consts[idx], err = NumberOf(val); err != nil {
+			return nil, err
+		}
+	}
+	return expr, nil
+}
+
+// checkNumeric verifies that the expression is supported by numeric expressions.
+func checkNumeric(node Node, declared map[string]struct{}) {
+	switch n := node.(type) {
+	case *Literal:
+		switch n.Value.(type) {
+		case int, float64, bool:
+			return
+		}
+		panic(fmt.Errorf("syntax error: %s literals are not supported within numeric expressions", TypeOf(n.Value)))
+	case *Ident:
+		if _, ok := declared[n.Name]; !ok {
+			panic(fmt.Errorf("var error: variable %q does not exist", n.Name))
+		}
+	case *UnaryExpr:
+		checkNumeric(n.X, declared)
+	case *BinaryExpr:
+		if n.Op == "in" {
+			panic(fmt.Errorf("syntax error: the 'in' operator is not supported within numeric expressions"))
+		}
+		checkNumeric(n.X, declared)
+		checkNumeric(n.Y, declared)
+	case *TernaryExpr:
+		checkNumeric(n.Cond, declared)
+		checkNumeric(n.Then, declared)
+		checkNumeric(n.Else, declared)
+	case *ParenExpr:
+		checkNumeric(n.X, declared)
+	case *InterpolatedString:
+		panic(fmt.Errorf("syntax error: strings are not supported within numeric expressions"))
+	case *ArrayLit, *ArrayComp:
+		panic(fmt.Errorf("syntax error: arrays are not supported within numeric expressions"))
+	case *ObjectLit, *ObjectComp:
+		panic(fmt.Errorf("syntax error: objects are not supported within numeric expressions"))
+	case *CallExpr, *PipeExpr:
+		panic(fmt.Errorf("syntax error: function calls are not supported within numeric expressions"))
+	case *SelectorExpr, *IndexExpr, *SliceExpr:
+		panic(fmt.Errorf("syntax error: member access is not supported within numeric expressions"))
+	default:
+		panic(fmt.Errorf("syntax error: unsupported node %T", node))
+	}
+}
+
+// Variables returns the names of the variables, in the order they need to be passed to Evaluate.
+func (x *NumericExpression) Variables() []string {
+	return append([]string(nil), x.variables...)
+}
+
+// Evaluate the numeric expression.
+// Accepts the values of all variables, in the order they were declared.
+func (x *NumericExpression) Evaluate(variables []Number) (result Number, err error) {
+	if len(variables) != len(x.variables) {
+		return Number{}, fmt.Errorf("var error: expected %d variables, but got %d", len(x.variables), len(variables))
+	}
+	defer recoverError(&err)
+
+	var buf [numericStackSize]Number
+	stack := buf[:]
+	if x.compiled.maxStack > len(buf) {
+		stack = make([]Number, x.compiled.maxStack)
+	}
+	return x.run(stack, variables), nil
+}
+
+func (x *NumericExpression) run(stack []Number, variables []Number) Number {
+	sp := 0 // stack pointer; number of values on the stack
+
+	for _, ins := range x.compiled.code {
+		switch ins.op {
+		case opConst:
+			stack[sp] = x.consts[ins.arg]
+			sp++
+		case opVar:
+			stack[sp] = variables[ins.arg]
+			sp++
+		case opSelect:
+			sp -= 2
+			if numericBool(stack[sp-1]) {
+				stack[sp-1] = stack[sp]
+			} else {
+				stack[sp-1] = stack[sp+1]
+			}
+
+		case opNeg:
+			stack[sp-1] = numericNeg(stack[sp-1])
+		case opNot:
+			stack[sp-1] = Bool(!numericBool(stack[sp-1]))
+		case opBitNot:
+			stack[sp-1] = Int(^numericInteger(stack[sp-1]))
+
+		default:
+			sp--
+			stack[sp-1] = numericBinary(ins.op, stack[sp-1], stack[sp])
+		}
+	}
+	return stack[0]
+}
+
+func numericBool(n Number) bool {
+	if n.kind != kindBool {
+		asBool(n.Interface()) // fails with the same error as other expressions
+	}
+	return n.b
+}
+
+// numericInteger is the counterpart of asInteger.
+func numericInteger(n Number) int {
+	switch n.kind {
+	case kindInt:
+		return n.i
+	case kindFloat:
+		if i := int(n.f); float64(i) == n.f {
+			return i
+		}
+	}
+	return asInteger(n.Interface()) // fails with the same error as other expressions
+}
+
+func numericNeg(n Number) Number {
+	switch n.kind {
+	case kindInt:
+		return Int(-n.i)
+	case kindFloat:
+		return Float(-n.f)
+	}
+	unaryMinus(n.Interface()) // fails with the same error as other expressions
+	return n
+}
+
+// numericBinary performs binary operations on unboxed values.
+// Operations that fail are delegated to the generic implementation, so that errors are identical.
+func numericBinary(op opcode, x, y Number) Number {
+	switch op {
+	case opAnd:
+		if x.kind == kindBool && y.kind == kindBool {
+			return Bool(x.b && y.b)
+		}
+	case opOr:
+		if x.kind == kindBool && y.kind == kindBool {
+			return Bool(x.b || y.b)
+		}
+	case opEqual:
+		return Bool(numericEqual(x, y))
+	case opNotEqual:
+		return Bool(!numericEqual(x, y))
+
+	case opBitOr:
+		return Int(numericInteger(x) | numericInteger(y))
+	case opBitAnd:
+		return Int(numericInteger(x) & numericInteger(y))
+	case opBitXor:
+		return Int(numericInteger(x) ^ numericInteger(y))
+	case opShiftLeft:
+		return Int(shiftLeft(numericInteger(x), numericInteger(y)))
+	case opShiftRight:
+		return Int(shiftRight(numericInteger(x), numericInteger(y)))
+
+	default:
+		if x.kind == kindInt && y.kind == kindInt {
+			if res, ok := intBinary(op, x.i, y.i); ok {
+				return res
+			}
+		} else if x.kind != kindBool && y.kind != kindBool {
+			if res, ok := floatBinary(op, x.Float(), y.Float()); ok {
+				return res
+			}
+		}
+	}
+
+	// Error, or an operation without fast path. Boxing is fine in both cases.
+	res, err := NumberOf(evalBinary(binaryOperator(op), x.Interface(), y.Interface()))
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func intBinary(op opcode, x, y int) (Number, bool) {
+	switch op {
+	case opAdd:
+		return Int(x + y), true
+	case opSub:
+		return Int(x - y), true
+	case opMul:
+		return Int(x * y), true
+	case opDiv:
+		if y == 0 {
+			panic(errors.New("math error: cannot divide by zero"))
+		}
+		return Int(x / y), true
+	case opMod:
+		return Int(x % y), true
+	case opPow:
+		res := math.Pow(float64(x), float64(y))
+		if i := int(res); float64(i) == res {
+			return Int(i), true
+		}
+		return Float(res), true
+	case opLess:
+		return Bool(x < y), true
+	case opLessEqual:
+		return Bool(x <= y), true
+	case opGreater:
+		return Bool(x > y), true
+	case opGreaterEqual:
+		return Bool(x >= y), true
+	}
+	return Number{}, false
+}
+
+func floatBinary(op opcode, x, y float64) (Number, bool) {
+	switch op {
+	case opAdd:
+		return Float(x + y), true
+	case opSub:
+		return Float(x - y), true
+	case opMul:
+		return Float(x * y), true
+	case opDiv:
+		if y == 0 {
+			panic(errors.New("math error: cannot divide by zero"))
+		}
+		return Float(x / y), true
+	case opMod:
+		return Float(math.Mod(x, y)), true
+	case opPow:
+		return Float(math.Pow(x, y)), true
+	case opLess:
+		return Bool(x < y), true
+	case opLessEqual:
+		return Bool(x <= y), true
+	case opGreater:
+		return Bool(x > y), true
+	case opGreaterEqual:
+		return Bool(x >= y), true
+	}
+	return Number{}, false
+}
+
+// numericEqual is the counterpart of deepEqual.
+func numericEqual(x, y Number) bool {
+	switch {
+	case x.kind == kindBool && y.kind == kindBool:
+		return x.b == y.b
+	case x.kind == kindBool || y.kind == kindBool:
+		return false
+	case x.kind == kindInt && y.kind == kindInt:
+		return x.i == y.i
+	}
+	return x.Float() == y.Float()
+}
+
+// binaryOperator returns the operator of a binary opcode.
+func binaryOperator(op opcode) string {
+	for str, code := range binaryOpcodes {
+		if code == op {
+			return str
+		}
+	}
+	panic(fmt.Errorf("syntax error: unsupported operation %d", op))
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Numeric_IdenticalResults(t *testing.T) {
+	names := []string{"i", "j", "zero", "f", "g", "big", "t", "n"}
+	values := []Number{Int(7), Int(-3), Int(0), Float(2.5), Float(-0.5), Float(1e300), Bool(true), Bool(false)}
+
+	variables := make(map[string]interface{})
+	for idx, name := range names {
+		variables[name] = values[idx].Interface()
+	}
+
+	expressions := []string{
+		// arithmetic
+		`i + j`, `i - j`, `i * j`, `i / j`, `i % j`, `i ** 2`, `j ** -1`, `2 ** 0.5`,
+		`f + g`, `f - i`, `i * f`, `f / g`, `f % 1`, `f ** 2`, `big * big`, `-big * big`,
+		`-i`, `-f`, `- -i`, `(i + f) * 2`, `i / 2`, `7 / 2.0`,
+		`i / zero`, `f / zero`, `f / 0.0`,
+		// comparison and logic
+		`i < j`, `i <= 7`, `f > i`, `g >= -0.5`, `i == 7.0`, `f != 2.5`, `t == n`, `t == 1`, `i == t`,
+		`t && n`, `t || n`, `!n`, `!!t`, `i > 0 && f < 3 || n`,
+		// bit manipulation
+		`i | j`, `i & 3`, `i ^ 1`, `~i`, `i << 2`, `i >> 1`, `i << -1`, `4.0 | 1`, `~2.0`,
+		// ternary
+		`t ? i : f`, `n ? i : f`, `i > 5 ? "a" : 1`,
+		// errors
+		`i + t`, `t * 2`, `-t`, `!i`, `i && t`, `t || f`, `f | 1`, `~f`, `i < t`, `i ? 1 : 2`, `2.5 << 1`,
+	}
+
+	for _, str := range expressions {
+		expected, expectedErr := Evaluate(str, variables, nil)
+
+		expr, err := CompileNumeric(str, names)
+		if err != nil {
+			assert.Contains(t, err.Error(), "not supported", str)
+			continue
+		}
+		result, err := expr.Evaluate(values)
+		if expectedErr != nil {
+			assert.EqualError(t, err, expectedErr.Error(), str)
+			continue
+		}
+		if assert.NoError(t, err, str) {
+			assert.Equal(t, expected, result.Interface(), str)
+		}
+	}
+}
+
+func Test_Numeric_NoAllocations(t *testing.T) {
+	expr, err := CompileNumeric(`x > threshold && y < limit ? (x * 1.8) + 32 : -x ** 2 % 7`, []string{"x", "y", "threshold", "limit"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	variables := []Number{Float(21.5), Int(3), Int(20), Float(5)}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		result, err := expr.Evaluate(variables)
+		if err != nil || result.Float() != 70.7 {
+			t.Fatal(result, err)
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+func Test_Numeric_CompileErrors(t *testing.T) {
+	assertError := func(expected string, str string, variables ...string) {
+		t.Helper()
+		_, err := CompileNumeric(str, variables)
+		assert.EqualError(t, err, expected)
+	}
+	assertError(`var error: variable "y" does not exist`, `x + y`, "x")
+	assertError(`var error: variable "x" is declared twice`, `x`, "x", "x")
+	assertError(`syntax error: string literals are not supported within numeric expressions`, `"text"`)
+	assertError(`syntax error: nil literals are not supported within numeric expressions`, `nil`)
+	assertError(`syntax error: strings are not supported within numeric expressions`, `f"{1}"`)
+	assertError(`syntax error: arrays are not supported within numeric expressions`, `[1]`)
+	assertError(`syntax error: objects are not supported within numeric expressions`, `{}`)
+	assertError(`syntax error: function calls are not supported within numeric expressions`, `abs(1)`)
+	assertError(`syntax error: member access is not supported within numeric expressions`, `x.y`, "x")
+	assertError(`syntax error: the 'in' operator is not supported within numeric expressions`, `1 in x`, "x")
+	assertError(`syntax error: unexpected $end`, `1 +`)
+}
+
+func Test_Numeric_Evaluate(t *testing.T) {
+	expr, err := CompileNumeric(`(celsius * 1.8) + 32`, []string{"celsius"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"celsius"}, expr.Variables())
+
+	result, err := expr.Evaluate([]Number{Int(100)})
+	assert.NoError(t, err)
+	assert.True(t, result.IsFloat())
+	assert.Equal(t, 212.0, result.Float())
+	assert.Equal(t, 212, result.Int())
+	assert.Equal(t, "212", result.String())
+
+	_, err = expr.Evaluate(nil)
+	assert.EqualError(t, err, "var error: expected 1 variables, but got 0")
+	_, err = expr.Evaluate([]Number{Bool(true)})
+	assert.EqualError(t, err, "type error: cannot multiply type bool and number")
+}
+
+func Test_NumberOf(t *testing.T) {
+	n, err := NumberOf(42)
+	assert.NoError(t, err)
+	assert.True(t, n.IsInt())
+	assert.Equal(t, 42, n.Interface())
+
+	n, err = NumberOf(true)
+	assert.NoError(t, err)
+	assert.True(t, n.IsBool())
+	assert.True(t, n.Bool())
+	assert.Equal(t, "true", n.String())
+
+	_, err = NumberOf("42")
+	assert.EqualError(t, err, "type error: required number or bool, but was string")
+
+	var zero Number
+	assert.Equal(t, 0, zero.Interface())
+}
@@ -9,30 +9,36 @@ import __yyfmt__ "fmt"
 
 //line parser.go.y:6
 type yySymType struct {
-	yys      int
-	token    Token
-	expr     interface{}
-	exprList []interface{}
-	exprMap  map[string]interface{}
+	yys       int
+	token     Token
+	node      Node
+	nodeList  []Node
+	identList []*Ident
+	clause    *ForClause
 }
 
 const LITERAL_NIL = 57346
 const LITERAL_BOOL = 57347
 const LITERAL_NUMBER = 57348
 const LITERAL_STRING = 57349
-const IDENT = 57350
-const AND = 57351
-const OR = 57352
-const EQL = 57353
-const NEQ = 57354
-const LSS = 57355
-const GTR = 57356
-const LEQ = 57357
-const GEQ = 57358
-const SHL = 57359
-const SHR = 57360
-const BIT_NOT = 57361
-const IN = 57362
+const LITERAL_INTERPOLATION = 57350
+const IDENT = 57351
+const AND = 57352
+const OR = 57353
+const EQL = 57354
+const NEQ = 57355
+const LSS = 57356
+const GTR = 57357
+const LEQ = 57358
+const GEQ = 57359
+const SHL = 57360
+const SHR = 57361
+const BIT_NOT = 57362
+const IN = 57363
+const ELLIPSIS = 57364
+const FOR = 57365
+const IF = 57366
+const PIPE = 57367
 
 var yyToknames = [...]string{
 	"$end",
@@ -42,6 +48,7 @@ var yyToknames = [...]string{
 	"LITERAL_BOOL",
 	"LITERAL_NUMBER",
 	"LITERAL_STRING",
+	"LITERAL_INTERPOLATION",
 	"IDENT",
 	"AND",
 	"OR",
@@ -55,6 +62,10 @@ var yyToknames = [...]string{
 	"SHR",
 	"BIT_NOT",
 	"IN",
+	"ELLIPSIS",
+	"FOR",
+	"IF",
+	"PIPE",
 	"'?'",
 	"':'",
 	"'|'",
@@ -82,7 +93,7 @@ const yyEofCode = 1
 const yyErrCode = 2
 const yyInitialStackSize = 16
 
-//line parser.go.y:146
+//line parser.go.y:190
 
 //line yacctab:1
 var yyExca = [...]int8{
@@ -93,154 +104,198 @@ var yyExca = [...]int8{
 
 const yyPrivate = 57344
 
-const yyLast = 597
-
-var yyAct = [...]int8{
-	45, 2, 90, 81, 82, 80, 42, 79, 72, 41,
-	47, 44, 80, 38, 39, 7, 48, 49, 50, 51,
-	52, 53, 54, 55, 57, 58, 59, 60, 61, 62,
-	63, 64, 65, 66, 67, 68, 69, 70, 71, 6,
-	73, 75, 5, 4, 31, 32, 25, 26, 27, 28,
-	29, 30, 36, 37, 78, 40, 19, 85, 33, 35,
-	34, 20, 21, 22, 23, 24, 40, 38, 39, 3,
-	1, 76, 0, 0, 0, 88, 0, 0, 38, 39,
-	0, 91, 0, 92, 93, 94, 0, 0, 95, 31,
-	32, 25, 26, 27, 28, 29, 30, 36, 37, 100,
-	40, 19, 87, 33, 35, 34, 20, 21, 22, 23,
-	24, 0, 38, 39, 86, 31, 32, 25, 26, 27,
-	28, 29, 30, 36, 37, 0, 40, 19, 0, 33,
-	35, 34, 20, 21, 22, 23, 24, 0, 38, 39,
-	99, 31, 32, 25, 26, 27, 28, 29, 30, 36,
-	37, 0, 40, 19, 0, 33, 35, 34, 20, 21,
-	22, 23, 24, 0, 38, 39, 97, 31, 32, 25,
-	26, 27, 28, 29, 30, 36, 37, 0, 40, 19,
-	98, 33, 35, 34, 20, 21, 22, 23, 24, 0,
-	38, 39, 31, 32, 25, 26, 27, 28, 29, 30,
-	36, 37, 0, 40, 19, 84, 33, 35, 34, 20,
-	21, 22, 23, 24, 0, 38, 39, 31, 32, 25,
-	26, 27, 28, 29, 30, 36, 37, 0, 40, 19,
-	83, 33, 35, 34, 20, 21, 22, 23, 24, 0,
-	38, 39, 31, 32, 25, 26, 27, 28, 29, 30,
-	36, 37, 0, 40, 19, 0, 33, 35, 34, 20,
-	21, 22, 23, 24, 0, 38, 39, 31, 0, 25,
-	26, 27, 28, 29, 30, 36, 37, 0, 40, 0,
-	0, 33, 35, 34, 20, 21, 22, 23, 24, 0,
-	38, 39, 25, 26, 27, 28, 29, 30, 36, 37,
-	0, 40, 0, 0, 33, 35, 34, 20, 21, 22,
-	23, 24, 0, 38, 39, 25, 26, 27, 28, 29,
-	30, 36, 37, 0, 40, 0, 0, 0, 35, 34,
-	20, 21, 22, 23, 24, 0, 38, 39, 25, 26,
-	27, 28, 29, 30, 36, 37, 0, 40, 0, 0,
-	0, 0, 34, 20, 21, 22, 23, 24, 0, 38,
-	39, 10, 11, 12, 13, 9, 0, 0, 0, 40,
-	10, 11, 12, 13, 9, 0, 18, 22, 23, 24,
-	0, 38, 39, 0, 16, 18, 0, 0, 17, 0,
-	14, 0, 8, 16, 15, 46, 0, 17, 0, 14,
-	96, 8, 0, 15, 10, 11, 12, 13, 9, 27,
-	28, 29, 30, 36, 37, 0, 40, 0, 0, 18,
-	0, 0, 20, 21, 22, 23, 24, 16, 38, 39,
-	0, 17, 0, 14, 89, 8, 0, 15, 25, 26,
-	27, 28, 29, 30, 36, 37, 0, 40, 10, 11,
-	12, 13, 9, 20, 21, 22, 23, 24, 0, 38,
-	39, 0, 0, 18, 0, 0, 0, 0, 0, 0,
-	0, 16, 0, 0, 0, 17, 0, 14, 0, 8,
-	77, 15, 10, 11, 12, 13, 9, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 18, 0, 0,
-	74, 0, 0, 0, 0, 16, 0, 0, 0, 17,
-	0, 14, 0, 8, 0, 15, 10, 11, 12, 13,
-	9, 10, 11, 12, 13, 9, 0, 0, 0, 0,
-	0, 18, 0, 0, 0, 0, 18, 0, 0, 16,
-	56, 0, 0, 17, 16, 14, 0, 8, 17, 15,
-	14, 43, 8, 0, 15, 10, 11, 12, 13, 9,
-	0, 0, 0, 0, 36, 37, 0, 40, 0, 0,
-	18, 0, 0, 20, 21, 22, 23, 24, 16, 38,
-	39, 0, 17, 40, 14, 0, 8, 0, 15, 20,
-	21, 22, 23, 24, 0, 38, 39,
+const yyLast = 909
+
+var yyAct = [...]uint8{
+	88, 2, 91, 126, 105, 50, 90, 90, 94, 95,
+	47, 89, 122, 131, 99, 48, 90, 51, 55, 57,
+	58, 59, 10, 108, 121, 60, 109, 62, 63, 64,
+	66, 67, 68, 69, 70, 71, 72, 73, 74, 75,
+	76, 77, 78, 79, 80, 54, 82, 84, 61, 44,
+	45, 8, 7, 93, 87, 6, 46, 97, 33, 34,
+	35, 36, 42, 43, 5, 46, 100, 81, 28, 29,
+	30, 46, 44, 45, 4, 26, 27, 28, 29, 30,
+	21, 44, 45, 3, 103, 1, 0, 44, 45, 0,
+	0, 106, 0, 0, 22, 23, 111, 113, 0, 114,
+	0, 0, 0, 117, 0, 116, 0, 0, 120, 0,
+	0, 0, 0, 124, 0, 110, 125, 0, 0, 0,
+	0, 0, 128, 0, 130, 0, 0, 0, 0, 0,
+	0, 0, 0, 133, 0, 37, 38, 31, 32, 33,
+	34, 35, 36, 42, 43, 129, 46, 0, 0, 0,
+	25, 24, 0, 39, 41, 40, 26, 27, 28, 29,
+	30, 0, 44, 45, 0, 0, 85, 37, 38, 31,
+	32, 33, 34, 35, 36, 42, 43, 0, 46, 0,
+	0, 0, 25, 24, 102, 39, 41, 40, 26, 27,
+	28, 29, 30, 0, 44, 45, 101, 37, 38, 31,
+	32, 33, 34, 35, 36, 42, 43, 0, 46, 0,
+	0, 0, 25, 24, 0, 39, 41, 40, 26, 27,
+	28, 29, 30, 0, 44, 45, 127, 37, 38, 31,
+	32, 33, 34, 35, 36, 42, 43, 0, 46, 0,
+	0, 0, 25, 24, 0, 39, 41, 40, 26, 27,
+	28, 29, 30, 0, 44, 45, 119, 37, 38, 31,
+	32, 33, 34, 35, 36, 42, 43, 0, 46, 0,
+	0, 132, 25, 24, 0, 39, 41, 40, 26, 27,
+	28, 29, 30, 0, 44, 45, 37, 38, 31, 32,
+	33, 34, 35, 36, 42, 43, 0, 46, 0, 92,
+	0, 25, 24, 0, 39, 41, 40, 26, 27, 28,
+	29, 30, 0, 44, 45, 37, 38, 31, 32, 33,
+	34, 35, 36, 42, 43, 0, 46, 0, 0, 0,
+	25, 24, 123, 39, 41, 40, 26, 27, 28, 29,
+	30, 0, 44, 45, 37, 38, 31, 32, 33, 34,
+	35, 36, 42, 43, 0, 46, 0, 0, 0, 25,
+	24, 98, 39, 41, 40, 26, 27, 28, 29, 30,
+	0, 44, 45, 37, 38, 31, 32, 33, 34, 35,
+	36, 42, 43, 0, 46, 0, 0, 0, 25, 24,
+	96, 39, 41, 40, 26, 27, 28, 29, 30, 0,
+	44, 45, 37, 38, 31, 32, 33, 34, 35, 36,
+	42, 43, 0, 46, 0, 0, 0, 25, 24, 0,
+	39, 41, 40, 26, 27, 28, 29, 30, 0, 44,
+	45, 37, 38, 31, 32, 33, 34, 35, 36, 42,
+	43, 0, 46, 0, 0, 0, 0, 24, 0, 39,
+	41, 40, 26, 27, 28, 29, 30, 0, 44, 45,
+	11, 12, 13, 14, 15, 21, 0, 0, 0, 0,
+	42, 43, 0, 46, 0, 0, 20, 0, 56, 22,
+	23, 0, 0, 26, 27, 28, 29, 30, 18, 44,
+	45, 0, 19, 0, 16, 0, 9, 46, 17, 53,
+	11, 12, 13, 14, 15, 21, 0, 26, 27, 28,
+	29, 30, 0, 44, 45, 0, 20, 0, 52, 22,
+	23, 0, 0, 0, 0, 0, 0, 0, 18, 0,
+	0, 0, 19, 0, 16, 0, 9, 115, 17, 37,
+	0, 31, 32, 33, 34, 35, 36, 42, 43, 0,
+	46, 0, 0, 0, 0, 0, 0, 39, 41, 40,
+	26, 27, 28, 29, 30, 0, 44, 45, 11, 12,
+	13, 14, 15, 21, 0, 11, 12, 13, 14, 15,
+	21, 0, 0, 0, 20, 0, 52, 22, 23, 0,
+	0, 20, 0, 52, 22, 23, 18, 0, 0, 0,
+	19, 0, 16, 18, 9, 86, 17, 19, 0, 16,
+	49, 9, 0, 17, 11, 12, 13, 14, 15, 21,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	20, 0, 0, 22, 23, 11, 12, 13, 14, 15,
+	21, 0, 18, 0, 0, 0, 19, 0, 16, 118,
+	9, 20, 17, 112, 22, 23, 11, 12, 13, 14,
+	15, 21, 0, 18, 0, 0, 0, 19, 0, 16,
+	0, 9, 20, 17, 107, 22, 23, 11, 12, 13,
+	14, 15, 21, 0, 18, 0, 0, 0, 19, 0,
+	16, 0, 9, 20, 17, 0, 22, 23, 0, 0,
+	0, 0, 0, 0, 0, 18, 0, 0, 0, 19,
+	0, 16, 104, 9, 0, 17, 31, 32, 33, 34,
+	35, 36, 42, 43, 0, 46, 0, 0, 0, 0,
+	0, 0, 39, 41, 40, 26, 27, 28, 29, 30,
+	0, 44, 45, 11, 12, 13, 14, 15, 21, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 20,
+	0, 0, 22, 23, 0, 0, 83, 0, 0, 0,
+	0, 18, 0, 0, 0, 19, 0, 16, 0, 9,
+	0, 17, 11, 12, 13, 14, 15, 21, 0, 11,
+	12, 13, 14, 15, 21, 0, 0, 0, 20, 0,
+	0, 22, 23, 0, 0, 20, 0, 0, 22, 23,
+	18, 65, 0, 0, 19, 0, 16, 18, 9, 0,
+	17, 19, 0, 16, 0, 9, 0, 17, 31, 32,
+	33, 34, 35, 36, 42, 43, 0, 46, 0, 0,
+	0, 0, 0, 0, 0, 41, 40, 26, 27, 28,
+	29, 30, 0, 44, 45, 31, 32, 33, 34, 35,
+	36, 42, 43, 0, 46, 0, 0, 0, 0, 0,
+	0, 0, 0, 40, 26, 27, 28, 29, 30, 0,
+	44, 45, 31, 32, 33, 34, 35, 36, 42, 43,
+	0, 46, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 26, 27, 28, 29, 30, 0, 44, 45,
 }
 
 var yyPact = [...]int16{
-	551, -1000, 233, -1000, -1000, -1000, -1000, -1000, 551, -29,
-	-1000, -1000, -1000, -1000, 517, 357, 551, 551, 551, 551,
-	551, 551, 512, 551, 551, 551, 551, 551, 551, 551,
-	551, 551, 551, 551, 551, 551, 551, 551, 0, 478,
-	551, 35, 444, -1000, -27, 233, -1000, -35, 208, 46,
-	46, 46, 183, 349, 349, 46, 551, 46, 46, 396,
-	396, 547, 547, 547, 547, 281, 258, 304, 427, 327,
-	563, 563, -1000, 80, 400, -19, -1000, -1000, -34, -1000,
-	551, -1000, 551, 551, 551, 46, -1000, 366, 132, -1000,
-	-1000, 233, 158, 233, 233, 106, -1000, -1000, 551, -1000,
-	233,
+	785, -1000, 392, -1000, -1000, -1000, -1000, -1000, -1000, 785,
+	-25, -1000, -1000, -1000, -1000, -1000, 571, 456, 785, 785,
+	785, -1000, -1000, -1000, 785, 71, 785, 785, 778, 785,
+	785, 785, 785, 785, 785, 785, 785, 785, 785, 785,
+	785, 785, 785, 785, 71, 739, 785, 125, 564, -1000,
+	-28, 276, 785, -1000, -35, 363, 785, 50, 50, 50,
+	334, -26, 35, 35, 50, 785, 50, 50, 44, 44,
+	452, 452, 452, 452, 704, 529, 816, 870, 843, 476,
+	476, -1000, 157, 673, 12, -1000, -1000, -37, 392, -1000,
+	652, -16, 71, 392, -1000, 631, 785, 392, 785, 496,
+	50, -1000, 610, 217, -1000, -1000, 392, 785, -1000, 3,
+	-32, 305, 785, 276, 421, -1000, -38, 187, -1000, -1000,
+	392, 785, 71, 785, 392, -30, -1000, -1000, 247, -1000,
+	392, -1000, 785, 392,
 }
 
 var yyPgo = [...]int8{
-	0, 70, 0, 69, 43, 42, 39, 15, 11, 10,
+	0, 85, 0, 83, 74, 64, 55, 52, 51, 5,
+	45, 26, 2, 22,
 }
 
 var yyR1 = [...]int8{
 	0, 1, 2, 2, 2, 2, 2, 2, 2, 2,
-	2, 3, 3, 3, 3, 3, 3, 3, 3, 4,
-	4, 4, 4, 4, 4, 4, 5, 5, 5, 5,
-	5, 5, 5, 5, 5, 6, 6, 6, 6, 6,
-	6, 7, 7, 7, 7, 7, 7, 7, 7, 8,
-	8, 9, 9,
+	2, 2, 2, 2, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 4, 4, 4, 4, 4, 4, 4,
+	5, 5, 5, 5, 5, 5, 5, 5, 5, 6,
+	6, 6, 6, 6, 6, 7, 7, 7, 7, 7,
+	7, 7, 7, 8, 8, 12, 12, 11, 11, 13,
+	13, 13, 9, 9, 9, 9, 10, 10, 10, 10,
 }
 
 var yyR2 = [...]int8{
-	0, 1, 1, 1, 1, 1, 1, 5, 3, 3,
-	4, 1, 1, 1, 1, 2, 3, 2, 3, 2,
-	3, 3, 3, 3, 4, 3, 2, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	2, 1, 3, 4, 3, 6, 5, 5, 4, 1,
-	3, 3, 5,
+	0, 1, 1, 1, 1, 1, 1, 1, 5, 3,
+	3, 4, 5, 6, 1, 1, 1, 1, 1, 2,
+	3, 2, 3, 2, 3, 3, 3, 3, 4, 3,
+	2, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 2, 1, 3, 4, 3, 6,
+	5, 5, 4, 4, 6, 4, 6, 1, 3, 1,
+	1, 1, 1, 2, 3, 4, 3, 2, 5, 4,
 }
 
 var yyChk = [...]int16{
-	-1000, -1, -2, -3, -4, -5, -6, -7, 35, 8,
-	4, 5, 6, 7, 33, 37, 27, 31, 19, 21,
-	26, 27, 28, 29, 30, 11, 12, 13, 14, 15,
-	16, 9, 10, 23, 25, 24, 17, 18, 32, 33,
-	20, -2, 35, 34, -8, -2, 38, -9, -2, -2,
-	-2, -2, -2, -2, -2, -2, 28, -2, -2, -2,
+	-1000, -1, -2, -3, -4, -5, -6, -7, -8, 40,
+	-13, 4, 5, 6, 7, 8, 38, 42, 32, 36,
+	20, 9, 23, 24, 26, 25, 31, 32, 33, 34,
+	35, 12, 13, 14, 15, 16, 17, 10, 11, 28,
+	30, 29, 18, 19, 37, 38, 21, -2, 40, 39,
+	-9, -2, 22, 43, -10, -2, 22, -2, -2, -2,
+	-2, -13, -2, -2, -2, 33, -2, -2, -2, -2,
 	-2, -2, -2, -2, -2, -2, -2, -2, -2, -2,
-	-2, -2, 8, -2, 22, -2, 36, 36, -8, 34,
-	39, 38, 39, 22, 22, -2, 34, 22, -2, 34,
-	36, -2, -2, -2, -2, -2, 34, 34, 22, 34,
-	-2,
+	-2, -13, -2, 27, -2, 41, 41, -9, -2, 39,
+	44, -12, 23, -2, 43, 44, 27, -2, 27, 40,
+	-2, 39, 27, -2, 39, 41, -2, 22, 39, -11,
+	-13, -2, 22, -2, -2, 41, -9, -2, 39, 39,
+	-2, 21, 44, 27, -2, -12, 41, 39, -2, -13,
+	-2, 43, 24, -2,
 }
 
 var yyDef = [...]int8{
-	0, -2, 1, 2, 3, 4, 5, 6, 0, 41,
-	11, 12, 13, 14, 0, 0, 0, 0, 0, 0,
+	0, -2, 1, 2, 3, 4, 5, 6, 7, 0,
+	45, 14, 15, 16, 17, 18, 0, 0, 0, 0,
+	0, 59, 60, 61, 0, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 15, 0, 49, 17, 0, 0, 19,
-	26, 40, 0, 20, 21, 22, 0, 23, 25, 27,
-	28, 29, 30, 31, 32, 33, 34, 35, 36, 37,
-	38, 39, 42, 0, 0, 44, 8, 9, 0, 16,
-	0, 18, 0, 0, 0, 24, 43, 0, 0, 48,
-	10, 50, 0, 51, 7, 0, 47, 46, 0, 45,
-	52,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 19,
+	0, 62, 0, 21, 0, 0, 0, 23, 30, 44,
+	0, 0, 24, 25, 26, 0, 27, 29, 31, 32,
+	33, 34, 35, 36, 37, 38, 39, 40, 41, 42,
+	43, 46, 0, 0, 48, 9, 10, 0, 62, 20,
+	0, 0, 0, 63, 22, 0, 0, 67, 0, 0,
+	28, 47, 0, 0, 52, 11, 64, 0, 53, 0,
+	57, 0, 0, 66, 8, 12, 0, 0, 51, 50,
+	65, 0, 0, 0, 69, 0, 13, 49, 55, 58,
+	68, 54, 0, 56,
 }
 
 var yyTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 31, 3, 3, 3, 30, 25, 3,
-	35, 36, 28, 26, 39, 27, 32, 29, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 22, 3,
-	3, 3, 3, 21, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 36, 3, 3, 3, 35, 30, 3,
+	40, 41, 33, 31, 44, 32, 37, 34, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 27, 3,
+	3, 3, 3, 26, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 33, 3, 34, 24, 3, 3, 3, 3, 3,
+	3, 38, 3, 39, 29, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 37, 23, 38,
+	3, 3, 3, 42, 28, 43,
 }
 
 var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
-	12, 13, 14, 15, 16, 17, 18, 19, 20,
+	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
+	22, 23, 24, 25,
 }
 
 var yyTok3 = [...]int8{
@@ -586,307 +641,364 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.go.y:65
-		{
-			yyVAL.expr = yyDollar[1].expr
-			yylex.(*Lexer).result = yyVAL.expr
-		}
-	case 7:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.go.y:77
+//line parser.go.y:79
 		{
-			if asBool(yyDollar[1].expr) {
-				yyVAL.expr = yyDollar[3].expr
-			} else {
-				yyVAL.expr = yyDollar[5].expr
-			}
+			yyVAL.node = yyDollar[1].node
+			yylex.(*Lexer).result = yyVAL.node
 		}
 	case 8:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:78
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line parser.go.y:92
 		{
-			yyVAL.expr = yyDollar[2].expr
+			yyVAL.node = &TernaryExpr{Cond: yyDollar[1].node, Question: yyDollar[2].token.pos, Then: yyDollar[3].node, Colon: yyDollar[4].token.pos, Else: yyDollar[5].node}
 		}
 	case 9:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:79
+//line parser.go.y:93
 		{
-			yyVAL.expr = callFunction(yylex.(*Lexer).functions, yyDollar[1].token.literal, []interface{}{})
+			yyVAL.node = &ParenExpr{Lparen: yyDollar[1].token.pos, X: yyDollar[2].node, Rparen: yyDollar[3].token.pos}
 		}
 	case 10:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.go.y:80
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:94
 		{
-			yyVAL.expr = callFunction(yylex.(*Lexer).functions, yyDollar[1].token.literal, yyDollar[3].exprList)
+			yyVAL.node = &CallExpr{Func: newIdent(yyDollar[1].token), Lparen: yyDollar[2].token.pos, Args: []Node{}, Rparen: yyDollar[3].token.pos}
 		}
 	case 11:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.go.y:84
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.go.y:95
 		{
-			yyVAL.expr = nil
+			yyVAL.node = &CallExpr{Func: newIdent(yyDollar[1].token), Lparen: yyDollar[2].token.pos, Args: yyDollar[3].nodeList, Rparen: yyDollar[4].token.pos}
 		}
 	case 12:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.go.y:85
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line parser.go.y:96
 		{
-			yyVAL.expr = yyDollar[1].token.value
+			yyVAL.node = &PipeExpr{X: yyDollar[1].node, OpPos: yyDollar[2].token.pos, Call: &CallExpr{Func: newIdent(yyDollar[3].token), Lparen: yyDollar[4].token.pos, Args: []Node{}, Rparen: yyDollar[5].token.pos}}
 		}
 	case 13:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.go.y:86
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line parser.go.y:97
 		{
-			yyVAL.expr = yyDollar[1].token.value
+			yyVAL.node = &PipeExpr{X: yyDollar[1].node, OpPos: yyDollar[2].token.pos, Call: &CallExpr{Func: newIdent(yyDollar[3].token), Lparen: yyDollar[4].token.pos, Args: yyDollar[5].nodeList, Rparen: yyDollar[6].token.pos}}
 		}
 	case 14:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.go.y:87
+//line parser.go.y:101
 		{
-			yyVAL.expr = yyDollar[1].token.value
+			yyVAL.node = newLiteral(yyDollar[1].token)
 		}
 	case 15:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.go.y:88
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.go.y:102
 		{
-			yyVAL.expr = []interface{}{}
+			yyVAL.node = newLiteral(yyDollar[1].token)
 		}
 	case 16:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:89
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.go.y:103
 		{
-			yyVAL.expr = yyDollar[2].exprList
+			yyVAL.node = newLiteral(yyDollar[1].token)
 		}
 	case 17:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.go.y:90
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.go.y:104
 		{
-			yyVAL.expr = map[string]interface{}{}
+			yyVAL.node = newLiteral(yyDollar[1].token)
 		}
 	case 18:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:91
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.go.y:105
 		{
-			yyVAL.expr = yyDollar[2].exprMap
+			yyVAL.node = yyDollar[1].token.value.(*InterpolatedString)
 		}
 	case 19:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.go.y:95
+//line parser.go.y:106
 		{
-			yyVAL.expr = unaryMinus(yyDollar[2].expr)
+			yyVAL.node = &ArrayLit{Lbrack: yyDollar[1].token.pos, Elems: []Node{}, Rbrack: yyDollar[2].token.pos}
 		}
 	case 20:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:96
+//line parser.go.y:107
 		{
-			yyVAL.expr = add(yyDollar[1].expr, yyDollar[3].expr)
+			yyVAL.node = &ArrayLit{Lbrack: yyDollar[1].token.pos, Elems: yyDollar[2].nodeList, Rbrack: yyDollar[3].token.pos}
 		}
 	case 21:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:97
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.go.y:108
 		{
-			yyVAL.expr = sub(yyDollar[1].expr, yyDollar[3].expr)
+			yyVAL.node = &ObjectLit{Lbrace: yyDollar[1].token.pos, Members: []Node{}, Rbrace: yyDollar[2].token.pos}
 		}
 	case 22:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:98
+//line parser.go.y:109
 		{
-			yyVAL.expr = mul(yyDollar[1].expr, yyDollar[3].expr)
+			yyVAL.node = &ObjectLit{Lbrace: yyDollar[1].token.pos, Members: yyDollar[2].nodeList, Rbrace: yyDollar[3].token.pos}
 		}
 	case 23:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:99
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.go.y:113
 		{
-			yyVAL.expr = div(yyDollar[1].expr, yyDollar[3].expr)
+			yyVAL.node = newUnary(yyDollar[1].token, yyDollar[2].node)
 		}
 	case 24:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.go.y:100
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:114
 		{
-			yyVAL.expr = pow(yyDollar[1].expr, yyDollar[4].expr)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 25:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:101
+//line parser.go.y:115
 		{
-			yyVAL.expr = mod(yyDollar[1].expr, yyDollar[3].expr)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 26:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.go.y:105
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:116
 		{
-			yyVAL.expr = !asBool(yyDollar[2].expr)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 27:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:106
+//line parser.go.y:117
 		{
-			yyVAL.expr = deepEqual(yyDollar[1].expr, yyDollar[3].expr)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 28:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:107
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.go.y:118
 		{
-			yyVAL.expr = !deepEqual(yyDollar[1].expr, yyDollar[3].expr)
+			yyVAL.node = &BinaryExpr{X: yyDollar[1].node, OpPos: yyDollar[2].token.pos, Op: "**", Y: yyDollar[4].node}
 		}
 	case 29:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:108
+//line parser.go.y:119
 		{
-			yyVAL.expr = compare(yyDollar[1].expr, yyDollar[3].expr, "<")
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 30:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:109
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.go.y:123
 		{
-			yyVAL.expr = compare(yyDollar[1].expr, yyDollar[3].expr, ">")
+			yyVAL.node = newUnary(yyDollar[1].token, yyDollar[2].node)
 		}
 	case 31:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:110
+//line parser.go.y:124
 		{
-			yyVAL.expr = compare(yyDollar[1].expr, yyDollar[3].expr, "<=")
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 32:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:111
+//line parser.go.y:125
 		{
-			yyVAL.expr = compare(yyDollar[1].expr, yyDollar[3].expr, ">=")
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 33:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:112
+//line parser.go.y:126
 		{
-			left := asBool(yyDollar[1].expr)
-			right := asBool(yyDollar[3].expr)
-			yyVAL.expr = left && right
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 34:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:113
+//line parser.go.y:127
 		{
-			left := asBool(yyDollar[1].expr)
-			right := asBool(yyDollar[3].expr)
-			yyVAL.expr = left || right
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 35:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:117
+//line parser.go.y:128
 		{
-			yyVAL.expr = asInteger(yyDollar[1].expr) | asInteger(yyDollar[3].expr)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 36:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:118
+//line parser.go.y:129
 		{
-			yyVAL.expr = asInteger(yyDollar[1].expr) & asInteger(yyDollar[3].expr)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 37:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:119
+//line parser.go.y:130
 		{
-			yyVAL.expr = asInteger(yyDollar[1].expr) ^ asInteger(yyDollar[3].expr)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 38:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:120
-		{
-			l := asInteger(yyDollar[1].expr)
-			r := asInteger(yyDollar[3].expr)
-			if r >= 0 {
-				yyVAL.expr = l << uint(r)
-			} else {
-				yyVAL.expr = l >> uint(-r)
-			}
+//line parser.go.y:131
+		{
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 39:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:121
-		{
-			l := asInteger(yyDollar[1].expr)
-			r := asInteger(yyDollar[3].expr)
-			if r >= 0 {
-				yyVAL.expr = l >> uint(r)
-			} else {
-				yyVAL.expr = l << uint(-r)
-			}
+//line parser.go.y:135
+		{
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 40:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line parser.go.y:122
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:136
 		{
-			yyVAL.expr = ^asInteger(yyDollar[2].expr)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 41:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.go.y:126
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:137
 		{
-			yyVAL.expr = accessVar(yylex.(*Lexer).variables, yyDollar[1].token.literal)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 42:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:127
+//line parser.go.y:138
 		{
-			yyVAL.expr = accessField(yyDollar[1].expr, yyDollar[3].token.literal)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 43:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.go.y:128
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:139
 		{
-			yyVAL.expr = accessField(yyDollar[1].expr, yyDollar[3].expr)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 44:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:129
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.go.y:140
 		{
-			yyVAL.expr = arrayContains(yyDollar[3].expr, yyDollar[1].expr)
+			yyVAL.node = newUnary(yyDollar[1].token, yyDollar[2].node)
 		}
 	case 45:
-		yyDollar = yyS[yypt-6 : yypt+1]
-//line parser.go.y:130
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.go.y:144
 		{
-			yyVAL.expr = slice(yyDollar[1].expr, yyDollar[3].expr, yyDollar[5].expr)
+			yyVAL.node = newIdent(yyDollar[1].token)
 		}
 	case 46:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.go.y:131
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:145
 		{
-			yyVAL.expr = slice(yyDollar[1].expr, nil, yyDollar[4].expr)
+			yyVAL.node = &SelectorExpr{X: yyDollar[1].node, Sel: newIdent(yyDollar[3].token)}
 		}
 	case 47:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.go.y:132
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.go.y:146
 		{
-			yyVAL.expr = slice(yyDollar[1].expr, yyDollar[3].expr, nil)
+			yyVAL.node = &IndexExpr{X: yyDollar[1].node, Lbrack: yyDollar[2].token.pos, Index: yyDollar[3].node, Rbrack: yyDollar[4].token.pos}
 		}
 	case 48:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line parser.go.y:133
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:147
 		{
-			yyVAL.expr = slice(yyDollar[1].expr, nil, nil)
+			yyVAL.node = newBinary(yyDollar[1].node, yyDollar[2].token, yyDollar[3].node)
 		}
 	case 49:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line parser.go.y:137
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line parser.go.y:148
 		{
-			yyVAL.exprList = []interface{}{yyDollar[1].expr}
+			yyVAL.node = &SliceExpr{X: yyDollar[1].node, Lbrack: yyDollar[2].token.pos, Low: yyDollar[3].node, High: yyDollar[5].node, Rbrack: yyDollar[6].token.pos}
 		}
 	case 50:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:138
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line parser.go.y:149
 		{
-			yyVAL.exprList = append(yyDollar[1].exprList, yyDollar[3].expr)
+			yyVAL.node = &SliceExpr{X: yyDollar[1].node, Lbrack: yyDollar[2].token.pos, High: yyDollar[4].node, Rbrack: yyDollar[5].token.pos}
 		}
 	case 51:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line parser.go.y:142
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line parser.go.y:150
 		{
-			yyVAL.exprMap = make(map[string]interface{})
-			yyVAL.exprMap[asObjectKey(yyDollar[1].expr)] = yyDollar[3].expr
+			yyVAL.node = &SliceExpr{X: yyDollar[1].node, Lbrack: yyDollar[2].token.pos, Low: yyDollar[3].node, Rbrack: yyDollar[5].token.pos}
 		}
 	case 52:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.go.y:151
+		{
+			yyVAL.node = &SliceExpr{X: yyDollar[1].node, Lbrack: yyDollar[2].token.pos, Rbrack: yyDollar[4].token.pos}
+		}
+	case 53:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.go.y:155
+		{
+			yyVAL.node = &ArrayComp{Lbrack: yyDollar[1].token.pos, Elem: yyDollar[2].node, Clause: yyDollar[3].clause, Rbrack: yyDollar[4].token.pos}
+		}
+	case 54:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line parser.go.y:156
+		{
+			yyVAL.node = &ObjectComp{Lbrace: yyDollar[1].token.pos, Key: yyDollar[2].node, Value: yyDollar[4].node, Clause: yyDollar[5].clause, Rbrace: yyDollar[6].token.pos}
+		}
+	case 55:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.go.y:160
+		{
+			yyVAL.clause = &ForClause{For: yyDollar[1].token.pos, Vars: yyDollar[2].identList, In: yyDollar[3].token.pos, X: yyDollar[4].node}
+		}
+	case 56:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line parser.go.y:161
+		{
+			yyVAL.clause = &ForClause{For: yyDollar[1].token.pos, Vars: yyDollar[2].identList, In: yyDollar[3].token.pos, X: yyDollar[4].node, If: yyDollar[5].token.pos, Cond: yyDollar[6].node}
+		}
+	case 57:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.go.y:165
+		{
+			yyVAL.identList = []*Ident{newIdent(yyDollar[1].token)}
+		}
+	case 58:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:166
+		{
+			yyVAL.identList = []*Ident{newIdent(yyDollar[1].token), newIdent(yyDollar[3].token)}
+		}
+	case 62:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line parser.go.y:177
+		{
+			yyVAL.nodeList = []Node{yyDollar[1].node}
+		}
+	case 63:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.go.y:178
+		{
+			yyVAL.nodeList = []Node{&Spread{Ellipsis: yyDollar[1].token.pos, X: yyDollar[2].node}}
+		}
+	case 64:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:179
+		{
+			yyVAL.nodeList = append(yyDollar[1].nodeList, yyDollar[3].node)
+		}
+	case 65:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.go.y:180
+		{
+			yyVAL.nodeList = append(yyDollar[1].nodeList, &Spread{Ellipsis: yyDollar[3].token.pos, X: yyDollar[4].node})
+		}
+	case 66:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line parser.go.y:184
+		{
+			yyVAL.nodeList = []Node{&KeyValue{Key: yyDollar[1].node, Colon: yyDollar[2].token.pos, Value: yyDollar[3].node}}
+		}
+	case 67:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line parser.go.y:185
+		{
+			yyVAL.nodeList = []Node{&Spread{Ellipsis: yyDollar[1].token.pos, X: yyDollar[2].node}}
+		}
+	case 68:
 		yyDollar = yyS[yypt-5 : yypt+1]
-//line parser.go.y:143
+//line parser.go.y:186
+		{
+			yyVAL.nodeList = append(yyDollar[1].nodeList, &KeyValue{Key: yyDollar[3].node, Colon: yyDollar[4].token.pos, Value: yyDollar[5].node})
+		}
+	case 69:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line parser.go.y:187
 		{
-			yyVAL.exprMap = addObjectMember(yyDollar[1].exprMap, yyDollar[3].expr, yyDollar[5].expr)
+			yyVAL.nodeList = append(yyDollar[1].nodeList, &Spread{Ellipsis: yyDollar[3].token.pos, X: yyDollar[4].node})
 		}
 	}
 	goto yystack /* stack new state and value */
@@ -5,28 +5,34 @@ package internal
 
 %union {
   token     Token
-  expr      interface{}
-  exprList  []interface{}
-  exprMap   map[string]interface{}
+  node      Node
+  nodeList  []Node
+  identList []*Ident
+  clause    *ForClause
 }
 
 
 %start program
 
-%type<expr> program
-%type<expr> expr
-%type<expr> literal
-%type<expr> math
-%type<expr> logic
-%type<expr> bitManipulation
-%type<expr> varAccess
-%type<exprList> exprList
-%type<exprMap> exprMap
+%type<node> program
+%type<node> expr
+%type<node> literal
+%type<node> math
+%type<node> logic
+%type<node> bitManipulation
+%type<node> varAccess
+%type<node> comprehension
+%type<nodeList> exprList
+%type<nodeList> exprMap
+%type<identList> loopVars
+%type<clause> forClause
+%type<token> ident
 
 %token<token> LITERAL_NIL    // nil
 %token<token> LITERAL_BOOL   // true false
 %token<token> LITERAL_NUMBER // 42 4.2 4e2 4.2e2
 %token<token> LITERAL_STRING // "text" 'text'
+%token<token> LITERAL_INTERPOLATION // f"text {expr}" f`text {expr}`
 %token<token> IDENT
 %token<token> AND            // &&
 %token<token> OR             // ||
@@ -40,9 +46,17 @@ package internal
 %token<token> SHR            // >>
 %token<token> BIT_NOT        // ~
 %token<token> IN             // in
+%token<token> ELLIPSIS       // ...
+%token<token> FOR            // for
+%token<token> IF             // if
+%token<token> PIPE           // |>
+
+%token<token> '?' ':' '|' '^' '&' '+' '-' '*' '/' '%' '!' '.' '[' ']' '(' ')' '{' '}' ','
 
 /* Operator precedence is taken from C/C++: http://en.cppreference.com/w/c/language/operator_precedence */
+/* The pipe operator is not part of C/C++ and has the lowest precedence. */
 
+%left  PIPE
 %right '?' ':'
 %left  OR
 %left  AND
@@ -74,73 +88,103 @@ expr
   | logic
   | bitManipulation
   | varAccess
-  | expr '?' expr ':' expr { if asBool($1) { $$ = $3 } else { $$ = $5 } }
-  | '(' expr ')'           { $$ = $2 }
-  | IDENT '(' ')'          { $$ = callFunction(yylex.(*Lexer).functions, $1.literal, []interface{}{}) }
-  | IDENT '(' exprList ')' { $$ = callFunction(yylex.(*Lexer).functions, $1.literal, $3) }
+  | comprehension
+  | expr '?' expr ':' expr { $$ = &TernaryExpr{Cond: $1, Question: $2.pos, Then: $3, Colon: $4.pos, Else: $5} }
+  | '(' expr ')'           { $$ = &ParenExpr{Lparen: $1.pos, X: $2, Rparen: $3.pos} }
+  | ident '(' ')'          { $$ = &CallExpr{Func: newIdent($1), Lparen: $2.pos, Args: []Node{}, Rparen: $3.pos} }
+  | ident '(' exprList ')' { $$ = &CallExpr{Func: newIdent($1), Lparen: $2.pos, Args: $3, Rparen: $4.pos} }
+  | expr PIPE ident '(' ')'          { $$ = &PipeExpr{X: $1, OpPos: $2.pos, Call: &CallExpr{Func: newIdent($3), Lparen: $4.pos, Args: []Node{}, Rparen: $5.pos}} }
+  | expr PIPE ident '(' exprList ')' { $$ = &PipeExpr{X: $1, OpPos: $2.pos, Call: &CallExpr{Func: newIdent($3), Lparen: $4.pos, Args: $5, Rparen: $6.pos}} }
   ;
 
 literal
-  : LITERAL_NIL           { $$ = nil }
-  | LITERAL_BOOL          { $$ = $1.value }
-  | LITERAL_NUMBER        { $$ = $1.value }
-  | LITERAL_STRING        { $$ = $1.value }
-  | '[' ']'               { $$ = []interface{}{} }
-  | '[' exprList ']'      { $$ = $2 }
-  | '{' '}'               { $$ = map[string]interface{}{} }
-  | '{' exprMap '}'       { $$ = $2 }
+  : LITERAL_NIL           { $$ = newLiteral($1) }
+  | LITERAL_BOOL          { $$ = newLiteral($1) }
+  | LITERAL_NUMBER        { $$ = newLiteral($1) }
+  | LITERAL_STRING        { $$ = newLiteral($1) }
+  | LITERAL_INTERPOLATION { $$ = $1.value.(*InterpolatedString) }
+  | '[' ']'               { $$ = &ArrayLit{Lbrack: $1.pos, Elems: []Node{}, Rbrack: $2.pos} }
+  | '[' exprList ']'      { $$ = &ArrayLit{Lbrack: $1.pos, Elems: $2, Rbrack: $3.pos} }
+  | '{' '}'               { $$ = &ObjectLit{Lbrace: $1.pos, Members: []Node{}, Rbrace: $2.pos} }
+  | '{' exprMap '}'       { $$ = &ObjectLit{Lbrace: $1.pos, Members: $2, Rbrace: $3.pos} }
   ;
 
 math
-  : '-' expr %prec  '!'   { $$ = unaryMinus($2)  }  /* unary minus has higher precedence */
-  | expr '+' expr         { $$ = add($1, $3) }
-  | expr '-' expr         { $$ = sub($1, $3) }
-  | expr '*' expr         { $$ = mul($1, $3) }
-  | expr '/' expr         { $$ = div($1, $3) }
-  | expr '*' '*' expr     { $$ = pow($1, $4) }
-  | expr '%' expr         { $$ = mod($1, $3) }
+  : '-' expr %prec  '!'   { $$ = newUnary($1, $2) }  /* unary minus has higher precedence */
+  | expr '+' expr         { $$ = newBinary($1, $2, $3) }
+  | expr '-' expr         { $$ = newBinary($1, $2, $3) }
+  | expr '*' expr         { $$ = newBinary($1, $2, $3) }
+  | expr '/' expr         { $$ = newBinary($1, $2, $3) }
+  | expr '*' '*' expr     { $$ = &BinaryExpr{X: $1, OpPos: $2.pos, Op: "**", Y: $4} }
+  | expr '%' expr         { $$ = newBinary($1, $2, $3) }
   ;
 
 logic
-  : '!' expr              { $$ = !asBool($2) }
-  | expr EQL expr         { $$ = deepEqual($1, $3) }
-  | expr NEQ expr         { $$ = !deepEqual($1, $3) }
-  | expr LSS expr         { $$ = compare($1, $3, "<") }
-  | expr GTR expr         { $$ = compare($1, $3, ">") }
-  | expr LEQ expr         { $$ = compare($1, $3, "<=") }
-  | expr GEQ expr         { $$ = compare($1, $3, ">=") }
-  | expr AND expr         { left := asBool($1); right := asBool($3); $$ = left && right }
-  | expr OR expr          { left := asBool($1); right := asBool($3); $$ = left || right }
+  : '!' expr              { $$ = newUnary($1, $2) }
+  | expr EQL expr         { $$ = newBinary($1, $2, $3) }
+  | expr NEQ expr         { $$ = newBinary($1, $2, $3) }
+  | expr LSS expr         { $$ = newBinary($1, $2, $3) }
+  | expr GTR expr         { $$ = newBinary($1, $2, $3) }
+  | expr LEQ expr         { $$ = newBinary($1, $2, $3) }
+  | expr GEQ expr         { $$ = newBinary($1, $2, $3) }
+  | expr AND expr         { $$ = newBinary($1, $2, $3) }
+  | expr OR expr          { $$ = newBinary($1, $2, $3) }
   ;
 
 bitManipulation
-  : expr '|' expr         { $$ = asInteger($1) | asInteger($3) }
-  | expr '&' expr         { $$ = asInteger($1) & asInteger($3) }
-  | expr '^' expr         { $$ = asInteger($1) ^ asInteger($3) }
-  | expr SHL expr         { l := asInteger($1); r := asInteger($3); if r >= 0 { $$ = l << uint(r) } else {$$ = l >> uint(-r)} }
-  | expr SHR expr         { l := asInteger($1); r := asInteger($3); if r >= 0 { $$ = l >> uint(r) } else {$$ = l << uint(-r)} }
-  | BIT_NOT expr          { $$ = ^asInteger($2) }
+  : expr '|' expr         { $$ = newBinary($1, $2, $3) }
+  | expr '&' expr         { $$ = newBinary($1, $2, $3) }
+  | expr '^' expr         { $$ = newBinary($1, $2, $3) }
+  | expr SHL expr         { $$ = newBinary($1, $2, $3) }
+  | expr SHR expr         { $$ = newBinary($1, $2, $3) }
+  | BIT_NOT expr          { $$ = newUnary($1, $2) }
   ;
 
 varAccess
-  : IDENT                        { $$ = accessVar(yylex.(*Lexer).variables, $1.literal) }
-  | expr '.' IDENT               { $$ = accessField($1, $3.literal) }
-  | expr '[' expr ']'            { $$ = accessField($1, $3) }
-  | expr IN expr                 { $$ = arrayContains($3, $1) }
-  | expr '[' expr ':' expr ']'   { $$ = slice($1, $3, $5) }
-  | expr '['      ':' expr ']'   { $$ = slice($1, nil, $4) }
-  | expr '[' expr ':'      ']'   { $$ = slice($1, $3, nil) }
-  | expr '['      ':'      ']'   { $$ = slice($1, nil, nil) }
+  : ident                        { $$ = newIdent($1) }
+  | expr '.' ident               { $$ = &SelectorExpr{X: $1, Sel: newIdent($3)} }
+  | expr '[' expr ']'            { $$ = &IndexExpr{X: $1, Lbrack: $2.pos, Index: $3, Rbrack: $4.pos} }
+  | expr IN expr                 { $$ = newBinary($1, $2, $3) }
+  | expr '[' expr ':' expr ']'   { $$ = &SliceExpr{X: $1, Lbrack: $2.pos, Low: $3, High: $5, Rbrack: $6.pos} }
+  | expr '['      ':' expr ']'   { $$ = &SliceExpr{X: $1, Lbrack: $2.pos, High: $4, Rbrack: $5.pos} }
+  | expr '[' expr ':'      ']'   { $$ = &SliceExpr{X: $1, Lbrack: $2.pos, Low: $3, Rbrack: $5.pos} }
+  | expr '['      ':'      ']'   { $$ = &SliceExpr{X: $1, Lbrack: $2.pos, Rbrack: $4.pos} }
+  ;
+
+comprehension
+  : '[' expr forClause ']'               { $$ = &ArrayComp{Lbrack: $1.pos, Elem: $2, Clause: $3, Rbrack: $4.pos} }
+  | '{' expr ':' expr forClause '}'      { $$ = &ObjectComp{Lbrace: $1.pos, Key: $2, Value: $4, Clause: $5, Rbrace: $6.pos} }
+  ;
+
+forClause
+  : FOR loopVars IN expr                 { $$ = &ForClause{For: $1.pos, Vars: $2, In: $3.pos, X: $4} }
+  | FOR loopVars IN expr IF expr         { $$ = &ForClause{For: $1.pos, Vars: $2, In: $3.pos, X: $4, If: $5.pos, Cond: $6} }
+  ;
+
+loopVars
+  : ident                 { $$ = []*Ident{newIdent($1)} }
+  | ident ',' ident       { $$ = []*Ident{newIdent($1), newIdent($3)} }
+  ;
+
+/* The keywords of comprehensions can only appear after an expression, and are identifiers everywhere else. */
+ident
+  : IDENT
+  | FOR
+  | IF
   ;
 
 exprList
-  : expr                  { $$ = []interface{}{$1} }
-  | exprList ',' expr     { $$ = append($1, $3) }
+  : expr                        { $$ = []Node{$1} }
+  | ELLIPSIS expr               { $$ = []Node{&Spread{Ellipsis: $1.pos, X: $2}} }
+  | exprList ',' expr           { $$ = append($1, $3) }
+  | exprList ',' ELLIPSIS expr  { $$ = append($1, &Spread{Ellipsis: $3.pos, X: $4}) }
   ;
 
 exprMap
-  : expr ':' expr               { $$ = make(map[string]interface{}); $$[asObjectKey($1)] = $3 }
-  | exprMap ',' expr ':' expr   { $$ = addObjectMember($1, $3, $5) }
+  : expr ':' expr               { $$ = []Node{&KeyValue{Key: $1, Colon: $2.pos, Value: $3}} }
+  | ELLIPSIS expr               { $$ = []Node{&Spread{Ellipsis: $1.pos, X: $2}} }
+  | exprMap ',' expr ':' expr   { $$ = append($1, &KeyValue{Key: $3, Colon: $4.pos, Value: $5}) }
+  | exprMap ',' ELLIPSIS expr   { $$ = append($1, &Spread{Ellipsis: $3.pos, X: $4}) }
   ;
 
 %%
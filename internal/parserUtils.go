@@ -15,13 +15,33 @@ func init() {
 }
 
 // ExpressionFunction can be called from within expressions.
-// The returned object needs to have one of the following types: `nil`, `bool`, `int`, `float64`, `string`, `[]interface{}` or `map[string]interface{}`.
+// The returned object needs to have one of the following types: `nil`, `bool`, `int`, `float64`, `string`, `[]interface{}`, `map[string]interface{}` or Value.
 type ExpressionFunction = func(args ...interface{}) (interface{}, error)
 
-func typeOf(val interface{}) string {
+func newLiteral(tok Token) *Literal {
+	return &Literal{ValuePos: tok.pos, Raw: tok.literal, Value: tok.value}
+}
+
+func newIdent(tok Token) *Ident {
+	return &Ident{NamePos: tok.pos, Name: tok.literal}
+}
+
+func newUnary(op Token, x Node) *UnaryExpr {
+	return &UnaryExpr{OpPos: op.pos, Op: op.literal, X: x}
+}
+
+func newBinary(x Node, op Token, y Node) *BinaryExpr {
+	return &BinaryExpr{X: x, OpPos: op.pos, Op: op.literal, Y: y}
+}
+
+// TypeOf returns the name of the value's type, as it appears within error messages.
+func TypeOf(val interface{}) string {
 	if val == nil {
 		return "nil"
 	}
+	if v, ok := val.(Value); ok {
+		return v.TypeName()
+	}
 
 	kind := reflect.TypeOf(val).Kind()
 
@@ -46,9 +66,12 @@ func typeOf(val interface{}) string {
 }
 
 func asBool(val interface{}) bool {
+	if v, ok := val.(Truther); ok {
+		return v.Truthy()
+	}
 	b, ok := val.(bool)
 	if !ok {
-		panic(fmt.Errorf("type error: required bool, but was %s", typeOf(val)))
+		panic(fmt.Errorf("type error: required bool, but was %s", TypeOf(val)))
 	}
 	return b
 }
@@ -60,7 +83,7 @@ func asInteger(val interface{}) int {
 	}
 	f, ok := val.(float64)
 	if !ok {
-		panic(fmt.Errorf("type error: required number of type integer, but was %s", typeOf(val)))
+		panic(fmt.Errorf("type error: required number of type integer, but was %s", TypeOf(val)))
 	}
 
 	i = int(f)
@@ -71,6 +94,10 @@ func asInteger(val interface{}) int {
 }
 
 func add(val1 interface{}, val2 interface{}) interface{} {
+	if res, ok := addValues(val1, val2); ok {
+		return res
+	}
+
 	str1, str1OK := val1.(string)
 	str2, str2OK := val2.(string)
 
@@ -127,8 +154,11 @@ func add(val1 interface{}, val2 interface{}) interface{} {
 	arr1, arr1OK := val1.([]interface{})
 	arr2, arr2OK := val2.([]interface{})
 
-	if arr1OK && arr2OK {
-		return append(arr1, arr2...)
+	if arr1OK && arr2OK { // never append in-place, arr1 can have spare capacity that is shared with other values
+		sum := make([]interface{}, len(arr1)+len(arr2))
+		copy(sum, arr1)
+		copy(sum[len(arr1):], arr2)
+		return sum
 	}
 
 	obj1, obj1OK := val1.(map[string]interface{})
@@ -145,10 +175,14 @@ func add(val1 interface{}, val2 interface{}) interface{} {
 		return sum
 	}
 
-	panic(fmt.Errorf("type error: cannot add or concatenate type %s and %s", typeOf(val1), typeOf(val2)))
+	panic(fmt.Errorf("type error: cannot add or concatenate type %s and %s", TypeOf(val1), TypeOf(val2)))
 }
 
 func sub(val1 interface{}, val2 interface{}) interface{} {
+	if res, ok := subValues(val1, val2); ok {
+		return res
+	}
+
 	int1, int1OK := val1.(int)
 	int2, int2OK := val2.(int)
 
@@ -171,7 +205,7 @@ func sub(val1 interface{}, val2 interface{}) interface{} {
 	if float1OK && float2OK {
 		return float1 - float2
 	}
-	panic(fmt.Errorf("type error: cannot subtract type %s and %s", typeOf(val1), typeOf(val2)))
+	panic(fmt.Errorf("type error: cannot subtract type %s and %s", TypeOf(val1), TypeOf(val2)))
 }
 
 func mul(val1 interface{}, val2 interface{}) interface{} {
@@ -197,7 +231,7 @@ func mul(val1 interface{}, val2 interface{}) interface{} {
 	if float1OK && float2OK {
 		return float1 * float2
 	}
-	panic(fmt.Errorf("type error: cannot multiply type %s and %s", typeOf(val1), typeOf(val2)))
+	panic(fmt.Errorf("type error: cannot multiply type %s and %s", TypeOf(val1), TypeOf(val2)))
 }
 
 func div(val1 interface{}, val2 interface{}) interface{} {
@@ -229,7 +263,7 @@ func div(val1 interface{}, val2 interface{}) interface{} {
 		}
 		return float1 / float2
 	}
-	panic(fmt.Errorf("type error: cannot divide type %s and %s", typeOf(val1), typeOf(val2)))
+	panic(fmt.Errorf("type error: cannot divide type %s and %s", TypeOf(val1), TypeOf(val2)))
 }
 
 func pow(val1 interface{}, val2 interface{}) interface{} {
@@ -243,14 +277,14 @@ func pow(val1 interface{}, val2 interface{}) interface{} {
 		float1 = float64(int1)
 	} else {
 		if float1, ok = val1.(float64); !ok {
-			panic(fmt.Errorf("type error: cannot multiply type %s and %s", typeOf(val1), typeOf(val2)))
+			panic(fmt.Errorf("type error: cannot multiply type %s and %s", TypeOf(val1), TypeOf(val2)))
 		}
 	}
 	if int2OK {
 		float2 = float64(int2)
 	} else {
 		if float2, ok = val2.(float64); !ok {
-			panic(fmt.Errorf("type error: cannot multiply type %s and %s", typeOf(val1), typeOf(val2)))
+			panic(fmt.Errorf("type error: cannot multiply type %s and %s", TypeOf(val1), TypeOf(val2)))
 		}
 	}
 	res := math.Pow(float1, float2)
@@ -286,7 +320,7 @@ func mod(val1 interface{}, val2 interface{}) interface{} {
 	if float1OK && float2OK {
 		return math.Mod(float1, float2)
 	}
-	panic(fmt.Errorf("type error: cannot perform modulo on type %s and %s", typeOf(val1), typeOf(val2)))
+	panic(fmt.Errorf("type error: cannot perform modulo on type %s and %s", TypeOf(val1), TypeOf(val2)))
 }
 
 func unaryMinus(val interface{}) interface{} {
@@ -298,10 +332,14 @@ func unaryMinus(val interface{}) interface{} {
 	if ok {
 		return -floatVal
 	}
-	panic(fmt.Errorf("type error: unary minus requires number, but was %s", typeOf(val)))
+	panic(fmt.Errorf("type error: unary minus requires number, but was %s", TypeOf(val)))
 }
 
 func deepEqual(val1 interface{}, val2 interface{}) bool {
+	if eq, ok := equalValues(val1, val2); ok {
+		return eq
+	}
+
 	switch typ1 := val1.(type) {
 
 	case []interface{}:
@@ -354,6 +392,10 @@ func deepEqual(val1 interface{}, val2 interface{}) bool {
 }
 
 func compare(val1 interface{}, val2 interface{}, operation string) bool {
+	if res, ok := compareValues(val1, val2, operation); ok {
+		return res
+	}
+
 	int1, int1OK := val1.(int)
 	int2, int2OK := val2.(int)
 
@@ -376,7 +418,7 @@ func compare(val1 interface{}, val2 interface{}, operation string) bool {
 	if float1OK && float2OK {
 		return compareFloat(float1, float2, operation)
 	}
-	panic(fmt.Errorf("type error: cannot compare type %s and %s", typeOf(val1), typeOf(val2)))
+	panic(fmt.Errorf("type error: cannot compare type %s and %s", TypeOf(val1), TypeOf(val2)))
 }
 
 func compareInt(val1 int, val2 int, operation string) bool {
@@ -410,21 +452,61 @@ func compareFloat(val1 float64, val2 float64, operation string) bool {
 func asObjectKey(key interface{}) string {
 	s, ok := key.(string)
 	if !ok {
-		panic(fmt.Errorf("type error: object key must be string, but was %s", typeOf(key)))
+		panic(fmt.Errorf("type error: object key must be string, but was %s", TypeOf(key)))
 	}
 	return s
 }
 
-func addObjectMember(obj map[string]interface{}, key, val interface{}) map[string]interface{} {
+// objectLiteral is an object that is currently being constructed.
+// It remembers which keys were defined explicitly, as opposed to keys copied from spread objects.
+type objectLiteral struct {
+	values   map[string]interface{}
+	explicit map[string]struct{}
+}
+
+func newObjectLiteral() *objectLiteral {
+	return &objectLiteral{
+		values:   make(map[string]interface{}),
+		explicit: make(map[string]struct{}),
+	}
+}
+
+func addObjectMember(obj *objectLiteral, key, val interface{}) *objectLiteral {
 	s := asObjectKey(key)
-	_, ok := obj[s]
+	_, ok := obj.explicit[s]
 	if ok {
 		panic(fmt.Errorf("syntax error: duplicate object key %q", s))
 	}
-	obj[s] = val
+	obj.explicit[s] = struct{}{}
+	obj.values[s] = val
 	return obj
 }
 
+func spreadObject(obj *objectLiteral, val interface{}) *objectLiteral {
+	src, ok := val.(map[string]interface{})
+	if !ok {
+		panic(fmt.Errorf("type error: spread operator requires object, but was %s", TypeOf(val)))
+	}
+	for k, v := range src {
+		obj.values[k] = v
+		delete(obj.explicit, k) // can be overridden by subsequent members
+	}
+	return obj
+}
+
+func spreadArray(arr []interface{}, val interface{}) []interface{} {
+	return append(arr, spreadSource(val)...)
+}
+
+// spreadSource returns the array that is expanded by a spread operator within array literals or function calls.
+func spreadSource(val interface{}) []interface{} {
+	src, ok := val.([]interface{})
+	if !ok {
+		panic(fmt.Errorf("type error: spread operator requires array, but was %s", TypeOf(val)))
+	}
+	return src
+}
+
 func accessVar(variables map[string]interface{}, varName string) interface{} {
 	val, ok := variables[varName]
 	if !ok {
@@ -434,11 +516,15 @@ func accessVar(variables map[string]interface{}, varName string) interface{} {
 }
 
 func accessField(s interface{}, field interface{}) interface{} {
+	if res, ok := accessValue(s, field); ok {
+		return res
+	}
+
 	obj, ok := s.(map[string]interface{})
 	if ok {
 		key, ok := field.(string)
 		if !ok {
-			panic(fmt.Errorf("syntax error: object key must be string, but was %s", typeOf(field)))
+			panic(fmt.Errorf("syntax error: object key must be string, but was %s", TypeOf(field)))
 		}
 		val, ok := obj[key]
 		if !ok {
@@ -453,7 +539,7 @@ func accessField(s interface{}, field interface{}) interface{} {
 		if !ok {
 			floatIdx, ok := field.(float64)
 			if !ok {
-				panic(fmt.Errorf("syntax error: array index must be number, but was %s", typeOf(field)))
+				panic(fmt.Errorf("syntax error: array index must be number, but was %s", TypeOf(field)))
 			}
 			intIdx = int(floatIdx)
 			if float64(intIdx) != floatIdx {
@@ -467,7 +553,7 @@ func accessField(s interface{}, field interface{}) interface{} {
 		return arrVar[intIdx]
 	}
 
-	panic(fmt.Errorf("syntax error: cannot access fields on type %s", typeOf(s)))
+	panic(fmt.Errorf("syntax error: cannot access fields on type %s", TypeOf(s)))
 }
 
 func slice(v interface{}, from, to interface{}) interface{} {
@@ -475,7 +561,7 @@ func slice(v interface{}, from, to interface{}) interface{} {
 	arr, isArr := v.([]interface{})
 
 	if !isStr && !isArr {
-		panic(fmt.Errorf("syntax error: slicing requires an array or string, but was %s", typeOf(v)))
+		panic(fmt.Errorf("syntax error: slicing requires an array or string, but was %s", TypeOf(v)))
 	}
 
 	var fromInt, toInt int
@@ -519,7 +605,7 @@ func slice(v interface{}, from, to interface{}) interface{} {
 func arrayContains(arr interface{}, val interface{}) bool {
 	a, ok := arr.([]interface{})
 	if !ok {
-		panic(fmt.Errorf("syntax error: in-operator requires array, but was %s", typeOf(arr)))
+		panic(fmt.Errorf("syntax error: in-operator requires array, but was %s", TypeOf(arr)))
 	}
 
 	for _, v := range a {
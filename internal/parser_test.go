@@ -48,40 +48,40 @@ func Test_Literals_Hex(t *testing.T) {
 	assertEvaluation(t, nil, 65535, "0xFFFF")  // 16bit
 	assertEvaluation(t, nil, 65535, "0xFF_FF") // 16bit
 
-	result, err := Evaluate("0x7FFF_FFFF", nil, nil) // 32bit, leading zero
+	result, err := evaluate(t, "0x7FFF_FFFF", nil, nil) // 32bit, leading zero
 	if assert.NoError(t, err) {
 		assert.Equal(t, int64(2147483647), int64(result.(int)))
 	}
 
 	if BitSizeOfInt == 32 {
-		result, err = Evaluate("0x8000_0000", nil, nil) // 32bit, leading one (highest negative)
+		result, err = evaluate(t, "0x8000_0000", nil, nil) // 32bit, leading one (highest negative)
 		if assert.NoError(t, err) {
 			assert.Equal(t, int32(-2147483648), int32(result.(int)))
 		}
 
-		result, err = Evaluate("0xFFFF_FFFF", nil, nil) // 32bit, leading one (lowest negative)
+		result, err = evaluate(t, "0xFFFF_FFFF", nil, nil) // 32bit, leading one (lowest negative)
 		if assert.NoError(t, err) {
 			assert.Equal(t, int32(-1), int32(result.(int)))
 		}
 	}
 
 	if BitSizeOfInt >= 64 {
-		result, err = Evaluate("0xFFFF_FFFF", nil, nil) // 32bit
+		result, err = evaluate(t, "0xFFFF_FFFF", nil, nil) // 32bit
 		if assert.NoError(t, err) {
 			assert.Equal(t, int64(4294967295), int64(result.(int)))
 		}
 
-		result, err = Evaluate("0x7FFF_FFFF_FFFF_FFFF", nil, nil) // 64bit, leading zero (highest positive)
+		result, err = evaluate(t, "0x7FFF_FFFF_FFFF_FFFF", nil, nil) // 64bit, leading zero (highest positive)
 		if assert.NoError(t, err) {
 			assert.Equal(t, int64(9223372036854775807), int64(result.(int)))
 		}
 
-		result, err = Evaluate("0x8000_0000_0000_0000", nil, nil) // 64bit, leading one (highest negative)
+		result, err = evaluate(t, "0x8000_0000_0000_0000", nil, nil) // 64bit, leading one (highest negative)
 		if assert.NoError(t, err) {
 			assert.Equal(t, int64(-9223372036854775808), int64(result.(int)))
 		}
 
-		result, err = Evaluate("0xFFFF_FFFF_FFFF_FFFF", nil, nil) // 64bit, leading one (lowest negative)
+		result, err = evaluate(t, "0xFFFF_FFFF_FFFF_FFFF", nil, nil) // 64bit, leading one (lowest negative)
 		if assert.NoError(t, err) {
 			assert.Equal(t, int64(-1), int64(result.(int)))
 		}
@@ -157,7 +157,7 @@ func Test_MissingOperator(t *testing.T) {
 
 func Test_UnsupportedTokens(t *testing.T) {
 	assertEvalError(t, nil, "unknown token \"ILLEGAL\" (\"§\") at position 3", "0 § 0")
-	assertEvalError(t, nil, "unknown token \"...\" (\"\") at position 3", "0 ... 0")
+	assertEvalError(t, nil, "unknown token \":=\" (\"\") at position 3", "0 := 0")
 	assertEvalError(t, nil, "unknown token \"+=\" (\"\") at position 3", "0 += 0")
 }
 
@@ -184,6 +184,42 @@ func Test_InvalidLiterals(t *testing.T) {
 	assertEvalError(t, nil, "syntax error: unexpected ':'", `{:1}`)
 }
 
+func Test_Comments(t *testing.T) {
+	vars := getTestVars()
+	assertEvaluation(t, vars, 42, "42 // the answer")
+	assertEvaluation(t, vars, 42, "// the answer\n42")
+	assertEvaluation(t, vars, 42, "/* the answer */ 42")
+	assertEvaluation(t, vars, 42, "40 /* almost */ + 2")
+	assertEvaluation(t, vars, 42, "40 + /* multi\nline */ 2")
+	assertEvaluation(t, vars, 42, "40 // almost\n + 2 // there")
+	assertEvaluation(t, vars, 42, "int /**/")
+	assertEvaluation(t, vars, []interface{}{1, 2}, "[1, // first\n 2 /* second */]")
+	assertEvaluation(t, vars, map[string]interface{}{"a": 1}, "{ /* key */ \"a\": 1 // value\n}")
+
+	assertEvaluation(t, vars, "// no comment", `"// no comment"`)
+	assertEvaluation(t, vars, "/* no comment */", "`/* no comment */`")
+	assertEvaluation(t, vars, 2, "4 / 2")
+	assertEvaluation(t, vars, 2, "4/2")
+}
+
+func Test_Comments_Invalid(t *testing.T) {
+	assertEvalError(t, nil, "parse error: comment not terminated at position 4", "42 /* the answer")
+	assertEvalError(t, nil, "parse error: comment not terminated at position 4", "42 /*/")
+	assertEvalError(t, nil, "syntax error: unexpected $end", "// only a comment")
+	assertEvalError(t, nil, "syntax error: unexpected $end", "40 + /* missing */")
+}
+
+func Test_Comments_AreKept(t *testing.T) {
+	program, err := Parse("/* a */ 1 + // b\n 2 /**/")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []Comment{
+			{Pos: 1, Text: "/* a */"},
+			{Pos: 13, Text: "// b"},
+			{Pos: 21, Text: "/**/"},
+		}, program.Comments)
+	}
+}
+
 func Test_Bool_Not(t *testing.T) {
 	vars := getTestVars()
 	assertEvaluation(t, vars, false, "!true")
@@ -344,6 +380,80 @@ func Test_UnaryMinus_IncompatibleTypes(t *testing.T) {
 	assertEvalError(t, vars, "type error: unary minus requires number, but was object", `-obj`)
 }
 
+func Test_Spread_Arrays(t *testing.T) {
+	vars := getTestVars()
+	vars["a"] = []interface{}{1, 2}
+	vars["b"] = []interface{}{5}
+	vars["empty"] = []interface{}{}
+
+	assertEvaluation(t, vars, []interface{}{1, 2}, `[...a]`)
+	assertEvaluation(t, vars, []interface{}{}, `[...empty]`)
+	assertEvaluation(t, vars, []interface{}{1, 2, 4, 5}, `[...a, 4, ...b]`)
+	assertEvaluation(t, vars, []interface{}{0, 1, 2, 1, 2}, `[0, ...a, ...a]`)
+	assertEvaluation(t, vars, []interface{}{1, 2, 5}, `[...a + b]`)
+	assertEvaluation(t, vars, []interface{}{3, 4}, `[...[3, 4]]`)
+	assertEvaluation(t, vars, []interface{}{[]interface{}{1, 2}}, `[[...a]]`)
+	assertEvaluation(t, vars, []interface{}{2}, `[...a[1:]]`)
+
+	// the spread array must not be modified:
+	assertEvaluation(t, vars, []interface{}{1, 2, 3}, `[...a[:1], ...a[1:], 3]`)
+	assertEvaluation(t, vars, []interface{}{1, 2}, `a`)
+}
+
+func Test_Spread_Objects(t *testing.T) {
+	vars := getTestVars()
+	vars["defaults"] = map[string]interface{}{"x": 0, "y": 0, "z": 0}
+	vars["overrides"] = map[string]interface{}{"y": 1}
+
+	assertEvaluation(t, vars, map[string]interface{}{"x": 0, "y": 0, "z": 0}, `{...defaults}`)
+	assertEvaluation(t, vars, map[string]interface{}{"x": 1, "y": 1, "z": 0}, `{...defaults, ...overrides, "x": 1}`)
+	assertEvaluation(t, vars, map[string]interface{}{"x": 0, "y": 0, "z": 0}, `{...overrides, ...defaults}`)
+	assertEvaluation(t, vars, map[string]interface{}{"x": 0, "y": 0, "z": 0, "a": 1}, `{"a": 1, ...defaults}`)
+	assertEvaluation(t, vars, map[string]interface{}{"x": 0, "y": 0, "z": 0}, `{"x": 1, ...defaults}`)
+	assertEvaluation(t, vars, map[string]interface{}{"y": 2}, `{...overrides, "y": 2}`)
+	assertEvaluation(t, vars, map[string]interface{}{"y": 3}, `{"y": 2, ...overrides, "y": 3}`)
+
+	// the spread object must not be modified:
+	assertEvaluation(t, vars, map[string]interface{}{"y": 1}, `overrides`)
+}
+
+func Test_Spread_FunctionCalls(t *testing.T) {
+	vars := getTestVars()
+	vars["scores"] = []interface{}{3, 7, 5}
+	functions := map[string]ExpressionFunction{
+		"args": func(args ...interface{}) (interface{}, error) {
+			return args, nil
+		},
+	}
+
+	assertEvaluationFuncs(t, vars, functions, []interface{}{3, 7, 5}, `args(...scores)`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{1, 3, 7, 5, 2}, `args(1, ...scores, 2)`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{}, `args(...[])`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{[]interface{}{3, 7, 5}}, `args(scores)`)
+}
+
+func Test_Spread_InvalidTypes(t *testing.T) {
+	vars := getTestVars()
+	for _, v := range []string{"nl", "tr", "int", "float", "str", "obj"} {
+		assertEvalError(t, vars, "type error: spread operator requires array, but was "+TypeOf(vars[v]), `[...`+v+`]`)
+	}
+	for _, v := range []string{"nl", "tr", "int", "float", "str", "arr"} {
+		assertEvalError(t, vars, "type error: spread operator requires object, but was "+TypeOf(vars[v]), `{...`+v+`}`)
+	}
+	assertEvalError(t, vars, "syntax error: duplicate object key \"a\"", `{...obj, "a": 1, "a": 2}`)
+	assertEvalError(t, vars, "syntax error: duplicate object key \"a\"", `{"a": 1, ...obj, "a": 2}`)
+}
+
+func Test_Spread_InvalidSyntax(t *testing.T) {
+	vars := getTestVars()
+	assertEvalError(t, vars, "syntax error: unexpected ELLIPSIS", `0 ... 0`)
+	assertEvalError(t, vars, "syntax error: unexpected ELLIPSIS", `...arr`)
+	assertEvalError(t, vars, "syntax error: unexpected ']'", `[...]`)
+	assertEvalError(t, vars, "syntax error: unexpected ':', expecting '}' or ','", `{...obj: 1}`)
+	assertEvalError(t, vars, "syntax error: unexpected ELLIPSIS", `{"a": ...obj}`)
+	assertEvalError(t, vars, "syntax error: unexpected ELLIPSIS, expecting ']' or ','", `[arr...]`)
+}
+
 func Test_Arithmetic_Subtract(t *testing.T) {
 	// int - int
 	assertEvaluation(t, nil, 21, "42 - 21")
@@ -586,7 +696,7 @@ func Test_AndOr_InvalidTypes(t *testing.T) {
 			expectedErr = fmt.Sprintf("type error: required bool, but was %s", nonBoolType)
 			assertEvalError(t, vars, expectedErr, t1+"||"+t2)
 
-			result, err := Evaluate(t1+"||"+t2, vars, nil)
+			result, err := evaluate(t, t1+"||"+t2, vars, nil)
 			assert.Errorf(t, err, "%v || %v\n", t1, t2)
 			assert.Nil(t, result)
 		}
@@ -1034,6 +1144,37 @@ func Test_VariableAccess_DoesNotExist(t *testing.T) {
 	assertEvalError(t, nil, "var error: variable \"var\" does not exist", "var[fieldName]")
 }
 
+func Test_VariableAccess_Numbered(t *testing.T) {
+	vars := map[string]interface{}{
+		"$1":  1,
+		"$12": 12,
+		"$3":  map[string]interface{}{"name": "ann", "a": map[string]interface{}{"b": true}},
+	}
+	assertEvaluation(t, vars, 1, "$1")
+	assertEvaluation(t, vars, 13, "$12 + $1")
+	assertEvaluation(t, vars, 13, "$12+$1")
+	assertEvaluation(t, vars, []interface{}{1}, "[$1]")
+	assertEvaluation(t, vars, "ann", "$3.name")
+	assertEvaluation(t, vars, true, "$3.a.b")
+	assertEvaluation(t, vars, "ann", `$3["name"]`)
+	assertEvaluation(t, vars, 2, "$1 /* one */ + $1")
+	assertEvaluation(t, vars, "name: ann", `f"name: {$3.name}"`)
+
+	assertEvalError(t, vars, "var error: variable \"$2\" does not exist", "$2")
+	assertEvalError(t, vars, "syntax error: unexpected LITERAL_NUMBER", "$1.5")
+	assertEvalError(t, vars, "syntax error: unexpected IDENT", "$0x1")
+	assertEvalError(t, vars, "syntax error: unexpected IDENT", "$2e3")
+
+	// positions after numbered variables are kept:
+	_, err := Parse("$1 + )")
+	var syntaxErr *SyntaxError
+	if assert.True(t, errors.As(err, &syntaxErr)) {
+		assert.Equal(t, 6, syntaxErr.Pos)
+	}
+	assertEvalError(t, vars, "unknown token \"ILLEGAL\" (\"$\") at position 1", "$a")
+	assertEvalError(t, vars, "unknown token \"ILLEGAL\" (\"$\") at position 1", "$ 1")
+}
+
 func Test_VariableAccess_Arithmetic(t *testing.T) {
 	vars := getTestVars()
 	assertEvaluation(t, vars, 84, "int + int")
@@ -1394,6 +1535,122 @@ func Test_Slicing_InvalidTypes(t *testing.T) {
 	}
 }
 
+func Test_Comprehension_Arrays(t *testing.T) {
+	vars := getTestVars()
+	vars["users"] = []interface{}{
+		map[string]interface{}{"name": "ann", "active": true, "age": 31},
+		map[string]interface{}{"name": "bob", "active": false, "age": 17},
+		map[string]interface{}{"name": "eve", "active": true, "age": 45},
+	}
+
+	assertEvaluation(t, vars, []interface{}{"ann", "eve"}, `[x.name for x in users if x.active]`)
+	assertEvaluation(t, vars, []interface{}{"ann", "bob", "eve"}, `[u.name for u in users]`)
+	assertEvaluation(t, vars, []interface{}{}, `[u.name for u in users if u.age > 100]`)
+	assertEvaluation(t, vars, []interface{}{}, `[x for x in []]`)
+	assertEvaluation(t, vars, []interface{}{2, 4, 6}, `[x * 2 for x in [1, 2, 3]]`)
+	assertEvaluation(t, vars, []interface{}{0, 2}, `[i for i, u in users if u.active]`)
+	assertEvaluation(t, vars, []interface{}{"0:ann", "1:bob", "2:eve"}, `[i + ":" + u.name for i, u in users]`)
+
+	// objects are iterated in key-order:
+	assertEvaluation(t, vars, []interface{}{"b", "f", "i", "s"}, `[k for k in obj]`)
+	assertEvaluation(t, vars, []interface{}{"b=false", "f=5.1", "i=51", "s=tx"}, `[k + "=" + v for k, v in obj]`)
+	assertEvaluation(t, vars, []interface{}{51}, `[v for k, v in obj if k == "i"]`)
+
+	// nesting:
+	assertEvaluation(t, vars, []interface{}{[]interface{}{1, 2}, []interface{}{2, 4}}, `[[x * y for x in [1, 2]] for y in [1, 2]]`)
+	assertEvaluation(t, vars, []interface{}{3, 4}, `[x for x in [y + 1 for y in [1, 2, 3]] if x > 2]`)
+	assertEvaluation(t, vars, 2, `[x for x in arr if x == 21][0] - 19`)
+	assertEvaluation(t, vars, true, `"eve" in [u.name for u in users]`)
+}
+
+func Test_Comprehension_Objects(t *testing.T) {
+	vars := getTestVars()
+	vars["prices"] = map[string]interface{}{"apple": 2, "pear": 1.5}
+
+	assertEvaluation(t, vars, map[string]interface{}{"apple": 4, "pear": 3.0}, `{k: v * 2 for k, v in prices}`)
+	assertEvaluation(t, vars, map[string]interface{}{"apple": 2}, `{k: v for k, v in prices if v > 1.5}`)
+	assertEvaluation(t, vars, map[string]interface{}{}, `{k: v for k, v in {}}`)
+	assertEvaluation(t, vars, map[string]interface{}{"apple": true, "pear": true}, `{k: true for k in prices}`)
+	assertEvaluation(t, vars, map[string]interface{}{"0": "a", "1": "b"}, `{"" + i: v for i, v in ["a", "b"]}`)
+	assertEvaluation(t, vars, map[string]interface{}{"a": 1, "b": 2}, `{v: i + 1 for i, v in ["a", "b"]}`)
+	assertEvaluation(t, vars, map[string]interface{}{"x": 2}, `{v: i for i, v in ["x", "x", "x"] if i < 3}`) // last key wins
+}
+
+func Test_Comprehension_ConcatenatedResults(t *testing.T) {
+	vars := getTestVars()
+	// comprehension results can have spare capacity, concatenation must not write into it:
+	assertEvaluation(t, vars, []interface{}{
+		[]interface{}{[]interface{}{1, 2, 3, 1}, []interface{}{1, 2, 3, 2}},
+	}, `[[a + [1], a + [2]] for a in [[y for y in [1, 2, 3]]]]`)
+
+	spare := make([]interface{}, 1, 10)
+	spare[0] = 0
+	vars["spare"] = spare
+	assertEvaluation(t, vars, []interface{}{[]interface{}{0, 1}, []interface{}{0, 2}}, `[spare + [1], spare + [2]]`)
+	assertEvaluation(t, vars, []interface{}{0}, `spare`)
+}
+
+func Test_Comprehension_Scoping(t *testing.T) {
+	vars := getTestVars()
+	vars["x"] = "outer"
+
+	// loop variables shadow other variables only within the comprehension:
+	assertEvaluation(t, vars, []interface{}{1, 2}, `[x for x in [1, 2]]`)
+	assertEvaluation(t, vars, []interface{}{[]interface{}{1, 2}, "outer"}, `[[x for x in [1, 2]], x]`)
+	assertEvaluation(t, vars, []interface{}{"outer1", "outer2"}, `[x for x in [x + 1, x + 2]]`)
+	assertEvaluation(t, vars, []interface{}{[]interface{}{0, "outer"}}, `[[i, x] for i, x in [x]]`)
+	assertEvaluation(t, vars, []interface{}{"ab", "b"}, `[[x + y for y in ["b"]][0] for x in ["a", ""]]`)
+
+	// loop variables are not accessible afterwards:
+	assertEvalError(t, vars, "var error: variable \"y\" does not exist", `[y for y in [1]] + [y]`)
+}
+
+func Test_Comprehension_InvalidTypes(t *testing.T) {
+	vars := getTestVars()
+	for _, v := range []string{"nl", "tr", "int", "float", "str"} {
+		assertEvalError(t, vars, "type error: cannot iterate over "+TypeOf(vars[v]), `[x for x in `+v+`]`)
+	}
+	assertEvalError(t, vars, "type error: required bool, but was number", `[x for x in arr if 1]`)
+	assertEvalError(t, vars, "type error: object key must be string, but was number", `{i: v for i, v in arr}`)
+}
+
+func Test_Comprehension_InvalidSyntax(t *testing.T) {
+	vars := getTestVars()
+	assertEvalError(t, vars, "syntax error: unexpected FOR", `x for x in arr`)
+	assertEvalError(t, vars, "syntax error: unexpected IN, expecting IDENT", `[x for in arr]`)
+	assertEvalError(t, vars, "syntax error: unexpected ']', expecting IN", `[x for x]`)
+	assertEvalError(t, vars, "syntax error: unexpected ']'", `[x for x in]`)
+	assertEvalError(t, vars, "syntax error: unexpected ']'", `[x for x in arr if]`)
+	assertEvalError(t, vars, "syntax error: unexpected '.', expecting IN", `[x for x.y in arr]`)
+	assertEvalError(t, vars, "syntax error: unexpected FOR", `{x for x in arr}`)
+	assertEvalError(t, vars, "syntax error: unexpected FOR, expecting ']' or ','", `[1, x for x in arr]`)
+	assertEvalError(t, vars, "syntax error: unexpected IF, expecting ']' or ','", `[x if x]`)
+	assertEvalError(t, vars, "syntax error: unexpected ']', expecting IN", `[x for for]`)
+	assertEvalError(t, vars, "syntax error: unexpected IF", `1 if 2`)
+}
+
+func Test_Comprehension_KeywordsAsIdentifiers(t *testing.T) {
+	vars := map[string]interface{}{
+		"for": 1,
+		"if":  true,
+		"obj": map[string]interface{}{"for": 2, "if": 3},
+		"arr": []interface{}{1, 2},
+	}
+	funcs := map[string]ExpressionFunction{
+		"if": func(args ...interface{}) (interface{}, error) {
+			return args[0], nil
+		},
+	}
+	assertEvaluationFuncs(t, vars, funcs, 1, `for`)
+	assertEvaluationFuncs(t, vars, funcs, true, `if`)
+	assertEvaluationFuncs(t, vars, funcs, 5, `obj.for + obj.if`)
+	assertEvaluationFuncs(t, vars, funcs, 3, `if(3)`)
+	assertEvaluationFuncs(t, vars, funcs, 4, `4 |> if()`)
+	assertEvaluationFuncs(t, vars, funcs, []interface{}{2, 3}, `[for + 1 for for in arr]`)
+	assertEvaluationFuncs(t, vars, funcs, []interface{}{1, 2}, `[x for x in arr if if]`)
+	assertEvaluationFuncs(t, vars, funcs, map[string]interface{}{"0": 1, "1": 2}, `{"" + if: for for if, for in arr}`)
+}
+
 func Test_FunctionCall_Simple(t *testing.T) {
 	var shouldReturn interface{}
 	var expectedArg interface{}
@@ -1495,9 +1752,11 @@ func Test_FunctionCall_Errors(t *testing.T) {
 	assertEvalErrorFuncs(t, nil, functions, "function error: \"func3\" - panic: error string", "func3()")
 	assertEvalErrorFuncs(t, nil, functions, "function error: \"func4\" - panic: 42", "func4()")
 
-	assert.Panics(t, func() {
-		_, _ = Evaluate("func5()", nil, functions)
-	})
+	for _, backend := range backends {
+		assert.Panics(t, func() {
+			_, _ = backend.evaluate("func5()", nil, functions)
+		}, backend.name)
+	}
 }
 
 func Test_InvalidFunctionCalls(t *testing.T) {
@@ -1514,6 +1773,66 @@ func Test_InvalidFunctionCalls(t *testing.T) {
 	assertEvalErrorFuncs(t, vars, functions, "syntax error: unexpected ','", `func((1, 2))`)
 }
 
+func Test_Pipe(t *testing.T) {
+	vars := getTestVars()
+	vars["prices"] = []interface{}{10, 20.5, 30}
+
+	functions := map[string]ExpressionFunction{
+		"args": func(args ...interface{}) (interface{}, error) {
+			return args, nil
+		},
+		"sum": func(args ...interface{}) (interface{}, error) {
+			var sum interface{} = 0
+			for _, v := range args[0].([]interface{}) {
+				sum = add(sum, v)
+			}
+			return sum, nil
+		},
+		"round": func(args ...interface{}) (interface{}, error) {
+			factor := math.Pow(10, float64(args[1].(int)))
+			return math.Round(args[0].(float64)*factor) / factor, nil
+		},
+	}
+
+	assertEvaluationFuncs(t, vars, functions, []interface{}{1}, `1 |> args()`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{1, 2, 3}, `1 |> args(2, 3)`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{1, 2, 3}, `1|>args(2, 3)`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{[]interface{}{1}, 2}, `1 |> args() |> args(2)`)
+	assertEvaluationFuncs(t, vars, functions, 60.5, `prices |> sum()`)
+	assertEvaluationFuncs(t, vars, functions, 72.6, `[p * 1.2 for p in prices] |> sum() |> round(2)`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{1, 2, 3}, `1 |> args(...[2, 3])`)
+
+	// lowest precedence on the left, function call on the right:
+	assertEvaluationFuncs(t, vars, functions, []interface{}{3}, `1 + 2 |> args()`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{true}, `1 < 2 && true |> args()`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{"a"}, `true ? "a" : "b" |> args()`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{"b"}, `false ? "a" : "b" |> args()`)
+	assertEvaluationFuncs(t, vars, functions, 3, `(1 |> args())[0] + 2`)
+	assertEvaluationFuncs(t, vars, functions, 1, `1 |> args()[0]`)
+	assertEvaluationFuncs(t, vars, functions, []interface{}{2, 3}, `[(x + 1 |> args())[0] for x in [1, 2]]`)
+
+	// bitwise-or and greater-than are still available:
+	assertEvaluationFuncs(t, vars, functions, true, `(1|2) > 2`)
+	assertEvaluationFuncs(t, vars, functions, 3, `1|(2)`)
+}
+
+func Test_Pipe_Errors(t *testing.T) {
+	functions := map[string]ExpressionFunction{
+		"fail": func(args ...interface{}) (interface{}, error) {
+			return nil, errors.New("simulated error")
+		},
+	}
+	assertEvalErrorFuncs(t, nil, functions, "syntax error: no such function \"noFunc\"", `1 |> noFunc()`)
+	assertEvalErrorFuncs(t, nil, functions, "function error: \"fail\" - simulated error", `1 |> fail()`)
+	assertEvalErrorFuncs(t, nil, functions, "syntax error: unexpected $end, expecting '('", `1 |> fail`)
+	assertEvalErrorFuncs(t, nil, functions, "syntax error: unexpected LITERAL_NUMBER, expecting IDENT", `1 |> 2`)
+	assertEvalErrorFuncs(t, nil, functions, "syntax error: unexpected '(', expecting IDENT", `1 |> (fail())`)
+	assertEvalErrorFuncs(t, nil, functions, "syntax error: unexpected PIPE", `|> fail()`)
+	assertEvalErrorFuncs(t, nil, functions, "syntax error: unexpected GTR", `1 | > fail()`)
+	assertEvalErrorFuncs(t, nil, functions, "syntax error: unexpected GEQ", `1 |>= 3`)
+	assertEvalErrorFuncs(t, nil, functions, "syntax error: unexpected SHR", `1 |>> 1`)
+}
+
 func Test_Ternary_Simple(t *testing.T) {
 	assertEvaluation(t, nil, 1, `true ? 1 : 2`)
 	assertEvaluation(t, nil, 2, `false ? 1 : 2`)
@@ -1573,13 +1892,14 @@ func Test_Ternary_NoShortCircuit(t *testing.T) {
 		},
 	}
 
+	// every backend evaluates the expression once
 	assertEvaluationFuncs(t, nil, functions, 1, `true ? func1() : func2()`)
-	assert.Equal(t, 1, func1Calls)
-	assert.Equal(t, 1, func2Calls)
+	assert.Equal(t, 1*len(backends), func1Calls)
+	assert.Equal(t, 1*len(backends), func2Calls)
 
 	assertEvaluationFuncs(t, nil, functions, 2, `false ? func1() : func2()`)
-	assert.Equal(t, 2, func1Calls)
-	assert.Equal(t, 2, func2Calls)
+	assert.Equal(t, 2*len(backends), func1Calls)
+	assert.Equal(t, 2*len(backends), func2Calls)
 }
 
 func Test_Ternary_InvalidSyntax(t *testing.T) {
@@ -1599,7 +1919,7 @@ func Test_Ternary_InvalidTypes(t *testing.T) {
 
 func assertEvaluation(t *testing.T, variables map[string]interface{}, expected interface{}, str string) {
 	t.Helper()
-	result, err := Evaluate(str, variables, nil)
+	result, err := evaluate(t, str, variables, nil)
 	if assert.NoError(t, err) {
 		assert.Equal(t, expected, result)
 	}
@@ -1607,7 +1927,7 @@ func assertEvaluation(t *testing.T, variables map[string]interface{}, expected i
 
 func assertEvaluationFuncs(t *testing.T, variables map[string]interface{}, functions map[string]ExpressionFunction, expected interface{}, str string) {
 	t.Helper()
-	result, err := Evaluate(str, variables, functions)
+	result, err := evaluate(t, str, variables, functions)
 	if assert.NoError(t, err) {
 		assert.Equal(t, expected, result)
 	}
@@ -1620,7 +1940,7 @@ func assertEvalError(t *testing.T, variables map[string]interface{}, expectedErr
 
 func assertEvalErrorFuncs(t *testing.T, variables map[string]interface{}, functions map[string]ExpressionFunction, expectedErr string, str string) {
 	t.Helper()
-	result, err := Evaluate(str, variables, functions)
+	result, err := evaluate(t, str, variables, functions)
 	if assert.Error(t, err) {
 		assert.Equal(t, expectedErr, err.Error())
 	}
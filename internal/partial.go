@@ -0,0 +1,508 @@
+package internal
+
+import "sort"
+
+// Partial evaluates the parsed expression as far as possible and returns the remaining (residual) expression.
+//
+// Variables that don't exist within the scope are unknown and remain within the residual expression,
+// the same as calls to functions that don't exist. Everything else is evaluated and replaced by literals.
+// Logical operators and the ternary operator are simplified if one of their operands is known,
+// like `false && unknown` becoming `false`, or `true ? a : b` becoming `a`.
+// `true && unknown` only becomes `unknown` if it always evaluates to a bool, like comparisons.
+// This assumes that the unknown operands don't fail (otherwise the full expression would have failed).
+//
+// Known values that cannot be written as literals (like custom types or NaN) are stored as Literal nodes without raw text.
+// Functions that are called during partial evaluation need to be pure.
+// Fails if more than maxSteps nodes are evaluated. Zero means unlimited.
+func (p *Program) Partial(s *Scope, functions map[string]ExpressionFunction, maxSteps int) (residual *Program, err error) {
+	defer recoverError(&err)
+
+	e := partialEvaluator{
+		evaluator: evaluator{functions: functions, maxSteps: maxSteps},
+		scope:     s,
+	}
+	return &Program{Root: residualNode(e.partial(p.Root))}, nil
+}
+
+// partialEvaluator replaces known sub-expressions with *Literal nodes holding their value.
+type partialEvaluator struct {
+	evaluator
+	scope *Scope   // known variables
+	bound []string // loop variables of the surrounding comprehensions; they are unknown
+}
+
+func (e *partialEvaluator) partial(node Node) Node {
+	switch n := node.(type) {
+	case *Literal:
+		return n
+	case *Ident:
+		if !e.known(n.Name) {
+			return n
+		}
+		return &Literal{Value: e.scope.lookup(n.Name)}
+	case *InterpolatedString:
+		return e.partialInterpolation(n)
+	case *ArrayLit:
+		return e.reduce(&ArrayLit{Elems: e.partialList(n.Elems)})
+	case *ObjectLit:
+		members := make([]Node, len(n.Members))
+		for idx, member := range n.Members {
+			if spread, ok := member.(*Spread); ok {
+				members[idx] = &Spread{X: e.partial(spread.X)}
+				continue
+			}
+			kv := member.(*KeyValue)
+			members[idx] = &KeyValue{Key: e.partial(kv.Key), Value: e.partial(kv.Value)}
+		}
+		return e.reduce(&ObjectLit{Members: members})
+	case *UnaryExpr:
+		return e.reduce(&UnaryExpr{Op: n.Op, X: e.partial(n.X)})
+	case *BinaryExpr:
+		x, y := e.partial(n.X), e.partial(n.Y)
+		if res := simplifyLogical(n.Op, x, y); res != nil {
+			return res
+		}
+		return e.reduce(&BinaryExpr{X: x, Op: n.Op, Y: y})
+	case *TernaryExpr:
+		cond, then, els := e.partial(n.Cond), e.partial(n.Then), e.partial(n.Else)
+		if lit, ok := cond.(*Literal); ok {
+			if asBool(lit.Value) {
+				return then
+			}
+			return els
+		}
+		return &TernaryExpr{Cond: cond, Then: then, Else: els}
+	case *ParenExpr:
+		return e.partial(n.X) // missing parentheses are added when converting the tree into source
+	case *CallExpr:
+		return e.reduce(e.partialCall(n))
+	case *PipeExpr:
+		return e.reduce(&PipeExpr{X: e.partial(n.X), Call: e.partialCall(n.Call)})
+	case *SelectorExpr:
+		return e.reduce(&SelectorExpr{X: e.partial(n.X), Sel: n.Sel})
+	case *IndexExpr:
+		return e.reduce(&IndexExpr{X: e.partial(n.X), Index: e.partial(n.Index)})
+	case *SliceExpr:
+		res := &SliceExpr{X: e.partial(n.X)}
+		if n.Low != nil {
+			res.Low = e.partial(n.Low)
+		}
+		if n.High != nil {
+			res.High = e.partial(n.High)
+		}
+		return e.reduce(res)
+	case *ArrayComp:
+		if e.closed(n) {
+			return &Literal{Value: e.eval(n, e.scope)}
+		}
+		clause := e.partialClause(n.Clause)
+		defer e.leave(e.enter(n.Clause))
+		return &ArrayComp{Elem: e.partial(n.Elem), Clause: clause}
+	case *ObjectComp:
+		if e.closed(n) {
+			return &Literal{Value: e.eval(n, e.scope)}
+		}
+		clause := e.partialClause(n.Clause)
+		defer e.leave(e.enter(n.Clause))
+		return &ObjectComp{Key: e.partial(n.Key), Value: e.partial(n.Value), Clause: clause}
+	}
+	// unsupported nodes fail the same way as during evaluation
+	return &Literal{Value: e.eval(node, e.scope)}
+}
+
+func (e *partialEvaluator) partialList(nodes []Node) []Node {
+	list := make([]Node, len(nodes))
+	for idx, node := range nodes {
+		if spread, ok := node.(*Spread); ok {
+			list[idx] = &Spread{X: e.partial(spread.X)}
+			continue
+		}
+		list[idx] = e.partial(node)
+	}
+	return list
+}
+
+func (e *partialEvaluator) partialCall(n *CallExpr) *CallExpr {
+	return &CallExpr{Func: n.Func, Args: e.partialList(n.Args)}
+}
+
+// partialInterpolation evaluates the known parts of an interpolated string and merges them with the surrounding text.
+func (e *partialEvaluator) partialInterpolation(n *InterpolatedString) Node {
+	var parts []Node
+	for _, part := range n.Parts {
+		res := e.partial(part)
+		lit, ok := res.(*Literal)
+		if !ok {
+			parts = append(parts, res)
+			continue
+		}
+		text := add("", lit.Value)
+		if len(parts) > 0 {
+			if prev, ok := parts[len(parts)-1].(*Literal); ok {
+				prev.Value = add(prev.Value, text)
+				continue
+			}
+		}
+		parts = append(parts, &Literal{Value: text})
+	}
+
+	switch {
+	case len(parts) == 0:
+		return &Literal{Value: ""}
+	case len(parts) == 1:
+		if lit, ok := parts[0].(*Literal); ok {
+			return lit
+		}
+	}
+	return &InterpolatedString{Parts: parts}
+}
+
+// partialClause evaluates the source of a comprehension, as well as its condition (within the loop's scope).
+func (e *partialEvaluator) partialClause(clause *ForClause) *ForClause {
+	res := &ForClause{Vars: clause.Vars, X: e.partial(clause.X)}
+	if clause.Cond != nil {
+		defer e.leave(e.enter(clause))
+		res.Cond = e.partial(clause.Cond)
+	}
+	return res
+}
+
+// enter marks the clause's loop variables as unknown. Returns the number of previously bound variables.
+func (e *partialEvaluator) enter(clause *ForClause) int {
+	prev := len(e.bound)
+	for _, v := range clause.Vars {
+		e.bound = append(e.bound, v.Name)
+	}
+	return prev
+}
+
+// leave restores the bound variables of the surrounding comprehension.
+func (e *partialEvaluator) leave(prev int) {
+	e.bound = e.bound[:prev]
+}
+
+// known reports whether the variable has a known value.
+func (e *partialEvaluator) known(name string) bool {
+	for _, b := range e.bound {
+		if b == name {
+			return false
+		}
+	}
+	return e.scope.has(name)
+}
+
+// reduce evaluates the node if all of its operands are known.
+// The node's operands need to be partially evaluated already.
+func (e *partialEvaluator) reduce(node Node) Node {
+	operands := operands(node)
+	for _, op := range operands {
+		if spread, ok := op.(*Spread); ok {
+			op = spread.X
+		}
+		if kv, ok := op.(*KeyValue); ok {
+			if _, ok := kv.Key.(*Literal); !ok {
+				return node
+			}
+			op = kv.Value
+		}
+		if _, ok := op.(*Literal); !ok {
+			return node
+		}
+	}
+	if call := calledFunction(node); call != nil {
+		if _, ok := e.functions[call.Func.Name]; !ok {
+			return node
+		}
+	}
+	return &Literal{Value: e.eval(node, e.scope)}
+}
+
+// operands returns the direct operands of a node that was created by partial evaluation.
+func operands(node Node) []Node {
+	switch n := node.(type) {
+	case *ArrayLit:
+		return n.Elems
+	case *ObjectLit:
+		return n.Members
+	case *UnaryExpr:
+		return []Node{n.X}
+	case *BinaryExpr:
+		return []Node{n.X, n.Y}
+	case *CallExpr:
+		return n.Args
+	case *PipeExpr:
+		return append([]Node{n.X}, n.Call.Args...)
+	case *SelectorExpr:
+		return []Node{n.X}
+	case *IndexExpr:
+		return []Node{n.X, n.Index}
+	case *SliceExpr:
+		ops := []Node{n.X}
+		if n.Low != nil {
+			ops = append(ops, n.Low)
+		}
+		if n.High != nil {
+			ops = append(ops, n.High)
+		}
+		return ops
+	}
+	return nil
+}
+
+func calledFunction(node Node) *CallExpr {
+	switch n := node.(type) {
+	case *CallExpr:
+		return n
+	case *PipeExpr:
+		return n.Call
+	}
+	return nil
+}
+
+// simplifyLogical simplifies logical operators with a single known bool operand.
+// Returns nil if the operation cannot be simplified.
+func simplifyLogical(op string, x, y Node) Node {
+	if op != "&&" && op != "||" {
+		return nil
+	}
+	// the operator's result if one of the operands has this value: false for &&, true for ||
+	decisive := op == "||"
+
+	for _, pair := range [][2]Node{{x, y}, {y, x}} {
+		lit, ok := pair[0].(*Literal)
+		if !ok {
+			continue
+		}
+		b, ok := lit.Value.(bool)
+		if !ok {
+			continue
+		}
+		if _, otherKnown := pair[1].(*Literal); otherKnown {
+			return nil // evaluated regularly, so that type errors are reported
+		}
+		if b == decisive {
+			return &Literal{Value: decisive}
+		}
+		if !isBool(pair[1]) {
+			return nil // the operator converts and checks the type of the other operand
+		}
+		return pair[1]
+	}
+	return nil
+}
+
+// isBool reports whether the node always evaluates to a bool, unless it fails.
+func isBool(node Node) bool {
+	switch n := node.(type) {
+	case *Literal:
+		_, ok := n.Value.(bool)
+		return ok
+	case *UnaryExpr:
+		return n.Op == "!"
+	case *BinaryExpr:
+		switch n.Op {
+		case "&&", "||", "==", "!=", "<", "<=", ">", ">=", "in":
+			return true
+		}
+	case *TernaryExpr:
+		return isBool(n.Then) && isBool(n.Else)
+	case *ParenExpr:
+		return isBool(n.X)
+	}
+	return false
+}
+
+// closed reports whether all variables and functions used by the node are known.
+func (e *partialEvaluator) closed(node Node) bool {
+	vars, funcs := references(node)
+	for _, name := range vars {
+		if !e.known(name) {
+			return false
+		}
+	}
+	for _, name := range funcs {
+		if _, ok := e.functions[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// References returns the names of all variables and functions that are used by the node, in sorted order.
+// Loop variables of comprehensions are not included.
+func References(node Node) (variables []string, functions []string) {
+	return references(node)
+}
+
+func references(node Node) (variables []string, functions []string) {
+	vars := make(map[string]struct{})
+	funcs := make(map[string]struct{})
+	collectReferences(node, nil, vars, funcs)
+	return sortedKeys(vars), sortedKeys(funcs)
+}
+
+func collectReferences(node Node, bound []string, vars, funcs map[string]struct{}) {
+	visit := func(nodes ...Node) {
+		for _, n := range nodes {
+			if n != nil {
+				collectReferences(n, bound, vars, funcs)
+			}
+		}
+	}
+	switch n := node.(type) {
+	case *Ident:
+		for _, b := range bound {
+			if b == n.Name {
+				return
+			}
+		}
+		vars[n.Name] = struct{}{}
+	case *InterpolatedString:
+		visit(n.Parts...)
+	case *ArrayLit:
+		visit(n.Elems...)
+	case *ObjectLit:
+		visit(n.Members...)
+	case *KeyValue:
+		visit(n.Key, n.Value)
+	case *Spread:
+		visit(n.X)
+	case *UnaryExpr:
+		visit(n.X)
+	case *BinaryExpr:
+		visit(n.X, n.Y)
+	case *TernaryExpr:
+		visit(n.Cond, n.Then, n.Else)
+	case *ParenExpr:
+		visit(n.X)
+	case *CallExpr:
+		funcs[n.Func.Name] = struct{}{}
+		visit(n.Args...)
+	case *PipeExpr:
+		visit(n.X, n.Call)
+	case *SelectorExpr:
+		visit(n.X)
+	case *IndexExpr:
+		visit(n.X, n.Index)
+	case *SliceExpr:
+		visit(n.X, n.Low, n.High)
+	case *ArrayComp:
+		visit(n.Clause.X)
+		inner := loopVariables(bound, n.Clause)
+		collectReferences(n.Elem, inner, vars, funcs)
+		if n.Clause.Cond != nil {
+			collectReferences(n.Clause.Cond, inner, vars, funcs)
+		}
+	case *ObjectComp:
+		visit(n.Clause.X)
+		inner := loopVariables(bound, n.Clause)
+		collectReferences(n.Key, inner, vars, funcs)
+		collectReferences(n.Value, inner, vars, funcs)
+		if n.Clause.Cond != nil {
+			collectReferences(n.Clause.Cond, inner, vars, funcs)
+		}
+	}
+}
+
+func loopVariables(bound []string, clause *ForClause) []string {
+	inner := append([]string(nil), bound...)
+	for _, v := range clause.Vars {
+		inner = append(inner, v.Name)
+	}
+	return inner
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// residualNode replaces literals holding arrays and objects with array and object literals, so that they can be written as source.
+func residualNode(node Node) Node {
+	switch n := node.(type) {
+	case *Literal:
+		return literalNode(n.Value)
+	case *InterpolatedString:
+		return &InterpolatedString{Parts: residualList(n.Parts)}
+	case *ArrayLit:
+		return &ArrayLit{Elems: residualList(n.Elems)}
+	case *ObjectLit:
+		return &ObjectLit{Members: residualList(n.Members)}
+	case *KeyValue:
+		return &KeyValue{Key: residualNode(n.Key), Value: residualNode(n.Value)}
+	case *Spread:
+		return &Spread{X: residualNode(n.X)}
+	case *UnaryExpr:
+		return &UnaryExpr{Op: n.Op, X: residualNode(n.X)}
+	case *BinaryExpr:
+		return &BinaryExpr{X: residualNode(n.X), Op: n.Op, Y: residualNode(n.Y)}
+	case *TernaryExpr:
+		return &TernaryExpr{Cond: residualNode(n.Cond), Then: residualNode(n.Then), Else: residualNode(n.Else)}
+	case *CallExpr:
+		return &CallExpr{Func: n.Func, Args: residualList(n.Args)}
+	case *PipeExpr:
+		return &PipeExpr{X: residualNode(n.X), Call: residualNode(n.Call).(*CallExpr)}
+	case *SelectorExpr:
+		return &SelectorExpr{X: residualNode(n.X), Sel: n.Sel}
+	case *IndexExpr:
+		return &IndexExpr{X: residualNode(n.X), Index: residualNode(n.Index)}
+	case *SliceExpr:
+		res := &SliceExpr{X: residualNode(n.X)}
+		if n.Low != nil {
+			res.Low = residualNode(n.Low)
+		}
+		if n.High != nil {
+			res.High = residualNode(n.High)
+		}
+		return res
+	case *ArrayComp:
+		return &ArrayComp{Elem: residualNode(n.Elem), Clause: residualClause(n.Clause)}
+	case *ObjectComp:
+		return &ObjectComp{Key: residualNode(n.Key), Value: residualNode(n.Value), Clause: residualClause(n.Clause)}
+	}
+	return node
+}
+
+func residualList(nodes []Node) []Node {
+	res := make([]Node, len(nodes))
+	for idx, node := range nodes {
+		res[idx] = residualNode(node)
+	}
+	return res
+}
+
+func residualClause(clause *ForClause) *ForClause {
+	res := &ForClause{Vars: clause.Vars, X: residualNode(clause.X)}
+	if clause.Cond != nil {
+		res.Cond = residualNode(clause.Cond)
+	}
+	return res
+}
+
+// literalNode converts a value into a literal. Arrays and objects become array and object literals.
+func literalNode(val interface{}) Node {
+	switch v := val.(type) {
+	case []interface{}:
+		elems := make([]Node, len(v))
+		for idx, elem := range v {
+			elems[idx] = literalNode(elem)
+		}
+		return &ArrayLit{Elems: elems}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		members := make([]Node, len(keys))
+		for idx, key := range keys {
+			members[idx] = &KeyValue{Key: &Literal{Value: key}, Value: literalNode(v[key])}
+		}
+		return &ObjectLit{Members: members}
+	}
+	return &Literal{Value: val}
+}
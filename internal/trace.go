@@ -0,0 +1,56 @@
+package internal
+
+import "runtime"
+
+// TraceNode records the evaluation of a single sub-expression.
+type TraceNode struct {
+	Node     Node
+	Value    interface{}  // result; nil if the evaluation failed
+	Err      error        // set if the evaluation of this node (or one of its children) failed
+	Children []*TraceNode // evaluated operands, in evaluation order
+}
+
+// Trace evaluates the parsed expression within the given scope and records every evaluated sub-expression.
+//
+// Parentheses are not recorded. Comprehensions record their source, followed by the condition and elements of every iteration.
+// If evaluation fails, the trace contains all nodes that were evaluated up to that point.
+// Fails if more than maxSteps nodes are evaluated. Zero means unlimited.
+func (p *Program) Trace(s *Scope, functions map[string]ExpressionFunction, maxSteps int) (result interface{}, trace *TraceNode, err error) {
+	root := &TraceNode{}
+	defer func() {
+		if len(root.Children) > 0 {
+			trace = root.Children[0]
+		}
+	}()
+	defer recoverError(&err)
+
+	e := evaluator{
+		functions: functions,
+		trace:     root,
+		maxSteps:  maxSteps,
+	}
+	return e.eval(p.Root, s), nil, nil
+}
+
+// evalTraced evaluates the node and records it as child of the currently evaluated node.
+func (e *evaluator) evalTraced(node Node, s *Scope) interface{} {
+	if _, ok := node.(*ParenExpr); ok {
+		return e.evalNode(node, s)
+	}
+	parent := e.trace
+	t := &TraceNode{Node: node}
+	parent.Children = append(parent.Children, t)
+
+	e.trace = t
+	defer func() {
+		e.trace = parent
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); !ok {
+				t.Err, _ = r.(error)
+			}
+			panic(r)
+		}
+	}()
+	t.Value = e.evalNode(node, s)
+	return t.Value
+}
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Value can be implemented by custom types that should be usable within expressions.
+//
+// On its own, a value can only be passed around and compared for identity.
+// Further operations are supported by implementing the optional interfaces
+// Adder, Subtractor, Comparer, Equaler, FieldAccessor, Indexer, Truther and fmt.Stringer.
+type Value interface {
+	// TypeName returns the name of the type, as it should appear within error messages.
+	TypeName() string
+}
+
+// Adder is implemented by values that support the `+` operator.
+type Adder interface {
+	Value
+	// Add returns `v + other`, or `other + v` if reversed is true.
+	Add(other interface{}, reversed bool) (interface{}, error)
+}
+
+// Subtractor is implemented by values that support the `-` operator.
+type Subtractor interface {
+	Value
+	// Sub returns `v - other`, or `other - v` if reversed is true.
+	Sub(other interface{}, reversed bool) (interface{}, error)
+}
+
+// Comparer is implemented by values that support the operators `<`, `<=`, `>` and `>=`.
+type Comparer interface {
+	Value
+	// Compare returns a negative number if v < other, 0 if v == other and a positive number if v > other.
+	Compare(other interface{}) (int, error)
+}
+
+// Equaler is implemented by values that support the operators `==`, `!=` and `in`.
+// Values that don't implement this interface are only equal to themselves.
+type Equaler interface {
+	Value
+	// Equal reports whether v and other are equal.
+	Equal(other interface{}) bool
+}
+
+// FieldAccessor is implemented by values that support field access via `v.field` and `v["field"]`.
+type FieldAccessor interface {
+	Value
+	// Field returns the value of the given field.
+	Field(name string) (interface{}, error)
+}
+
+// Indexer is implemented by values that support index access via `v[index]`.
+// Takes precedence over FieldAccessor for index access.
+type Indexer interface {
+	Value
+	// Index returns the element at the given index or key.
+	Index(index interface{}) (interface{}, error)
+}
+
+// Truther is implemented by values that can be used as booleans,
+// for example as operands of `&&`, `||`, `!` and as conditions of the ternary operator.
+type Truther interface {
+	Value
+	// Truthy returns the boolean representation of the value.
+	Truthy() bool
+}
+
+// valueError converts an error returned by a custom value into an evaluation error.
+func valueError(v Value, err error) error {
+	return fmt.Errorf("value error: %s - %w", v.TypeName(), err)
+}
+
+// addValues performs `val1 + val2` if one of the operands is a custom value.
+func addValues(val1, val2 interface{}) (interface{}, bool) {
+	// string concatenation takes precedence, the same way it does for all other types
+	if str, ok := val1.(string); ok {
+		if s, ok := valueToString(val2); ok {
+			return str + s, true
+		}
+	}
+	if str, ok := val2.(string); ok {
+		if s, ok := valueToString(val1); ok {
+			return s + str, true
+		}
+	}
+
+	if v, ok := val1.(Adder); ok {
+		res, err := v.Add(val2, false)
+		if err != nil {
+			panic(valueError(v, err))
+		}
+		return res, true
+	}
+	if v, ok := val2.(Adder); ok {
+		res, err := v.Add(val1, true)
+		if err != nil {
+			panic(valueError(v, err))
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+// subValues performs `val1 - val2` if one of the operands is a Subtractor.
+func subValues(val1, val2 interface{}) (interface{}, bool) {
+	if v, ok := val1.(Subtractor); ok {
+		res, err := v.Sub(val2, false)
+		if err != nil {
+			panic(valueError(v, err))
+		}
+		return res, true
+	}
+	if v, ok := val2.(Subtractor); ok {
+		res, err := v.Sub(val1, true)
+		if err != nil {
+			panic(valueError(v, err))
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+// compareValues compares val1 and val2 if one of the operands is a Comparer.
+func compareValues(val1, val2 interface{}, operation string) (bool, bool) {
+	if v, ok := val1.(Comparer); ok {
+		res, err := v.Compare(val2)
+		if err != nil {
+			panic(valueError(v, err))
+		}
+		return compareInt(res, 0, operation), true
+	}
+	if v, ok := val2.(Comparer); ok {
+		res, err := v.Compare(val1)
+		if err != nil {
+			panic(valueError(v, err))
+		}
+		return compareInt(0, res, operation), true
+	}
+	return false, false
+}
+
+// equalValues compares val1 and val2 if one of the operands is a Value.
+func equalValues(val1, val2 interface{}) (bool, bool) {
+	if v, ok := val1.(Equaler); ok {
+		return v.Equal(val2), true
+	}
+	if v, ok := val2.(Equaler); ok {
+		return v.Equal(val1), true
+	}
+
+	_, ok1 := val1.(Value)
+	_, ok2 := val2.(Value)
+	if !ok1 && !ok2 {
+		return false, false
+	}
+	if !ok1 || !ok2 || reflect.TypeOf(val1) != reflect.TypeOf(val2) || !reflect.TypeOf(val1).Comparable() {
+		return false, true
+	}
+	return comparableEqual(val1, val2), true
+}
+
+// comparableEqual compares two values of the same comparable type.
+// Comparable structs can still contain interface fields with incomparable values, which panic when compared.
+// These are compared with reflect.DeepEqual instead.
+func comparableEqual(val1, val2 interface{}) (equal bool) {
+	defer func() {
+		if recover() != nil {
+			equal = reflect.DeepEqual(val1, val2)
+		}
+	}()
+	return val1 == val2
+}
+
+// accessValue performs `s[field]` if s is an Indexer or FieldAccessor.
+func accessValue(s interface{}, field interface{}) (interface{}, bool) {
+	if v, ok := s.(Indexer); ok {
+		res, err := v.Index(field)
+		if err != nil {
+			panic(valueError(v, err))
+		}
+		return res, true
+	}
+	if v, ok := s.(FieldAccessor); ok {
+		name, ok := field.(string)
+		if !ok {
+			panic(fmt.Errorf("syntax error: field name must be string, but was %s", TypeOf(field)))
+		}
+		res, err := v.Field(name)
+		if err != nil {
+			panic(valueError(v, err))
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+// accessMember performs `s.name`.
+func accessMember(s interface{}, name string) interface{} {
+	if v, ok := s.(FieldAccessor); ok {
+		res, err := v.Field(name)
+		if err != nil {
+			panic(valueError(v, err))
+		}
+		return res
+	}
+	if _, ok := s.(Value); ok {
+		panic(fmt.Errorf("syntax error: cannot access fields on type %s", TypeOf(s)))
+	}
+	return accessField(s, name)
+}
+
+// valueToString converts a custom value into a string, if it implements fmt.Stringer.
+func valueToString(val interface{}) (string, bool) {
+	if _, ok := val.(Value); !ok {
+		return "", false
+	}
+	if s, ok := val.(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return "", false
+}
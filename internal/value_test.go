@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// money implements all optional interfaces
+type money struct {
+	cents    int
+	currency string
+}
+
+func (m money) TypeName() string { return "money" }
+func (m money) String() string {
+	return fmt.Sprintf("%d.%02d %s", m.cents/100, m.cents%100, m.currency)
+}
+func (m money) Truthy() bool { return m.cents != 0 }
+
+func (m money) Add(other interface{}, reversed bool) (interface{}, error) {
+	o, ok := other.(money)
+	if !ok {
+		return nil, fmt.Errorf("cannot add %s", TypeOf(other))
+	}
+	if o.currency != m.currency {
+		return nil, errors.New("currency mismatch")
+	}
+	return money{m.cents + o.cents, m.currency}, nil
+}
+
+func (m money) Sub(other interface{}, reversed bool) (interface{}, error) {
+	o, ok := other.(money)
+	if !ok {
+		return nil, fmt.Errorf("cannot subtract %s", TypeOf(other))
+	}
+	if reversed {
+		m, o = o, m
+	}
+	return money{m.cents - o.cents, m.currency}, nil
+}
+
+func (m money) Compare(other interface{}) (int, error) {
+	switch o := other.(type) {
+	case money:
+		return m.cents - o.cents, nil
+	case int:
+		return m.cents - o*100, nil
+	}
+	return 0, fmt.Errorf("cannot compare with %s", TypeOf(other))
+}
+
+func (m money) Equal(other interface{}) bool {
+	o, ok := other.(money)
+	return ok && o == m
+}
+
+func (m money) Field(name string) (interface{}, error) {
+	switch name {
+	case "cents":
+		return m.cents, nil
+	case "currency":
+		return m.currency, nil
+	}
+	return nil, fmt.Errorf("no field %q", name)
+}
+
+// version only supports indexing
+type version []int
+
+func (v version) TypeName() string { return "version" }
+
+func (v version) Index(index interface{}) (interface{}, error) {
+	i, ok := index.(int)
+	if !ok || i < 0 || i >= len(v) {
+		return nil, fmt.Errorf("invalid index %v", index)
+	}
+	return v[i], nil
+}
+
+// opaque does not support any operations
+type opaque struct {
+	id int
+}
+
+func (o opaque) TypeName() string { return "opaque" }
+
+// tagged is comparable, but can contain incomparable data
+type tagged struct {
+	data interface{}
+}
+
+func (t tagged) TypeName() string { return "tagged" }
+
+func getValueTestVars() map[string]interface{} {
+	return map[string]interface{}{
+		"price":    money{1250, "EUR"},
+		"discount": money{250, "EUR"},
+		"dollars":  money{1000, "USD"},
+		"free":     money{0, "EUR"},
+		"ver":      version{1, 2, 3},
+		"op1":      opaque{1},
+		"op2":      opaque{2},
+		"tag1":     tagged{[]int{1}},
+		"tag2":     tagged{[]int{1}},
+		"tag3":     tagged{[]int{2}},
+	}
+}
+
+func Test_Value_Add(t *testing.T) {
+	vars := getValueTestVars()
+	assertEvaluation(t, vars, money{1500, "EUR"}, `price + discount`)
+	assertEvaluation(t, vars, money{1750, "EUR"}, `price + discount + discount`)
+	assertEvaluation(t, vars, money{1000, "EUR"}, `price - discount`)
+	assertEvaluation(t, vars, money{-1000, "EUR"}, `discount - price`)
+
+	assertEvalError(t, vars, "value error: money - currency mismatch", `price + dollars`)
+	assertEvalError(t, vars, "value error: money - cannot add number", `price + 1`)
+	assertEvalError(t, vars, "value error: money - cannot add number", `1 + price`)
+	assertEvalError(t, vars, "value error: money - cannot subtract nil", `nil - price`)
+}
+
+func Test_Value_StringConversion(t *testing.T) {
+	vars := getValueTestVars()
+	assertEvaluation(t, vars, "total: 12.50 EUR", `"total: " + price`)
+	assertEvaluation(t, vars, "12.50 EUR!", `price + "!"`)
+	assertEvaluation(t, vars, "15.00 EUR", `"" + (price + discount)`)
+
+	assertEvalError(t, vars, "type error: cannot add or concatenate type string and opaque", `"id: " + op1`)
+	assertEvalError(t, vars, "type error: cannot add or concatenate type version and string", `ver + ""`)
+}
+
+func Test_Value_Compare(t *testing.T) {
+	vars := getValueTestVars()
+	assertEvaluation(t, vars, true, `price > discount`)
+	assertEvaluation(t, vars, false, `price < discount`)
+	assertEvaluation(t, vars, true, `price >= price`)
+	assertEvaluation(t, vars, true, `price <= price`)
+	assertEvaluation(t, vars, true, `price > 12`)
+	assertEvaluation(t, vars, false, `price > 13`)
+	assertEvaluation(t, vars, true, `13 > price`)
+	assertEvaluation(t, vars, false, `12 >= price`)
+
+	assertEvalError(t, vars, "value error: money - cannot compare with string", `price < "a"`)
+	assertEvalError(t, vars, "type error: cannot compare type opaque and opaque", `op1 < op2`)
+	assertEvalError(t, vars, "type error: cannot compare type number and version", `1 < ver`)
+}
+
+func Test_Value_Equality(t *testing.T) {
+	vars := getValueTestVars()
+	assertEvaluation(t, vars, true, `price == price`)
+	assertEvaluation(t, vars, true, `price == discount + discount + discount + discount + discount`)
+	assertEvaluation(t, vars, false, `price == discount`)
+	assertEvaluation(t, vars, true, `price != discount`)
+	assertEvaluation(t, vars, false, `price == 12.5`)
+	assertEvaluation(t, vars, false, `12.5 == price`)
+	assertEvaluation(t, vars, true, `price in [1, discount, price]`)
+	assertEvaluation(t, vars, false, `price in [1, discount, dollars]`)
+	assertEvaluation(t, vars, true, `[price, 1] == [price, 1]`)
+
+	// without Equaler:
+	assertEvaluation(t, vars, true, `op1 == op1`)
+	assertEvaluation(t, vars, false, `op1 == op2`)
+	assertEvaluation(t, vars, false, `op1 == 1`)
+	assertEvaluation(t, vars, false, `nil == op1`)
+	assertEvaluation(t, vars, false, `ver == ver`)  // not comparable
+	assertEvaluation(t, vars, true, `tag1 == tag2`) // comparable type, but incomparable data
+	assertEvaluation(t, vars, false, `tag1 == tag3`)
+	assertEvaluation(t, vars, true, `tag1 in [tag3, tag2]`)
+}
+
+func Test_Value_FieldAccess(t *testing.T) {
+	vars := getValueTestVars()
+	assertEvaluation(t, vars, 1250, `price.cents`)
+	assertEvaluation(t, vars, "EUR", `price["currency"]`)
+	assertEvaluation(t, vars, "EUR", `{"p": price}.p.currency`)
+	assertEvaluation(t, vars, 2, `ver[1]`)
+	assertEvaluation(t, vars, 3, `ver[1 + 1]`)
+
+	assertEvalError(t, vars, "value error: money - no field \"amount\"", `price.amount`)
+	assertEvalError(t, vars, "syntax error: field name must be string, but was number", `price[0]`)
+	assertEvalError(t, vars, "value error: version - invalid index 3", `ver[3]`)
+	assertEvalError(t, vars, "syntax error: cannot access fields on type version", `ver.major`)
+	assertEvalError(t, vars, "syntax error: cannot access fields on type opaque", `op1.id`)
+	assertEvalError(t, vars, "syntax error: cannot access fields on type opaque", `op1[0]`)
+}
+
+func Test_Value_Truthiness(t *testing.T) {
+	vars := getValueTestVars()
+	assertEvaluation(t, vars, "paid", `price ? "paid" : "free"`)
+	assertEvaluation(t, vars, "free", `free ? "paid" : "free"`)
+	assertEvaluation(t, vars, true, `!free`)
+	assertEvaluation(t, vars, true, `price && !free`)
+	assertEvaluation(t, vars, true, `free || true`)
+	assertEvaluation(t, vars, []interface{}{"12.50 EUR", "2.50 EUR"}, `["" + p for p in [price, free, discount] if p]`)
+
+	assertEvalError(t, vars, "type error: required bool, but was opaque", `!op1`)
+	assertEvalError(t, vars, "type error: required bool, but was version", `ver ? 1 : 2`)
+}
+
+func Test_Value_UnsupportedOperations(t *testing.T) {
+	vars := getValueTestVars()
+	assertEvalError(t, vars, "type error: cannot add or concatenate type opaque and number", `op1 + 1`)
+	assertEvalError(t, vars, "type error: cannot subtract type opaque and opaque", `op1 - op2`)
+	assertEvalError(t, vars, "type error: cannot multiply type money and number", `price * 2`)
+	assertEvalError(t, vars, "type error: unary minus requires number, but was money", `-price`)
+	assertEvalError(t, vars, "type error: required number of type integer, but was money", `price | 1`)
+	assertEvalError(t, vars, "syntax error: in-operator requires array, but was version", `1 in ver`)
+	assertEvalError(t, vars, "syntax error: slicing requires an array or string, but was version", `ver[:]`)
+
+	functions := map[string]ExpressionFunction{
+		"cents": func(args ...interface{}) (interface{}, error) {
+			return args[0].(money).cents, nil
+		},
+		"euros": func(args ...interface{}) (interface{}, error) {
+			return money{args[0].(int) * 100, "EUR"}, nil
+		},
+	}
+	assertEvaluationFuncs(t, vars, functions, 1250, `cents(price)`)
+	assertEvaluationFuncs(t, vars, functions, money{1500, "EUR"}, `euros(15)`)
+	assertEvaluationFuncs(t, vars, functions, true, `euros(15) == price + discount`)
+
+	result, err := evaluate(t, `op1`, vars, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, opaque{1}, result)
+	}
+}
@@ -0,0 +1,128 @@
+package goval
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// ErrNonFiniteNumber is returned by EvaluateJSON if the result contains NaN or an infinite number,
+// which cannot be represented in JSON.
+var ErrNonFiniteNumber = errors.New("json error: result contains a non-finite number")
+
+// EvaluateJSON evaluates the given expression string with variables and result encoded as JSON.
+//
+// jsonVariables needs to be a JSON object, or empty if there are no variables.
+// JSON values are decoded into the types used within expressions.
+// Numbers without fraction and exponent become `int` (without rounding them to float64 first), as long as they fit.
+// All other numbers become `float64`.
+//
+// Optionally accepts a list of expression functions (can be called from within expressions).
+//
+// Returns the JSON-encoded result or an error.
+// Returns ErrNonFiniteNumber if the result contains NaN or an infinite number.
+func (e *Evaluator) EvaluateJSON(str string, jsonVariables []byte, functions map[string]ExpressionFunction) ([]byte, error) {
+	variables, err := DecodeJSONVariables(jsonVariables)
+	if err != nil {
+		return nil, err
+	}
+	result, err := e.Evaluate(str, variables, functions)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeJSON(result)
+}
+
+// DecodeJSONVariables decodes a JSON object into variables, the same way as EvaluateJSON does.
+// Returns nil if data is empty.
+func DecodeJSONVariables(data []byte) (map[string]interface{}, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("json error: unexpected data after variables")
+	}
+
+	variables, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("json error: variables need to be a JSON object")
+	}
+	if _, err := convertJSONValue(variables); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// convertJSONValue replaces all json.Number values with int or float64.
+func convertJSONValue(val interface{}) (interface{}, error) {
+	var err error
+	switch v := val.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, strconv.IntSize); err == nil {
+			return int(i), nil
+		}
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("json error: number %s is out of range", v)
+		}
+		return f, nil
+	case []interface{}:
+		for i, elem := range v {
+			if v[i], err = convertJSONValue(elem); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		for k, elem := range v {
+			if v[k], err = convertJSONValue(elem); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return val, nil
+}
+
+// EncodeJSON encodes an evaluation result as JSON, the same way as EvaluateJSON does.
+// Returns ErrNonFiniteNumber if the value contains NaN or an infinite number.
+func EncodeJSON(val interface{}) ([]byte, error) {
+	if !isFinite(val) {
+		return nil, ErrNonFiniteNumber
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+	return data, nil
+}
+
+// isFinite reports whether the value does not contain NaN or infinite numbers.
+func isFinite(val interface{}) bool {
+	switch v := val.(type) {
+	case float64:
+		return !math.IsNaN(v) && !math.IsInf(v, 0)
+	case []interface{}:
+		for _, elem := range v {
+			if !isFinite(elem) {
+				return false
+			}
+		}
+	case map[string]interface{}:
+		for _, elem := range v {
+			if !isFinite(elem) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package goval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertJSON(t *testing.T, expected string, expr string, vars string) {
+	t.Helper()
+	result, err := NewEvaluator().EvaluateJSON(expr, []byte(vars), nil)
+	if assert.NoError(t, err, "expression: %s", expr) {
+		assert.Equal(t, expected, string(result), "expression: %s", expr)
+	}
+}
+
+func assertJSONError(t *testing.T, expected string, expr string, vars string) {
+	t.Helper()
+	_, err := NewEvaluator().EvaluateJSON(expr, []byte(vars), nil)
+	if assert.Error(t, err, "expression: %s", expr) {
+		assert.Equal(t, expected, err.Error(), "expression: %s", expr)
+	}
+}
+
+func Test_EvaluateJSON(t *testing.T) {
+	vars := `{
+		"int": 42,
+		"big": 9007199254740993,
+		"float": 4.5,
+		"exp": 1e2,
+		"str": "text",
+		"nil": null,
+		"bool": true,
+		"arr": [1, 2.5, "three"],
+		"obj": {"nested": {"n": 7}}
+	}`
+
+	assertJSON(t, `42`, `int`, vars)
+	assertJSON(t, `9007199254740993`, `big`, vars)
+	assertJSON(t, `9007199254740994`, `big + 1`, vars)
+	assertJSON(t, `10`, `int / 4`, vars) // integer division
+	assertJSON(t, `4.5`, `float`, vars)
+	assertJSON(t, `100`, `exp`, vars)
+	assertJSON(t, `true`, `exp == 100.0`, vars)
+	assertJSON(t, `"text!"`, `str + "!"`, vars)
+	assertJSON(t, `null`, `nil`, vars)
+	assertJSON(t, `false`, `!bool`, vars)
+	assertJSON(t, `[1,2.5,"three",4]`, `arr + [4]`, vars)
+	assertJSON(t, `7`, `obj.nested.n`, vars)
+	assertJSON(t, `{"a":1,"n":7}`, `{"a": 1, ...obj.nested}`, vars)
+	assertJSON(t, `[2,3]`, `[x + 1 for x in [1, 2]]`, ``)
+	assertJSON(t, `3`, `1 + 2`, ` `)
+}
+
+func Test_EvaluateJSON_Errors(t *testing.T) {
+	assertJSONError(t, "json error: variables need to be a JSON object", `1`, `[1, 2]`)
+	assertJSONError(t, "json error: variables need to be a JSON object", `1`, `null`)
+	assertJSONError(t, "json error: unexpected EOF", `1`, `{"a": `)
+	assertJSONError(t, "json error: unexpected data after variables", `1`, `{} {}`)
+	assertJSONError(t, "json error: number 1e400 is out of range", `1`, `{"a": [1e400]}`)
+	assertJSONError(t, "var error: variable \"b\" does not exist", `b`, `{"a": 1}`)
+
+	assertJSONError(t, ErrNonFiniteNumber.Error(), `a * 10`, `{"a": 1e308}`)
+	assertJSONError(t, ErrNonFiniteNumber.Error(), `[{"x": a * -10}]`, `{"a": 1e308}`)
+
+	_, err := NewEvaluator().EvaluateJSON(`a * 10`, []byte(`{"a": 1e308}`), nil)
+	assert.Equal(t, ErrNonFiniteNumber, err)
+}
@@ -0,0 +1,107 @@
+// Package lint reports likely mistakes within expressions.
+//
+// Each warning has a rule ID. Rules can be disabled for all expressions via Linter.Disable,
+// or for a single expression with a comment:
+//
+//	x == 0.1 // lint:ignore float-equality
+//
+// Multiple rule IDs are separated by commas. The comment applies to the whole expression.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+)
+
+// Rule IDs.
+const (
+	NilRequired       = "nil-required"       // required variables compared with nil
+	ConstantCondition = "constant-condition" // conditions that are always true or always false
+	IdenticalBranches = "identical-branches" // ternary operators whose branches are the same
+	RepeatedOperand   = "repeated-operand"   // the same operand used twice, like `a && a` or `x - x`
+	FloatEquality     = "float-equality"     // equality comparisons with fractional numbers or divisions
+	ShiftOverflow     = "shift-overflow"     // shift amounts that are not smaller than the size of int
+	HexLiteral        = "hex-literal"        // hex literals that are negative or invalid on 32bit architectures
+)
+
+// Rules contains the IDs of all rules.
+var Rules = []string{NilRequired, ConstantCondition, IdenticalBranches, RepeatedOperand, FloatEquality, ShiftOverflow, HexLiteral}
+
+// Warning is a likely mistake within an expression.
+type Warning struct {
+	Pos     int    // position of the first character of the affected node
+	End     int    // position of the first character after the affected node
+	Rule    string // rule ID
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("position %d: %s [%s]", w.Pos, w.Message, w.Rule)
+}
+
+// Linter checks expressions for likely mistakes.
+type Linter struct {
+	// Required contains variables and field paths that are never nil, like "id" or "user.address.city".
+	// Comparing them with nil is reported.
+	Required []string
+
+	// Disable contains the IDs of rules that are not reported.
+	Disable []string
+}
+
+// Lint parses the expression and returns its warnings, ordered by position.
+func (l *Linter) Lint(expr string) ([]Warning, error) {
+	program, err := goval.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return l.LintAST(program), nil
+}
+
+// LintAST returns the warnings of a parsed expression, ordered by position.
+// Rules that are ignored by comments within the program are not reported.
+func (l *Linter) LintAST(program *ast.Program) []Warning {
+	c := &checker{linter: l}
+	c.check(program.Root)
+
+	disabled := make(map[string]bool)
+	for _, rule := range l.Disable {
+		disabled[rule] = true
+	}
+	for _, comment := range program.Comments {
+		for _, rule := range ignoredRules(comment.Text) {
+			disabled[rule] = true
+		}
+	}
+
+	var warnings []Warning
+	for _, w := range c.warnings {
+		if !disabled[w.Rule] {
+			warnings = append(warnings, w)
+		}
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].Pos < warnings[j].Pos
+	})
+	return warnings
+}
+
+// ignoreDirective is the prefix of comments that disable rules.
+const ignoreDirective = "lint:ignore "
+
+// ignoredRules returns the rule IDs listed within a `lint:ignore` comment.
+func ignoredRules(comment string) []string {
+	text := strings.TrimSuffix(comment, "*/")
+	idx := strings.Index(text, ignoreDirective)
+	if idx < 0 {
+		return nil
+	}
+	fields := strings.FieldsFunc(text[idx+len(ignoreDirective):], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	return fields
+}
@@ -0,0 +1,78 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertWarnings(t *testing.T, l *Linter, expected []string, expr string) {
+	t.Helper()
+	warnings, err := l.Lint(expr)
+	if !assert.NoError(t, err, "expression: %s", expr) {
+		return
+	}
+	var res []string
+	for _, w := range warnings {
+		res = append(res, w.String())
+	}
+	assert.Equal(t, expected, res, "expression: %s", expr)
+}
+
+func Test_Lint(t *testing.T) {
+	l := &Linter{Required: []string{"id", "user.name"}}
+	assert := func(expected []string, expr string) {
+		t.Helper()
+		assertWarnings(t, l, expected, expr)
+	}
+	assert(nil, `a && b || c ? x / 2 : [1 << 3, 0xFF]`)
+
+	assert([]string{"position 1: id is required and never nil, the comparison is always false [nil-required]"}, `id == nil`)
+	assert([]string{"position 1: user.name is required and never nil, the comparison is always true [nil-required]"}, `nil != user["name"]`)
+	assert(nil, `user == nil || user.age == nil || name == nil`)
+
+	assert([]string{"position 7: condition is always false [constant-condition]"}, `a || (x > 5 && x < 3)`)
+	assert([]string{"position 1: condition is always true [constant-condition]"}, `b || !b ? 1 : 2`)
+
+	assert([]string{"position 1: both branches of the ternary operator are identical [identical-branches]"}, `a ? (x + 1) : x + 1`)
+
+	assert([]string{"position 11: operand of && is repeated [repeated-operand]"}, `a && b && (a) || c`)
+	assert([]string{"position 15: operand of || is repeated [repeated-operand]"}, `x > 1 || y || (x > 1)`)
+	assert([]string{"position 1: both operands of - are identical [repeated-operand]"}, `f(x) - f(x)`)
+	assert([]string{"position 1: both operands of == are identical [repeated-operand]"}, `a.b == (a.b)`)
+	assert(nil, `x + x + x * x`)
+
+	assert([]string{"position 1: floating point numbers are compared for equality [float-equality]"}, `x == 0.1`)
+	assert([]string{"position 1: floating point numbers are compared for equality [float-equality]"}, `a / b != c`)
+	assert(nil, `x == 1.0 && y == -2 && z < 0.5`)
+
+	assert([]string{"position 6: shift amount 64 is not smaller than the size of int (64 bits) [shift-overflow]"}, `1 << 64`)
+	assert([]string{"position 6: shift amount -100 is not smaller than the size of int (64 bits) [shift-overflow]"}, `x >> -100`)
+	assert(nil, `1 << 63 | x >> -63`)
+
+	assert([]string{"position 1: hex literal 0x80000000 is negative on 32bit architectures [hex-literal]"}, `0x80000000`)
+	assert([]string{"position 5: hex literal 0X1FFFFFFFF cannot be parsed on 32bit architectures [hex-literal]"}, `1 + 0X1FFFFFFFF`)
+	assert(nil, `0x7FFFFFFF + 2147483648`)
+
+	// ordered by position:
+	assert([]string{
+		"position 1: floating point numbers are compared for equality [float-equality]",
+		"position 19: shift amount 99 is not smaller than the size of int (64 bits) [shift-overflow]",
+		"position 30: operand of || is repeated [repeated-operand]",
+		"position 30: floating point numbers are compared for equality [float-equality]",
+	}, `x == 0.5 || (1 << 99) > 0 || x == 0.5`)
+}
+
+func Test_Lint_Suppress(t *testing.T) {
+	l := &Linter{Disable: []string{HexLiteral}}
+	assertWarnings(t, l, nil, `0xFFFFFFFF`)
+	assertWarnings(t, l, nil, `x == 0.1 // lint:ignore float-equality`)
+	assertWarnings(t, l, nil, `/* lint:ignore float-equality, repeated-operand */ x == 0.1 || x == 0.1`)
+	assertWarnings(t, l, []string{"position 1: floating point numbers are compared for equality [float-equality]"},
+		`x == 0.1 // lint:ignore repeated-operand`)
+}
+
+func Test_Lint_Error(t *testing.T) {
+	_, err := (&Linter{}).Lint("a &&")
+	assert.EqualError(t, err, "syntax error: unexpected $end")
+}
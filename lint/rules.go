@@ -0,0 +1,217 @@
+package lint
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/maja42/goval/ast"
+	"github.com/maja42/goval/internal"
+	"github.com/maja42/goval/logic"
+)
+
+// checker collects the warnings of a single expression.
+type checker struct {
+	linter   *Linter
+	warnings []Warning
+	chained  map[ast.Node]bool // nested operations of && and || chains, which were already checked
+}
+
+func (c *checker) warn(node ast.Node, rule string, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, Warning{
+		Pos:     node.Pos(),
+		End:     node.End(),
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) check(root ast.Node) {
+	for _, constant := range logic.Constants(root) {
+		c.warn(constant.Node, ConstantCondition, "condition is always %t", constant.Value)
+	}
+	c.chained = make(map[ast.Node]bool)
+	ast.Inspect(root, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.BinaryExpr:
+			c.binary(n)
+		case *ast.TernaryExpr:
+			if same(n.Then, n.Else) {
+				c.warn(n, IdenticalBranches, "both branches of the ternary operator are identical")
+			}
+		case *ast.Literal:
+			c.literal(n)
+		}
+		return true
+	})
+}
+
+// repeatable contains the operators for which identical operands are likely a mistake, apart from && and ||.
+var repeatable = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"-": true, "/": true, "%": true, "^": true, "&": true, "|": true,
+}
+
+func (c *checker) binary(n *ast.BinaryExpr) {
+	switch n.Op {
+	case "&&", "||":
+		if !c.chained[n] {
+			c.chain(n)
+		}
+	case "<<", ">>":
+		if val, ok := number(n.Y); ok && math.Abs(val) >= float64(internal.BitSizeOfInt) {
+			c.warn(n.Y, ShiftOverflow, "shift amount %v is not smaller than the size of int (%d bits)", val, internal.BitSizeOfInt)
+		}
+	}
+
+	if repeatable[n.Op] && same(n.X, n.Y) {
+		c.warn(n, RepeatedOperand, "both operands of %s are identical", n.Op)
+	}
+	if n.Op != "==" && n.Op != "!=" {
+		return
+	}
+	for _, operands := range [][2]ast.Node{{n.X, n.Y}, {n.Y, n.X}} {
+		if isNil(operands[0]) {
+			if path := fieldPath(operands[1]); path != "" && c.linter.required(path) {
+				c.warn(n, NilRequired, "%s is required and never nil, the comparison is always %t", path, n.Op == "!=")
+			}
+		}
+		if fractional(operands[0]) {
+			c.warn(n, FloatEquality, "floating point numbers are compared for equality")
+			break
+		}
+	}
+}
+
+// chain checks a chain of && or || operations for repeated operands.
+func (c *checker) chain(n *ast.BinaryExpr) {
+	var operands []ast.Node
+	var collect func(node ast.Node)
+	collect = func(node ast.Node) {
+		if b, ok := unparen(node).(*ast.BinaryExpr); ok && b.Op == n.Op {
+			c.chained[b] = true
+			collect(b.X)
+			collect(b.Y)
+			return
+		}
+		operands = append(operands, node)
+	}
+	collect(n)
+
+	for i, operand := range operands {
+		for _, previous := range operands[:i] {
+			if same(operand, previous) {
+				c.warn(operand, RepeatedOperand, "operand of %s is repeated", n.Op)
+				break
+			}
+		}
+	}
+}
+
+func (c *checker) literal(n *ast.Literal) {
+	raw := strings.ToLower(n.Raw)
+	if !strings.HasPrefix(raw, "0x") {
+		return
+	}
+	val, err := strconv.ParseUint(raw, 0, 64)
+	switch {
+	case err != nil:
+		return // cannot be parsed on 64bit architectures either
+	case val > math.MaxUint32:
+		c.warn(n, HexLiteral, "hex literal %s cannot be parsed on 32bit architectures", n.Raw)
+	case val > math.MaxInt32:
+		c.warn(n, HexLiteral, "hex literal %s is negative on 32bit architectures", n.Raw)
+	}
+}
+
+// required reports whether the field path is required.
+func (l *Linter) required(path string) bool {
+	for _, r := range l.Required {
+		if r == path {
+			return true
+		}
+	}
+	return false
+}
+
+func unparen(node ast.Node) ast.Node {
+	for {
+		p, ok := node.(*ast.ParenExpr)
+		if !ok {
+			return node
+		}
+		node = p.X
+	}
+}
+
+// same reports whether both nodes have the same source, ignoring surrounding parentheses.
+func same(a, b ast.Node) bool {
+	x, err := ast.Source(unparen(a))
+	if err != nil {
+		return false
+	}
+	y, err := ast.Source(unparen(b))
+	return err == nil && x == y
+}
+
+// fieldPath returns the path of variable and field accesses, like "user.address.city".
+// Returns an empty string for all other nodes.
+func fieldPath(node ast.Node) string {
+	switch n := unparen(node).(type) {
+	case *ast.Ident:
+		return n.Name
+	case *ast.SelectorExpr:
+		if x := fieldPath(n.X); x != "" {
+			return x + "." + n.Sel.Name
+		}
+	case *ast.IndexExpr:
+		if lit, ok := unparen(n.Index).(*ast.Literal); ok {
+			if key, ok := lit.Value.(string); ok {
+				if x := fieldPath(n.X); x != "" {
+					return x + "." + key
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func isNil(node ast.Node) bool {
+	lit, ok := unparen(node).(*ast.Literal)
+	return ok && lit.Value == nil
+}
+
+// number returns the value of number literals, including negative ones.
+func number(node ast.Node) (float64, bool) {
+	negate := false
+	if u, ok := unparen(node).(*ast.UnaryExpr); ok && u.Op == "-" {
+		node, negate = u.X, true
+	}
+	lit, ok := unparen(node).(*ast.Literal)
+	if !ok {
+		return 0, false
+	}
+	var val float64
+	switch v := lit.Value.(type) {
+	case int:
+		val = float64(v)
+	case float64:
+		val = v
+	default:
+		return 0, false
+	}
+	if negate {
+		val = -val
+	}
+	return val, true
+}
+
+// fractional reports whether the node is a number literal with decimal places, or a division.
+func fractional(node ast.Node) bool {
+	if b, ok := unparen(node).(*ast.BinaryExpr); ok && b.Op == "/" {
+		return true
+	}
+	val, ok := number(node)
+	return ok && val != math.Trunc(val)
+}
@@ -0,0 +1,162 @@
+package logic
+
+import (
+	"fmt"
+
+	"github.com/maja42/goval/ast"
+)
+
+// maxAtoms limits the number of atoms that are analysed together.
+// All combinations of atom values are checked, which takes exponential time.
+const maxAtoms = 16
+
+// Satisfiable reports whether the formula can be true.
+// Fails if the formula has more than 16 atoms.
+func (f *Formula) Satisfiable() (bool, error) {
+	return search([]*Formula{f}, func(v []bool) bool { return v[0] })
+}
+
+// AlwaysTrue reports whether the formula is true for all values of its atoms.
+// Fails if the formula has more than 16 atoms.
+func (f *Formula) AlwaysTrue() (bool, error) {
+	found, err := search([]*Formula{f}, func(v []bool) bool { return !v[0] })
+	return !found && err == nil, err
+}
+
+// AlwaysFalse reports whether the formula is false for all values of its atoms.
+// Fails if the formula has more than 16 atoms.
+func (f *Formula) AlwaysFalse() (bool, error) {
+	found, err := f.Satisfiable()
+	return !found && err == nil, err
+}
+
+// Equivalent reports whether both formulas always have the same value.
+// Fails if the formulas have more than 16 distinct atoms.
+func Equivalent(a, b *Formula) (bool, error) {
+	found, err := search([]*Formula{a, b}, func(v []bool) bool { return v[0] != v[1] })
+	return !found && err == nil, err
+}
+
+// Implies reports whether b is true whenever a is true.
+// Fails if the formulas have more than 16 distinct atoms.
+func Implies(a, b *Formula) (bool, error) {
+	found, err := search([]*Formula{a, b}, func(v []bool) bool { return v[0] && !v[1] })
+	return !found && err == nil, err
+}
+
+// Exclusive reports whether the formulas are never true at the same time.
+// Fails if the formulas have more than 16 distinct atoms.
+func Exclusive(a, b *Formula) (bool, error) {
+	found, err := search([]*Formula{a, b}, func(v []bool) bool { return v[0] && v[1] })
+	return !found && err == nil, err
+}
+
+// Constant is a condition that always has the same value.
+type Constant struct {
+	Node  ast.Node
+	Value bool
+}
+
+// Constants returns the conditions within the expression that are always true or always false.
+//
+// Conditions are logical operations, comparisons and `in` operations. Bool literals are ignored.
+// Only the outermost constant conditions are reported. Conditions with more than 16 atoms are skipped.
+func Constants(node ast.Node) []Constant {
+	var res []Constant
+	ast.Inspect(node, func(n ast.Node) bool {
+		if !isCondition(n) {
+			return true
+		}
+		f := FromAST(n)
+		if ok, _ := f.AlwaysTrue(); ok {
+			res = append(res, Constant{Node: n, Value: true})
+			return false
+		}
+		if ok, _ := f.AlwaysFalse(); ok {
+			res = append(res, Constant{Node: n, Value: false})
+			return false
+		}
+		return true
+	})
+	return res
+}
+
+func isCondition(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.UnaryExpr:
+		return n.Op == "!"
+	case *ast.BinaryExpr:
+		switch n.Op {
+		case "&&", "||", "==", "!=", "<", "<=", ">", ">=", "in":
+			return true
+		}
+	}
+	return false
+}
+
+// search looks for atom values for which the predicate is true.
+// The predicate receives the values of the formulas.
+// Atom values that contradict each other, like `x > 5` and `x < 3` both being true, are skipped.
+func search(formulas []*Formula, predicate func(values []bool) bool) (bool, error) {
+	var atoms []*Atom
+	index := make(map[string]int)
+	for _, f := range formulas {
+		for _, atom := range f.atoms {
+			if _, ok := index[atom.Key]; !ok {
+				index[atom.Key] = len(atoms)
+				atoms = append(atoms, atom)
+			}
+		}
+	}
+	if len(atoms) > maxAtoms {
+		return false, fmt.Errorf("logic error: the expressions have %d distinct conditions, but at most %d are supported", len(atoms), maxAtoms)
+	}
+
+	constraints := make([]*constraint, len(atoms))
+	for idx, atom := range atoms {
+		constraints[idx] = parseConstraint(atom.Node)
+	}
+
+	assignment := make([]bool, len(atoms))
+	values := make([]bool, len(formulas))
+	for bits := 0; bits < 1<<len(atoms); bits++ {
+		for idx := range assignment {
+			assignment[idx] = bits&(1<<idx) != 0
+		}
+		if !consistent(constraints, assignment) {
+			continue
+		}
+		for idx, f := range formulas {
+			values[idx] = f.root.eval(index, assignment)
+		}
+		if predicate(values) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *expr) eval(index map[string]int, assignment []bool) bool {
+	switch e.op {
+	case opAtom:
+		return assignment[index[e.atom.Key]]
+	case opConst:
+		return e.value
+	case opNot:
+		return !e.args[0].eval(index, assignment)
+	case opAnd:
+		for _, arg := range e.args {
+			if !arg.eval(index, assignment) {
+				return false
+			}
+		}
+		return true
+	default: // opOr
+		for _, arg := range e.args {
+			if arg.eval(index, assignment) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,128 @@
+package logic
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+)
+
+func Test_AlwaysTrue_AlwaysFalse(t *testing.T) {
+	assertConstant := func(expected interface{}, str string) {
+		t.Helper()
+		f := parse(t, str)
+		alwaysTrue, err := f.AlwaysTrue()
+		assert.NoError(t, err)
+		alwaysFalse, err := f.AlwaysFalse()
+		assert.NoError(t, err)
+		switch expected {
+		case true:
+			assert.True(t, alwaysTrue && !alwaysFalse, "expression: %s", str)
+		case false:
+			assert.True(t, !alwaysTrue && alwaysFalse, "expression: %s", str)
+		default:
+			assert.True(t, !alwaysTrue && !alwaysFalse, "expression: %s", str)
+		}
+	}
+	assertConstant(nil, "a")
+	assertConstant(nil, "a && b || c")
+	assertConstant(true, "a || !a")
+	assertConstant(false, "a && !(a)")
+	assertConstant(true, "true")
+	assertConstant(false, "(a || b) && !a && !b")
+
+	// numeric ranges:
+	assertConstant(false, "x > 5 && x < 3")
+	assertConstant(false, "x > 5 && 5 >= x")
+	assertConstant(false, "x >= 5 && x < 5")
+	assertConstant(nil, "x >= 5 && x <= 5")
+	assertConstant(false, "x >= 5 && x <= 5 && x != 5.0")
+	assertConstant(nil, "x > 5 && x < 6") // not restricted to integers
+	assertConstant(true, "x > 5 || x <= 5")
+	assertConstant(true, "x > 5 || x < 7")
+	assertConstant(nil, "x > 5 || y < 7")
+	assertConstant(nil, "x > 5 || x + 1 < 7")
+	assertConstant(false, "(x) > -1 && x < -1")
+	assertConstant(nil, `x > 5 || x < "a"`) // not a constraint
+
+	// equality and membership:
+	assertConstant(false, `x == "a" && x == "b"`)
+	assertConstant(false, `x == "a" && x != "a"`)
+	assertConstant(nil, `x != "a" && x != "b"`)
+	assertConstant(false, `x == nil && x > 0`)
+	assertConstant(false, `x == 1 && x in [2, 3]`)
+	assertConstant(nil, `x == 2 && x in [2, 3]`)
+	assertConstant(false, `x in [1, 2] && x > 2`)
+	assertConstant(false, `x in [1, 2] && !(x in [1, 2.0])`)
+	assertConstant(false, `x in []`)
+	assertConstant(nil, `x in [1, y]`)
+	assertConstant(true, `x != 1 || x != 2`)
+}
+
+func Test_Equivalent_Implies(t *testing.T) {
+	assertRelation := func(equivalent, implies, exclusive bool, a, b string) {
+		t.Helper()
+		fa, fb := parse(t, a), parse(t, b)
+		res, err := Equivalent(fa, fb)
+		assert.NoError(t, err)
+		assert.Equal(t, equivalent, res, "equivalent: %s, %s", a, b)
+		res, err = Implies(fa, fb)
+		assert.NoError(t, err)
+		assert.Equal(t, implies, res, "implies: %s, %s", a, b)
+		res, err = Exclusive(fa, fb)
+		assert.NoError(t, err)
+		assert.Equal(t, exclusive, res, "exclusive: %s, %s", a, b)
+	}
+	assertRelation(true, true, false, "a && b", "b && a")
+	assertRelation(true, true, false, "!(a || b)", "!a && !b")
+	assertRelation(true, true, false, "a && (b || c)", "a && b || a && c")
+	assertRelation(false, true, false, "a && b", "a")
+	assertRelation(false, false, false, "a", "a && b")
+	assertRelation(false, false, true, "a && b", "!a")
+	assertRelation(true, true, false, "x > 5", "5 < x")
+	assertRelation(true, true, false, "!(x > 5)", "x <= 5")
+	assertRelation(false, true, false, "x > 5", "x >= 5")
+	assertRelation(false, true, false, "x == 6", "x > 5")
+	assertRelation(false, false, true, "x < 18", "x >= 18")
+	assertRelation(false, true, false, `x in ["AT", "DE"]`, `x == "AT" || x == "DE" || x == "CH"`)
+	assertRelation(true, true, false, `x in ["AT", "DE"]`, `x == "DE" || x == "AT"`)
+	assertRelation(false, false, true, `x == "AT"`, `country == "DE" && x == "DE"`)
+
+	atoms := make([]string, 17)
+	for i := range atoms {
+		atoms[i] = fmt.Sprintf("a%d", i)
+	}
+	_, err := Equivalent(parse(t, strings.Join(atoms[:9], " && ")), parse(t, strings.Join(atoms[8:], " || ")))
+	assert.EqualError(t, err, "logic error: the expressions have 17 distinct conditions, but at most 16 are supported")
+}
+
+func Test_Constants(t *testing.T) {
+	assertConstants := func(expected []string, str string) {
+		t.Helper()
+		program, err := goval.Parse(str)
+		if !assert.NoError(t, err) {
+			return
+		}
+		var found []string
+		for _, c := range Constants(program.Root) {
+			src, _ := ast.Source(c.Node)
+			found = append(found, fmt.Sprintf("%s: %v", src, c.Value))
+		}
+		assert.Equal(t, expected, found, "expression: %s", str)
+	}
+	assertConstants(nil, "a && b || c")
+	assertConstants([]string{"a && b || true: true"}, "a && b || true")
+	assertConstants([]string{"x > 5 && x < 3: false"}, "x > 5 && x < 3")
+	assertConstants([]string{"x > 5 && x < 3: false"}, "a || (x > 5 && x < 3)")
+	assertConstants([]string{"a || !a: true", "c && (b && !b): false"}, "(a || !a) && b || c && (b && !b)")
+	assertConstants([]string{"(a || !a) && (b && !b): false"}, "(a || !a) && (b && !b)") // outermost only
+	assertConstants([]string{"x == 1 && x == 2: false", "y > 0 || y <= 0: true"}, "f(x == 1 && x == 2) ? [y > 0 || y <= 0] : z")
+	assertConstants([]string{"!(x in []): true"}, "!(x in [])")
+	assertConstants([]string{"1 == 1: true"}, "1 == 1")
+	assertConstants([]string{"5 < 3: false"}, "5 < 3")
+	assertConstants([]string{"-1 < 0.5 && \"a\" != \"b\": true", "2 in [1, 2.0]: true"}, "x && (-1 < 0.5 && \"a\" != \"b\") ? 2 in [1, 2.0] : 1 in [x]")
+}
@@ -0,0 +1,293 @@
+package logic
+
+import (
+	"math"
+
+	"github.com/maja42/goval/ast"
+)
+
+// constraint restricts the value of an expression (the subject) if an atom is true.
+// Atoms that don't compare an expression with literals have no constraint.
+type constraint struct {
+	subject string        // source of the restricted expression
+	op      string        // ==, !=, <, <=, >, >=, in or !in
+	values  []interface{} // one literal; multiple ones for `in`
+}
+
+// negated contains the operators of negated constraints.
+var negated = map[string]string{
+	"==": "!=",
+	"!=": "==",
+	"<":  ">=",
+	"<=": ">",
+	">":  "<=",
+	">=": "<",
+	"in": "!in",
+}
+
+// parseConstraint returns the constraint of an atom, or nil.
+func parseConstraint(node ast.Node) *constraint {
+	b, ok := node.(*ast.BinaryExpr)
+	if !ok {
+		return nil
+	}
+	subject, op := b.X, b.Op
+	var values []interface{}
+
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		val, ok := literalValue(b.Y)
+		if !ok {
+			if val, ok = literalValue(b.X); !ok {
+				return nil
+			}
+			subject, op = b.Y, mirrored[op]
+		}
+		if _, ok := number(val); !ok && op != "==" && op != "!=" {
+			return nil // other types cannot be ordered; the comparison always fails
+		}
+		values = []interface{}{val}
+	case "in":
+		arr, ok := unparen(b.Y).(*ast.ArrayLit)
+		if !ok {
+			return nil
+		}
+		for _, elem := range arr.Elems {
+			val, ok := literalValue(elem)
+			if !ok {
+				return nil
+			}
+			values = append(values, val)
+		}
+	default:
+		return nil
+	}
+
+	if _, ok := literalValue(subject); ok {
+		return nil // the atom is constant
+	}
+	src, err := ast.Source(unparen(subject))
+	if err != nil {
+		return nil
+	}
+	return &constraint{subject: src, op: op, values: values}
+}
+
+// constantValue returns the result of comparisons and `in` operations that only contain literals.
+func constantValue(b *ast.BinaryExpr) (bool, bool) {
+	x, ok := literalValue(b.X)
+	if !ok {
+		return false, false
+	}
+	if b.Op == "in" {
+		arr, ok := unparen(b.Y).(*ast.ArrayLit)
+		if !ok {
+			return false, false
+		}
+		found := false
+		for _, elem := range arr.Elems {
+			val, ok := literalValue(elem)
+			if !ok {
+				return false, false
+			}
+			found = found || equal(x, val)
+		}
+		return found, true
+	}
+
+	y, ok := literalValue(b.Y)
+	if !ok {
+		return false, false
+	}
+	switch b.Op {
+	case "==":
+		return equal(x, y), true
+	case "!=":
+		return !equal(x, y), true
+	}
+	a, okX := number(x)
+	c, okY := number(y)
+	if !okX || !okY {
+		return false, false // only numbers can be ordered
+	}
+	switch b.Op {
+	case "<":
+		return a < c, true
+	case "<=":
+		return a <= c, true
+	case ">":
+		return a > c, true
+	case ">=":
+		return a >= c, true
+	}
+	return false, false
+}
+
+// literalValue returns the value of nil, bool, number and string literals, including negative numbers.
+func literalValue(node ast.Node) (interface{}, bool) {
+	switch n := unparen(node).(type) {
+	case *ast.Literal:
+		return n.Value, true
+	case *ast.UnaryExpr:
+		if n.Op != "-" {
+			return nil, false
+		}
+		switch v := unparen(n.X).(type) {
+		case *ast.Literal:
+			switch val := v.Value.(type) {
+			case int:
+				return -val, true
+			case float64:
+				return -val, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// number returns the value of ints and floats, excluding NaN.
+func number(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v)
+	}
+	return 0, false
+}
+
+// equal compares literal values the same way as the == operator.
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return a == b
+}
+
+// domain contains the possible values of a subject.
+type domain struct {
+	numeric    bool // only numbers are possible
+	lower      bound
+	upper      bound
+	restricted bool          // only the allowed values are possible
+	allowed    []interface{} // if restricted
+	excluded   []interface{}
+}
+
+type bound struct {
+	set    bool
+	value  float64
+	strict bool
+}
+
+// consistent reports whether the atoms can have the given values at the same time.
+func consistent(constraints []*constraint, assignment []bool) bool {
+	domains := make(map[string]*domain)
+	for idx, c := range constraints {
+		if c == nil {
+			continue
+		}
+		d := domains[c.subject]
+		if d == nil {
+			d = &domain{}
+			domains[c.subject] = d
+		}
+		op := c.op
+		if !assignment[idx] {
+			op = negated[op]
+		}
+		d.apply(op, c.values)
+	}
+	for _, d := range domains {
+		if !d.possible() {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *domain) apply(op string, values []interface{}) {
+	switch op {
+	case "==", "in":
+		d.restrict(values)
+	case "!=", "!in":
+		d.excluded = append(d.excluded, values...)
+	default:
+		d.numeric = true
+		val, _ := number(values[0])
+		switch op {
+		case "<", "<=":
+			strict := op == "<"
+			if !d.upper.set || val < d.upper.value || (val == d.upper.value && strict) {
+				d.upper = bound{set: true, value: val, strict: strict}
+			}
+		case ">", ">=":
+			strict := op == ">"
+			if !d.lower.set || val > d.lower.value || (val == d.lower.value && strict) {
+				d.lower = bound{set: true, value: val, strict: strict}
+			}
+		}
+	}
+}
+
+// restrict removes all values that are not contained within the given ones.
+func (d *domain) restrict(values []interface{}) {
+	if !d.restricted {
+		d.restricted = true
+		d.allowed = values
+		return
+	}
+	var allowed []interface{}
+	for _, a := range d.allowed {
+		if contains(values, a) {
+			allowed = append(allowed, a)
+		}
+	}
+	d.allowed = allowed
+}
+
+// possible reports whether the domain contains at least one value.
+func (d *domain) possible() bool {
+	if d.restricted {
+		for _, val := range d.allowed {
+			if !contains(d.excluded, val) && d.inBounds(val) {
+				return true
+			}
+		}
+		return false
+	}
+	if !d.lower.set || !d.upper.set {
+		return true // infinitely many values, only a finite number of them can be excluded
+	}
+	if d.lower.value != d.upper.value {
+		return d.lower.value < d.upper.value
+	}
+	return !d.lower.strict && !d.upper.strict && !contains(d.excluded, d.lower.value)
+}
+
+func (d *domain) inBounds(val interface{}) bool {
+	if !d.numeric {
+		return true
+	}
+	n, ok := number(val)
+	if !ok {
+		return false
+	}
+	if d.lower.set && (n < d.lower.value || (n == d.lower.value && d.lower.strict)) {
+		return false
+	}
+	if d.upper.set && (n > d.upper.value || (n == d.upper.value && d.upper.strict)) {
+		return false
+	}
+	return true
+}
+
+func contains(values []interface{}, val interface{}) bool {
+	for _, v := range values {
+		if equal(v, val) {
+			return true
+		}
+	}
+	return false
+}
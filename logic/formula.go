@@ -0,0 +1,167 @@
+// Package logic analyses the boolean structure of expressions.
+//
+// An expression is split into atoms, which are combined by &&, || and !.
+// Atoms are comparisons, `in` operations and all other operands of logical operators, like variables or function calls.
+// Atoms are identified by their source. Bool literals and comparisons between literals, like `1 == 1`, are constants.
+//
+// Formulas can be converted into conjunctive and disjunctive normal form,
+// checked for logical equivalence and implication, and searched for conditions that are always true or always false.
+//
+// Atoms are not fully opaque. Comparisons between an expression and a literal, like `x > 5`, `x == "DE"` or `x in [1, 2]`,
+// restrict the values of their expression. This allows detecting that `x > 5 && x < 3` is always false,
+// or that `x > 5` implies `x >= 5`. All other atoms can be true or false independently of each other.
+//
+// The analysis assumes that the evaluation succeeds.
+// If `x > 5` is false, x is assumed to be a number that is less than or equal to 5, and not a string or NaN, which would cause an error.
+// Since && and || do not short-circuit within goval, this holds for all atoms of the expression.
+package logic
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+)
+
+// Atom is a condition that is not split any further.
+type Atom struct {
+	Node ast.Node // the condition, without surrounding parentheses
+	Key  string   // normalized source of the condition; atoms with the same key are considered equal
+}
+
+// Formula is the boolean structure of an expression.
+// Immutable. Can be used concurrently.
+type Formula struct {
+	root  *expr
+	atoms []*Atom // unique, in order of appearance
+}
+
+type op int
+
+const (
+	opAtom op = iota
+	opConst
+	opNot
+	opAnd
+	opOr
+)
+
+// expr is a node of a formula.
+type expr struct {
+	op    op
+	atom  *Atom // opAtom
+	value bool  // opConst
+	args  []*expr
+}
+
+// Parse parses the expression and returns its boolean structure.
+func Parse(str string) (*Formula, error) {
+	program, err := goval.Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	return FromAST(program.Root), nil
+}
+
+// FromAST returns the boolean structure of a parsed expression.
+func FromAST(node ast.Node) *Formula {
+	f := &Formula{}
+	f.root = f.convert(node, make(map[string]*Atom))
+	return f
+}
+
+// Atoms returns the atoms of the formula, in order of their first appearance.
+func (f *Formula) Atoms() []*Atom {
+	return f.atoms
+}
+
+func (f *Formula) convert(node ast.Node, atoms map[string]*Atom) *expr {
+	node = unparen(node)
+	switch n := node.(type) {
+	case *ast.Literal:
+		if b, ok := n.Value.(bool); ok {
+			return &expr{op: opConst, value: b}
+		}
+	case *ast.UnaryExpr:
+		if n.Op == "!" {
+			return &expr{op: opNot, args: []*expr{f.convert(n.X, atoms)}}
+		}
+	case *ast.BinaryExpr:
+		if n.Op == "&&" || n.Op == "||" {
+			e := &expr{op: opAnd}
+			if n.Op == "||" {
+				e.op = opOr
+			}
+			for _, operand := range []ast.Node{n.X, n.Y} {
+				arg := f.convert(operand, atoms)
+				if arg.op == e.op { // flatten
+					e.args = append(e.args, arg.args...)
+				} else {
+					e.args = append(e.args, arg)
+				}
+			}
+			return e
+		}
+		if val, ok := constantValue(n); ok {
+			return &expr{op: opConst, value: val}
+		}
+	}
+
+	key := atomKey(node)
+	atom, ok := atoms[key]
+	if !ok {
+		atom = &Atom{Node: node, Key: key}
+		atoms[key] = atom
+		f.atoms = append(f.atoms, atom)
+	}
+	return &expr{op: opAtom, atom: atom}
+}
+
+func unparen(node ast.Node) ast.Node {
+	for {
+		p, ok := node.(*ast.ParenExpr)
+		if !ok {
+			return node
+		}
+		node = p.X
+	}
+}
+
+// mirrored contains the comparison operators that are used when swapping their operands.
+var mirrored = map[string]string{
+	"==": "==",
+	"!=": "!=",
+	"<":  ">",
+	"<=": ">=",
+	">":  "<",
+	">=": "<=",
+}
+
+// atomKey returns the source of the atom.
+// Comparisons with the literal on the left side are swapped, so that `5 < x` and `x > 5` are the same atom.
+func atomKey(node ast.Node) string {
+	if b, ok := node.(*ast.BinaryExpr); ok {
+		_, litX := literalValue(b.X)
+		_, litY := literalValue(b.Y)
+		if op, ok := mirrored[b.Op]; ok && litX && !litY {
+			node = &ast.BinaryExpr{X: b.Y, Op: op, Y: b.X}
+		}
+	}
+	src, err := ast.Source(node)
+	if err != nil { // like NaN literals; the atom is not equal to any other atom
+		return fmt.Sprintf("%p", node)
+	}
+	return src
+}
+
+// recoverError converts panics into errors.
+// Runtime errors are bugs, and therefore not recovered.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		if _, ok := r.(runtime.Error); ok {
+			panic(r)
+		}
+		*err = r.(error)
+	}
+}
@@ -0,0 +1,285 @@
+package logic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maja42/goval/ast"
+)
+
+// Term is an atom or its negation.
+type Term struct {
+	Atom    *Atom
+	Negated bool
+}
+
+// Node returns the term as a syntax tree.
+// Negated equality comparisons are inverted, all other negated terms are wrapped into a `!` operation.
+func (t Term) Node() ast.Node {
+	if !t.Negated {
+		return t.Atom.Node
+	}
+	if b, ok := t.Atom.Node.(*ast.BinaryExpr); ok && (b.Op == "==" || b.Op == "!=") {
+		return &ast.BinaryExpr{X: b.X, OpPos: b.OpPos, Op: negated[b.Op], Y: b.Y}
+	}
+	return &ast.UnaryExpr{Op: "!", X: t.Atom.Node}
+}
+
+// Clause is a set of terms.
+// The terms are combined by || within conjunctive normal forms, and by && within disjunctive normal forms.
+type Clause []Term
+
+// CNF is a conjunctive normal form: clauses of terms combined by ||, which are combined by &&.
+// An empty CNF is always true. A CNF containing an empty clause is always false.
+type CNF []Clause
+
+// DNF is a disjunctive normal form: clauses of terms combined by &&, which are combined by ||.
+// An empty DNF is always false. A DNF containing an empty clause is always true.
+type DNF []Clause
+
+// Node returns the normal form as a syntax tree.
+func (c CNF) Node() ast.Node {
+	return normalNode(c, "&&", "||")
+}
+
+// String returns the source of the normal form, like `(a || !b) && c`.
+func (c CNF) String() string {
+	src, _ := ast.Source(c.Node())
+	return src
+}
+
+// Node returns the normal form as a syntax tree.
+func (d DNF) Node() ast.Node {
+	return normalNode(d, "||", "&&")
+}
+
+// String returns the source of the normal form, like `a && !b || c`.
+func (d DNF) String() string {
+	src, _ := ast.Source(d.Node())
+	return src
+}
+
+func normalNode(clauses []Clause, outer, inner string) ast.Node {
+	join := func(op string, nodes []ast.Node, empty bool) ast.Node {
+		if len(nodes) == 0 {
+			return &ast.Literal{Value: empty}
+		}
+		node := nodes[0]
+		for _, n := range nodes[1:] {
+			node = &ast.BinaryExpr{X: node, Op: op, Y: n}
+		}
+		return node
+	}
+	nodes := make([]ast.Node, len(clauses))
+	for i, clause := range clauses {
+		terms := make([]ast.Node, len(clause))
+		for j, term := range clause {
+			terms[j] = term.Node()
+		}
+		nodes[i] = join(inner, terms, inner == "&&")
+	}
+	return join(outer, nodes, outer == "&&")
+}
+
+// maxClauses limits the size of normal forms, which can grow exponentially.
+const maxClauses = 10000
+
+// CNF converts the formula into conjunctive normal form.
+//
+// Constants are removed, duplicate terms and clauses are merged,
+// and clauses that are always true (`a || !a`) or implied by smaller clauses are dropped.
+// Clauses that only differ in the negation of a single term are merged: `(a || b) && (a || !b)` becomes `a`.
+// Fails if the result would have more than 10000 clauses.
+func (f *Formula) CNF() (cnf CNF, err error) {
+	defer recoverError(&err)
+	return merge(normalize(f.root, true, false)), nil
+}
+
+// DNF converts the formula into disjunctive normal form.
+//
+// Constants are removed, duplicate terms and clauses are merged,
+// and clauses that are always false (`a && !a`) or that imply smaller clauses are dropped.
+// Clauses that only differ in the negation of a single term are merged: `a && b || a && !b` becomes `a`.
+// Fails if the result would have more than 10000 clauses.
+func (f *Formula) DNF() (dnf DNF, err error) {
+	defer recoverError(&err)
+	return merge(normalize(f.root, false, false)), nil
+}
+
+// normalize converts the expression into a list of clauses.
+// If cnf is true, the clauses are combined by && (and their terms by ||), otherwise the other way round.
+// Negations are pushed down to the atoms.
+func normalize(e *expr, cnf bool, negate bool) []Clause {
+	switch e.op {
+	case opAtom:
+		return []Clause{{Term{Atom: e.atom, Negated: negate}}}
+	case opConst:
+		if (e.value != negate) == cnf {
+			return nil // neutral element of the outer operation
+		}
+		return []Clause{{}}
+	case opNot:
+		return normalize(e.args[0], cnf, !negate)
+	}
+
+	and := (e.op == opAnd) != negate // De Morgan: negating && results in ||, and the other way round
+	outer := and == cnf              // the operation that combines clauses, which only requires concatenation
+	var res []Clause
+	for idx, arg := range e.args {
+		clauses := normalize(arg, cnf, negate)
+		if outer || idx == 0 {
+			res = append(res, clauses...)
+		} else {
+			res = distribute(res, clauses)
+		}
+		res = simplify(res)
+		if len(res) > maxClauses {
+			panic(fmt.Errorf("logic error: the normal form has more than %d clauses", maxClauses))
+		}
+	}
+	return res
+}
+
+// distribute combines each clause of a with each clause of b.
+func distribute(a, b []Clause) []Clause {
+	if len(a)*len(b) > maxClauses {
+		panic(fmt.Errorf("logic error: the normal form has more than %d clauses", maxClauses))
+	}
+	res := make([]Clause, 0, len(a)*len(b))
+	for _, x := range a {
+		for _, y := range b {
+			clause := make(Clause, 0, len(x)+len(y))
+			clause = append(clause, x...)
+			res = append(res, append(clause, y...))
+		}
+	}
+	return res
+}
+
+// simplify removes duplicate terms, clauses containing both an atom and its negation, duplicate clauses and subsumed clauses.
+// A clause is subsumed by another clause if it contains all of its terms.
+func simplify(clauses []Clause) []Clause {
+	var res []Clause
+	seen := make(map[string]struct{})
+	for _, clause := range clauses {
+		clause, ok := dedupe(clause)
+		if !ok {
+			continue
+		}
+		key := clause.key()
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			res = append(res, clause)
+		}
+	}
+
+	var kept []Clause
+	for _, clause := range res {
+		subsumed := false
+		for _, other := range res {
+			if len(other) < len(clause) && subset(other, clause) {
+				subsumed = true
+				break
+			}
+		}
+		if !subsumed {
+			kept = append(kept, clause)
+		}
+	}
+	return kept
+}
+
+// key identifies the clause, independent of the order of its terms.
+func (c Clause) key() string {
+	terms := make([]string, len(c))
+	for idx, term := range c {
+		terms[idx] = term.Atom.Key
+		if term.Negated {
+			terms[idx] = "!" + terms[idx]
+		}
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, "\x00")
+}
+
+// merge repeatedly merges pairs of clauses that only differ in the negation of a single term.
+func merge(clauses []Clause) []Clause {
+	for {
+		merged := false
+		for i := 0; i < len(clauses) && !merged; i++ {
+			for j := i + 1; j < len(clauses) && !merged; j++ {
+				if clause, ok := resolve(clauses[i], clauses[j]); ok {
+					clauses = append(clauses[:j:j], clauses[j+1:]...)
+					clauses[i] = clause
+					merged = true
+				}
+			}
+		}
+		if !merged {
+			return clauses
+		}
+		clauses = simplify(clauses)
+	}
+}
+
+// resolve returns the clause without the term whose negation is the only difference between both clauses.
+func resolve(a, b Clause) (Clause, bool) {
+	if len(a) != len(b) {
+		return nil, false
+	}
+	diff := -1
+	for idx, term := range a {
+		other := indexOf(b, term.Atom)
+		if other < 0 {
+			return nil, false
+		}
+		if b[other].Negated != term.Negated {
+			if diff >= 0 {
+				return nil, false
+			}
+			diff = idx
+		}
+	}
+	if diff < 0 {
+		return nil, false
+	}
+	res := make(Clause, 0, len(a)-1)
+	res = append(res, a[:diff]...)
+	return append(res, a[diff+1:]...), true
+}
+
+// dedupe removes duplicate terms.
+// Returns false if the clause contains both an atom and its negation.
+func dedupe(clause Clause) (Clause, bool) {
+	res := make(Clause, 0, len(clause))
+	for _, term := range clause {
+		if idx := indexOf(res, term.Atom); idx >= 0 {
+			if res[idx].Negated != term.Negated {
+				return nil, false
+			}
+			continue
+		}
+		res = append(res, term)
+	}
+	return res, true
+}
+
+func indexOf(clause Clause, atom *Atom) int {
+	for idx, term := range clause {
+		if term.Atom == atom {
+			return idx
+		}
+	}
+	return -1
+}
+
+// subset reports whether all terms of a are contained within b.
+func subset(a, b Clause) bool {
+	for _, term := range a {
+		if idx := indexOf(b, term.Atom); idx < 0 || b[idx].Negated != term.Negated {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package logic
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, str string) *Formula {
+	t.Helper()
+	f, err := Parse(str)
+	if !assert.NoError(t, err, "expression: %s", str) {
+		t.FailNow()
+	}
+	return f
+}
+
+func Test_Atoms(t *testing.T) {
+	f := parse(t, `(x > 5 && f(a || b)) || !(5 < x) || y.active && "DE" == country || x > 5`)
+	var keys []string
+	for _, atom := range f.Atoms() {
+		keys = append(keys, atom.Key)
+	}
+	assert.Equal(t, []string{"x > 5", "f(a || b)", "y.active", `country == "DE"`}, keys)
+	assert.Equal(t, 11, f.Atoms()[1].Node.Pos())
+}
+
+func Test_CNF(t *testing.T) {
+	assertCNF := func(expected string, str string) {
+		t.Helper()
+		cnf, err := parse(t, str).CNF()
+		if assert.NoError(t, err, "expression: %s", str) {
+			assert.Equal(t, expected, cnf.String(), "expression: %s", str)
+		}
+	}
+	assertCNF("a", "a")
+	assertCNF("a && b && c", "a && (b && c)")
+	assertCNF("(a || c) && (b || c)", "a && b || c")
+	assertCNF("(a || c) && (a || d) && (b || c) && (b || d)", "a && b || c && d")
+	assertCNF("(!a || !b) && c", "!(a && b) && c")
+	assertCNF("!a && !b", "!(a || b)")
+	assertCNF("a", "!!a")
+	assertCNF("!(x > 5) && x != 1", "!(x > 5 || x == 1) && !(x == 1 && x == 2) || false && true")
+
+	// simplification:
+	assertCNF("a", "a && a || a")
+	assertCNF("a", "a && (a || b)")
+	assertCNF("true", "a || !a")
+	assertCNF("false", "a && !a")
+	assertCNF("true", "true || a")
+	assertCNF("a", "a || false")
+	assertCNF("false", "!true")
+	assertCNF("b", "(a || b) && (!a || b)")
+}
+
+func Test_DNF(t *testing.T) {
+	assertDNF := func(expected string, str string) {
+		t.Helper()
+		dnf, err := parse(t, str).DNF()
+		if assert.NoError(t, err, "expression: %s", str) {
+			assert.Equal(t, expected, dnf.String(), "expression: %s", str)
+		}
+	}
+	assertDNF("a", "a")
+	assertDNF("a && c || b && c", "(a || b) && c")
+	assertDNF("a && c || a && d || b && c || b && d", "(a || b) && (c || d)")
+	assertDNF("!a && !b || c", "!(a || b) || c")
+	assertDNF("x in [1, 2] && !f(x)", "!(!(x in [1, 2]) || f(x))")
+
+	// simplification:
+	assertDNF("a", "a || a && b")
+	assertDNF("false", "a && !a")
+	assertDNF("true", "a || !a")
+	assertDNF("a && b", "(a || !a) && a && b")
+	assertDNF("b", "a && b || !a && b || b")
+}
+
+func Test_NormalForm_Limit(t *testing.T) {
+	clauses := make([]string, 14)
+	for i := range clauses {
+		clauses[i] = fmt.Sprintf("(a%d || b%d)", i, i)
+	}
+	f := parse(t, strings.Join(clauses, " && "))
+	_, err := f.CNF()
+	assert.NoError(t, err)
+	_, err = f.DNF()
+	assert.EqualError(t, err, "logic error: the normal form has more than 10000 clauses")
+}
@@ -0,0 +1,63 @@
+package goval
+
+import (
+	"github.com/maja42/goval/internal"
+)
+
+// Number is an int, float64 or bool. It is used by numeric expressions to avoid boxing values into interfaces.
+// The zero value is the int 0.
+type Number = internal.Number
+
+// Int returns an int Number.
+func Int(i int) Number {
+	return internal.Int(i)
+}
+
+// Float returns a float64 Number.
+func Float(f float64) Number {
+	return internal.Float(f)
+}
+
+// Bool returns a bool Number.
+func Bool(b bool) Number {
+	return internal.Bool(b)
+}
+
+// NumberOf converts an int, float64 or bool into a Number.
+func NumberOf(val interface{}) (Number, error) {
+	return internal.NumberOf(val)
+}
+
+// CompileNumeric compiles an expression that only operates on numbers and bools,
+// like `(celsius * 1.8) + 32` or `x > threshold && y < limit`.
+//
+// The expression can only access the declared variables. Their values are passed to Evaluate in the same order.
+// Strings, arrays, objects, member access, function calls and the `in` operator are not supported.
+func CompileNumeric(str string, variables ...string) (*NumericExpression, error) {
+	expr, err := internal.CompileNumeric(str, variables)
+	if err != nil {
+		return nil, err
+	}
+	return &NumericExpression{expr: expr}, nil
+}
+
+// NumericExpression is a compiled expression that only operates on numbers and bools.
+//
+// Evaluations work on unboxed values and don't allocate memory, unless an error occurs.
+// Immutable. Can be evaluated concurrently.
+type NumericExpression struct {
+	expr *internal.NumericExpression
+}
+
+// Variables returns the names of the declared variables, in the order they need to be passed to Evaluate.
+func (x *NumericExpression) Variables() []string {
+	return x.expr.Variables()
+}
+
+// Evaluate the numeric expression.
+//
+// Accepts the values of all declared variables, in the order they were declared.
+// Results and errors are identical to Evaluator.Evaluate.
+func (x *NumericExpression) Evaluate(variables ...Number) (result Number, err error) {
+	return x.expr.Evaluate(variables)
+}
@@ -0,0 +1,31 @@
+package goval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CompileNumeric(t *testing.T) {
+	expr, err := CompileNumeric(`x > threshold && y < limit`, "x", "y", "threshold", "limit")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"x", "y", "threshold", "limit"}, expr.Variables())
+
+	result, err := expr.Evaluate(Float(10.5), Int(3), Int(10), Float(5))
+	assert.NoError(t, err)
+	assert.True(t, result.IsBool())
+	assert.True(t, result.Bool())
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = expr.Evaluate(Float(9.5), Int(3), Int(10), Float(5))
+	})
+	assert.Zero(t, allocs)
+
+	_, err = expr.Evaluate(Float(10.5), Bool(true), Int(10), Float(5))
+	assert.EqualError(t, err, "type error: cannot compare type bool and number")
+
+	_, err = CompileNumeric(`x + "°F"`, "x")
+	assert.EqualError(t, err, "syntax error: string literals are not supported within numeric expressions")
+}
@@ -0,0 +1,75 @@
+package goval
+
+// Option configures an Evaluator.
+type Option func(e *Evaluator)
+
+// WithCache enables a cache for the given number of expressions.
+//
+// Evaluating an expression string that is already cached skips parsing it.
+// If the cache is full, the least recently used expression is replaced.
+// A size of zero or below disables the cache.
+func WithCache(size int) Option {
+	return func(e *Evaluator) {
+		if size <= 0 {
+			e.cache = nil
+			return
+		}
+		e.cache = newExpressionCache(size)
+	}
+}
+
+// WithVariables registers default variables, which are accessible from within all expressions.
+//
+// Variables passed to Evaluate shadow default variables with the same name, unless strict mode is enabled (see WithStrictMode).
+// The map is copied. Its values are not, and need to be treated as read-only if the evaluator is used concurrently.
+// Can be used multiple times; later variables replace earlier ones with the same name.
+func WithVariables(variables map[string]interface{}) Option {
+	return func(e *Evaluator) {
+		if e.variables == nil {
+			e.variables = make(map[string]interface{}, len(variables))
+		}
+		for name, val := range variables {
+			e.variables[name] = val
+		}
+	}
+}
+
+// WithFunctions registers default functions, which can be called from within all expressions.
+//
+// Functions passed to Evaluate shadow default functions with the same name, unless strict mode is enabled (see WithStrictMode).
+// The map is copied. Functions need to be safe for concurrent use if the evaluator is used concurrently.
+// Can be used multiple times; later functions replace earlier ones with the same name.
+func WithFunctions(functions map[string]ExpressionFunction) Option {
+	return func(e *Evaluator) {
+		if e.functions == nil {
+			e.functions = make(map[string]ExpressionFunction, len(functions))
+		}
+		for name, f := range functions {
+			e.functions[name] = f
+		}
+	}
+}
+
+// WithStrictMode forbids shadowing.
+// Evaluations fail if a variable or function passed to Evaluate has the same name as a default variable or function.
+func WithStrictMode() Option {
+	return func(e *Evaluator) {
+		e.strict = true
+	}
+}
+
+// Limits restrict the resources used by evaluations, for example to evaluate untrusted expressions.
+// Zero values mean unlimited.
+type Limits struct {
+	MaxLength int // Maximum length of expression strings, in bytes
+	MaxDepth  int // Maximum nesting depth of expressions; each operator, literal and variable is one level
+	MaxSteps  int // Maximum number of steps per evaluation; bounds the work done by comprehensions, but not by expression functions
+}
+
+// WithLimits restricts the resources used by evaluations.
+// Evaluations that exceed a limit fail with a `limit error`.
+func WithLimits(limits Limits) Option {
+	return func(e *Evaluator) {
+		e.limits = limits
+	}
+}
@@ -0,0 +1,101 @@
+package goval
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithVariables(t *testing.T) {
+	defaults := map[string]interface{}{"x": 1, "y": 2}
+	eval := NewEvaluator(WithVariables(defaults), WithVariables(map[string]interface{}{"y": 3}))
+	defaults["x"] = 100 // copied
+
+	result, err := eval.Evaluate(`x + y`, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, result)
+
+	result, err = eval.Evaluate(`x + y`, map[string]interface{}{"x": 10}, nil) // shadows default
+	assert.NoError(t, err)
+	assert.Equal(t, 13, result)
+
+	result, err = eval.Evaluate(`[x for x in [y]]`, map[string]interface{}{"x": 10}, nil) // loop variables shadow everything
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{3}, result)
+
+	_, err = eval.Evaluate(`z`, map[string]interface{}{"x": 10}, nil)
+	assert.EqualError(t, err, "var error: variable \"z\" does not exist")
+}
+
+func Test_WithFunctions(t *testing.T) {
+	constant := func(val interface{}) ExpressionFunction {
+		return func(args ...interface{}) (interface{}, error) {
+			return val, nil
+		}
+	}
+	eval := NewEvaluator(WithFunctions(map[string]ExpressionFunction{
+		"a": constant("default a"),
+		"b": constant("default b"),
+	}))
+
+	result, err := eval.Evaluate(`[a(), b()]`, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"default a", "default b"}, result)
+
+	result, err = eval.Evaluate(`[a(), b(), c()]`, nil, map[string]ExpressionFunction{
+		"b": constant("custom b"),
+		"c": constant("custom c"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"default a", "custom b", "custom c"}, result)
+
+	_, err = eval.Evaluate(`c()`, nil, nil)
+	assert.EqualError(t, err, "syntax error: no such function \"c\"")
+}
+
+func Test_WithStrictMode(t *testing.T) {
+	eval := NewEvaluator(
+		WithVariables(map[string]interface{}{"x": 1}),
+		WithFunctions(map[string]ExpressionFunction{"f": func(args ...interface{}) (interface{}, error) { return 2, nil }}),
+		WithStrictMode(),
+	)
+
+	result, err := eval.Evaluate(`x + y + f()`, map[string]interface{}{"y": 3}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, result)
+
+	_, err = eval.Evaluate(`x`, map[string]interface{}{"x": 3}, nil)
+	assert.EqualError(t, err, "var error: variable \"x\" shadows a default variable")
+
+	_, err = eval.Evaluate(`x`, nil, map[string]ExpressionFunction{"f": nil})
+	assert.EqualError(t, err, "syntax error: function \"f\" shadows a default function")
+}
+
+func Test_WithLimits(t *testing.T) {
+	eval := NewEvaluator(WithLimits(Limits{
+		MaxLength: 50,
+		MaxDepth:  6,
+		MaxSteps:  100,
+	}))
+
+	result, err := eval.Evaluate(`[x * 2 for x in [1, 2, 3]]`, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{2, 4, 6}, result)
+
+	_, err = eval.Evaluate(`"`+strings.Repeat("a", 50)+`"`, nil, nil)
+	assert.EqualError(t, err, "limit error: expression is longer than 50 bytes")
+
+	_, err = eval.Evaluate(`((((((1))))))`, nil, nil)
+	assert.EqualError(t, err, "limit error: expression is nested deeper than 6 levels")
+
+	_, err = eval.Evaluate(`[[x + y for y in arr] for x in arr]`, map[string]interface{}{
+		"arr": []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+	}, nil)
+	assert.EqualError(t, err, "limit error: evaluation exceeds 100 steps")
+
+	program, err := Parse(`((((((1))))))`)
+	assert.NoError(t, err)
+	_, err = eval.EvaluateAST(program, nil, nil)
+	assert.EqualError(t, err, "limit error: expression is nested deeper than 6 levels")
+}
@@ -0,0 +1,70 @@
+package goval
+
+import (
+	"github.com/maja42/goval/ast"
+	"github.com/maja42/goval/internal"
+)
+
+// Partial evaluates the given expression string as far as possible, if only some of its variables are known.
+//
+// Accepts the known variables and functions. Variables and functions that don't exist are unknown
+// and remain within the returned residual expression, which can be evaluated once they are known.
+// Everything else is evaluated and replaced by its value.
+// The evaluator's default variables and functions are known, and its options are applied, except for the cache.
+//
+// Logical operators and the ternary operator are simplified if one of their operands is known,
+// like `false && unknown` becoming `false`, or `true ? a : b` becoming `a`.
+// `true && unknown` only becomes `unknown` if it always evaluates to a bool, like `unknown > 5`.
+// This assumes that the evaluation of unknown operands doesn't fail.
+//
+// Functions are called during partial evaluation if all of their arguments are known, and therefore need to be pure.
+// Errors caused by known sub-expressions are returned immediately.
+func (e *Evaluator) Partial(str string, variables map[string]interface{}, functions map[string]ExpressionFunction) (*Residual, error) {
+	program, scope, functions, err := e.parse(str, variables, functions)
+	if err != nil {
+		return nil, err
+	}
+	residual, err := program.Partial(scope, functions, e.limits.MaxSteps)
+	if err != nil {
+		return nil, err
+	}
+	return &Residual{Root: residual.Root}, nil
+}
+
+// Residual is the remaining expression after partial evaluation.
+type Residual struct {
+	// Root is the syntax tree of the residual expression. Nodes don't have positions.
+	// Known values that cannot be written as literals, like custom types or NaN, are Literal nodes without raw text.
+	Root ast.Node
+}
+
+// Source converts the residual expression into an expression string.
+// Fails if it contains values that cannot be written as literals.
+func (r *Residual) Source() (string, error) {
+	return ast.Source(r.Root)
+}
+
+// Variables returns the names of the unknown variables that are used by the residual expression, in sorted order.
+func (r *Residual) Variables() []string {
+	variables, _ := internal.References(r.Root)
+	return variables
+}
+
+// Functions returns the names of the functions that are called by the residual expression, in sorted order.
+// This includes known functions with unknown arguments.
+func (r *Residual) Functions() []string {
+	_, functions := internal.References(r.Root)
+	return functions
+}
+
+// Compile compiles the residual expression, so that it can be evaluated repeatedly.
+//
+// Unlike Source, this works for all residual expressions, including those with values that cannot be written as literals.
+func (r *Residual) Compile() (*Expression, error) {
+	p := internal.Program{Root: r.Root}
+	compiled, err := p.Compile()
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{compiled: compiled}, nil
+}
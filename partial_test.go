@@ -0,0 +1,167 @@
+package goval
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Partial(t *testing.T) {
+	known := map[string]interface{}{
+		"tenant": map[string]interface{}{"minAge": 18, "premium": false, "regions": []interface{}{"eu", "us"}},
+		"k":      2,
+	}
+	functions := map[string]ExpressionFunction{
+		"double": func(args ...interface{}) (interface{}, error) {
+			return args[0].(int) * 2, nil
+		},
+	}
+	assertResidual := func(expected string, str string) {
+		t.Helper()
+		residual, err := NewEvaluator().Partial(str, known, functions)
+		if !assert.NoError(t, err, str) {
+			return
+		}
+		src, err := residual.Source()
+		assert.NoError(t, err, str)
+		assert.Equal(t, expected, src, str)
+	}
+
+	assertResidual(`false`, `false && unknown`)
+	assertResidual(`false`, `unknown && tenant.premium`)
+	assertResidual(`true`, `!tenant.premium || unknown`)
+	assertResidual(`true && unknown`, `true && unknown`) // the operator checks the type of unknown
+	assertResidual(`false || user.vip`, `tenant.premium || user.vip`)
+	assertResidual(`user.age > 18`, `!tenant.premium && user.age > tenant.minAge`)
+	assertResidual(`!u`, `tenant.premium || !u`)
+	assertResidual(`u ? v > 1 : true`, `true && (u ? v > 1 : true)`)
+	assertResidual(`user.x`, `tenant.premium ? 1 : user.x`)
+	assertResidual(`u ? 2 : 4`, `u ? k : double(k)`)
+	assertResidual(`user.age >= 18 && user.region in ["eu", "us"]`, `user.age >= tenant.minAge && user.region in tenant.regions`)
+	assertResidual(`(u + 1) * 3`, `(u + 1) * (k + 1)`)
+	assertResidual(`-u - 2`, `-u - k`)
+	assertResidual(`f"Hi {user.name}, min 18!"`, `f"Hi {user.name}, min {tenant.minAge}!"`)
+	assertResidual(`"min 18"`, `f"min {tenant.minAge}"`)
+	assertResidual(`{"a": 2, ...user}`, `{"a": k, ...user}`)
+	assertResidual(`[1, 2, ...u]`, `[1, k, ...u]`)
+	assertResidual(`user.items[2:]`, `user.items[k:]`)
+	assertResidual(`lookup(3, user) |> double()`, `lookup(k + 1, user) |> double()`)
+	assertResidual(`{"minAge": 18, "premium": false, "regions": ["eu", "us"]}[u]`, `tenant[u]`)
+
+	// comprehensions
+	assertResidual(`[2, 4]`, `[x * k for x in [1, 2]]`)
+	assertResidual(`[x * 2 + 4 for x in user.items if x > 18]`, `[x * k + double(k) for x in user.items if x > tenant.minAge]`)
+	assertResidual(`[k + u for k in [1, 2]]`, `[k + u for k in [1, k]]`) // loop variables shadow known variables
+	assertResidual(`{"" + x: [2 * x for _ in [1]] for x in u}`, `{"" + x: [k * x for _ in [1]] for x in u}`)
+}
+
+func Test_Partial_EvaluateResidual(t *testing.T) {
+	vars := map[string]interface{}{
+		"a":     true,
+		"b":     false,
+		"i":     7,
+		"f":     2.5,
+		"s":     "text",
+		"items": []interface{}{1, 2, 3},
+		"obj":   map[string]interface{}{"x": 1, "y": []interface{}{"z"}},
+	}
+	expressions := []string{
+		`a && b || !a`, `i * f - i / 2`, `s + i + obj.x`, `items[1:] + [i]`, `obj.y[0] + s`,
+		`a ? i : f`, `b ? i : s`, `{"k": i, ...obj}`, `[x * i for x in items if x != 2]`,
+		`{k: v for k, v in obj}`, `f"{s}-{i}-{obj.x}"`, `i in items`, `[...items, ...obj.y]`,
+	}
+
+	// Every variable is known once, while all others are unknown.
+	for _, str := range expressions {
+		expected, err := NewEvaluator().Evaluate(str, vars, nil)
+		if !assert.NoError(t, err, str) {
+			continue
+		}
+		for name := range vars {
+			known := make(map[string]interface{})
+			unknown := make(map[string]interface{})
+			for n, v := range vars {
+				if n == name {
+					known[n] = v
+				} else {
+					unknown[n] = v
+				}
+			}
+
+			residual, err := NewEvaluator().Partial(str, known, nil)
+			if !assert.NoError(t, err, str) {
+				continue
+			}
+			expr, err := residual.Compile()
+			if !assert.NoError(t, err, str) {
+				continue
+			}
+			result, err := expr.Evaluate(unknown, nil)
+			assert.NoError(t, err, str)
+			assert.Equal(t, expected, result, "%s with known %q", str, name)
+
+			src, err := residual.Source()
+			assert.NoError(t, err, str)
+			result, err = NewEvaluator().Evaluate(src, unknown, nil)
+			assert.NoError(t, err, str)
+			assert.Equal(t, expected, result, "%s with known %q", src, name)
+		}
+	}
+}
+
+func Test_Partial_Residual(t *testing.T) {
+	eval := NewEvaluator(WithVariables(map[string]interface{}{"nan": math.NaN()}))
+
+	residual, err := eval.Partial(`lookup(u, v) > nan || w`, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u", "v", "w"}, residual.Variables())
+	assert.Equal(t, []string{"lookup"}, residual.Functions())
+
+	_, err = residual.Source()
+	assert.EqualError(t, err, "ast error: non-finite number NaN")
+
+	expr, err := residual.Compile()
+	assert.NoError(t, err)
+	result, err := expr.Evaluate(map[string]interface{}{"u": 1, "v": 2, "w": true}, map[string]ExpressionFunction{
+		"lookup": func(args ...interface{}) (interface{}, error) {
+			return args[0].(int) + args[1].(int), nil
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+}
+
+func Test_Partial_Errors(t *testing.T) {
+	eval := NewEvaluator()
+
+	_, err := eval.Partial(`1 +`, nil, nil)
+	assert.EqualError(t, err, "syntax error: unexpected $end")
+	_, err = eval.Partial(`u + 1 / 0`, nil, nil)
+	assert.EqualError(t, err, "math error: cannot divide by zero")
+	_, err = eval.Partial(`x.y || u`, map[string]interface{}{"x": 1}, nil)
+	assert.EqualError(t, err, "syntax error: cannot access fields on type number")
+
+	// type errors of unknown operands are kept
+	residual, err := eval.Partial(`k && u`, map[string]interface{}{"k": true}, nil)
+	if assert.NoError(t, err) {
+		expr, err := residual.Compile()
+		assert.NoError(t, err)
+		_, err = expr.Evaluate(map[string]interface{}{"u": 5}, nil)
+		assert.EqualError(t, err, "type error: required bool, but was number")
+	}
+	_, err = eval.Partial(`1 ? u : 2`, nil, nil)
+	assert.EqualError(t, err, "type error: required bool, but was number")
+
+	limited := NewEvaluator(WithLimits(Limits{MaxSteps: 100}))
+	residual, err = limited.Partial(`[x for x in arr] + u`, map[string]interface{}{"arr": []interface{}{1, 2, 3}}, nil)
+	if assert.NoError(t, err) {
+		src, err := residual.Source()
+		assert.NoError(t, err)
+		assert.Equal(t, `[1, 2, 3] + u`, src)
+	}
+	_, err = limited.Partial(`[[x + y for y in arr] for x in arr] + u`, map[string]interface{}{
+		"arr": []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+	}, nil)
+	assert.EqualError(t, err, "limit error: evaluation exceeds 100 steps")
+}
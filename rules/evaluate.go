@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/internal"
+)
+
+// Mode decides which rules are evaluated.
+type Mode int
+
+const (
+	// FirstMatch evaluates rules until the first one fires, and evaluates its output.
+	FirstMatch Mode = iota
+	// AllMatches evaluates the conditions of all rules. Outputs are not evaluated.
+	AllMatches
+	// CollectOutputs evaluates the conditions of all rules, and the outputs of all rules that fire.
+	CollectOutputs
+)
+
+func (m Mode) String() string {
+	switch m {
+	case FirstMatch:
+		return "first-match"
+	case AllMatches:
+		return "all-matches"
+	case CollectOutputs:
+		return "collect-outputs"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// Result contains the rules that fired, and the errors of rules that failed.
+type Result struct {
+	Fired  []Match  // in the order the rules were evaluated
+	Errors []*Error // rules whose condition or output failed
+}
+
+// Match is a rule that fired.
+type Match struct {
+	Rule   *Rule
+	Output interface{} // nil if the rule has no output or outputs were not evaluated
+}
+
+// Outputs returns the outputs of all fired rules that have an output expression.
+func (r *Result) Outputs() []interface{} {
+	outputs := make([]interface{}, 0, len(r.Fired))
+	for _, m := range r.Fired {
+		if m.Rule.output != nil {
+			outputs = append(outputs, m.Output)
+		}
+	}
+	return outputs
+}
+
+// Evaluate evaluates the rule set against the given variables.
+//
+// Accepts the same variables and functions as goval.Evaluator.Evaluate.
+// Rules that fail are reported within Result.Errors, and don't fire.
+// A rule whose output fails is reported as failed. In FirstMatch mode, evaluation continues with the next rule.
+func (s *RuleSet) Evaluate(mode Mode, variables map[string]interface{}, functions map[string]goval.ExpressionFunction) *Result {
+	res := &Result{}
+	for _, r := range s.rules {
+		fired, err := r.evaluate(mode, variables, functions)
+		if err != nil {
+			res.Errors = append(res.Errors, err)
+			continue
+		}
+		if fired != nil {
+			res.Fired = append(res.Fired, *fired)
+			if mode == FirstMatch {
+				break
+			}
+		}
+	}
+	return res
+}
+
+// evaluate evaluates a single rule. Returns nil if the rule did not fire.
+func (r *Rule) evaluate(mode Mode, variables map[string]interface{}, functions map[string]goval.ExpressionFunction) (*Match, *Error) {
+	cond, err := r.condition.Evaluate(variables, functions)
+	if err != nil {
+		return nil, &Error{Rule: r.Name, Field: "condition", Err: err}
+	}
+	fire, err := internal.ToBool(cond)
+	if err != nil {
+		return nil, &Error{Rule: r.Name, Field: "condition", Err: err}
+	}
+	if !fire {
+		return nil, nil
+	}
+
+	m := &Match{Rule: r}
+	if r.output != nil && mode != AllMatches {
+		if m.Output, err = r.output.Evaluate(variables, functions); err != nil {
+			return nil, &Error{Rule: r.Name, Field: "output", Err: err}
+		}
+	}
+	return m, nil
+}
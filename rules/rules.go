@@ -0,0 +1,140 @@
+// Package rules evaluates rule sets, which map conditions to outputs.
+//
+// Each rule has a unique name, a condition expression, an optional output expression, a priority and tags.
+// Rule sets are usually loaded from JSON:
+//
+//	[
+//	  {"name": "minor",   "condition": "user.age < 18",      "output": "`denied`", "priority": 10},
+//	  {"name": "premium", "condition": "user.plan == `pro`", "output": "user.discount * 2", "tags": ["pricing"]},
+//	  {"name": "default", "condition": "true",               "output": "`allowed`", "priority": -1}
+//	]
+//
+// Rules are evaluated in the order of their priority (highest first). Rules with the same priority keep their order.
+// Expressions have the same syntax and semantics as for goval.Evaluator. Conditions need to evaluate to bools.
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/maja42/goval"
+)
+
+// Rule is a named condition with an optional output.
+type Rule struct {
+	Name      string   `json:"name"`
+	Condition string   `json:"condition"`          // expression that decides whether the rule fires
+	Output    string   `json:"output,omitempty"`   // optional expression that is evaluated if the rule fires
+	Priority  int      `json:"priority,omitempty"` // rules with higher priorities are evaluated first
+	Tags      []string `json:"tags,omitempty"`
+
+	condition *goval.Expression
+	output    *goval.Expression // nil if the rule has no output
+}
+
+// HasTag returns true if the rule has the given tag.
+func (r *Rule) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Error is returned if a rule cannot be compiled or evaluated.
+type Error struct {
+	Rule  string // rule name
+	Field string // "condition" or "output"
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rule %q: %s: %s", e.Rule, e.Field, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// RuleSet is a compiled set of rules.
+// Immutable. Can be evaluated concurrently.
+type RuleSet struct {
+	rules []*Rule // sorted by priority
+}
+
+// Load parses a JSON array of rules and compiles them.
+// Unknown fields are rejected.
+func Load(data []byte) (*RuleSet, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var rules []Rule
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+	return New(rules)
+}
+
+// New compiles the given rules into a rule set.
+// Rule names need to be unique and non-empty.
+func New(rules []Rule) (*RuleSet, error) {
+	set := &RuleSet{
+		rules: make([]*Rule, len(rules)),
+	}
+	names := make(map[string]struct{}, len(rules))
+
+	for idx := range rules {
+		r := rules[idx] // copy
+		r.Tags = append([]string(nil), r.Tags...)
+
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule error: rule #%d has no name", idx+1)
+		}
+		if _, ok := names[r.Name]; ok {
+			return nil, fmt.Errorf("rule error: rule %q is declared twice", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		var err error
+		if r.condition, err = goval.Compile(r.Condition); err != nil {
+			return nil, &Error{Rule: r.Name, Field: "condition", Err: err}
+		}
+		if r.Output != "" {
+			if r.output, err = goval.Compile(r.Output); err != nil {
+				return nil, &Error{Rule: r.Name, Field: "output", Err: err}
+			}
+		}
+		set.rules[idx] = &r
+	}
+
+	sort.SliceStable(set.rules, func(i, j int) bool {
+		return set.rules[i].Priority > set.rules[j].Priority
+	})
+	return set, nil
+}
+
+// Rules returns all rules, in the order they are evaluated.
+func (s *RuleSet) Rules() []Rule {
+	rules := make([]Rule, len(s.rules))
+	for idx, r := range s.rules {
+		rules[idx] = *r
+	}
+	return rules
+}
+
+// WithTags returns a rule set that only contains the rules that have at least one of the given tags.
+func (s *RuleSet) WithTags(tags ...string) *RuleSet {
+	subset := &RuleSet{}
+	for _, r := range s.rules {
+		for _, tag := range tags {
+			if r.HasTag(tag) {
+				subset.rules = append(subset.rules, r)
+				break
+			}
+		}
+	}
+	return subset
+}
@@ -0,0 +1,128 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maja42/goval"
+	"github.com/stretchr/testify/assert"
+)
+
+const ruleSet = `[
+	{"name": "minor",    "condition": "user.age < 18",           "output": "\"denied\"", "priority": 10},
+	{"name": "premium",  "condition": "user.plan == \"pro\"",    "output": "user.discount * 2", "tags": ["pricing"]},
+	{"name": "student",  "condition": "\"student\" in user.tags", "output": "10", "tags": ["pricing"]},
+	{"name": "flagged",  "condition": "user.flagged",            "tags": ["audit"]},
+	{"name": "default",  "condition": "true",                    "output": "\"allowed\"", "priority": -1}
+]`
+
+func names(matches []Match) []string {
+	names := make([]string, len(matches))
+	for idx, m := range matches {
+		names[idx] = m.Rule.Name
+	}
+	return names
+}
+
+func Test_Load(t *testing.T) {
+	set, err := Load([]byte(ruleSet))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var order []string
+	for _, r := range set.Rules() {
+		order = append(order, r.Name)
+	}
+	assert.Equal(t, []string{"minor", "premium", "student", "flagged", "default"}, order)
+
+	r := set.Rules()[1]
+	assert.Equal(t, "user.discount * 2", r.Output)
+	assert.True(t, r.HasTag("pricing"))
+	assert.False(t, r.HasTag("audit"))
+}
+
+func Test_Load_Errors(t *testing.T) {
+	assertError := func(expected string, data string) {
+		t.Helper()
+		_, err := Load([]byte(data))
+		assert.EqualError(t, err, expected)
+	}
+	assertError(`json error: unexpected EOF`, `[{"name": "a"`)
+	assertError(`json error: json: unknown field "conditon"`, `[{"name": "a", "conditon": "true"}]`)
+	assertError(`rule error: rule #2 has no name`, `[{"name": "a", "condition": "true"}, {"condition": "true"}]`)
+	assertError(`rule error: rule "a" is declared twice`, `[{"name": "a", "condition": "true"}, {"name": "a", "condition": "false"}]`)
+	assertError(`rule "a": condition: syntax error: unexpected $end`, `[{"name": "a", "condition": "1 +"}]`)
+	assertError(`rule "a": condition: syntax error: unexpected $end`, `[{"name": "a"}]`)
+	assertError(`rule "a": output: syntax error: unexpected $end`, `[{"name": "a", "condition": "true", "output": "-"}]`)
+}
+
+func Test_Evaluate(t *testing.T) {
+	set, err := Load([]byte(ruleSet))
+	if !assert.NoError(t, err) {
+		return
+	}
+	adult := map[string]interface{}{
+		"user": map[string]interface{}{"age": 30, "plan": "pro", "discount": 5, "tags": []interface{}{"student"}, "flagged": false},
+	}
+	minor := map[string]interface{}{
+		"user": map[string]interface{}{"age": 12, "plan": "free", "discount": 0, "tags": []interface{}{}, "flagged": true},
+	}
+
+	res := set.Evaluate(FirstMatch, adult, nil)
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, []string{"premium"}, names(res.Fired))
+	assert.Equal(t, []interface{}{10}, res.Outputs())
+
+	res = set.Evaluate(FirstMatch, minor, nil)
+	assert.Equal(t, []string{"minor"}, names(res.Fired))
+	assert.Equal(t, []interface{}{"denied"}, res.Outputs())
+
+	res = set.Evaluate(AllMatches, adult, nil)
+	assert.Equal(t, []string{"premium", "student", "default"}, names(res.Fired))
+	assert.Nil(t, res.Fired[0].Output) // not evaluated
+	assert.Equal(t, []interface{}{nil, nil, nil}, res.Outputs())
+
+	res = set.Evaluate(CollectOutputs, minor, nil)
+	assert.Equal(t, []string{"minor", "flagged", "default"}, names(res.Fired))
+	assert.Equal(t, []interface{}{"denied", "allowed"}, res.Outputs())
+
+	res = set.WithTags("pricing", "audit").Evaluate(CollectOutputs, adult, nil)
+	assert.Equal(t, []string{"premium", "student"}, names(res.Fired))
+	assert.Equal(t, []interface{}{10, 10}, res.Outputs())
+}
+
+func Test_Evaluate_Errors(t *testing.T) {
+	set, err := New([]Rule{
+		{Name: "missing", Condition: `user.age > 18`, Priority: 2},
+		{Name: "type", Condition: `1 + 1`, Priority: 1},
+		{Name: "output", Condition: `true`, Output: `fail()`},
+		{Name: "ok", Condition: `true`, Output: `"ok"`},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	functions := map[string]goval.ExpressionFunction{
+		"fail": func(args ...interface{}) (interface{}, error) {
+			return nil, errors.New("failed")
+		},
+	}
+
+	res := set.Evaluate(FirstMatch, nil, functions)
+	assert.Equal(t, []string{"ok"}, names(res.Fired))
+	if assert.Len(t, res.Errors, 3) {
+		assert.EqualError(t, res.Errors[0], `rule "missing": condition: var error: variable "user" does not exist`)
+		assert.EqualError(t, res.Errors[1], `rule "type": condition: type error: required bool, but was number`)
+		assert.EqualError(t, res.Errors[2], `rule "output": output: function error: "fail" - failed`)
+		assert.Equal(t, "output", res.Errors[2].Field)
+	}
+
+	res = set.Evaluate(AllMatches, nil, functions) // outputs are not evaluated
+	assert.Equal(t, []string{"output", "ok"}, names(res.Fired))
+	assert.Len(t, res.Errors, 2)
+}
+
+func Test_Mode_String(t *testing.T) {
+	assert.Equal(t, "first-match", FirstMatch.String())
+	assert.Equal(t, "collect-outputs", CollectOutputs.String())
+	assert.Equal(t, "Mode(7)", Mode(7).String())
+}
@@ -0,0 +1,332 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+	"github.com/maja42/goval/internal"
+)
+
+// HitPolicy decides which rows of a decision table are selected if multiple rows match.
+type HitPolicy string
+
+const (
+	// Unique requires that at most one row matches. Multiple matching rows cause an error. This is the default.
+	Unique HitPolicy = "unique"
+	// First selects the first matching row.
+	First HitPolicy = "first"
+	// Priority selects the matching row with the highest priority. If multiple rows have the same priority, the first one is selected.
+	Priority HitPolicy = "priority"
+	// Collect selects all matching rows.
+	Collect HitPolicy = "collect"
+)
+
+// Table is the definition of a decision table.
+//
+// Each row contains one condition cell per input column and one expression per output column.
+// Input cells are applied to the value of the column's input.
+// The expression after the operator is the right operand as a whole, "< 1 + x" compares with "1 + x":
+//
+//	>= 18                  comparisons (==, !=, <, <=, >, >=)
+//	in ["DE", "AT"]        array contains
+//	"DE"                   everything else is compared for equality
+//	- or empty             matches every value
+//
+// Input and output expressions are evaluated against the variables passed to DecisionTable.Evaluate.
+// Decision tables are usually loaded from JSON:
+//
+//	{
+//	  "hitPolicy": "first",
+//	  "inputs":  ["user.age", "user.country"],
+//	  "outputs": ["discount"],
+//	  "rows": [
+//	    {"inputs": ["< 18", "-"],                  "outputs": ["0"]},
+//	    {"inputs": [">= 18", "in [\"DE\", \"AT\"]"], "outputs": ["0.1"]},
+//	    {"inputs": ["-", "-"],                     "outputs": ["0.05"]}
+//	  ]
+//	}
+type Table struct {
+	HitPolicy HitPolicy `json:"hitPolicy,omitempty"` // defaults to Unique
+	Inputs    []string  `json:"inputs"`              // input expressions
+	Outputs   []string  `json:"outputs"`             // output names
+	Rows      []Row     `json:"rows"`
+}
+
+// Row is a single row of a decision table.
+type Row struct {
+	Inputs   []string `json:"inputs"`             // one condition per input column
+	Outputs  []string `json:"outputs"`            // one expression per output column; empty cells result in nil
+	Priority int      `json:"priority,omitempty"` // only used by the Priority hit policy
+}
+
+// TableError is returned if a cell of a decision table cannot be compiled or evaluated.
+type TableError struct {
+	Row    int    // row index; -1 for the input expressions
+	Column string // input expression or output name
+	Err    error
+}
+
+func (e *TableError) Error() string {
+	if e.Row < 0 {
+		return fmt.Sprintf("input %q: %s", e.Column, e.Err)
+	}
+	return fmt.Sprintf("row #%d, column %q: %s", e.Row+1, e.Column, e.Err)
+}
+
+func (e *TableError) Unwrap() error {
+	return e.Err
+}
+
+// DecisionTable is a compiled decision table.
+// Immutable. Can be evaluated concurrently.
+type DecisionTable struct {
+	table  Table
+	inputs []*goval.Expression
+	rows   []compiledRow
+}
+
+type compiledRow struct {
+	conditions []*internal.Compiled // nil for cells that match every value
+	outputs    []*goval.Expression  // nil for empty cells
+}
+
+// inputVariable is the name of the variable that holds the column's input value while evaluating conditions.
+// It cannot be written within expressions, and therefore never shadows other variables.
+const inputVariable = "?"
+
+// LoadTable parses a decision table from JSON and compiles it.
+// Unknown fields are rejected.
+func LoadTable(data []byte) (*DecisionTable, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var t Table
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+	return CompileTable(t)
+}
+
+// CompileTable compiles the given decision table.
+func CompileTable(t Table) (*DecisionTable, error) {
+	if t.HitPolicy == "" {
+		t.HitPolicy = Unique
+	}
+	switch t.HitPolicy {
+	case Unique, First, Priority, Collect:
+	default:
+		return nil, fmt.Errorf("rule error: unknown hit policy %q", t.HitPolicy)
+	}
+	names := make(map[string]struct{}, len(t.Outputs))
+	for _, name := range t.Outputs {
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("rule error: output %q is declared twice", name)
+		}
+		names[name] = struct{}{}
+	}
+
+	dt := &DecisionTable{
+		table:  t,
+		inputs: make([]*goval.Expression, len(t.Inputs)),
+		rows:   make([]compiledRow, len(t.Rows)),
+	}
+	for col, input := range t.Inputs {
+		expr, err := goval.Compile(input)
+		if err != nil {
+			return nil, &TableError{Row: -1, Column: input, Err: err}
+		}
+		dt.inputs[col] = expr
+	}
+
+	for idx, row := range t.Rows {
+		if len(row.Inputs) != len(t.Inputs) {
+			return nil, fmt.Errorf("rule error: row #%d has %d input cells, but the table has %d inputs", idx+1, len(row.Inputs), len(t.Inputs))
+		}
+		if len(row.Outputs) != len(t.Outputs) {
+			return nil, fmt.Errorf("rule error: row #%d has %d output cells, but the table has %d outputs", idx+1, len(row.Outputs), len(t.Outputs))
+		}
+		compiled := compiledRow{
+			conditions: make([]*internal.Compiled, len(row.Inputs)),
+			outputs:    make([]*goval.Expression, len(row.Outputs)),
+		}
+		for col, cell := range row.Inputs {
+			cond, err := compileCondition(cell)
+			if err != nil {
+				return nil, &TableError{Row: idx, Column: t.Inputs[col], Err: err}
+			}
+			compiled.conditions[col] = cond
+		}
+		for col, cell := range row.Outputs {
+			if strings.TrimSpace(cell) == "" {
+				continue
+			}
+			expr, err := goval.Compile(cell)
+			if err != nil {
+				return nil, &TableError{Row: idx, Column: t.Outputs[col], Err: err}
+			}
+			compiled.outputs[col] = expr
+		}
+		dt.rows[idx] = compiled
+	}
+	return dt, nil
+}
+
+// conditionOperators are the operators that input cells can start with.
+// Longer operators come first, so that "<=" is not mistaken for "<".
+var conditionOperators = []string{"==", "!=", "<=", ">=", "<", ">", "in ", "in[", "in\t"}
+
+// compileCondition compiles an input cell into an expression that compares the input variable.
+// Returns nil for cells that match every value.
+func compileCondition(cell string) (*internal.Compiled, error) {
+	program, err := parseCondition(cell)
+	if program == nil || err != nil {
+		return nil, err
+	}
+	return program.Compile()
+}
+
+// parseCondition parses an input cell into an expression that compares the input variable.
+// The remainder of the cell after the operator is parsed on its own and becomes the right operand.
+// Returns nil for cells that match every value.
+func parseCondition(cell string) (*internal.Program, error) {
+	trimmed := strings.TrimSpace(cell)
+	if trimmed == "" || trimmed == "-" {
+		return nil, nil
+	}
+	leading := len(cell) - len(strings.TrimLeftFunc(cell, unicode.IsSpace))
+	op, opPos, operand := "==", 0, trimmed // equality cells don't contain the operator
+	for _, prefix := range conditionOperators {
+		if strings.HasPrefix(trimmed, prefix) {
+			op = strings.TrimRight(strings.TrimSuffix(prefix, "["), " \t")
+			opPos, operand = leading+1, trimmed[len(op):]
+			break
+		}
+	}
+	program, err := internal.ParseAt(operand, leading+len(trimmed)-len(operand)+1) // positions are relative to the cell
+	if err != nil {
+		return nil, err
+	}
+	program.Root = &ast.BinaryExpr{
+		X:     &ast.Ident{Name: inputVariable},
+		OpPos: opPos,
+		Op:    op,
+		Y:     program.Root,
+	}
+	return program, nil
+}
+
+// Table returns the definition of the decision table.
+func (t *DecisionTable) Table() Table {
+	return t.table
+}
+
+// TableResult contains the selected rows of a decision table.
+type TableResult struct {
+	Rows    []int                    // indices of the selected rows
+	Outputs []map[string]interface{} // outputs of the selected rows, by output name
+}
+
+// Evaluate evaluates the decision table against the given variables.
+//
+// Accepts the same variables and functions as goval.Evaluator.Evaluate.
+// Returns the selected rows, according to the hit policy. The result is empty if no row matches.
+// Fails if a cell cannot be evaluated, or if multiple rows match a table with the Unique hit policy.
+func (t *DecisionTable) Evaluate(variables map[string]interface{}, functions map[string]goval.ExpressionFunction) (*TableResult, error) {
+	inputs := make([]interface{}, len(t.inputs))
+	for col, expr := range t.inputs {
+		val, err := expr.Evaluate(variables, functions)
+		if err != nil {
+			return nil, &TableError{Row: -1, Column: t.table.Inputs[col], Err: err}
+		}
+		inputs[col] = val
+	}
+	matches, err := t.match(inputs, variables, functions)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []int
+	switch t.table.HitPolicy {
+	case Unique:
+		if len(matches) > 1 {
+			return nil, fmt.Errorf("rule error: rows #%d and #%d match, but the hit policy is unique", matches[0]+1, matches[1]+1)
+		}
+		selected = matches
+	case First:
+		if len(matches) > 0 {
+			selected = matches[:1]
+		}
+	case Priority:
+		for _, idx := range matches {
+			if len(selected) == 0 || t.table.Rows[idx].Priority > t.table.Rows[selected[0]].Priority {
+				selected = []int{idx}
+			}
+		}
+	case Collect:
+		selected = matches
+	}
+
+	res := &TableResult{
+		Rows:    selected,
+		Outputs: make([]map[string]interface{}, len(selected)),
+	}
+	for i, idx := range selected {
+		outputs := make(map[string]interface{}, len(t.table.Outputs))
+		for col, expr := range t.rows[idx].outputs {
+			var val interface{}
+			if expr != nil {
+				if val, err = expr.Evaluate(variables, functions); err != nil {
+					return nil, &TableError{Row: idx, Column: t.table.Outputs[col], Err: err}
+				}
+			}
+			outputs[t.table.Outputs[col]] = val
+		}
+		res.Outputs[i] = outputs
+	}
+	return res, nil
+}
+
+// match returns the indices of all rows whose conditions match the given input values.
+func (t *DecisionTable) match(inputs []interface{}, variables map[string]interface{}, functions map[string]goval.ExpressionFunction) ([]int, error) {
+	var matches []int
+	scope := internal.NewScope(variables)
+	for idx := range t.rows {
+		ok, err := t.matchRow(idx, inputs, scope, functions)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, idx)
+		}
+	}
+	return matches, nil
+}
+
+func (t *DecisionTable) matchRow(idx int, inputs []interface{}, scope *internal.Scope, functions map[string]goval.ExpressionFunction) (bool, error) {
+	for col, cond := range t.rows[idx].conditions {
+		if cond == nil {
+			continue
+		}
+		ok, err := t.matchCell(cond, inputs[col], scope, functions)
+		if err != nil {
+			return false, &TableError{Row: idx, Column: t.table.Inputs[col], Err: err}
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (t *DecisionTable) matchCell(cond *internal.Compiled, input interface{}, scope *internal.Scope, functions map[string]goval.ExpressionFunction) (bool, error) {
+	res, err := cond.EvaluateIn(scope.With(inputVariable, input), functions)
+	if err != nil {
+		return false, err
+	}
+	return internal.ToBool(res)
+}
@@ -0,0 +1,254 @@
+package rules
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/maja42/goval/ast"
+	"github.com/maja42/goval/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+const discountTable = `{
+	"hitPolicy": "first",
+	"inputs":  ["user.age", "user.country"],
+	"outputs": ["discount", "reason"],
+	"rows": [
+		{"inputs": ["< 18", "-"],                      "outputs": ["0", "\"minor\""]},
+		{"inputs": [">= 18", "in [\"DE\", \"AT\"]"],   "outputs": ["0.1", "\"local\""]},
+		{"inputs": [">= 65", "-"],                     "outputs": ["0.2", "\"senior\""], "priority": 1},
+		{"inputs": ["-", "-"],                         "outputs": ["base", ""]}
+	]
+}`
+
+func loadTable(t *testing.T, data string, policy HitPolicy) *DecisionTable {
+	t.Helper()
+	dt, err := LoadTable([]byte(data))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	def := dt.Table()
+	def.HitPolicy = policy
+	dt, err = CompileTable(def)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return dt
+}
+
+func user(age int, country string) map[string]interface{} {
+	return map[string]interface{}{
+		"user": map[string]interface{}{"age": age, "country": country},
+		"base": 0.05,
+	}
+}
+
+func Test_DecisionTable_HitPolicies(t *testing.T) {
+	first := loadTable(t, discountTable, First)
+	res, err := first.Evaluate(user(70, "DE"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, res.Rows)
+	assert.Equal(t, []map[string]interface{}{{"discount": 0.1, "reason": "local"}}, res.Outputs)
+
+	res, err = first.Evaluate(user(30, "FR"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"discount": 0.05, "reason": nil}}, res.Outputs)
+
+	priority := loadTable(t, discountTable, Priority)
+	res, err = priority.Evaluate(user(70, "DE"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, res.Rows)
+
+	collect := loadTable(t, discountTable, Collect)
+	res, err = collect.Evaluate(user(70, "DE"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, res.Rows)
+	assert.Len(t, res.Outputs, 3)
+
+	unique := loadTable(t, discountTable, Unique)
+	_, err = unique.Evaluate(user(70, "DE"), nil)
+	assert.EqualError(t, err, "rule error: rows #2 and #3 match, but the hit policy is unique")
+
+	empty := loadTable(t, `{"inputs": ["x"], "outputs": ["y"], "rows": [{"inputs": ["1"], "outputs": ["2"]}]}`, Unique)
+	res, err = empty.Evaluate(map[string]interface{}{"x": 2}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, res.Rows)
+	assert.Empty(t, res.Outputs)
+}
+
+func Test_DecisionTable_Cells(t *testing.T) {
+	dt, err := CompileTable(Table{
+		HitPolicy: Collect,
+		Inputs:    []string{"x"},
+		Outputs:   []string{"row"},
+		Rows: []Row{
+			{Inputs: []string{"== 1"}, Outputs: []string{"1"}},
+			{Inputs: []string{"!= 1"}, Outputs: []string{"2"}},
+			{Inputs: []string{"in[1, 2]"}, Outputs: []string{"3"}},
+			{Inputs: []string{"-1"}, Outputs: []string{"4"}},
+			{Inputs: []string{"limit"}, Outputs: []string{"5"}},         // compared with a variable
+			{Inputs: []string{"> 0 && ? == 1"}, Outputs: []string{"6"}}, // the input cannot be accessed directly
+		},
+	})
+	assert.EqualError(t, err, `row #6, column "x": syntax error: unexpected '?'`)
+
+	dt, err = CompileTable(Table{
+		HitPolicy: Collect,
+		Inputs:    []string{"x"},
+		Outputs:   []string{"row"},
+		Rows: []Row{
+			{Inputs: []string{"== 1"}, Outputs: []string{"1"}},
+			{Inputs: []string{"!= 1"}, Outputs: []string{"2"}},
+			{Inputs: []string{"in[1, 2]"}, Outputs: []string{"3"}},
+			{Inputs: []string{"-1"}, Outputs: []string{"4"}},
+			{Inputs: []string{"limit"}, Outputs: []string{"5"}},
+			{Inputs: []string{" "}, Outputs: []string{"6"}},
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assertRows := func(expected []int, x interface{}) {
+		t.Helper()
+		res, err := dt.Evaluate(map[string]interface{}{"x": x, "limit": 2}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, res.Rows, x)
+	}
+	assertRows([]int{0, 2, 5}, 1)
+	assertRows([]int{1, 2, 4, 5}, 2)
+	assertRows([]int{1, 3, 5}, -1)
+}
+
+func Test_DecisionTable_Errors(t *testing.T) {
+	assertError := func(expected string, data string) {
+		t.Helper()
+		_, err := LoadTable([]byte(data))
+		assert.EqualError(t, err, expected)
+	}
+	assertError(`json error: json: unknown field "row"`, `{"row": []}`)
+	assertError(`rule error: unknown hit policy "any"`, `{"hitPolicy": "any"}`)
+	assertError(`rule error: output "a" is declared twice`, `{"outputs": ["a", "a"]}`)
+	assertError(`input "x +": syntax error: unexpected $end`, `{"inputs": ["x +"]}`)
+	assertError(`rule error: row #1 has 0 input cells, but the table has 1 inputs`, `{"inputs": ["x"], "rows": [{}]}`)
+	assertError(`rule error: row #1 has 1 output cells, but the table has 0 outputs`, `{"rows": [{"outputs": ["1"]}]}`)
+	assertError(`row #1, column "out": syntax error: unexpected $end`, `{"outputs": ["out"], "rows": [{"outputs": ["1 +"]}]}`)
+	assertError(`row #1, column "x": syntax error: unexpected $end`, `{"inputs": ["x"], "rows": [{"inputs": ["1 +"]}]}`)
+	assertError(`row #1, column "x": syntax error: unexpected $end`, `{"inputs": ["x"], "rows": [{"inputs": ["< 1 +"]}]}`)
+	assertError(`row #1, column "x": syntax error: unexpected ')'`, `{"inputs": ["x"], "rows": [{"inputs": ["1)"]}]}`)
+
+	dt := loadTable(t, `{"inputs": ["x"], "outputs": ["y"], "rows": [{"inputs": ["> 1"], "outputs": ["z"]}]}`, Unique)
+	_, err := dt.Evaluate(nil, nil)
+	assert.EqualError(t, err, `input "x": var error: variable "x" does not exist`)
+	_, err = dt.Evaluate(map[string]interface{}{"x": "text"}, nil)
+	assert.EqualError(t, err, `row #1, column "x": type error: cannot compare type string and number`)
+	_, err = dt.Evaluate(map[string]interface{}{"x": 2}, nil)
+	assert.EqualError(t, err, `row #1, column "y": var error: variable "z" does not exist`)
+}
+
+func Test_DecisionTable_CellPositions(t *testing.T) {
+	var syntaxErr *internal.SyntaxError
+	_, err := parseCondition("  >= 1 + )")
+	if assert.True(t, errors.As(err, &syntaxErr)) {
+		assert.Equal(t, 10, syntaxErr.Pos)
+	}
+	_, err = parseCondition(" 1 )")
+	if assert.True(t, errors.As(err, &syntaxErr)) {
+		assert.Equal(t, 4, syntaxErr.Pos)
+	}
+
+	program, err := parseCondition(" in [1]")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &ast.BinaryExpr{
+			X:     &ast.Ident{Name: inputVariable},
+			OpPos: 2,
+			Op:    "in",
+			Y:     &ast.ArrayLit{Lbrack: 5, Elems: []ast.Node{&ast.Literal{ValuePos: 6, Raw: "1", Value: 1}}, Rbrack: 7},
+		}, program.Root)
+	}
+}
+
+func Test_DecisionTable_Validate(t *testing.T) {
+	dt := loadTable(t, discountTable, Unique)
+	issues, err := dt.Validate(nil, nil)
+	assert.NoError(t, err)
+	var messages []string
+	for _, issue := range issues {
+		messages = append(messages, issue.String())
+	}
+	assert.Equal(t, []string{ // the catch-all row overlaps with every other row
+		`rows #1, #4 overlap for the inputs [17, "DE"]`,
+		`rows #2, #4 overlap for the inputs [18, "DE"]`,
+		`rows #2, #3, #4 overlap for the inputs [65, "DE"]`,
+		`rows #3, #4 overlap for the inputs [65, "other"]`,
+	}, messages)
+
+	gaps := loadTable(t, `{
+		"inputs": ["age", "country"],
+		"outputs": [],
+		"rows": [
+			{"inputs": ["< 18", "-"], "outputs": []},
+			{"inputs": ["> 18", "\"DE\""], "outputs": []},
+			{"inputs": ["> 16", "\"AT\""], "outputs": []}
+		]
+	}`, Unique)
+	issues, err = gaps.Validate(nil, nil)
+	assert.NoError(t, err)
+	messages = nil
+	for _, issue := range issues {
+		messages = append(messages, issue.String())
+	}
+	assert.Equal(t, []string{
+		`rows #1, #3 overlap for the inputs [17, "AT"]`,
+		`no row matches the inputs [18, "DE"]`,
+		`no row matches the inputs [18, "other"]`,
+		`no row matches the inputs [19, "other"]`,
+	}, messages)
+}
+
+func Test_DecisionTable_ValidateGaps(t *testing.T) {
+	validate := func(data string) []string {
+		t.Helper()
+		issues, err := loadTable(t, data, Unique).Validate(nil, nil)
+		assert.NoError(t, err)
+		var messages []string
+		for _, issue := range issues {
+			messages = append(messages, issue.String())
+		}
+		return messages
+	}
+
+	assert.Equal(t, []string{`no row matches the inputs [17.5]`}, validate(`{
+		"inputs": ["age"],
+		"outputs": [],
+		"rows": [
+			{"inputs": ["<= 17"], "outputs": []},
+			{"inputs": [">= 18"], "outputs": []}
+		]
+	}`))
+
+	// each uncovered region is reported once, instead of 2, 4, 6, 8 and 10
+	assert.Equal(t, []string{`no row matches the inputs [0]`}, validate(`{
+		"inputs": ["x"],
+		"outputs": [],
+		"rows": [
+			{"inputs": ["== 1"], "outputs": []},
+			{"inputs": ["== 5"], "outputs": []},
+			{"inputs": ["== 9"], "outputs": []}
+		]
+	}`))
+}
+
+func Test_DecisionTable_CandidatesWithoutOverflow(t *testing.T) {
+	dt := loadTable(t, `{
+		"inputs": ["x"],
+		"outputs": [],
+		"rows": [
+			{"inputs": ["< 9223372036854775807"], "outputs": []},
+			{"inputs": ["> 0x8000000000000000"], "outputs": []}
+		]
+	}`, Unique)
+	assert.Equal(t, []interface{}{
+		math.MaxInt - 1, math.MaxInt, math.MinInt, math.MinInt + 1,
+	}, dt.candidates(0, nil, nil))
+}
@@ -0,0 +1,295 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+	"github.com/maja42/goval/internal"
+)
+
+// IssueKind is the kind of problem found by DecisionTable.Validate.
+type IssueKind int
+
+const (
+	// Overlap means that multiple rows match the same inputs.
+	// This is only a mistake for tables with the Unique hit policy.
+	Overlap IssueKind = iota
+	// Gap means that no row matches the inputs.
+	Gap
+)
+
+func (k IssueKind) String() string {
+	switch k {
+	case Overlap:
+		return "overlap"
+	case Gap:
+		return "gap"
+	}
+	return fmt.Sprintf("IssueKind(%d)", int(k))
+}
+
+// Issue is a problem of a decision table.
+type Issue struct {
+	Kind   IssueKind
+	Rows   []int         // indices of the overlapping rows; empty for gaps
+	Inputs []interface{} // example input values, one per input column
+}
+
+func (i Issue) String() string {
+	inputs := make([]string, len(i.Inputs))
+	for idx, val := range i.Inputs {
+		inputs[idx] = literal(val)
+	}
+	example := "[" + strings.Join(inputs, ", ") + "]"
+
+	if i.Kind == Gap {
+		return "no row matches the inputs " + example
+	}
+	rows := make([]string, len(i.Rows))
+	for idx, row := range i.Rows {
+		rows[idx] = fmt.Sprintf("#%d", row+1)
+	}
+	return fmt.Sprintf("rows %s overlap for the inputs %s", strings.Join(rows, ", "), example)
+}
+
+func literal(val interface{}) string {
+	if src, err := ast.Source(&ast.Literal{Value: val}); err == nil {
+		return src
+	}
+	return fmt.Sprint(val)
+}
+
+// maxValidationCombinations limits the number of input combinations that are checked by Validate.
+const maxValidationCombinations = 100000
+
+// Validate searches for overlapping rows and inputs that are not matched by any row.
+//
+// The table is not analysed symbolically. Instead, all combinations of representative input values are evaluated.
+// Representative values are derived from the literals within input cells:
+// each literal itself, numbers directly below and above it, and a string that differs from all string literals.
+// Values that cause errors in any cell of their column (like strings within numeric columns) are not used.
+// Issues are therefore only found for values that are close to the literals, which is the case for typical tables.
+//
+// Accepts the variables and functions used by input cells. Input expressions are not evaluated.
+// Each combination of overlapping rows is reported once.
+// Gaps are reported once per uncovered region, which contains all combinations whose values match the same cells.
+func (t *DecisionTable) Validate(variables map[string]interface{}, functions map[string]goval.ExpressionFunction) ([]Issue, error) {
+	candidates := make([][]interface{}, len(t.inputs))
+	combinations := 1
+	for col := range t.inputs {
+		candidates[col] = t.candidates(col, variables, functions)
+		if len(candidates[col]) == 0 {
+			return nil, fmt.Errorf("rule error: input %q: no valid input values found", t.table.Inputs[col])
+		}
+		combinations *= len(candidates[col])
+		if combinations > maxValidationCombinations {
+			return nil, errors.New("rule error: the table has too many input combinations to validate")
+		}
+	}
+
+	// cells[col][idx] describes which cells of the column match the candidate value
+	cells := make([][]string, len(t.inputs))
+	scope := internal.NewScope(variables)
+	for col := range t.inputs {
+		cells[col] = make([]string, len(candidates[col]))
+		for idx, val := range candidates[col] {
+			key, err := t.matchingCells(col, val, scope, functions)
+			if err != nil {
+				return nil, err
+			}
+			cells[col][idx] = key
+		}
+	}
+
+	var issues []Issue
+	overlaps := make(map[string]struct{}) // reported row combinations
+	gaps := make(map[string]struct{})     // reported regions
+	inputs := make([]interface{}, len(t.inputs))
+	region := make([]string, len(t.inputs))
+
+	var check func(col int) error
+	check = func(col int) error {
+		if col < len(inputs) {
+			for idx, val := range candidates[col] {
+				inputs[col] = val
+				region[col] = cells[col][idx]
+				if err := check(col + 1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		matches, err := t.match(inputs, variables, functions)
+		if err != nil {
+			return err
+		}
+		example := append([]interface{}(nil), inputs...)
+		switch {
+		case len(matches) == 0:
+			key := strings.Join(region, ",")
+			if _, ok := gaps[key]; !ok {
+				gaps[key] = struct{}{}
+				issues = append(issues, Issue{Kind: Gap, Inputs: example})
+			}
+		case len(matches) > 1:
+			key := fmt.Sprint(matches)
+			if _, ok := overlaps[key]; !ok {
+				overlaps[key] = struct{}{}
+				issues = append(issues, Issue{Kind: Overlap, Rows: matches, Inputs: example})
+			}
+		}
+		return nil
+	}
+	if err := check(0); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// candidates returns the representative input values of a column.
+func (t *DecisionTable) candidates(col int, variables map[string]interface{}, functions map[string]goval.ExpressionFunction) []interface{} {
+	var lits literals
+	for _, row := range t.table.Rows {
+		if program, _ := parseCondition(row.Inputs[col]); program != nil { // compiled before, cannot fail
+			lits.collect(program.Root)
+		}
+	}
+	if len(lits.others) == 0 && len(lits.ints) == 0 && len(lits.floats) == 0 && len(lits.strs) == 0 {
+		return []interface{}{nil} // all cells match every value
+	}
+
+	values := lits.others
+	for _, i := range lits.ints {
+		if i > math.MinInt {
+			values = append(values, i-1)
+		}
+		values = append(values, i)
+		if i < math.MaxInt {
+			values = append(values, i+1)
+		}
+	}
+	for _, f := range lits.floats {
+		values = append(values, f-1, f, f+1)
+	}
+	sort.Ints(lits.ints)
+	for idx := 1; idx < len(lits.ints); idx++ { // values in between adjacent ints, which might not be covered
+		if lits.ints[idx-1]+1 == lits.ints[idx] {
+			values = append(values, float64(lits.ints[idx-1])+0.5)
+		}
+	}
+	sort.Float64s(lits.floats)
+	for idx := 1; idx < len(lits.floats); idx++ { // values in between floats, which might not be covered
+		values = append(values, (lits.floats[idx-1]+lits.floats[idx])/2)
+	}
+	if len(lits.strs) > 0 {
+		other := "other"
+		for contains(lits.strs, other) {
+			other += "_"
+		}
+		lits.strs = append(lits.strs, other)
+	}
+	for _, s := range lits.strs {
+		values = append(values, s)
+	}
+
+	// Only keep values that are accepted by all cells of the column.
+	var valid []interface{}
+	seen := make(map[interface{}]struct{})
+	scope := internal.NewScope(variables)
+	for _, val := range values {
+		if _, ok := seen[val]; ok {
+			continue
+		}
+		seen[val] = struct{}{}
+		if t.validCandidate(col, val, scope, functions) {
+			valid = append(valid, val)
+		}
+	}
+	return valid
+}
+
+func (t *DecisionTable) validCandidate(col int, val interface{}, scope *internal.Scope, functions map[string]goval.ExpressionFunction) bool {
+	for _, row := range t.rows {
+		if cond := row.conditions[col]; cond != nil {
+			if _, err := t.matchCell(cond, val, scope, functions); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchingCells returns which cells of the column match the value, as one character per row.
+func (t *DecisionTable) matchingCells(col int, val interface{}, scope *internal.Scope, functions map[string]goval.ExpressionFunction) (string, error) {
+	key := make([]byte, len(t.rows))
+	for idx, row := range t.rows {
+		key[idx] = '1'
+		if cond := row.conditions[col]; cond != nil {
+			ok, err := t.matchCell(cond, val, scope, functions)
+			if err != nil {
+				return "", &TableError{Row: idx, Column: t.table.Inputs[col], Err: err}
+			}
+			if !ok {
+				key[idx] = '0'
+			}
+		}
+	}
+	return string(key), nil
+}
+
+// literals contains the literals used within input cells.
+type literals struct {
+	ints   []int
+	floats []float64
+	strs   []string
+	others []interface{} // nil and bools
+}
+
+// collect collects the literals used within comparisons and arrays.
+func (l *literals) collect(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Literal:
+		switch v := n.Value.(type) {
+		case int:
+			l.ints = append(l.ints, v)
+		case float64:
+			l.floats = append(l.floats, v)
+		case string:
+			l.strs = append(l.strs, v)
+		case nil, bool:
+			l.others = append(l.others, v)
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := n.X.(*ast.Literal); ok && n.Op == "-" {
+			switch v := lit.Value.(type) {
+			case int:
+				l.ints = append(l.ints, -v)
+			case float64:
+				l.floats = append(l.floats, -v)
+			}
+		}
+	case *ast.BinaryExpr:
+		l.collect(n.X)
+		l.collect(n.Y)
+	case *ast.ParenExpr:
+		l.collect(n.X)
+	case *ast.ArrayLit:
+		for _, elem := range n.Elems {
+			l.collect(elem)
+		}
+	}
+}
+
+func contains(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,420 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/maja42/goval/ast"
+)
+
+// Error is a problem found by Check.
+// Messages are the same as the ones returned when evaluating the expression.
+type Error struct {
+	Pos int // position of the affected node
+	End int // position after the affected node
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// Info contains the results of Check.
+type Info struct {
+	Errors []*Error
+	Types  map[ast.Node]*Type // static types of the checked nodes, including the selectors of field accesses
+}
+
+// Check checks a parsed expression against the schema, without evaluating it.
+//
+// Reports unknown variables, fields and functions, wrong numbers of arguments,
+// and operations whose operands have types that always cause errors.
+// The check is conservative: values of type Any, and operations with custom types, are never reported.
+// Optional values are assumed to be present.
+func (s *Schema) Check(node ast.Node) *Info {
+	c := &checker{
+		schema: s,
+		info:   &Info{Types: make(map[ast.Node]*Type)},
+	}
+	c.expr(node, nil)
+	return c.info
+}
+
+type checker struct {
+	schema *Schema
+	info   *Info
+}
+
+// scope contains the loop variables of comprehensions.
+type scope map[string]*Type
+
+func (c *checker) errorf(node ast.Node, format string, args ...interface{}) {
+	c.info.Errors = append(c.info.Errors, &Error{
+		Pos: node.Pos(),
+		End: node.End(),
+		Msg: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) expr(node ast.Node, s scope) *Type {
+	t := c.infer(node, s)
+	if t == nil {
+		t = AnyType
+	}
+	c.info.Types[node] = t
+	return t
+}
+
+// known reports whether the kind of the type is known.
+func known(t *Type) bool {
+	return t.kind() != Any
+}
+
+// mismatch reports whether the type is known, but of a different kind.
+func mismatch(t *Type, kind Kind) bool {
+	return known(t) && t.kind() != kind
+}
+
+func (c *checker) require(node ast.Node, t *Type, kind Kind, format string) {
+	if mismatch(t, kind) {
+		c.errorf(node, format, t)
+	}
+}
+
+func (c *checker) infer(node ast.Node, s scope) *Type {
+	switch n := node.(type) {
+	case *ast.Literal:
+		switch n.Value.(type) {
+		case nil:
+			return NilType
+		case bool:
+			return BoolType
+		case int, float64:
+			return NumberType
+		case string:
+			return StringType
+		}
+		return AnyType
+	case *ast.InterpolatedString:
+		for _, part := range n.Parts {
+			c.expr(part, s)
+		}
+		return StringType
+	case *ast.ArrayLit:
+		return c.array(n, s)
+	case *ast.ObjectLit:
+		return c.object(n, s)
+	case *ast.Ident:
+		if t, ok := s[n.Name]; ok {
+			return t
+		}
+		if t, ok := c.schema.Variables[n.Name]; ok {
+			return t
+		}
+		c.errorf(n, "var error: variable %q does not exist", n.Name)
+		return AnyType
+	case *ast.UnaryExpr:
+		x := c.expr(n.X, s)
+		switch n.Op {
+		case "-":
+			c.require(n, x, Number, "type error: unary minus requires number, but was %s")
+			return NumberType
+		case "!":
+			c.require(n, x, Bool, "type error: required bool, but was %s")
+			return BoolType
+		case "~":
+			c.require(n, x, Number, "type error: required number of type integer, but was %s")
+			return NumberType
+		}
+	case *ast.BinaryExpr:
+		return c.binary(n, s)
+	case *ast.TernaryExpr:
+		cond := c.expr(n.Cond, s)
+		c.require(n.Cond, cond, Bool, "type error: required bool, but was %s")
+		then, els := c.expr(n.Then, s), c.expr(n.Else, s)
+		if then.String() == els.String() {
+			return then
+		}
+	case *ast.ParenExpr:
+		return c.expr(n.X, s)
+	case *ast.CallExpr:
+		return c.call(n, nil, s)
+	case *ast.PipeExpr:
+		x := c.expr(n.X, s)
+		t := c.call(n.Call, x, s)
+		c.info.Types[n.Call] = t
+		return t
+	case *ast.SelectorExpr:
+		t := c.field(n, c.expr(n.X, s), n.Sel.Name)
+		c.info.Types[n.Sel] = t
+		return t
+	case *ast.IndexExpr:
+		return c.index(n, s)
+	case *ast.SliceExpr:
+		x := c.expr(n.X, s)
+		for _, bound := range []ast.Node{n.Low, n.High} {
+			if bound != nil {
+				c.require(bound, c.expr(bound, s), Number, "type error: required number of type integer, but was %s")
+			}
+		}
+		switch x.kind() {
+		case Array, String, Any:
+			return x
+		}
+		c.errorf(n, "syntax error: slicing requires an array or string, but was %s", x)
+	case *ast.ArrayComp:
+		inner := c.clause(n.Clause, s)
+		return &Type{Kind: Array, Elem: c.expr(n.Elem, inner)}
+	case *ast.ObjectComp:
+		inner := c.clause(n.Clause, s)
+		c.require(n.Key, c.expr(n.Key, inner), String, "type error: object key must be string, but was %s")
+		c.expr(n.Value, inner)
+		return &Type{Kind: Object}
+	}
+	return AnyType
+}
+
+func (c *checker) array(n *ast.ArrayLit, s scope) *Type {
+	var elem *Type
+	for idx, e := range n.Elems {
+		t := AnyType
+		if spread, ok := e.(*ast.Spread); ok {
+			x := c.expr(spread.X, s)
+			c.require(spread, x, Array, "type error: spread operator requires array, but was %s")
+			if x.Elem != nil {
+				t = x.Elem
+			}
+		} else {
+			t = c.expr(e, s)
+		}
+		if idx == 0 {
+			elem = t
+		} else if elem.String() != t.String() {
+			elem = AnyType
+		}
+	}
+	return &Type{Kind: Array, Elem: elem}
+}
+
+func (c *checker) object(n *ast.ObjectLit, s scope) *Type {
+	fields := make(map[string]*Type)
+	for _, member := range n.Members {
+		switch m := member.(type) {
+		case *ast.Spread:
+			c.require(m, c.expr(m.X, s), Object, "type error: spread operator requires object, but was %s")
+			fields = nil // arbitrary fields
+		case *ast.KeyValue:
+			c.require(m.Key, c.expr(m.Key, s), String, "type error: object key must be string, but was %s")
+			value := c.expr(m.Value, s)
+			if key, ok := stringLiteral(m.Key); ok && fields != nil {
+				fields[key] = value
+			} else {
+				fields = nil
+			}
+		}
+	}
+	return &Type{Kind: Object, Fields: fields}
+}
+
+// arithmetic contains the error messages of arithmetic operators, which require numbers.
+var arithmetic = map[string]string{
+	"-":  "type error: cannot subtract type %s and %s",
+	"*":  "type error: cannot multiply type %s and %s",
+	"**": "type error: cannot multiply type %s and %s",
+	"/":  "type error: cannot divide type %s and %s",
+	"%":  "type error: cannot perform modulo on type %s and %s",
+	"<":  "type error: cannot compare type %s and %s",
+	"<=": "type error: cannot compare type %s and %s",
+	">":  "type error: cannot compare type %s and %s",
+	">=": "type error: cannot compare type %s and %s",
+}
+
+func (c *checker) binary(n *ast.BinaryExpr, s scope) *Type {
+	x, y := c.expr(n.X, s), c.expr(n.Y, s)
+
+	if format, ok := arithmetic[n.Op]; ok {
+		if mismatch(x, Number) || mismatch(y, Number) {
+			c.errorf(n, format, x, y)
+		}
+		switch n.Op {
+		case "<", "<=", ">", ">=":
+			return BoolType
+		}
+		return NumberType
+	}
+
+	switch n.Op {
+	case "&&", "||":
+		c.require(n.X, x, Bool, "type error: required bool, but was %s")
+		c.require(n.Y, y, Bool, "type error: required bool, but was %s")
+		return BoolType
+	case "==", "!=":
+		return BoolType
+	case "|", "&", "^", "<<", ">>":
+		c.require(n.X, x, Number, "type error: required number of type integer, but was %s")
+		c.require(n.Y, y, Number, "type error: required number of type integer, but was %s")
+		return NumberType
+	case "in":
+		c.require(n.Y, y, Array, "syntax error: in-operator requires array, but was %s")
+		return BoolType
+	case "+":
+		return c.add(n, x, y)
+	}
+	return AnyType
+}
+
+// concatenable contains the kinds that can be concatenated with strings.
+var concatenable = map[Kind]bool{String: true, Number: true, Nil: true, Bool: true}
+
+func (c *checker) add(n *ast.BinaryExpr, x, y *Type) *Type {
+	kx, ky := x.kind(), y.kind()
+	switch {
+	case kx == String && (ky == Any || concatenable[ky]), ky == String && (kx == Any || concatenable[kx]):
+		return StringType
+	case kx == Any || ky == Any:
+		return AnyType
+	case kx == Number && ky == Number:
+		return NumberType
+	case kx == Array && ky == Array:
+		if x.Elem.String() == y.Elem.String() {
+			return x
+		}
+		return &Type{Kind: Array}
+	case kx == Object && ky == Object:
+		return &Type{Kind: Object}
+	}
+	c.errorf(n, "type error: cannot add or concatenate type %s and %s", x, y)
+	return AnyType
+}
+
+// field returns the type of a field access.
+func (c *checker) field(n ast.Node, x *Type, name string) *Type {
+	switch x.kind() {
+	case Any, Object:
+		if t := x.Field(name); t != nil {
+			return t
+		}
+		c.errorf(n, "var error: object has no member %q", name)
+	case Array:
+		c.errorf(n, "syntax error: array index must be number, but was string")
+	default:
+		c.errorf(n, "syntax error: cannot access fields on type %s", x)
+	}
+	return AnyType
+}
+
+func (c *checker) index(n *ast.IndexExpr, s scope) *Type {
+	x, idx := c.expr(n.X, s), c.expr(n.Index, s)
+	switch x.kind() {
+	case Any:
+		return AnyType
+	case Array:
+		c.require(n.Index, idx, Number, "syntax error: array index must be number, but was %s")
+		return x.Elem
+	case Object:
+		if key, ok := stringLiteral(n.Index); ok {
+			return c.field(n, x, key)
+		}
+		c.require(n.Index, idx, String, "syntax error: object key must be string, but was %s")
+		return AnyType
+	}
+	c.errorf(n, "syntax error: cannot access fields on type %s", x)
+	return AnyType
+}
+
+func stringLiteral(node ast.Node) (string, bool) {
+	lit, ok := node.(*ast.Literal)
+	if !ok {
+		return "", false
+	}
+	str, ok := lit.Value.(string)
+	return str, ok
+}
+
+// clause checks the for clause of a comprehension and returns the scope containing its loop variables.
+func (c *checker) clause(fc *ast.ForClause, s scope) scope {
+	x := c.expr(fc.X, s)
+	key, value := AnyType, AnyType
+	switch x.kind() {
+	case Array:
+		key = NumberType
+		if x.Elem != nil {
+			value = x.Elem
+		}
+	case Object:
+		key = StringType
+	case Any:
+	default:
+		c.errorf(fc.X, "type error: cannot iterate over %s", x)
+	}
+
+	inner := make(scope, len(s)+len(fc.Vars))
+	for name, t := range s {
+		inner[name] = t
+	}
+	vars := []*Type{value}
+	if len(fc.Vars) == 2 || x.kind() == Object {
+		vars = []*Type{key, value}
+	}
+	for idx, v := range fc.Vars {
+		inner[v.Name] = vars[idx]
+		c.info.Types[v] = vars[idx]
+	}
+	if fc.Cond != nil {
+		c.require(fc.Cond, c.expr(fc.Cond, inner), Bool, "type error: required bool, but was %s")
+	}
+	return inner
+}
+
+// call checks a function call. first is the type of the piped value, or nil.
+func (c *checker) call(n *ast.CallExpr, first *Type, s scope) *Type {
+	var args []*Type
+	if first != nil {
+		args = append(args, first)
+	}
+	spread := false
+	for _, arg := range n.Args {
+		if sp, ok := arg.(*ast.Spread); ok {
+			c.require(sp, c.expr(sp.X, s), Array, "type error: spread operator requires array, but was %s")
+			spread = true
+			continue
+		}
+		args = append(args, c.expr(arg, s))
+	}
+
+	name := n.Func.Name
+	f, ok := c.schema.Functions[name]
+	if !ok {
+		c.errorf(n.Func, "syntax error: no such function %q", name)
+		return AnyType
+	}
+	params := len(f.Params)
+	switch {
+	case spread:
+	case f.Variadic && len(args) < params-1:
+		c.errorf(n, "function error: %q requires at least %d arguments, but got %d", name, params-1, len(args))
+	case !f.Variadic && len(args) != params:
+		c.errorf(n, "function error: %q requires %d arguments, but got %d", name, params, len(args))
+	}
+	if !spread {
+		for idx, arg := range args {
+			if idx >= params && !f.Variadic {
+				break
+			}
+			param := f.Params[min(idx, params-1)]
+			if known(param.Type) && mismatch(arg, param.Type.kind()) {
+				c.errorf(n, "type error: argument %d of %q requires %s, but was %s", idx+1, name, param.Type, arg)
+			}
+		}
+	}
+	if f.Result == nil {
+		return AnyType
+	}
+	return f.Result
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
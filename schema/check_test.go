@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maja42/goval"
+)
+
+func Test_Check(t *testing.T) {
+	s := loadTestSchema(t)
+	assertErrors := func(expr string, expected ...string) {
+		t.Helper()
+		program, err := goval.Parse(expr)
+		if !assert.NoError(t, err) {
+			return
+		}
+		var errs []string
+		for _, e := range s.Check(program.Root).Errors {
+			errs = append(errs, e.Error())
+		}
+		assert.Equal(t, expected, errs, "expression: %s", expr)
+	}
+	assertErrors(`user.age > limit && len(user.tags) > 0`)
+	assertErrors(`data.x.y + data[1] - 1`)
+	assertErrors(`user.meta.anything`)
+	assertErrors(`"id: " + user.id + user.age`)
+	assertErrors(`upper(user.address.city) |> len()`)
+	assertErrors(`max(1, 2, user.age) + max()`)
+	assertErrors(`[t + "!" for t in user.tags if t != ""]`)
+	assertErrors(`{k: v for k, v in user if k != "id"}`)
+	assertErrors(`"a" in user.tags ? 1 : 2`)
+
+	assertErrors(`usr.id`, `var error: variable "usr" does not exist at position 1`)
+	assertErrors(`user.name`, `var error: object has no member "name" at position 1`)
+	assertErrors(`user.address.street`, `var error: object has no member "street" at position 1`)
+	assertErrors(`user["name"]`, `var error: object has no member "name" at position 1`)
+	assertErrors(`user.tags.first`, `syntax error: array index must be number, but was string at position 1`)
+	assertErrors(`user.tags["x"]`, `syntax error: array index must be number, but was string at position 11`)
+	assertErrors(`limit.x`, `syntax error: cannot access fields on type number at position 1`)
+	assertErrors(`user.id[0]`, `syntax error: cannot access fields on type string at position 1`)
+	assertErrors(`limit[1:]`, `syntax error: slicing requires an array or string, but was number at position 1`)
+	assertErrors(`foo(1)`, `syntax error: no such function "foo" at position 1`)
+
+	assertErrors(`user.id > 5`, `type error: cannot compare type string and number at position 1`)
+	assertErrors(`user.age - user.id`, `type error: cannot subtract type number and string at position 1`)
+	assertErrors(`user.tags + 1`, `type error: cannot add or concatenate type array<string> and number at position 1`)
+	assertErrors(`user.tags + user.meta`, `type error: cannot add or concatenate type array<string> and object at position 1`)
+	assertErrors(`-user.id`, `type error: unary minus requires number, but was string at position 1`)
+	assertErrors(`!limit || user.age`,
+		`type error: required bool, but was number at position 1`,
+		`type error: required bool, but was number at position 11`)
+	assertErrors(`limit | user.id`, `type error: required number of type integer, but was string at position 9`)
+	assertErrors(`1 in user.id`, `syntax error: in-operator requires array, but was string at position 6`)
+	assertErrors(`limit ? 1 : 2`, `type error: required bool, but was number at position 1`)
+	assertErrors(`[x for x in limit]`, `type error: cannot iterate over number at position 13`)
+	assertErrors(`[t - 1 for t in user.tags]`, `type error: cannot subtract type string and number at position 2`)
+	assertErrors(`{limit: 1}`, `type error: object key must be string, but was number at position 2`)
+
+	assertErrors(`len()`, `function error: "len" requires 1 arguments, but got 0 at position 1`)
+	assertErrors(`len(1, 2)`, `function error: "len" requires 1 arguments, but got 2 at position 1`)
+	assertErrors(`upper(limit)`, `type error: argument 1 of "upper" requires string, but was number at position 1`)
+	assertErrors(`limit |> upper()`, `type error: argument 1 of "upper" requires string, but was number at position 10`)
+	assertErrors(`max(1, "2")`, `type error: argument 2 of "max" requires number, but was string at position 1`)
+	assertErrors(`upper(...user.tags)`)
+}
+
+func Test_Check_Types(t *testing.T) {
+	s := loadTestSchema(t)
+	assertType := func(expected, expr string) {
+		t.Helper()
+		program, err := goval.Parse(expr)
+		if !assert.NoError(t, err) {
+			return
+		}
+		info := s.Check(program.Root)
+		assert.Empty(t, info.Errors, "expression: %s", expr)
+		assert.Equal(t, expected, info.Types[program.Root].String(), "expression: %s", expr)
+	}
+	assertType("number", `user.age`)
+	assertType("array<string>", `user.tags`)
+	assertType("array<string>", `user.tags[1:]`)
+	assertType("string", `user.tags[0]`)
+	assertType("string", `user["address"].city`)
+	assertType("any", `data.x`)
+	assertType("bool", `user.age > 18 && true`)
+	assertType("string", `"age: " + user.age`)
+	assertType("string", `f"{user.id}"`)
+	assertType("number", `len(user.tags)`)
+	assertType("string", `user.id |> upper()`)
+	assertType("any", `random()`)
+	assertType("array<number>", `[1, 2, user.age]`)
+	assertType("array", `[1, "2"]`)
+	assertType("array<string>", `[...user.tags, "x"]`)
+	assertType("array<number>", `[len(t) for t in user.tags]`)
+	assertType("string", `{"a": {"b": user.id}}.a.b`)
+	assertType("number", `limit > 5 ? 1 : 2`)
+	assertType("any", `limit > 5 ? 1 : "2"`)
+}
@@ -0,0 +1,262 @@
+// Package schema describes the variables and functions that are available to expressions,
+// and checks expressions against them without evaluating them.
+//
+// Schemas are usually loaded from JSON:
+//
+//	{
+//	  "variables": {
+//	    "user": {"type": "object", "doc": "The current user.", "fields": {
+//	      "id":   {"type": "string", "required": true},
+//	      "age":  {"type": "number"},
+//	      "tags": {"type": "array", "elem": {"type": "string"}}
+//	    }}
+//	  },
+//	  "functions": {
+//	    "len": {"params": [{"name": "value"}], "result": {"type": "number"}, "doc": "Returns the length of a string, array or object."}
+//	  }
+//	}
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kind is the kind of a type.
+type Kind string
+
+// Kinds. The names are the same as within error messages.
+const (
+	Any    Kind = "any"
+	Nil    Kind = "nil"
+	Bool   Kind = "bool"
+	Number Kind = "number"
+	String Kind = "string"
+	Array  Kind = "array"
+	Object Kind = "object"
+)
+
+// Type is the static type of a variable, field, or expression.
+type Type struct {
+	Kind     Kind             `json:"type,omitempty"` // defaults to Any
+	Doc      string           `json:"doc,omitempty"`
+	Required bool             `json:"required,omitempty"` // the value is never nil
+	Elem     *Type            `json:"elem,omitempty"`     // element type of arrays; nil for unknown elements
+	Fields   map[string]*Type `json:"fields,omitempty"`   // fields of objects; nil if the object can have arbitrary fields
+}
+
+// Common types.
+var (
+	AnyType    = &Type{Kind: Any}
+	NilType    = &Type{Kind: Nil}
+	BoolType   = &Type{Kind: Bool}
+	NumberType = &Type{Kind: Number}
+	StringType = &Type{Kind: String}
+)
+
+// String returns the type, like "number", "array<string>" or "object".
+func (t *Type) String() string {
+	if t == nil {
+		return string(Any)
+	}
+	if t.Kind == Array && t.Elem != nil && t.Elem.Kind != Any {
+		return "array<" + t.Elem.String() + ">"
+	}
+	if t.Kind == "" {
+		return string(Any)
+	}
+	return string(t.Kind)
+}
+
+func (t *Type) kind() Kind {
+	if t == nil || t.Kind == "" {
+		return Any
+	}
+	return t.Kind
+}
+
+// Field returns the type of the field of an object.
+// Returns nil if the type is not an object, or if the field is unknown.
+// Objects without declared fields have fields of type Any.
+func (t *Type) Field(name string) *Type {
+	switch t.kind() {
+	case Any:
+		return AnyType
+	case Object:
+		if t.Fields == nil {
+			return AnyType
+		}
+		return t.Fields[name]
+	}
+	return nil
+}
+
+// FieldNames returns the sorted names of all declared fields.
+func (t *Type) FieldNames() []string {
+	if t == nil {
+		return nil
+	}
+	names := make([]string, 0, len(t.Fields))
+	for name := range t.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Param is a function parameter.
+type Param struct {
+	Name string `json:"name"`
+	Type *Type  `json:"type,omitempty"` // defaults to Any
+}
+
+// Function describes a function.
+type Function struct {
+	Params   []Param `json:"params"`
+	Variadic bool    `json:"variadic,omitempty"` // the last parameter can be repeated, or omitted
+	Result   *Type   `json:"result,omitempty"`   // defaults to Any
+	Doc      string  `json:"doc,omitempty"`
+}
+
+// Signature returns the function signature, like "len(value: any) number".
+func (f *Function) Signature(name string) string {
+	params := make([]string, len(f.Params))
+	for idx, p := range f.Params {
+		params[idx] = p.Name + ": " + p.Type.String()
+		if f.Variadic && idx == len(f.Params)-1 {
+			params[idx] = "..." + params[idx]
+		}
+	}
+	return fmt.Sprintf("%s(%s) %s", name, strings.Join(params, ", "), f.Result.String())
+}
+
+// Schema describes the variables and functions that are available to expressions.
+type Schema struct {
+	Variables map[string]*Type     `json:"variables"`
+	Functions map[string]*Function `json:"functions"`
+}
+
+// Load parses a schema from JSON.
+// Unknown fields and kinds are rejected.
+func Load(data []byte) (*Schema, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var s Schema
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+	for name, t := range s.Variables {
+		if t == nil {
+			s.Variables[name] = AnyType
+		}
+		if err := validate(name, t); err != nil {
+			return nil, err
+		}
+	}
+	for name, f := range s.Functions {
+		if f == nil {
+			return nil, fmt.Errorf("schema error: function %q has no definition", name)
+		}
+		if f.Variadic && len(f.Params) == 0 {
+			return nil, fmt.Errorf("schema error: variadic function %q has no parameters", name)
+		}
+		for _, p := range f.Params {
+			if err := validate(name+"("+p.Name+")", p.Type); err != nil {
+				return nil, err
+			}
+		}
+		if err := validate(name+"()", f.Result); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+// validate checks the given type recursively.
+// Fields and array elements that are declared as null are replaced by AnyType.
+func validate(path string, t *Type) error {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind {
+	case "", Any, Nil, Bool, Number, String, Array, Object:
+	default:
+		return fmt.Errorf("schema error: %s: unknown type %q", path, t.Kind)
+	}
+	if t.Elem != nil && t.Kind != Array {
+		return fmt.Errorf("schema error: %s: only arrays can have an element type", path)
+	}
+	if t.Fields != nil && t.Kind != Object {
+		return fmt.Errorf("schema error: %s: only objects can have fields", path)
+	}
+	if t.Kind == Array && t.Elem == nil {
+		t.Elem = AnyType
+	}
+	if err := validate(path+"[]", t.Elem); err != nil {
+		return err
+	}
+	for name, field := range t.Fields {
+		if field == nil {
+			t.Fields[name] = AnyType
+			continue
+		}
+		if err := validate(path+"."+name, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VariableNames returns the sorted names of all variables.
+func (s *Schema) VariableNames() []string {
+	return (&Type{Kind: Object, Fields: s.Variables}).FieldNames()
+}
+
+// FunctionNames returns the sorted names of all functions.
+func (s *Schema) FunctionNames() []string {
+	names := make([]string, 0, len(s.Functions))
+	for name := range s.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the type of a variable or field path, like "user.address.city".
+// Returns nil if the path does not exist.
+func (s *Schema) Lookup(path string) *Type {
+	parts := strings.Split(path, ".")
+	t := s.Variables[parts[0]]
+	for _, part := range parts[1:] {
+		if t == nil {
+			return nil
+		}
+		t = t.Field(part)
+	}
+	return t
+}
+
+// RequiredPaths returns the sorted paths of all required variables and fields, like "user.id".
+// Fields of optional objects are included as well, since accessing fields of nil fails instead of returning nil.
+func (s *Schema) RequiredPaths() []string {
+	var paths []string
+	var collect func(prefix string, fields map[string]*Type)
+	collect = func(prefix string, fields map[string]*Type) {
+		for name, t := range fields {
+			if t == nil {
+				continue
+			}
+			if t.Required {
+				paths = append(paths, prefix+name)
+			}
+			collect(prefix+name+".", t.Fields)
+		}
+	}
+	collect("", s.Variables)
+	sort.Strings(paths)
+	return paths
+}
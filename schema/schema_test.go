@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maja42/goval"
+)
+
+const testSchema = `{
+	"variables": {
+		"user": {"type": "object", "doc": "The current user.", "fields": {
+			"id":      {"type": "string", "required": true},
+			"age":     {"type": "number"},
+			"tags":    {"type": "array", "elem": {"type": "string"}},
+			"address": {"type": "object", "fields": {
+				"city": {"type": "string", "required": true}
+			}},
+			"meta":    {"type": "object"}
+		}},
+		"limit": {"type": "number", "required": true},
+		"data":  {}
+	},
+	"functions": {
+		"len":    {"params": [{"name": "value"}], "result": {"type": "number"}, "doc": "Returns the length."},
+		"upper":  {"params": [{"name": "str", "type": {"type": "string"}}], "result": {"type": "string"}},
+		"max":    {"params": [{"name": "values", "type": {"type": "number"}}], "variadic": true, "result": {"type": "number"}},
+		"random": {"params": []}
+	}
+}`
+
+func loadTestSchema(t *testing.T) *Schema {
+	t.Helper()
+	s, err := Load([]byte(testSchema))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return s
+}
+
+func Test_Load(t *testing.T) {
+	s := loadTestSchema(t)
+	assert.Equal(t, []string{"data", "limit", "user"}, s.VariableNames())
+	assert.Equal(t, []string{"len", "max", "random", "upper"}, s.FunctionNames())
+	assert.Equal(t, "any", s.Variables["data"].String())
+	assert.Equal(t, []string{"address", "age", "id", "meta", "tags"}, s.Variables["user"].FieldNames())
+}
+
+func Test_Load_Null(t *testing.T) {
+	s, err := Load([]byte(`{"variables": {"a": {"type": "object", "fields": {"b": null, "c": {"type": "array", "elem": null}}}}}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, AnyType, s.Lookup("a.b"))
+	assert.Equal(t, &Type{Kind: Array, Elem: AnyType}, s.Lookup("a.c"))
+
+	program, err := goval.Parse(`a.b.x + a.c[0]`)
+	if assert.NoError(t, err) {
+		assert.Empty(t, s.Check(program.Root).Errors)
+	}
+}
+
+func Test_Load_Errors(t *testing.T) {
+	assertError := func(expected, data string) {
+		t.Helper()
+		s, err := Load([]byte(data))
+		assert.Nil(t, s)
+		if assert.Error(t, err) {
+			assert.Equal(t, expected, err.Error())
+		}
+	}
+	assertError("json error: unexpected EOF", `{"variables": {`)
+	assertError(`json error: json: unknown field "variable"`, `{"variable": {}}`)
+	assertError(`schema error: a: unknown type "int"`, `{"variables": {"a": {"type": "int"}}}`)
+	assertError(`schema error: a.b[]: unknown type "list"`, `{"variables": {"a": {"type": "object", "fields": {"b": {"type": "array", "elem": {"type": "list"}}}}}}`)
+	assertError(`schema error: a: only arrays can have an element type`, `{"variables": {"a": {"type": "string", "elem": {}}}}`)
+	assertError(`schema error: a: only objects can have fields`, `{"variables": {"a": {"fields": {}}}}`)
+	assertError(`schema error: function "f" has no definition`, `{"functions": {"f": null}}`)
+	assertError(`schema error: variadic function "f" has no parameters`, `{"functions": {"f": {"params": [], "variadic": true}}}`)
+	assertError(`schema error: f(x): unknown type "int"`, `{"functions": {"f": {"params": [{"name": "x", "type": {"type": "int"}}]}}}`)
+	assertError(`schema error: f(): unknown type "int"`, `{"functions": {"f": {"params": [], "result": {"type": "int"}}}}`)
+}
+
+func Test_Type_String(t *testing.T) {
+	var nilType *Type
+	assert.Equal(t, "any", nilType.String())
+	assert.Equal(t, "any", (&Type{}).String())
+	assert.Equal(t, "number", NumberType.String())
+	assert.Equal(t, "array", (&Type{Kind: Array}).String())
+	assert.Equal(t, "array<array<string>>", (&Type{Kind: Array, Elem: &Type{Kind: Array, Elem: StringType}}).String())
+}
+
+func Test_Lookup(t *testing.T) {
+	s := loadTestSchema(t)
+	assert.Equal(t, NumberType.Kind, s.Lookup("limit").Kind)
+	assert.Equal(t, "The current user.", s.Lookup("user").Doc)
+	assert.Equal(t, "array<string>", s.Lookup("user.tags").String())
+	assert.Equal(t, String, s.Lookup("user.address.city").Kind)
+	assert.Equal(t, AnyType, s.Lookup("user.meta.anything"))
+	assert.Equal(t, AnyType, s.Lookup("data.x.y"))
+	assert.Nil(t, s.Lookup("unknown"))
+	assert.Nil(t, s.Lookup("user.unknown.x"))
+	assert.Nil(t, s.Lookup("limit.x"))
+}
+
+func Test_RequiredPaths(t *testing.T) {
+	s := loadTestSchema(t)
+	assert.Equal(t, []string{"limit", "user.address.city", "user.id"}, s.RequiredPaths())
+}
+
+func Test_Signature(t *testing.T) {
+	s := loadTestSchema(t)
+	assert.Equal(t, "len(value: any) number", s.Functions["len"].Signature("len"))
+	assert.Equal(t, "upper(str: string) string", s.Functions["upper"].Signature("upper"))
+	assert.Equal(t, "max(...values: number) number", s.Functions["max"].Signature("max"))
+	assert.Equal(t, "random() any", s.Functions["random"].Signature("random"))
+}
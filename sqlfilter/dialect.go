@@ -0,0 +1,42 @@
+package sqlfilter
+
+import "strconv"
+
+// Dialect describes the SQL syntax of a database.
+type Dialect struct {
+	Name string
+
+	placeholder func(n int) string       // placeholder for the n-th (1-based) parameter
+	equal       func(x, y string) string // null-safe equality
+	notEqual    func(x, y string) string // null-safe inequality
+	concat      func(x, y string) string // string concatenation
+}
+
+// Supported dialects.
+var (
+	SQLite = &Dialect{
+		Name:        "sqlite",
+		placeholder: questionMark,
+		equal:       func(x, y string) string { return x + " IS " + y },
+		notEqual:    func(x, y string) string { return x + " IS NOT " + y },
+		concat:      func(x, y string) string { return x + " || " + y },
+	}
+	PostgreSQL = &Dialect{
+		Name:        "postgresql",
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		equal:       func(x, y string) string { return x + " IS NOT DISTINCT FROM " + y },
+		notEqual:    func(x, y string) string { return x + " IS DISTINCT FROM " + y },
+		concat:      func(x, y string) string { return x + " || " + y },
+	}
+	MySQL = &Dialect{
+		Name:        "mysql",
+		placeholder: questionMark,
+		equal:       func(x, y string) string { return x + " <=> " + y },
+		notEqual:    func(x, y string) string { return "NOT (" + x + " <=> " + y + ")" },
+		concat:      func(x, y string) string { return "CONCAT(" + x + ", " + y + ")" },
+	}
+)
+
+func questionMark(int) string {
+	return "?"
+}
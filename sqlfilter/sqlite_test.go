@@ -0,0 +1,163 @@
+//go:build cgo
+
+package sqlfilter
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maja42/goval"
+)
+
+type testUser struct {
+	id      int
+	name    interface{}
+	age     interface{}
+	score   interface{}
+	country interface{}
+	active  interface{}
+}
+
+var testUsers = []testUser{
+	{1, "Ann", 42, 7.5, "AT", true},
+	{2, "Bob", 17, 3.0, "DE", false},
+	{3, "Carl", 18, 9.25, "CH", true},
+	{4, "Dora", nil, 5.0, "AT", true},
+	{5, nil, 65, nil, nil, false},
+	{6, "Eve", 0, -1.5, "DE", nil},
+	{7, "", -3, 0.0, "US", true},
+	{8, "Ann", 30, 10.0, "at", false},
+}
+
+// Test_SQLite verifies that the translated conditions select the same rows for which expressions evaluate to true.
+func Test_SQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, age INTEGER, score REAL, country TEXT, active BOOLEAN)`)
+	require.NoError(t, err)
+	for _, u := range testUsers {
+		_, err = db.Exec(`INSERT INTO users VALUES (?, ?, ?, ?, ?, ?)`, u.id, u.name, u.age, u.score, u.country, u.active)
+		require.NoError(t, err)
+	}
+
+	params := map[string]interface{}{
+		"minAge":  18,
+		"allowed": []interface{}{"AT", "DE"},
+		"limits":  map[string]interface{}{"score": 5.0},
+	}
+	tr := &Translator{
+		Dialect: SQLite,
+		Columns: map[string]string{
+			"user.name":    "name",
+			"user.age":     "age",
+			"user.score":   "score",
+			"user.country": "country",
+			"user.active":  "active",
+		},
+		Variables: params,
+	}
+
+	expressions := []string{
+		`true`,
+		`false`,
+		`user.age >= 18`,
+		`user.age >= minAge && user.active`,
+		`user.age < 18 || user.active == false`,
+		`!(user.age > 20)`,
+		`user.active`,
+		`!user.active`,
+		`user.active == true`,
+		`user.active != true`,
+		`user.name == "Ann"`,
+		`user.name != "Ann"`,
+		`user.name == nil`,
+		`user.name != nil`,
+		`nil == user.country`,
+		`user.age == nil || user.age > 40`,
+		`user.country in ["AT", "DE"]`,
+		`!(user.country in ["AT", "DE"])`,
+		`user.country in allowed`,
+		`user.country in ["CH", nil]`,
+		`user.country in []`,
+		`!(user.country in [])`,
+		`user.name in [user.country, "Ann", "Eve"]`,
+		`user.name == user.name`,
+		`user.name != user.name`,
+		`user.age + 2 > 20`,
+		`user.age * 2 - 1 >= 35`,
+		`user.age / 2 == 9`,
+		`user.age % 4 == 2`,
+		`-user.age < -20`,
+		`user.score >= limits.score`,
+		`user.score * 2 == 15`,
+		`user.score + user.age > 20`,
+		`user.age == 18.0`,
+		`user.score == 3`,
+		`user.name + "!" == "Ann!"`,
+		`user.name + "" + user.country == "AnnAT"`,
+		`"#" + user.age == "#42"`,
+		`"#" + user.score == "#7.5"`,
+		`user.name + "" + limits.score == "Ann5"`,
+		`user.name + "" + 1e21 == "Ann1000000000000000000000"`,
+		`user.age > 10 && (user.country == "AT" || user.country == "DE") && user.name != "Bob"`,
+		`!(user.active && user.age >= 18) || user.country == "US"`,
+		`minAge == 18 && user.age > minAge + 1`,
+		`minAge != nil || user.active`,
+		`"AT" in allowed == (user.age > -minAge * 2)`,
+	}
+
+	// number columns are formatted by the database when they are concatenated, SQLite formats the REAL value 3 as "3.0"
+	mismatches := map[string]map[int]bool{
+		`"#" + user.score == "#3"`:   {2: true},
+		`"#" + user.score == "#3.0"`: {2: true},
+	}
+	expressions = append(expressions, `"#" + user.score == "#3"`, `"#" + user.score == "#3.0"`)
+
+	for _, expr := range expressions {
+		where, args, err := tr.Translate(expr)
+		if !assert.NoError(t, err, "expression: %s", expr) {
+			continue
+		}
+
+		selected := make(map[int]bool)
+		rows, err := db.Query("SELECT id FROM users WHERE "+where, args...)
+		if !assert.NoError(t, err, "expression: %s\nsql: %s", expr, where) {
+			continue
+		}
+		for rows.Next() {
+			var id int
+			require.NoError(t, rows.Scan(&id))
+			selected[id] = true
+		}
+		require.NoError(t, rows.Close())
+
+		evaluated := 0
+		for _, u := range testUsers {
+			vars := map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":    u.name,
+					"age":     u.age,
+					"score":   u.score,
+					"country": u.country,
+					"active":  u.active,
+				},
+			}
+			for k, v := range params {
+				vars[k] = v
+			}
+			result, err := goval.NewEvaluator().Evaluate(expr, vars, nil)
+			if err != nil {
+				continue // the row may or may not be selected
+			}
+			evaluated++
+			assert.Equal(t, (result == true) != mismatches[expr][u.id], selected[u.id], "expression: %s\nsql: %s\nuser: %d", expr, where, u.id)
+		}
+		assert.NotZero(t, evaluated, "expression: %s", expr)
+	}
+}
@@ -0,0 +1,424 @@
+// Package sqlfilter translates expressions into SQL conditions with bound parameters,
+// so that rules can be used as filters within database queries.
+//
+// Only a subset of the language is supported:
+//
+//	comparisons   ==  !=  <  <=  >  >=
+//	logic         &&  ||  !
+//	arrays        x in [a, b, c]
+//	arithmetic    +  -  *  /  %  and unary -
+//	literals      nil, bool, number and string
+//	variables     mapped to columns, or bound as parameters
+//
+// All other constructs (function calls, pipes, ternaries, comprehensions, ...) cause an error.
+// Literals and variable values are never inlined. They are passed as bound parameters.
+// Operations without columns, like `minAge + 1`, are evaluated in memory and their result is passed as a single parameter.
+//
+// The condition selects the same rows for which the expression evaluates to true in memory.
+// Equality (==, != and in) is null-safe, so that nil behaves the same way as within goval:
+// `x == nil` matches NULL columns, and `x != 1` also matches NULL columns.
+// If the in-memory evaluation fails (like when comparing nil with a number), the row may or may not be selected.
+//
+// Columns have no static type. The operator + is translated into a string concatenation if one of its operands
+// is a string literal or string variable, and into an addition otherwise. `a + "" + b` always concatenates,
+// while `name + 1` is an addition even if name is a string column. Comparing values that are known to have different types,
+// like `name + 1 == "Ann1"`, causes an error.
+// Number variables and literals are formatted the same way as within goval before they are concatenated,
+// but number columns are formatted by the database: SQLite formats the REAL value 3 as "3.0", and large numbers differ as well.
+// Further differences are caused by the database:
+// SQLite and MySQL compare numbers with strings after converting them,
+// MySQL performs decimal division for integers, and SQLite converts floats to integers before performing modulo.
+package sqlfilter
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/ast"
+	"github.com/maja42/goval/internal"
+)
+
+// Translator converts expressions into SQL conditions.
+type Translator struct {
+	Dialect *Dialect // defaults to SQLite
+
+	// Columns maps variables to column names.
+	// Keys are variables or field paths, like "age" or "user.address.city".
+	// Column names are inserted verbatim and can be qualified, like "u.age".
+	Columns map[string]string
+
+	// Variables contains values that are not stored within the database.
+	// They are passed as bound parameters. Supports nil, bool, int, float64, string and arrays thereof.
+	Variables map[string]interface{}
+}
+
+// Translate converts the expression into a SQL condition, like `age >= ? AND country IS ?`.
+// Returns the condition together with the arguments for its placeholders.
+func (t *Translator) Translate(expr string) (where string, args []interface{}, err error) {
+	program, err := goval.Parse(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	return t.TranslateAST(program.Root)
+}
+
+// TranslateAST converts a parsed expression into a SQL condition.
+func (t *Translator) TranslateAST(node ast.Node) (where string, args []interface{}, err error) {
+	defer recoverError(&err)
+
+	tr := *t
+	if tr.Dialect == nil {
+		tr.Dialect = SQLite
+	}
+	f := tr.expr(node)
+	requireKind(f, node, kindBool)
+
+	// replace parameter markers with placeholders
+	var sb strings.Builder
+	parts := strings.Split(f.sql, paramMarker)
+	for i, part := range parts {
+		if i > 0 {
+			sb.WriteString(tr.Dialect.placeholder(i))
+		}
+		sb.WriteString(part)
+	}
+	return sb.String(), f.args, nil
+}
+
+// recoverError converts panics caused by unsupported expressions into errors.
+// Runtime errors are bugs, and therefore not recovered.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		if _, ok := r.(runtime.Error); ok {
+			panic(r)
+		}
+		*err = r.(error)
+	}
+}
+
+// paramMarker is used within fragments instead of placeholders, which are numbered in the end.
+const paramMarker = "\x00"
+
+// kind is the type of a fragment, as far as it is known.
+type kind int
+
+const (
+	kindUnknown kind = iota // columns and results of operations with columns
+	kindNil
+	kindBool
+	kindNumber
+	kindString
+	kindArray
+)
+
+func (k kind) String() string {
+	return [...]string{"unknown", "nil", "bool", "number", "string", "array"}[k]
+}
+
+// SQL precedence levels, from lowest to highest.
+// The levels are a simplification that is valid for all dialects. Parentheses are added where dialects differ.
+const (
+	precOr = iota + 1
+	precAnd
+	precNot
+	precComparison // including equality and IS
+	precConcat
+	precAdditive
+	precMultiplicative
+	precUnary
+	precAtom
+)
+
+// fragment is a translated sub-expression.
+type fragment struct {
+	sql   string
+	args  []interface{} // values for all parameter markers within sql, in order
+	kind  kind
+	prec  int
+	elems []fragment // array elements
+	known bool       // does not contain columns
+}
+
+// operand returns the SQL of a fragment that is used as an operand of another operation.
+// It is wrapped in parentheses if its precedence is below minPrec.
+func operand(f fragment, minPrec int) string {
+	if f.prec >= minPrec {
+		return f.sql
+	}
+	return "(" + f.sql + ")"
+}
+
+func param(val interface{}, k kind) fragment {
+	return fragment{sql: paramMarker, args: []interface{}{val}, kind: k, prec: precAtom, known: true}
+}
+
+func args(fragments ...fragment) []interface{} {
+	var res []interface{}
+	for _, f := range fragments {
+		res = append(res, f.args...)
+	}
+	return res
+}
+
+// requireKind panics if the fragment is known to have a different kind.
+func requireKind(f fragment, n ast.Node, k kind) {
+	if f.kind != kindUnknown && f.kind != k {
+		panic(fmt.Errorf("sql error: required %s, but was %s at position %d", k, f.kind, n.Pos()))
+	}
+}
+
+// requireConcatenable panics if the fragment is known to be of a type that cannot be concatenated with a string.
+func requireConcatenable(f fragment, n ast.Node) {
+	if f.kind == kindNumber {
+		return
+	}
+	requireKind(f, n, kindString)
+}
+
+// requireComparable panics if the fragments are known to have different kinds, so that they can never be equal.
+// Databases might convert them into each other before comparing them, like `age + 1 == "19"`.
+func requireComparable(x, y fragment, n ast.Node) {
+	if x.kind != kindUnknown && y.kind != kindUnknown && x.kind != kindNil && y.kind != kindNil && x.kind != y.kind {
+		panic(fmt.Errorf("sql error: cannot compare %s and %s at position %d", x.kind, y.kind, n.Pos()))
+	}
+}
+
+// formatNumber converts a number parameter into a string parameter before it is concatenated.
+// Databases format floats differently, like "3.0" instead of "3".
+func formatNumber(f fragment) fragment {
+	if f.kind != kindNumber || !f.known {
+		return f
+	}
+	str, err := internal.ToString(f.args[0])
+	if err != nil {
+		panic(err)
+	}
+	return param(str, kindString)
+}
+
+// requireValue panics if the fragment is an array.
+func requireValue(f fragment, n ast.Node) {
+	if f.kind == kindArray {
+		panic(fmt.Errorf("sql error: arrays are only supported on the right side of 'in' at position %d", n.Pos()))
+	}
+}
+
+func unsupported(what string, n ast.Node) error {
+	return fmt.Errorf("sql error: %s not supported at position %d", what, n.Pos())
+}
+
+func (t *Translator) expr(node ast.Node) fragment {
+	switch n := node.(type) {
+	case *ast.ParenExpr:
+		return t.expr(n.X)
+	case *ast.Literal:
+		return value(n.Value, n)
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr:
+		return t.variable(n)
+	case *ast.ArrayLit:
+		arr := fragment{kind: kindArray, known: true}
+		for _, elem := range n.Elems {
+			if _, ok := elem.(*ast.Spread); ok {
+				panic(unsupported("spread operators are", elem))
+			}
+			f := t.expr(elem)
+			requireValue(f, elem)
+			arr.elems = append(arr.elems, f)
+			arr.known = arr.known && f.known
+		}
+		return arr
+	case *ast.UnaryExpr:
+		return t.fold(n, t.unary(n))
+	case *ast.BinaryExpr:
+		return t.fold(n, t.binary(n))
+	case *ast.InterpolatedString:
+		panic(unsupported("interpolated strings are", n))
+	case *ast.ObjectLit:
+		panic(unsupported("objects are", n))
+	case *ast.CallExpr, *ast.PipeExpr:
+		panic(unsupported("function calls are", n))
+	case *ast.TernaryExpr:
+		panic(unsupported("the ternary operator is", n))
+	case *ast.SliceExpr:
+		panic(unsupported("slices are", n))
+	case *ast.ArrayComp, *ast.ObjectComp:
+		panic(unsupported("comprehensions are", n))
+	}
+	panic(unsupported(fmt.Sprintf("%T is", node), node))
+}
+
+// value converts a literal or variable value into a fragment.
+func value(val interface{}, n ast.Node) fragment {
+	switch v := val.(type) {
+	case nil:
+		return fragment{sql: "NULL", kind: kindNil, prec: precAtom, known: true}
+	case bool:
+		return param(v, kindBool)
+	case int, float64:
+		return param(v, kindNumber)
+	case string:
+		return param(v, kindString)
+	case []interface{}:
+		arr := fragment{kind: kindArray, known: true}
+		for _, elem := range v {
+			f := value(elem, n)
+			requireValue(f, n)
+			arr.elems = append(arr.elems, f)
+		}
+		return arr
+	}
+	panic(fmt.Errorf("sql error: values of type %s are not supported at position %d", internal.TypeOf(val), n.Pos()))
+}
+
+// fold replaces operations without columns by their result, which is evaluated in memory and passed as parameter.
+// Otherwise, databases might not be able to determine the types of parameters, like within `$1 IS NOT DISTINCT FROM $2`.
+func (t *Translator) fold(n ast.Node, f fragment) fragment {
+	if !f.known {
+		return f
+	}
+	val, err := goval.NewEvaluator().EvaluateAST(&ast.Program{Root: n}, t.Variables, nil)
+	if err != nil {
+		panic(err)
+	}
+	return value(val, n)
+}
+
+// variable translates a variable or field access into a column, or into the variable's value.
+func (t *Translator) variable(n ast.Node) fragment {
+	path := fieldPath(n)
+	if col, ok := t.Columns[path]; ok {
+		return fragment{sql: col, kind: kindUnknown, prec: precAtom}
+	}
+
+	fields := strings.Split(path, ".")
+	val, ok := t.Variables[fields[0]]
+	if !ok {
+		panic(fmt.Errorf("sql error: variable %q is not mapped to a column at position %d", path, n.Pos()))
+	}
+	for _, field := range fields[1:] {
+		obj, isObj := val.(map[string]interface{})
+		if val, ok = obj[field]; !isObj || !ok {
+			panic(fmt.Errorf("sql error: variable %q does not exist at position %d", path, n.Pos()))
+		}
+	}
+	return value(val, n)
+}
+
+// fieldPath returns the path of a (nested) field access, like "user.address.city".
+// Index expressions need to use string literals: `user["address"]`.
+func fieldPath(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.ParenExpr:
+		return fieldPath(n.X)
+	case *ast.Ident:
+		return n.Name
+	case *ast.SelectorExpr:
+		return fieldPath(n.X) + "." + n.Sel.Name
+	case *ast.IndexExpr:
+		if lit, ok := n.Index.(*ast.Literal); ok {
+			if key, ok := lit.Value.(string); ok {
+				return fieldPath(n.X) + "." + key
+			}
+		}
+		panic(unsupported("index expressions without string literals are", n.Index))
+	}
+	panic(unsupported("field access on computed values is", node))
+}
+
+func (t *Translator) unary(n *ast.UnaryExpr) fragment {
+	x := t.expr(n.X)
+	switch n.Op {
+	case "!":
+		requireKind(x, n.X, kindBool)
+		return fragment{sql: "NOT " + operand(x, precNot), args: x.args, kind: kindBool, prec: precNot, known: x.known}
+	case "-":
+		requireKind(x, n.X, kindNumber)
+		// "--" would start a comment
+		return fragment{sql: "-" + operand(x, precAtom), args: x.args, kind: kindNumber, prec: precUnary, known: x.known}
+	}
+	panic(unsupported(fmt.Sprintf("the operator %q is", n.Op), n))
+}
+
+var comparisonOperators = map[string]bool{"<": true, "<=": true, ">": true, ">=": true}
+
+var arithmeticOperators = map[string]int{
+	"+": precAdditive,
+	"-": precAdditive,
+	"*": precMultiplicative,
+	"/": precMultiplicative,
+	"%": precMultiplicative,
+}
+
+func (t *Translator) binary(n *ast.BinaryExpr) fragment {
+	if n.Op == "in" {
+		return t.in(n)
+	}
+	x, y := t.expr(n.X), t.expr(n.Y)
+	requireValue(x, n.X)
+	requireValue(y, n.Y)
+
+	switch n.Op {
+	case "&&", "||":
+		requireKind(x, n.X, kindBool)
+		requireKind(y, n.Y, kindBool)
+		op, prec := "AND", precAnd
+		if n.Op == "||" {
+			op, prec = "OR", precOr
+		}
+		return fragment{sql: operand(x, prec) + " " + op + " " + operand(y, prec), args: args(x, y), kind: kindBool, prec: prec, known: x.known && y.known}
+	case "==":
+		requireComparable(x, y, n)
+		return fragment{sql: t.Dialect.equal(operand(x, precConcat), operand(y, precConcat)), args: args(x, y), kind: kindBool, prec: precComparison, known: x.known && y.known}
+	case "!=":
+		requireComparable(x, y, n)
+		return fragment{sql: t.Dialect.notEqual(operand(x, precConcat), operand(y, precConcat)), args: args(x, y), kind: kindBool, prec: precComparison, known: x.known && y.known}
+	}
+
+	if comparisonOperators[n.Op] {
+		requireKind(x, n.X, kindNumber)
+		requireKind(y, n.Y, kindNumber)
+		return fragment{sql: operand(x, precConcat) + " " + n.Op + " " + operand(y, precConcat), args: args(x, y), kind: kindBool, prec: precComparison, known: x.known && y.known}
+	}
+
+	if n.Op == "+" && (x.kind == kindString || y.kind == kindString) {
+		// numbers are converted into strings, nil and bool are formatted differently by databases
+		requireConcatenable(x, n.X)
+		requireConcatenable(y, n.Y)
+		x, y = formatNumber(x), formatNumber(y)
+		// the precedence of concatenation differs between dialects, arithmetic operands are therefore always wrapped
+		sql := t.Dialect.concat(operand(x, precConcat), operand(y, precUnary))
+		return fragment{sql: sql, args: args(x, y), kind: kindString, prec: precConcat, known: x.known && y.known}
+	}
+	if prec, ok := arithmeticOperators[n.Op]; ok {
+		requireKind(x, n.X, kindNumber)
+		requireKind(y, n.Y, kindNumber)
+		return fragment{sql: operand(x, prec) + " " + n.Op + " " + operand(y, prec+1), args: args(x, y), kind: kindNumber, prec: prec, known: x.known && y.known}
+	}
+	panic(unsupported(fmt.Sprintf("the operator %q is", n.Op), n))
+}
+
+// in translates `x in [a, b]` into `x = a OR x = b`, using null-safe equality.
+func (t *Translator) in(n *ast.BinaryExpr) fragment {
+	x, arr := t.expr(n.X), t.expr(n.Y)
+	requireValue(x, n.X)
+	if arr.kind != kindArray {
+		panic(fmt.Errorf("sql error: the 'in' operator requires an array literal or variable at position %d", n.Y.Pos()))
+	}
+	if len(arr.elems) == 0 {
+		return fragment{sql: "1 = 0", kind: kindBool, prec: precComparison, known: x.known}
+	}
+
+	res := fragment{kind: kindBool, prec: precOr, known: x.known && arr.known}
+	conds := make([]string, len(arr.elems))
+	for i, elem := range arr.elems {
+		conds[i] = t.Dialect.equal(operand(x, precConcat), operand(elem, precConcat))
+		res.args = append(res.args, args(x, elem)...)
+	}
+	res.sql = strings.Join(conds, " OR ")
+	if len(conds) == 1 {
+		res.prec = precComparison
+	}
+	return res
+}
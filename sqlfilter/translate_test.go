@@ -0,0 +1,130 @@
+package sqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testColumns = map[string]string{
+	"age":          "age",
+	"name":         "name",
+	"user.country": "u.country",
+	"user.active":  "u.active",
+}
+
+func assertSQL(t *testing.T, dialect *Dialect, expectedSQL string, expectedArgs []interface{}, expr string) {
+	t.Helper()
+	tr := &Translator{
+		Dialect: dialect,
+		Columns: testColumns,
+		Variables: map[string]interface{}{
+			"minAge":  18,
+			"allowed": []interface{}{"AT", "DE"},
+			"limits":  map[string]interface{}{"age": 65},
+		},
+	}
+	where, args, err := tr.Translate(expr)
+	if assert.NoError(t, err, "expression: %s", expr) {
+		assert.Equal(t, expectedSQL, where, "expression: %s", expr)
+		assert.Equal(t, expectedArgs, args, "expression: %s", expr)
+	}
+}
+
+func assertSQLError(t *testing.T, expected string, expr string) {
+	t.Helper()
+	tr := &Translator{Columns: testColumns}
+	_, _, err := tr.Translate(expr)
+	assert.EqualError(t, err, expected, "expression: %s", expr)
+}
+
+func Test_Translate(t *testing.T) {
+	assertSQL(t, SQLite, "age >= ?", []interface{}{18}, "age >= 18")
+	assertSQL(t, SQLite, "age >= ?", []interface{}{18}, "age >= minAge")
+	assertSQL(t, SQLite, "age < ?", []interface{}{65}, `age < limits.age`)
+	assertSQL(t, SQLite, "u.country IS ?", []interface{}{"AT"}, `user["country"] == "AT"`)
+	assertSQL(t, SQLite, "u.active", nil, `(user).active`)
+	assertSQL(t, SQLite, "?", []interface{}{true}, `true`)
+
+	// logic:
+	assertSQL(t, SQLite, "age > ? AND name IS NOT NULL AND u.active", []interface{}{1},
+		"age > 1 && name != nil && user.active")
+	assertSQL(t, SQLite, "age > ? AND u.active OR NOT u.active", []interface{}{1},
+		"(age > 1 && user.active) || !user.active")
+	assertSQL(t, SQLite, "NOT (age > ? OR age < ?)", []interface{}{1, 2}, "!(age > 1 || age < 2)")
+	assertSQL(t, SQLite, "u.active IS (age > ?)", []interface{}{1}, "user.active == age > 1")
+	assertSQL(t, SQLite, "(u.active OR u.active) AND NOT NOT u.active", nil, "(user.active || user.active) && !!user.active")
+
+	// arithmetic:
+	assertSQL(t, SQLite, "(age + ?) * ? > -age", []interface{}{1, 2.5}, "(age + 1) * 2.5 > -age")
+	assertSQL(t, SQLite, "age / ? % ? IS ?", []interface{}{2, 3, 0}, "age / 2 % 3 == 0")
+	assertSQL(t, SQLite, "age - (age - ?) > -(-age)", []interface{}{1}, "age - (age - 1) > -(-age)")
+	assertSQL(t, SQLite, "name || ? || (age + ?) || name IS ?", []interface{}{"", 1, "x"}, `name + "" + (age + 1) + name == "x"`)
+	assertSQL(t, SQLite, "name || ? IS ?", []interface{}{"!", "Ann!"}, `name + "!" == "Ann!"`)
+	assertSQL(t, SQLite, "name || ? || ? IS ?", []interface{}{"", "3", "Ann3"}, `name + "" + 3.0 == "Ann3"`) // formatted like within goval
+	assertSQL(t, SQLite, "? || (? || name) IS ?", []interface{}{"1000000000000000000000", "", "x"}, `1e21 + ("" + name) == "x"`)
+	assertSQL(t, SQLite, "name || ? IS ?", []interface{}{"18", "Ann18"}, `name + ("" + minAge) == "Ann18"`)
+	assertSQL(t, SQLite, "name + ? IS ?", []interface{}{1, 2}, `name + 1 == 2`) // columns have no static type
+
+	// in:
+	assertSQL(t, SQLite, "u.country IS ? OR u.country IS ?", []interface{}{"AT", "DE"}, `user.country in ["AT", "DE"]`)
+	assertSQL(t, SQLite, "u.country IS ? OR u.country IS ?", []interface{}{"AT", "DE"}, `user.country in allowed`)
+	assertSQL(t, SQLite, "u.country IS NULL OR u.country IS name", nil, `user.country in [nil, name]`)
+	assertSQL(t, SQLite, "u.active AND age IS ?", []interface{}{1}, `user.active && age in [1]`)
+	assertSQL(t, SQLite, "u.active AND (age IS ? OR age IS ?)", []interface{}{1, 2}, `user.active && age in [1, 2]`)
+	assertSQL(t, SQLite, "1 = 0", nil, `age in []`)
+	assertSQL(t, SQLite, "NOT (age + ? IS ? OR age + ? IS ?)", []interface{}{1, 2, 1, 3}, `!((age + 1) in [2, 3])`)
+}
+
+func Test_Translate_Dialects(t *testing.T) {
+	expr := `age >= 18 && user.country != "AT" && (name + "x") in ["a", "b"]`
+	assertSQL(t, SQLite,
+		"age >= ? AND u.country IS NOT ? AND (name || ? IS ? OR name || ? IS ?)",
+		[]interface{}{18, "AT", "x", "a", "x", "b"}, expr)
+	assertSQL(t, PostgreSQL,
+		"age >= $1 AND u.country IS DISTINCT FROM $2 AND (name || $3 IS NOT DISTINCT FROM $4 OR name || $5 IS NOT DISTINCT FROM $6)",
+		[]interface{}{18, "AT", "x", "a", "x", "b"}, expr)
+	assertSQL(t, MySQL,
+		"age >= ? AND NOT (u.country <=> ?) AND (CONCAT(name, ?) <=> ? OR CONCAT(name, ?) <=> ?)",
+		[]interface{}{18, "AT", "x", "a", "x", "b"}, expr)
+	assertSQL(t, nil, "age IS ?", []interface{}{1}, "age == 1") // SQLite
+
+	// operations without columns are evaluated in memory, since databases cannot determine the type of parameters
+	// within conditions like `$1 IS NOT DISTINCT FROM $2`
+	assertSQL(t, PostgreSQL, "$1 AND age > $2", []interface{}{true, 19}, "minAge == 18 && age > minAge + 1")
+	assertSQL(t, PostgreSQL, "$1 OR u.active", []interface{}{false}, `limits.age != 65 || user.active`)
+	assertSQL(t, PostgreSQL, "name || $1 IS NOT DISTINCT FROM $2", []interface{}{"-18", "a"}, `name + ("-" + minAge) == "a"`)
+	assertSQL(t, PostgreSQL, "$1", []interface{}{true}, `"AT" in allowed && !(minAge in [])`)
+	assertSQL(t, PostgreSQL, "NULL IS NOT DISTINCT FROM age", nil, `nil == age`)
+}
+
+func Test_Translate_Errors(t *testing.T) {
+	assertSQLError(t, "syntax error: unexpected $end", "age >")
+	assertSQLError(t, "sql error: variable \"user.name\" is not mapped to a column at position 1", "user.name == 1")
+	assertSQLError(t, "sql error: function calls are not supported at position 1", "len(name) > 1")
+	assertSQLError(t, "sql error: function calls are not supported at position 1", "name |> len() > 1")
+	assertSQLError(t, "sql error: the ternary operator is not supported at position 1", "age > 1 ? true : false")
+	assertSQLError(t, "sql error: slices are not supported at position 1", `name[1:] == "a"`)
+	assertSQLError(t, "sql error: comprehensions are not supported at position 6", "1 in [x for x in age]")
+	assertSQLError(t, "sql error: objects are not supported at position 1", `{} == {}`)
+	assertSQLError(t, "sql error: interpolated strings are not supported at position 9", `name == f"{age}"`)
+	assertSQLError(t, "sql error: spread operators are not supported at position 9", `age in [...[1]]`)
+	assertSQLError(t, "sql error: the operator \"**\" is not supported at position 1", "age ** 2 > 1")
+	assertSQLError(t, "sql error: the operator \"&\" is not supported at position 1", "age & 1 == 1")
+	assertSQLError(t, "sql error: the operator \"~\" is not supported at position 1", "~age == 1")
+	assertSQLError(t, "sql error: index expressions without string literals are not supported at position 6", "user[1] == 1")
+	assertSQLError(t, "sql error: field access on computed values is not supported at position 2", "(age + 1).x == 1")
+
+	// static type errors:
+	assertSQLError(t, "sql error: required bool, but was number at position 1", "age + 1")
+	assertSQLError(t, "sql error: required bool, but was string at position 12", `age > 1 && "a"`)
+	assertSQLError(t, "sql error: required number, but was string at position 7", `age > "18"`)
+	assertSQLError(t, "sql error: required number, but was nil at position 2", `-nil == 1`)
+	assertSQLError(t, "sql error: required string, but was bool at position 7", `"a" + true == "atrue"`)
+	assertSQLError(t, "sql error: required bool, but was array at position 1", `[1]`)
+	assertSQLError(t, "sql error: cannot compare number and string at position 1", `name + 1 == "Ann1"`)
+	assertSQLError(t, "sql error: cannot compare bool and string at position 1", `(age > 1) == "x"`)
+	assertSQLError(t, "sql error: arrays are only supported on the right side of 'in' at position 8", `age == [1]`)
+	assertSQLError(t, "sql error: the 'in' operator requires an array literal or variable at position 8", `age in name`)
+	assertSQLError(t, "math error: cannot divide by zero", `age > 1 / 0`)
+}
@@ -0,0 +1,100 @@
+package template
+
+import (
+	"io"
+	"strings"
+
+	"github.com/maja42/goval"
+	"github.com/maja42/goval/internal"
+)
+
+// Execute renders the template and writes the output to w.
+//
+// Optionally accepts a list of variables (accessible but not modifiable from within expressions).
+//
+// Optionally accepts a list of expression functions (can be called from within expressions).
+//
+// Expression results are converted into strings the same way as during string concatenation.
+// Errors are returned as *Error and contain the position of the failing action.
+// Output that was written before the error occurred is not reverted.
+func (t *Template) Execute(w io.Writer, variables map[string]interface{}, functions map[string]goval.ExpressionFunction) error {
+	e := executor{
+		tmpl:      t,
+		w:         w,
+		functions: functions,
+	}
+	return e.execList(t.nodes, internal.NewScope(variables))
+}
+
+// ExecuteString renders the template and returns the output.
+func (t *Template) ExecuteString(variables map[string]interface{}, functions map[string]goval.ExpressionFunction) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, variables, functions); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+type executor struct {
+	tmpl      *Template
+	w         io.Writer
+	functions map[string]goval.ExpressionFunction
+}
+
+func (e *executor) execList(nodes []node, s *internal.Scope) error {
+	for _, n := range nodes {
+		if err := e.exec(n, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *executor) exec(n node, s *internal.Scope) error {
+	switch n := n.(type) {
+	case textNode:
+		_, err := io.WriteString(e.w, string(n))
+		return err
+	case *outputNode:
+		val, err := n.program.EvaluateIn(s, e.functions)
+		if err != nil {
+			return e.tmpl.wrapError(n.pos, err)
+		}
+		str, err := internal.ToString(val)
+		if err != nil {
+			return e.tmpl.wrapError(n.pos, err)
+		}
+		_, err = io.WriteString(e.w, str)
+		return err
+	case *ifNode:
+		for _, b := range n.branches {
+			val, err := b.cond.EvaluateIn(s, e.functions)
+			if err != nil {
+				return e.tmpl.wrapError(b.pos, err)
+			}
+			cond, err := internal.ToBool(val)
+			if err != nil {
+				return e.tmpl.wrapError(b.pos, err)
+			}
+			if cond {
+				return e.execList(b.body, s)
+			}
+		}
+		return e.execList(n.elseBody, s)
+	case *forNode:
+		src, err := n.source.EvaluateIn(s, e.functions)
+		if err != nil {
+			return e.tmpl.wrapError(n.pos, err)
+		}
+		var bodyErr error
+		err = internal.Iterate(s, src, n.vars, func(inner *internal.Scope) error {
+			bodyErr = e.execList(n.body, inner)
+			return bodyErr
+		})
+		if err != nil && err != bodyErr {
+			return e.tmpl.wrapError(n.pos, err)
+		}
+		return err
+	}
+	panic("unknown template node")
+}
@@ -0,0 +1,417 @@
+// Package template renders text templates that embed goval expressions.
+//
+// Templates contain plain text and actions, which are enclosed within double braces:
+//
+//	{{ expr }}                         outputs the result of the expression
+//	{{ if expr }} ... {{ end }}        conditional block
+//	{{ if expr }} ... {{ else if expr }} ... {{ else }} ... {{ end }}
+//	{{ for x in expr }} ... {{ end }}  iterates over array elements or object keys
+//	{{ for k, v in expr }} ... {{ end }}
+//
+// Expressions have the same syntax and semantics as for goval.Evaluator.
+// Loop variables are accessible within the loop body and shadow variables with the same name.
+//
+// A hyphen directly after the opening or before the closing braces, followed or preceded by a space,
+// trims all whitespace preceding or following the action: "{{- expr -}}".
+package template
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/maja42/goval/internal"
+)
+
+// Template is a parsed template.
+// Stateless. Can be executed concurrently.
+type Template struct {
+	name  string
+	text  string
+	nodes []node
+}
+
+// Error is returned if a template cannot be parsed or executed.
+type Error struct {
+	Name   string // template name
+	Line   int    // 1-based line of the action that caused the error
+	Column int    // 1-based column (in bytes) of the action that caused the error
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Name, e.Line, e.Column, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+type node interface{}
+
+type textNode string
+
+type outputNode struct {
+	pos     int // offset of the expression within the template
+	program *internal.Program
+}
+
+type ifNode struct {
+	branches []*branch
+	elseBody []node // nil if there is no else-block
+}
+
+type branch struct {
+	pos  int
+	cond *internal.Program
+	body []node
+}
+
+type forNode struct {
+	pos    int
+	vars   []string
+	source *internal.Program
+	body   []node
+}
+
+// Parse parses the given template text.
+// The name is only used within error messages.
+func Parse(name, text string) (*Template, error) {
+	p := &parser{
+		tmpl: &Template{name: name, text: text},
+	}
+	nodes, term, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if term != nil {
+		return nil, p.errorf(term.pos, "unexpected {{ %s }}", term.keyword)
+	}
+	p.tmpl.nodes = nodes
+	return p.tmpl, nil
+}
+
+// Must panics if err is non-nil. Intended for templates that are known at compile time.
+func Must(t *Template, err error) *Template {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Name returns the name of the template.
+func (t *Template) Name() string {
+	return t.name
+}
+
+// position converts an offset within the template into a line and column.
+func (t *Template) position(offset int) (line, col int) {
+	before := t.text[:offset]
+	line = strings.Count(before, "\n") + 1
+	col = offset - strings.LastIndexByte(before, '\n')
+	return line, col
+}
+
+func (t *Template) wrapError(offset int, err error) error {
+	line, col := t.position(offset)
+	return &Error{Name: t.name, Line: line, Column: col, Err: err}
+}
+
+// action is a single {{ ... }} block.
+type action struct {
+	pos     int    // offset of the first non-whitespace character within the action
+	keyword string // "if", "else if", "else", "for", "end", or empty for expressions
+	expr    string // expression, or the loop header of for-blocks
+	exprPos int    // offset of expr
+	end     int    // offset after the closing braces
+	trimL   bool   // trim whitespace preceding the action
+	trimR   bool   // trim whitespace following the action
+}
+
+// skip removes the first n bytes of the expression.
+func (a *action) skip(n int) {
+	a.expr = a.expr[n:]
+	a.exprPos += n
+}
+
+type parser struct {
+	tmpl   *Template
+	offset int  // current position within the template text
+	trim   bool // the previous action requested to trim leading whitespace of the following text
+}
+
+const spaces = " \t\r\n"
+
+var forPattern = regexp.MustCompile(`^([A-Za-z_]\w*)(?:\s*,\s*([A-Za-z_]\w*))?\s+in\s`)
+
+func (p *parser) errorf(offset int, format string, args ...interface{}) error {
+	return p.tmpl.wrapError(offset, fmt.Errorf(format, args...))
+}
+
+// parseList parses nodes until the end of the template or a terminating action (else, else if, end).
+// Returns the terminating action, or nil if the end of the template was reached.
+func (p *parser) parseList() ([]node, *action, error) {
+	nodes := make([]node, 0)
+	for {
+		text, act, err := p.next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if text != "" {
+			nodes = append(nodes, textNode(text))
+		}
+		if act == nil {
+			return nodes, nil, nil
+		}
+
+		switch act.keyword {
+		case "else", "else if", "end":
+			return nodes, act, nil
+		case "if":
+			n, err := p.parseIf(act)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, n)
+		case "for":
+			n, err := p.parseFor(act)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, n)
+		default:
+			program, err := p.parseExpr(act.exprPos, act.expr)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, &outputNode{pos: act.pos, program: program})
+		}
+	}
+}
+
+func (p *parser) parseIf(act *action) (node, error) {
+	n := &ifNode{}
+	start := act.pos
+	for {
+		cond, err := p.parseExpr(act.exprPos, act.expr)
+		if err != nil {
+			return nil, err
+		}
+		body, term, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		n.branches = append(n.branches, &branch{pos: act.pos, cond: cond, body: body})
+
+		if term == nil {
+			return nil, p.errorf(start, "unclosed if-block")
+		}
+		switch term.keyword {
+		case "else if":
+			act = term
+			continue
+		case "else":
+			body, end, err := p.parseList()
+			if err != nil {
+				return nil, err
+			}
+			if end == nil {
+				return nil, p.errorf(start, "unclosed if-block")
+			}
+			if end.keyword != "end" {
+				return nil, p.errorf(end.pos, "unexpected {{ %s }} after {{ else }}", end.keyword)
+			}
+			n.elseBody = body
+		}
+		return n, nil
+	}
+}
+
+func (p *parser) parseFor(act *action) (node, error) {
+	header := strings.TrimLeft(act.expr, spaces)
+	headerPos := act.exprPos + len(act.expr) - len(header)
+
+	match := forPattern.FindStringSubmatchIndex(header)
+	if match == nil {
+		return nil, p.errorf(act.pos, "invalid for-block, expected {{ for x in expr }} or {{ for key, value in expr }}")
+	}
+	n := &forNode{
+		pos:  act.pos,
+		vars: []string{header[match[2]:match[3]]},
+	}
+	if match[4] >= 0 {
+		n.vars = append(n.vars, header[match[4]:match[5]])
+	}
+
+	source, err := p.parseExpr(headerPos+match[1], header[match[1]:])
+	if err != nil {
+		return nil, err
+	}
+	n.source = source
+
+	body, term, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if term == nil {
+		return nil, p.errorf(act.pos, "unclosed for-block")
+	}
+	if term.keyword != "end" {
+		return nil, p.errorf(term.pos, "unexpected {{ %s }} within for-block", term.keyword)
+	}
+	n.body = body
+	return n, nil
+}
+
+// parseExpr parses an expression that starts at the given template offset.
+func (p *parser) parseExpr(offset int, src string) (*internal.Program, error) {
+	trimmed := strings.TrimLeft(src, spaces)
+	offset += len(src) - len(trimmed)
+	if strings.TrimSpace(trimmed) == "" {
+		return nil, p.errorf(offset, "missing expression")
+	}
+	program, err := internal.Parse(trimmed)
+	if err != nil {
+		var syntaxErr *internal.SyntaxError
+		if errors.As(err, &syntaxErr) && syntaxErr.Pos > 0 {
+			offset += syntaxErr.Pos - 1 // point to the invalid token
+		}
+		return nil, p.tmpl.wrapError(offset, err)
+	}
+	return program, nil
+}
+
+// next returns the text up to the next action, and the action itself.
+// Returns a nil action if the end of the template was reached.
+func (p *parser) next() (string, *action, error) {
+	text := p.tmpl.text
+	start := strings.Index(text[p.offset:], "{{")
+	if start < 0 {
+		res := p.trimText(text[p.offset:], false)
+		p.offset = len(text)
+		return res, nil, nil
+	}
+	start += p.offset
+
+	act, err := p.scanAction(start)
+	if err != nil {
+		return "", nil, err
+	}
+	res := p.trimText(text[p.offset:start], act.trimL)
+	p.offset = act.end
+	p.trim = act.trimR
+	return res, act, nil
+}
+
+func (p *parser) trimText(text string, trimRight bool) string {
+	if p.trim {
+		text = strings.TrimLeft(text, spaces)
+	}
+	if trimRight {
+		text = strings.TrimRight(text, spaces)
+	}
+	return text
+}
+
+// scanAction scans the action that starts with the opening braces at the given offset.
+func (p *parser) scanAction(start int) (*action, error) {
+	text := p.tmpl.text
+	act := &action{}
+
+	contentStart := start + 2
+	if contentStart+1 < len(text) && text[contentStart] == '-' && isSpace(text[contentStart+1]) {
+		act.trimL = true
+		contentStart++
+	}
+	contentEnd := actionEnd(text, contentStart)
+	if contentEnd < 0 {
+		return nil, p.errorf(start, "unclosed action")
+	}
+	act.end = contentEnd + 2
+	if contentEnd-1 > contentStart && text[contentEnd-1] == '-' && isSpace(text[contentEnd-2]) {
+		act.trimR = true
+		contentEnd--
+	}
+
+	content := text[contentStart:contentEnd]
+	trimmed := strings.TrimSpace(content)
+	act.pos = contentStart + len(content) - len(strings.TrimLeft(content, spaces))
+	act.expr, act.exprPos = trimmed, act.pos
+
+	switch {
+	case trimmed == "else" || trimmed == "end":
+		act.keyword = trimmed
+		act.expr = ""
+	case hasKeyword(trimmed, "else"):
+		rest := strings.TrimLeft(trimmed[len("else"):], spaces)
+		if !hasKeyword(rest, "if") {
+			return nil, p.errorf(act.pos, "unexpected %q after else", rest)
+		}
+		act.keyword = "else if"
+		act.skip(len(trimmed) - len(rest) + len("if"))
+	case hasKeyword(trimmed, "if"):
+		act.keyword = "if"
+		act.skip(len("if"))
+	case hasKeyword(trimmed, "for"):
+		act.keyword = "for"
+		act.skip(len("for"))
+	}
+	return act, nil
+}
+
+// hasKeyword reports whether str is the keyword, or starts with the keyword followed by whitespace.
+func hasKeyword(str, keyword string) bool {
+	if !strings.HasPrefix(str, keyword) {
+		return false
+	}
+	return len(str) == len(keyword) || isSpace(str[len(keyword)])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// actionEnd returns the offset of the closing braces that terminate the action starting at 'start'.
+// Braces within string literals, comments and nested object literals are skipped.
+// Returns -1 if there is none.
+func actionEnd(text string, start int) int {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '"', '`':
+			// skip string literals, which may contain braces
+			for i++; i < len(text) && text[i] != c; i++ {
+				if c == '"' && text[i] == '\\' {
+					i++
+				}
+			}
+		case '/':
+			if strings.HasPrefix(text[i:], "/*") {
+				end := strings.Index(text[i+2:], "*/")
+				if end < 0 {
+					return -1
+				}
+				i += end + 3
+			} else if strings.HasPrefix(text[i:], "//") {
+				// line comments end at the next line break, the action can't end before
+				end := strings.IndexByte(text[i:], '\n')
+				if end < 0 {
+					return -1
+				}
+				i += end
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				if i+1 < len(text) && text[i+1] == '}' {
+					return i
+				}
+				return -1
+			}
+			depth--
+		}
+	}
+	return -1
+}
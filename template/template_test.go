@@ -0,0 +1,189 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/maja42/goval"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestVars() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  "Ann",
+		"count": 3,
+		"admin": true,
+		"items": []interface{}{"apple", "pear"},
+		"prices": map[string]interface{}{
+			"pear":  2,
+			"apple": 1,
+		},
+	}
+}
+
+func assertRender(t *testing.T, expected string, text string) {
+	t.Helper()
+	tmpl, err := Parse("test", text)
+	if !assert.NoError(t, err, "parse: %s", text) {
+		return
+	}
+	functions := map[string]goval.ExpressionFunction{
+		"upper": func(args ...interface{}) (interface{}, error) {
+			return strings.ToUpper(args[0].(string)), nil
+		},
+	}
+	res, err := tmpl.ExecuteString(getTestVars(), functions)
+	if assert.NoError(t, err, "execute: %s", text) {
+		assert.Equal(t, expected, res, "template: %s", text)
+	}
+}
+
+func assertParseError(t *testing.T, expected string, text string) {
+	t.Helper()
+	_, err := Parse("test", text)
+	if assert.Error(t, err, "template: %s", text) {
+		assert.Equal(t, expected, err.Error(), "template: %s", text)
+	}
+}
+
+func assertExecError(t *testing.T, expected string, text string) {
+	t.Helper()
+	tmpl, err := Parse("test", text)
+	if !assert.NoError(t, err, "parse: %s", text) {
+		return
+	}
+	_, err = tmpl.ExecuteString(getTestVars(), nil)
+	if assert.Error(t, err, "template: %s", text) {
+		assert.Equal(t, expected, err.Error(), "template: %s", text)
+	}
+}
+
+func Test_Text(t *testing.T) {
+	assertRender(t, "", "")
+	assertRender(t, "plain text", "plain text")
+	assertRender(t, "single { and } braces", "single { and } braces")
+	assertRender(t, "multi\nline\n", "multi\nline\n")
+}
+
+func Test_Output(t *testing.T) {
+	assertRender(t, "Hello Ann!", "Hello {{ name }}!")
+	assertRender(t, "Hello Ann!", "Hello {{name}}!")
+	assertRender(t, "3 items", "{{ count }} items")
+	assertRender(t, "4.5", "{{ count + 1.5 }}")
+	assertRender(t, "true nil", "{{ admin }} {{ nil }}")
+	assertRender(t, "ANN", "{{ upper(name) }}")
+	assertRender(t, "ANN", "{{ name |> upper() }}")
+	assertRender(t, "Ann has 3", `{{ f"{name} has {count}" }}`)
+	assertRender(t, "2", `{{ {"a": {"b": 2}}.a.b }}`)
+	assertRender(t, "}}", `{{ "}}" }}`)
+	assertRender(t, "1", "{{ 1 /* }} */ }}")
+	assertRender(t, "x 1", "x {{ // }} \n 1 }}")
+	assertRender(t, "2", "{{ 4 / 2 }}")
+	assertRender(t, "AnnAnn", "{{ name }}{{ name }}")
+}
+
+func Test_If(t *testing.T) {
+	assertRender(t, "admin", "{{ if admin }}admin{{ end }}")
+	assertRender(t, "", "{{ if !admin }}admin{{ end }}")
+	assertRender(t, "many", "{{ if count > 2 }}many{{ else }}few{{ end }}")
+	assertRender(t, "few", "{{ if count > 5 }}many{{ else }}few{{ end }}")
+	assertRender(t, "three", "{{ if count == 1 }}one{{ else if count == 2 }}two{{ else if count == 3 }}three{{ else }}more{{ end }}")
+	assertRender(t, "more", "{{ if count == 1 }}one{{ else if count == 2 }}two{{ else }}more{{ end }}")
+	assertRender(t, "", "{{ if count == 1 }}one{{ else if count == 2 }}two{{ end }}")
+	assertRender(t, "yes", "{{ if admin }}{{ if count > 0 }}yes{{ end }}{{ end }}")
+	assertRender(t, "b", "{{if false}}a{{else if true}}b{{end}}")
+}
+
+func Test_For(t *testing.T) {
+	assertRender(t, "apple,pear,", "{{ for x in items }}{{ x }},{{ end }}")
+	assertRender(t, "0=apple 1=pear ", "{{ for i, x in items }}{{ i }}={{ x }} {{ end }}")
+	assertRender(t, "apple pear ", "{{ for k in prices }}{{ k }} {{ end }}")
+	assertRender(t, "apple:1 pear:2 ", "{{ for k, v in prices }}{{ k }}:{{ v }} {{ end }}")
+	assertRender(t, "", "{{ for x in [] }}{{ x }}{{ end }}")
+	assertRender(t, "2 4 ", "{{ for x in [1, 2] }}{{ x * 2 }} {{ end }}")
+	assertRender(t, "a1 a2 b1 b2 ", `{{ for a in ["a", "b"] }}{{ for b in [1, 2] }}{{ a + b }} {{ end }}{{ end }}`)
+	assertRender(t, "pear", "{{ for x in items }}{{ if x != `apple` }}{{ x }}{{ end }}{{ end }}")
+
+	// loop variables shadow other variables only within the loop:
+	assertRender(t, "1 2 Ann", "{{ for name in [1, 2] }}{{ name }} {{ end }}{{ name }}")
+}
+
+func Test_TrimWhitespace(t *testing.T) {
+	assertRender(t, "ab", "a \n {{- `` -}} \n b")
+	assertRender(t, "ab", "a   {{- `` }}b")
+	assertRender(t, "ab", "a{{ `` -}}   \n  b")
+	assertRender(t, "-3", "{{-count}}") // no trimming without space
+	assertRender(t, "2", "{{ count-1 }}")
+
+	text := `
+<ul>
+	{{- for x in items }}
+	<li>{{ x }}</li>
+	{{- end }}
+</ul>`
+	assertRender(t, "\n<ul>\n\t<li>apple</li>\n\t<li>pear</li>\n</ul>", text)
+}
+
+func Test_ParseErrors(t *testing.T) {
+	assertParseError(t, "test:1:7: unclosed action", "Hello {{ name")
+	assertParseError(t, "test:1:7: unclosed action", "Hello {{ name }")
+	assertParseError(t, "test:1:7: unclosed action", "Hello {{ name // }}")
+	assertParseError(t, "test:1:6: missing expression", "a {{ }}")
+	assertParseError(t, "test:2:9: syntax error: unexpected $end", "a\nb {{ 1 + }}")
+	assertParseError(t, "test:1:6: missing expression", "{{ if }}x{{ end }}")
+	assertParseError(t, "test:1:4: unclosed if-block", "{{ if admin }}x")
+	assertParseError(t, "test:1:4: unclosed if-block", "{{ if admin }}x{{ else }}y")
+	assertParseError(t, "test:1:4: unclosed for-block", "{{ for x in items }}x")
+	assertParseError(t, "test:1:4: unexpected {{ end }}", "{{ end }}")
+	assertParseError(t, "test:1:4: unexpected {{ else }}", "{{ else }}")
+	assertParseError(t, "test:1:24: unexpected {{ else }} after {{ else }}", "{{ if a }}{{ else }}{{ else }}{{ end }}")
+	assertParseError(t, "test:1:20: unexpected {{ else }} within for-block", "{{ for x in y }}{{ else }}{{ end }}")
+	assertParseError(t, "test:1:4: unexpected \"x\" after else", "{{ else x }}")
+	assertParseError(t, "test:3:3: invalid for-block, expected {{ for x in expr }} or {{ for key, value in expr }}", "\n\n{{for 1 in items}}{{end}}")
+	assertParseError(t, "test:1:13: syntax error: unexpected ']'", "{{ for x in ] }}{{ end }}")
+	assertParseError(t, "test:1:13: unknown token \"ILLEGAL\" (\"§\") at position 4", "Hello {{ 1 +§ }}")
+	assertParseError(t, "test:3:3: syntax error: unexpected ')'", "a\n{{ 1 +\n  ) }}")
+}
+
+func Test_ExecErrors(t *testing.T) {
+	assertExecError(t, "test:1:10: var error: variable \"unknown\" does not exist", "Hello {{ unknown }}")
+	assertExecError(t, "test:2:4: type error: cannot add or concatenate type string and array", "Hello\n{{ items }}")
+	assertExecError(t, "test:1:4: type error: required bool, but was number", "{{ if count }}x{{ end }}")
+	assertExecError(t, "test:1:18: type error: required bool, but was string", "{{ if false }}{{ else if name }}{{ end }}")
+	assertExecError(t, "test:1:4: type error: cannot iterate over string", "{{ for x in name }}{{ end }}")
+	assertExecError(t, "test:1:24: var error: variable \"y\" does not exist", "{{ for x in items }}{{ y }}{{ end }}")
+	assertExecError(t, "test:1:4: syntax error: no such function \"f\"", "{{ f() }}")
+
+	tmpl := Must(Parse("test", "{{ unknown }}"))
+	_, err := tmpl.ExecuteString(nil, nil)
+	var templateErr *Error
+	if assert.True(t, errors.As(err, &templateErr)) {
+		assert.Equal(t, &Error{
+			Name:   "test",
+			Line:   1,
+			Column: 4,
+			Err:    errors.New("var error: variable \"unknown\" does not exist"),
+		}, templateErr)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("write failed")
+}
+
+func Test_WriteError(t *testing.T) {
+	tmpl := Must(Parse("test", "{{ for x in items }}{{ x }}{{ end }}"))
+	err := tmpl.Execute(failingWriter{}, getTestVars(), nil)
+	assert.EqualError(t, err, "write failed")
+}
+
+func Test_Must(t *testing.T) {
+	assert.Panics(t, func() {
+		Must(Parse("test", "{{"))
+	})
+	assert.Equal(t, "name", Must(Parse("name", "")).Name())
+}
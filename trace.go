@@ -0,0 +1,188 @@
+package goval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maja42/goval/ast"
+	"github.com/maja42/goval/internal"
+)
+
+// Trace records the evaluation of a sub-expression, including all of its evaluated operands.
+//
+// Traces are meant to explain results, for example to find out why a rule evaluated to false.
+// Use String to render a human-readable representation.
+type Trace struct {
+	Node   ast.Node // evaluated sub-expression
+	Source string   // source text of the sub-expression
+	// Op is the operator ("+", "!", "?:", ...) or function name of the sub-expression.
+	// Empty for other nodes, like literals, variables or member access.
+	Op       string
+	Value    interface{} // result; nil if the evaluation failed
+	Err      error       // set if the evaluation of this sub-expression (or one of its operands) failed
+	Children []*Trace    // evaluated operands and function arguments, in evaluation order
+}
+
+// Trace evaluates the given expression string and records every evaluated sub-expression.
+//
+// Accepts the same variables and functions as Evaluate, and returns the same result.
+// The evaluator's options are applied, except for the cache.
+// Tracing is considerably slower than regular evaluation and should only be used to explain results.
+//
+// If evaluation fails, the trace contains all sub-expressions that were evaluated up to that point.
+// The trace is nil if the expression could not be parsed.
+func (e *Evaluator) Trace(str string, variables map[string]interface{}, functions map[string]ExpressionFunction) (result interface{}, trace *Trace, err error) {
+	program, scope, functions, err := e.parse(str, variables, functions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, node, err := program.Trace(scope, functions, e.limits.MaxSteps)
+	if node != nil {
+		trace = newTrace(str, node)
+	}
+	return result, trace, err
+}
+
+func newTrace(str string, node *internal.TraceNode) *Trace {
+	t := &Trace{
+		Node:  node.Node,
+		Op:    operator(node.Node),
+		Value: node.Value,
+		Err:   node.Err,
+	}
+	if pos, end := node.Node.Pos(), node.Node.End(); pos > 0 && end <= len(str)+1 {
+		t.Source = str[pos-1 : end-1]
+	}
+	for _, child := range node.Children {
+		t.Children = append(t.Children, newTrace(str, child))
+	}
+	return t
+}
+
+func operator(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.UnaryExpr:
+		return n.Op
+	case *ast.BinaryExpr:
+		return n.Op
+	case *ast.TernaryExpr:
+		return "?:"
+	case *ast.CallExpr:
+		return n.Func.Name
+	case *ast.PipeExpr:
+		return n.Call.Func.Name
+	}
+	return ""
+}
+
+// String renders the trace as text, with one line per operation.
+//
+// Each line contains the source of an operation, annotated with the values of its operands, and the result:
+//
+//	user.age (17) >= 18 → false
+//
+// Operations within operands are rendered on the following lines, indented below their parents.
+// Literals, variables and member access on them are not rendered separately.
+func (t *Trace) String() string {
+	var lines []string
+	t.render(&lines, 0)
+	return strings.Join(lines, "\n")
+}
+
+func (t *Trace) render(lines *[]string, depth int) {
+	*lines = append(*lines, strings.Repeat("  ", depth)+t.annotated()+" → "+t.result(true))
+	for _, child := range t.Children {
+		if child.operation() {
+			child.render(lines, depth+1)
+		}
+	}
+}
+
+// annotated returns the source, with the values of all operands inserted after them.
+func (t *Trace) annotated() string {
+	// Operands that are evaluated repeatedly (within comprehensions) have no unique value and are not annotated.
+	count := make(map[ast.Node]int)
+	for _, child := range t.Children {
+		count[child.Node]++
+	}
+	var operands []*Trace
+	for _, child := range t.Children {
+		if _, ok := child.Node.(*ast.Literal); !ok && count[child.Node] == 1 {
+			operands = append(operands, child)
+		}
+	}
+	sort.SliceStable(operands, func(i, j int) bool {
+		return operands[i].Node.Pos() < operands[j].Node.Pos()
+	})
+
+	var sb strings.Builder
+	start := t.Node.Pos()
+	cursor := start // position of the next character to copy
+	for _, op := range operands {
+		pos, end := op.Node.Pos(), op.Node.End()
+		if pos < cursor || end-start > len(t.Source) {
+			continue // outside of the parent's source; only happens for manually constructed trees
+		}
+		sb.WriteString(t.Source[cursor-start : pos-start])
+		sb.WriteString(op.Source)
+		sb.WriteString(" (" + op.result(false) + ")")
+		cursor = end
+	}
+	sb.WriteString(t.Source[cursor-start:])
+	return sb.String()
+}
+
+// result formats the value or error of the sub-expression.
+// Error messages are only returned if detailed is set and the error originates from this sub-expression.
+func (t *Trace) result(detailed bool) string {
+	if t.Err == nil {
+		return formatValue(t.Value)
+	}
+	if !detailed {
+		return "error"
+	}
+	for _, child := range t.Children {
+		if child.Err != nil && child.operation() {
+			return "error" // the error is reported on the failing operand's line
+		}
+	}
+	return "error: " + t.Err.Error()
+}
+
+// operation reports whether the sub-expression is rendered on a separate line.
+func (t *Trace) operation() bool {
+	if t.simple() {
+		return false
+	}
+	for _, child := range t.Children {
+		if _, ok := child.Node.(*ast.Literal); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// simple reports whether the sub-expression is a literal, variable or member access on them.
+func (t *Trace) simple() bool {
+	switch t.Node.(type) {
+	case *ast.Literal, *ast.Ident:
+		return true
+	case *ast.SelectorExpr, *ast.IndexExpr:
+		for _, child := range t.Children {
+			if !child.simple() {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func formatValue(val interface{}) string {
+	if data, err := EncodeJSON(val); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(val)
+}
@@ -0,0 +1,137 @@
+package goval
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Trace(t *testing.T) {
+	vars := map[string]interface{}{
+		"user": map[string]interface{}{"age": 17, "roles": []interface{}{"guest"}},
+	}
+	functions := map[string]ExpressionFunction{
+		"max": func(args ...interface{}) (interface{}, error) {
+			if args[0].(int) > args[1].(int) {
+				return args[0], nil
+			}
+			return args[1], nil
+		},
+	}
+
+	result, trace, err := NewEvaluator().Trace(`user.age >= 18 || max(user.age, 16) > 20`, vars, functions)
+	assert.NoError(t, err)
+	assert.Equal(t, false, result)
+
+	assert.Equal(t, "||", trace.Op)
+	assert.Equal(t, `user.age >= 18 || max(user.age, 16) > 20`, trace.Source)
+	assert.Equal(t, false, trace.Value)
+	if !assert.Len(t, trace.Children, 2) {
+		return
+	}
+
+	cmp := trace.Children[0]
+	assert.Equal(t, ">=", cmp.Op)
+	assert.Equal(t, `user.age >= 18`, cmp.Source)
+	if assert.Len(t, cmp.Children, 2) {
+		assert.Equal(t, `user.age`, cmp.Children[0].Source)
+		assert.Equal(t, 17, cmp.Children[0].Value)
+		assert.Equal(t, 18, cmp.Children[1].Value)
+	}
+
+	call := trace.Children[1].Children[0]
+	assert.Equal(t, "max", call.Op)
+	assert.Equal(t, `max(user.age, 16)`, call.Source)
+	assert.Equal(t, 17, call.Value)
+	if assert.Len(t, call.Children, 2) { // arguments
+		assert.Equal(t, 17, call.Children[0].Value)
+		assert.Equal(t, 16, call.Children[1].Value)
+	}
+
+	assert.Equal(t, ""+
+		"user.age >= 18 (false) || max(user.age, 16) > 20 (false) → false\n"+
+		"  user.age (17) >= 18 → false\n"+
+		"  max(user.age, 16) (17) > 20 → false\n"+
+		"    max(user.age (17), 16) → 17", trace.String())
+}
+
+func Test_Trace_Render(t *testing.T) {
+	vars := map[string]interface{}{
+		"items": []interface{}{1, 2, 3},
+		"name":  "Ann",
+	}
+	assertRendering := func(expected string, str string) {
+		t.Helper()
+		_, trace, _ := NewEvaluator().Trace(str, vars, nil)
+		assert.Equal(t, expected, trace.String(), str)
+	}
+
+	assertRendering(`items → [1,2,3]`, `items`)
+	assertRendering(`1 + 2 → 3`, `1 + 2`)
+	assertRendering(""+
+		`(items[0] + 1 (2)) * 2 → 4`+"\n"+
+		`  items[0] (1) + 1 → 2`, `(items[0] + 1) * 2`)
+	assertRendering(""+
+		`name == "Bob" (false) ? "-" : f"Hi {name}" ("Hi Ann") → "Hi Ann"`+"\n"+
+		`  name ("Ann") == "Bob" → false`+"\n"+
+		`  f"Hi {name ("Ann")}" → "Hi Ann"`, `name == "Bob" ? "-" : f"Hi {name}"`)
+	assertRendering(""+ // elements are evaluated repeatedly and rendered once per iteration
+		`[x * 10 for x in items ([1,2,3]) if x != 2] → [10,30]`+"\n"+
+		`  x (1) != 2 → true`+"\n"+
+		`  x (1) * 10 → 10`+"\n"+
+		`  x (2) != 2 → false`+"\n"+
+		`  x (3) != 2 → true`+"\n"+
+		`  x (3) * 10 → 30`, `[x * 10 for x in items if x != 2]`)
+}
+
+func Test_Trace_Errors(t *testing.T) {
+	eval := NewEvaluator()
+
+	_, trace, err := eval.Trace(`1 +`, nil, nil)
+	assert.EqualError(t, err, "syntax error: unexpected $end")
+	assert.Nil(t, trace)
+
+	fail := map[string]ExpressionFunction{
+		"fail": func(args ...interface{}) (interface{}, error) {
+			return nil, errors.New("failed")
+		},
+	}
+	_, trace, err = eval.Trace(`1 + (2 * fail(3)) + missing`, nil, fail)
+	assert.EqualError(t, err, "function error: \"fail\" - failed")
+	assert.Equal(t, ""+
+		"1 + (2 * fail(3)) (error) + missing → error\n"+
+		"  1 + (2 * fail(3) (error)) → error\n"+
+		"    2 * fail(3) (error) → error: function error: \"fail\" - failed", trace.String())
+	assert.Equal(t, err, trace.Err)
+	assert.Nil(t, trace.Value)
+
+	_, trace, err = eval.Trace(`x.y + 1`, map[string]interface{}{"x": 1}, nil)
+	assert.EqualError(t, err, "syntax error: cannot access fields on type number")
+	assert.Equal(t, `x.y (error) + 1 → error: syntax error: cannot access fields on type number`, trace.String())
+}
+
+func Test_Trace_Options(t *testing.T) {
+	eval := NewEvaluator(
+		WithVariables(map[string]interface{}{"x": 1}),
+		WithStrictMode(),
+		WithLimits(Limits{MaxDepth: 2}),
+	)
+
+	result, trace, err := eval.Trace(`x + 1`, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result)
+	assert.Equal(t, `x (1) + 1 → 2`, trace.String())
+
+	_, _, err = eval.Trace(`x`, map[string]interface{}{"x": 2}, nil)
+	assert.EqualError(t, err, "var error: variable \"x\" shadows a default variable")
+	_, _, err = eval.Trace(`[[[x]]]`, nil, nil)
+	assert.EqualError(t, err, "limit error: expression is nested deeper than 2 levels")
+
+	eval = NewEvaluator(WithLimits(Limits{MaxSteps: 100}))
+	_, trace, err = eval.Trace(`[[x + y for y in arr] for x in arr]`, map[string]interface{}{
+		"arr": []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+	}, nil)
+	assert.EqualError(t, err, "limit error: evaluation exceeds 100 steps")
+	assert.Equal(t, err, trace.Err)
+}
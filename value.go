@@ -0,0 +1,41 @@
+package goval
+
+import (
+	"github.com/maja42/goval/internal"
+)
+
+// Value can be implemented by custom types that should be usable within expressions,
+// for example to represent money, IP addresses or semantic versions.
+//
+// On its own, a value can only be passed around and compared for identity.
+// Further operations are supported by implementing the optional interfaces
+// Adder, Subtractor, Comparer, Equaler, FieldAccessor, Indexer, Truther and fmt.Stringer (string concatenation).
+//
+// If an operator is applied to a custom value and a value of a different type,
+// the custom value decides about the result.
+// Errors returned by custom values are reported as `value error`.
+type Value = internal.Value
+
+// Adder is implemented by values that support the `+` operator.
+type Adder = internal.Adder
+
+// Subtractor is implemented by values that support the `-` operator.
+type Subtractor = internal.Subtractor
+
+// Comparer is implemented by values that support the operators `<`, `<=`, `>` and `>=`.
+type Comparer = internal.Comparer
+
+// Equaler is implemented by values that support the operators `==`, `!=` and `in`.
+// Values that don't implement this interface are only equal to themselves.
+type Equaler = internal.Equaler
+
+// FieldAccessor is implemented by values that support field access via `v.field` and `v["field"]`.
+type FieldAccessor = internal.FieldAccessor
+
+// Indexer is implemented by values that support index access via `v[index]`.
+// Takes precedence over FieldAccessor for index access.
+type Indexer = internal.Indexer
+
+// Truther is implemented by values that can be used as booleans,
+// for example as operands of `&&`, `||`, `!` and as conditions of the ternary operator.
+type Truther = internal.Truther